@@ -0,0 +1,44 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"time"
+
+	"v.io/v23/context"
+)
+
+// KeepaliveOpts controls the messages used to keep connections alive and to
+// detect unresponsive peers. It is typically used to prevent NATs and load
+// balancers that drop idle TCP connections from doing so.
+type KeepaliveOpts struct {
+	// Interval, if non-zero, is the maximum interval between the health
+	// checks sent over a connection regardless of its channel timeout.
+	Interval time.Duration
+
+	// MissedThreshold, if non-zero, is the number of consecutive health
+	// checks sent at Interval that may go unanswered before the connection
+	// is closed. It is ignored if Interval is zero.
+	MissedThreshold int
+
+	// TCPPeriod, if non-zero, is the period used for the TCP-level
+	// keepalives of TCP connections in place of the protocol's default.
+	TCPPeriod time.Duration
+}
+
+type keepaliveKey struct{}
+
+// WithKeepalive returns a context that will cause the connections that are
+// dialed, or accepted by listeners that are created, using it to be kept
+// alive as per opts.
+func WithKeepalive(ctx *context.T, opts KeepaliveOpts) *context.T {
+	return context.WithValue(ctx, keepaliveKey{}, opts)
+}
+
+// Keepalive returns the KeepaliveOpts attached to ctx via WithKeepalive.
+func Keepalive(ctx *context.T) KeepaliveOpts {
+	opts, _ := ctx.Value(keepaliveKey{}).(KeepaliveOpts)
+	return opts
+}
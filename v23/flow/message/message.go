@@ -76,6 +76,8 @@ func ReadNoPayload(ctx *context.T, from []byte) (Message, error) {
 		return EnterLameDuck{}.Read(ctx, from)
 	case AckLameDuckType:
 		return AckLameDuck{}.Read(ctx, from)
+	case RekeyType:
+		return Rekey{}.Read(ctx, from)
 	case MultiProxyType:
 		return MultiProxyRequest{}.Read(ctx, from)
 	case ProxyServerType:
@@ -140,6 +142,7 @@ const (
 	ProxyErrorReponseType
 	AuthED25519Type
 	AuthRSAType
+	RekeyType
 )
 
 // setup options.
@@ -150,6 +153,20 @@ const (
 	peerLocalEndpointOption
 	mtuOption
 	sharedTokensOption
+	compressionOption
+	resumableOption
+	resumeIDOption
+	resumeReceivedOption
+	rekeyOption
+	resumeNonceOption
+	resumeProofOption
+)
+
+// compression algorithms, as advertised in the Setup message.
+const (
+	// CompressionFlate indicates support for DEFLATE (RFC 1951) compressed
+	// Data message payloads.
+	CompressionFlate = 1 << iota
 )
 
 // data flags.
@@ -171,6 +188,17 @@ const (
 	// as a side channel. This means that the flow will not be counted
 	// towards the "idleness" of the underlying connection.
 	SideChannelFlag
+	// CompressedFlag, when set on a Data message, indicates that the
+	// Payload has been compressed using an algorithm supported by both
+	// ends of the connection, as advertised in their Setup messages. It is
+	// never set for peers that do not advertise any such algorithms.
+	CompressedFlag
+	// ControlPriorityFlag and BulkPriorityFlag, when set on an OpenFlow
+	// message, indicate the priority of the Flow, which the accepting end
+	// uses for its own writes to the Flow. Neither is set for Flows of
+	// the default priority.
+	ControlPriorityFlag
+	BulkPriorityFlag
 )
 
 // Setup is the first message over the wire.  It negotiates protocol version
@@ -178,12 +206,32 @@ const (
 // New fields to Setup must be added in order of creation. i.e. the order of the fields
 // should not be changed.
 type Setup struct {
-	Versions             version.RPCVersionRange
-	PeerNaClPublicKey    [32]byte
-	PeerRemoteEndpoint   naming.Endpoint
-	PeerLocalEndpoint    naming.Endpoint
-	Mtu                  uint64
-	SharedTokens         uint64
+	Versions           version.RPCVersionRange
+	PeerNaClPublicKey  [32]byte
+	PeerRemoteEndpoint naming.Endpoint
+	PeerLocalEndpoint  naming.Endpoint
+	Mtu                uint64
+	SharedTokens       uint64
+	Compression        uint64 // bitmask of the supported Compression* algorithms.
+	// Resumable indicates support for resuming the connection over a new
+	// network connection should the current one fail.
+	Resumable bool
+	// ResumeID identifies the connection to be resumed. It is set by a
+	// dialer that is resuming a connection rather than creating a new one.
+	ResumeID []byte
+	// ResumeReceived is the number of frames received on the connection
+	// being resumed.
+	ResumeReceived uint64
+	// Rekey indicates support for receiving Rekey messages.
+	Rekey bool
+	// ResumeNonce is the nonce chosen by a dialer that is resuming a
+	// connection.
+	ResumeNonce []byte
+	// ResumeProof proves that the sender of a Setup message resuming a
+	// connection knows the secret shared by the two ends of the connection.
+	// It is set by the dialer and by the acceptor when it agrees to the
+	// resumption, and covers both ends' nonces and ResumeReceived.
+	ResumeProof          []byte
 	uninterpretedOptions []option
 }
 
@@ -234,6 +282,27 @@ func (m Setup) Append(ctx *context.T, data []byte) ([]byte, error) {
 	if m.SharedTokens != 0 {
 		data = appendSetupOption(sharedTokensOption, writeVarUint64(m.SharedTokens, nil), data)
 	}
+	if m.Compression != 0 {
+		data = appendSetupOption(compressionOption, writeVarUint64(m.Compression, nil), data)
+	}
+	if m.Resumable {
+		data = appendSetupOption(resumableOption, writeVarUint64(1, nil), data)
+	}
+	if len(m.ResumeID) > 0 {
+		data = appendSetupOption(resumeIDOption, m.ResumeID, data)
+	}
+	if len(m.ResumeProof) > 0 {
+		data = appendSetupOption(resumeReceivedOption, writeVarUint64(m.ResumeReceived, nil), data)
+	}
+	if m.Rekey {
+		data = appendSetupOption(rekeyOption, writeVarUint64(1, nil), data)
+	}
+	if len(m.ResumeNonce) > 0 {
+		data = appendSetupOption(resumeNonceOption, m.ResumeNonce, data)
+	}
+	if len(m.ResumeProof) > 0 {
+		data = appendSetupOption(resumeProofOption, m.ResumeProof, data)
+	}
 	for _, o := range m.uninterpretedOptions {
 		data = appendSetupOption(o.opt, o.payload, data)
 	}
@@ -286,6 +355,36 @@ func (m Setup) ReadDirect(ctx *context.T, orig []byte) (Setup, error) {
 			} else {
 				return Setup{}, ErrInvalidSetupOption.Errorf(ctx, "setup option: %v failed decoding at field: %v", opt, field)
 			}
+		case compressionOption:
+			if c, _, valid := readVarUint64(payload); valid {
+				m.Compression = c
+			} else {
+				return Setup{}, ErrInvalidSetupOption.Errorf(ctx, "setup option: %v failed decoding at field: %v", opt, field)
+			}
+		case resumableOption:
+			if r, _, valid := readVarUint64(payload); valid {
+				m.Resumable = r != 0
+			} else {
+				return Setup{}, ErrInvalidSetupOption.Errorf(ctx, "setup option: %v failed decoding at field: %v", opt, field)
+			}
+		case resumeIDOption:
+			m.ResumeID = append([]byte(nil), payload...)
+		case resumeReceivedOption:
+			if r, _, valid := readVarUint64(payload); valid {
+				m.ResumeReceived = r
+			} else {
+				return Setup{}, ErrInvalidSetupOption.Errorf(ctx, "setup option: %v failed decoding at field: %v", opt, field)
+			}
+		case rekeyOption:
+			if r, _, valid := readVarUint64(payload); valid {
+				m.Rekey = r != 0
+			} else {
+				return Setup{}, ErrInvalidSetupOption.Errorf(ctx, "setup option: %v failed decoding at field: %v", opt, field)
+			}
+		case resumeNonceOption:
+			m.ResumeNonce = append([]byte(nil), payload...)
+		case resumeProofOption:
+			m.ResumeProof = append([]byte(nil), payload...)
 		default:
 			m.uninterpretedOptions = append(m.uninterpretedOptions, option{opt, payload})
 		}
@@ -758,6 +857,32 @@ func (m HealthCheckResponse) Read(ctx *context.T, data []byte) (Message, error)
 
 func (m HealthCheckResponse) Copy() Message { return m }
 
+// Rekey is sent by a peer that has rotated the key that it uses to encrypt
+// the messages that it sends. It is the last message encrypted using the
+// previous key, which, together with the Salt, is used to derive the new
+// key. It may only be sent to peers that advertise support for it in their
+// Setup message.
+type Rekey struct {
+	Salt []byte
+}
+
+func (m Rekey) Append(ctx *context.T, data []byte) ([]byte, error) {
+	data = append(data, RekeyType)
+	return append(data, m.Salt...), nil
+}
+
+func (m Rekey) Read(ctx *context.T, data []byte) (Message, error) {
+	if len(data) > 0 {
+		m.Salt = data
+	}
+	return m, nil
+}
+
+func (m Rekey) Copy() Message {
+	m.Salt = copyBytes(m.Salt)
+	return m
+}
+
 func copyBytes(b []byte) []byte {
 	if len(b) == 0 {
 		return nil
@@ -78,6 +78,30 @@ func TestSetup(t *testing.T) {
 			Mtu:          1 << 16,
 			SharedTokens: 1 << 20,
 		},
+		message.Setup{
+			Versions:    version.RPCVersionRange{Min: 1, Max: 5},
+			Compression: message.CompressionFlate,
+		},
+		message.Setup{
+			Versions:  version.RPCVersionRange{Min: 1, Max: 5},
+			Resumable: true,
+		},
+		message.Setup{
+			Versions:       version.RPCVersionRange{Min: 1, Max: 5},
+			ResumeID:       []byte("id"),
+			ResumeNonce:    []byte("nonce"),
+			ResumeProof:    []byte("proof"),
+			ResumeReceived: 42,
+		},
+		message.Setup{
+			Versions:       version.RPCVersionRange{Min: 1, Max: 5},
+			ResumeProof:    []byte("proof"),
+			ResumeReceived: 42,
+		},
+		message.Setup{
+			Versions: version.RPCVersionRange{Min: 1, Max: 5},
+			Rekey:    true,
+		},
 		message.Setup{},
 	})
 }
@@ -91,6 +115,15 @@ func TestTearDown(t *testing.T) {
 	})
 }
 
+func TestRekey(t *testing.T) {
+	ctx, shutdown := v23.Init()
+	defer shutdown()
+	testMessages(t, ctx, []message.Message{
+		message.Rekey{Salt: []byte("salt")},
+		message.Rekey{},
+	})
+}
+
 func TestAuth(t *testing.T) {
 	ctx, shutdown := v23.Init()
 	defer shutdown()
@@ -15,6 +15,7 @@
 package flow
 
 import (
+	"fmt"
 	"io"
 	"net"
 	"time"
@@ -282,6 +283,45 @@ type Flow interface {
 	// DisableFragmentation disables fragmentation of the []byte. This is used by
 	// xproxyd.
 	DisableFragmentation()
+
+	// DisableCompression disables compression of the data written to the flow,
+	// eg. for data that is already compressed.
+	DisableCompression()
+
+	// SetPriority sets the priority with which the data written to the flow
+	// is scheduled relative to that of the other flows on the same Conn.
+	// When called on a dialed flow before it is first written to, the
+	// priority is also adopted by the accepted end of the flow.
+	SetPriority(p Priority)
+}
+
+// Priority is the class of the traffic carried by a Flow.
+type Priority int
+
+const (
+	// InteractivePriority is the default priority of a Flow. Its writes are
+	// interleaved with those of BulkPriority flows, but are scheduled more
+	// often when both are waiting to be written.
+	InteractivePriority Priority = iota
+	// ControlPriority is for latency critical flows that carry small
+	// amounts of data. Its writes are scheduled ahead of those of all other
+	// flows.
+	ControlPriority
+	// BulkPriority is for flows that carry large amounts of data whose
+	// latency is not critical.
+	BulkPriority
+)
+
+func (p Priority) String() string {
+	switch p {
+	case InteractivePriority:
+		return "interactive"
+	case ControlPriority:
+		return "control"
+	case BulkPriority:
+		return "bulk"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
 }
 
 // Conn is the connection onto which flows are mulitplexed.
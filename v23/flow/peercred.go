@@ -0,0 +1,43 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flow
+
+import (
+	"v.io/v23/context"
+)
+
+// PeerCredentials are the operating system credentials of the process at the
+// remote end of a connection. They are only available for connections
+// between processes on the same host, such as those over unix domain
+// sockets, for which they are reported by the kernel (eg. via SO_PEERCRED)
+// and hence cannot be forged by the peer.
+type PeerCredentials struct {
+	UID, GID, PID int
+}
+
+// PeerCredentialer is implemented by Conns, and the ManagedConns created
+// directly over them, for which the PeerCredentials of the remote process
+// may be known.
+type PeerCredentialer interface {
+	// PeerCredentials returns the credentials of the remote process and
+	// true, or false if they are not known.
+	PeerCredentials() (PeerCredentials, bool)
+}
+
+type peerCredentialsKey struct{}
+
+// WithPeerCredentials returns a context that records the credentials of
+// the remote process for a call. It is used by RPC servers to make them
+// available to authorizers.
+func WithPeerCredentials(ctx *context.T, creds PeerCredentials) *context.T {
+	return context.WithValue(ctx, peerCredentialsKey{}, creds)
+}
+
+// GetPeerCredentials returns the PeerCredentials attached to ctx via
+// WithPeerCredentials and true, or false if there are none.
+func GetPeerCredentials(ctx *context.T) (PeerCredentials, bool) {
+	creds, ok := ctx.Value(peerCredentialsKey{}).(PeerCredentials)
+	return creds, ok
+}
@@ -75,6 +75,34 @@ type T interface {
 	//	}
 	Glob(ctx *context.T, pattern string, opts ...naming.NamespaceOpt) (<-chan naming.GlobReply, error)
 
+	// Watch returns the names that match the pattern, named as Glob would
+	// name them, followed by a Change every time a matching name is mounted,
+	// unmounted, expires or has its permissions changed.  Unlike Glob, Watch
+	// only descends into mount tables.  Mount tables that don't support
+	// watching are polled with Glob.
+	//
+	// The returned channel is closed once ctx is done, and must be drained
+	// until then.
+	//
+	// Example:
+	//	ctx, cancel := context.WithCancel(ctx)
+	//	defer cancel()
+	//	changes, err := ns.Watch(ctx, pattern)
+	//	if err != nil {
+	//		boom(t, "Watch(%s): %s", pattern, err)
+	//	}
+	//	for c := range changes {
+	//		switch {
+	//		case c.Error != nil:
+	//			fmt.Fprintf(stderr, "%s can't be watched: %s\n", c.Name, c.Error)
+	//		case c.Entry == nil:
+	//			fmt.Printf("%s removed\n", c.Name)
+	//		default:
+	//			fmt.Printf("%s: %v\n", c.Name, c.Entry.Servers)
+	//		}
+	//	}
+	Watch(ctx *context.T, pattern string, opts ...naming.NamespaceOpt) (<-chan Change, error)
+
 	// SetRoots sets the roots that the local Namespace is
 	// relative to. All relative names passed to the methods above
 	// will be interpreted as relative to these roots. The roots
@@ -95,3 +123,15 @@ type T interface {
 	// GetPermissions returns the Permissions in a node in a mount table.
 	GetPermissions(ctx *context.T, name string, opts ...naming.NamespaceOpt) (perms access.Permissions, version string, err error)
 }
+
+// Change describes a change to a name returned by T.Watch.
+type Change struct {
+	// Name is the name that changed.
+	Name string
+	// Entry is the current mount entry for Name, as Glob would return it,
+	// or nil if Name no longer exists or Error is set.
+	Entry *naming.MountEntry
+	// Error, if not nil, is why the part of the namespace at Name can't
+	// be watched at the moment.  Watch keeps trying to watch it.
+	Error error
+}
@@ -54,8 +54,11 @@ package options
 import (
 	"time"
 
+	"v.io/v23/flow"
 	"v.io/v23/naming"
+	"v.io/v23/rpc"
 	"v.io/v23/security"
+	"v.io/v23/verror"
 )
 
 // ServerPeers is the set of peers to whom a process (a "server") accepting
@@ -146,3 +149,140 @@ func (ChannelTimeout) RPCServerOpt() {}
 type ConnectionTimeout time.Duration
 
 func (ConnectionTimeout) RPCCallOpt() {}
+
+// Priority specifies the priority of the flow used for an RPC, which
+// determines how its data is scheduled relative to that of the other RPCs
+// that share the same network connection. See flow.Priority.
+type Priority flow.Priority
+
+func (Priority) RPCCallOpt() {}
+
+// Keepalive specifies the keepalive behaviour of any connection that is
+// dialed to make an RPC. It has no effect on existing connections. See
+// flow.KeepaliveOpts.
+type Keepalive flow.KeepaliveOpts
+
+func (Keepalive) RPCCallOpt() {}
+
+// ServerInterceptors specifies the chains of interceptors to be invoked
+// around every call to a server. Interceptors are invoked in the order in
+// which they appear, that is, the first interceptor is the outermost. If
+// multiple ServerInterceptors options are provided their chains are
+// concatenated.
+type ServerInterceptors struct {
+	Unary  []rpc.UnaryServerInterceptor
+	Stream []rpc.StreamServerInterceptor
+}
+
+func (ServerInterceptors) RPCServerOpt() {}
+
+// ClientInterceptors specifies the chains of interceptors to be invoked
+// around every call made by a client: the Unary chain is invoked around
+// Client.Call and the Stream chain around Client.StartCall. Interceptors are
+// invoked in the order in which they appear and if multiple
+// ClientInterceptors options are provided their chains are concatenated.
+type ClientInterceptors struct {
+	Unary  []rpc.UnaryClientInterceptor
+	Stream []rpc.StreamClientInterceptor
+}
+
+func (ClientInterceptors) RPCClientOpt() {}
+
+// LoadBalancer specifies the policy used by a client to choose amongst the
+// servers that a name resolves to. If this option is not provided the
+// client prefers servers by protocol and network locality alone.
+type LoadBalancer struct{ rpc.LoadBalancer }
+
+func (LoadBalancer) RPCClientOpt() {}
+
+// HedgedRequest specifies that Client.Call may send a second copy of a call
+// to a method tagged with access.Read to another of the servers that the
+// name resolves to, if the first has not completed within a delay. The
+// result of whichever copy completes first is used and the other is
+// canceled.
+//
+// The delay is the Percentile (e.g. 95) of the latencies recently observed
+// by the client for the method, or Delay until sufficient latencies have
+// been observed. Delay is also used as a lower bound on the delay.
+type HedgedRequest struct {
+	Percentile float64
+	Delay      time.Duration
+}
+
+func (HedgedRequest) RPCCallOpt() {}
+
+// RetryPolicy specifies how a client retries calls that fail with a
+// retryable error. When specified as a ClientOpt it applies to all calls made
+// by the client, when specified as a CallOpt it replaces the client's policy
+// for that call. Retries are never attempted beyond the deadline of the call
+// or when NoRetry is specified.
+//
+// A call is attempted at most MaxAttempts times, or until its deadline when
+// MaxAttempts is zero. Attempts to connect to the servers for a call count
+// towards the same limit as attempts to make the call itself. The delay
+// before the n'th retry is InitialBackoff * 2^n, capped at MaxBackoff, with
+// up to Jitter (a fraction between 0 and 1) of that delay added at random to
+// deter retry convoys.
+//
+// Errors returned by servers are retried if their verror.ActionCode is one of
+// Actions or their verror.ID is one of IDs. If neither is specified, errors
+// are retried based on their action codes as described in the documentation
+// for verror. Errors encountered while connecting to servers are retried if
+// their action code is verror.RetryConnection or verror.RetryRefetch, as well
+// as if they match Actions or IDs.
+//
+// Methods contains per-method overrides, keyed by method name, that replace
+// the policy for calls to those methods.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Jitter         float64
+	Actions        []verror.ActionCode
+	IDs            []verror.ID
+	Methods        map[string]RetryPolicy
+}
+
+func (RetryPolicy) RPCClientOpt() {}
+func (RetryPolicy) RPCCallOpt()   {}
+
+// ConcurrencyLimits limits the number of calls that a server executes
+// concurrently. Calls in excess of MaxInFlight, or of the per-method limit
+// in MethodMaxInFlight, wait in a queue of at most MaxQueued calls for at
+// most MaxQueueDelay. Calls that cannot be queued, or that wait too long,
+// are rejected with an error whose action code is verror.RetryBackoff so
+// that clients back off before retrying them. Once every call for the last
+// 100ms has waited for longer than TargetQueueDelay, calls that would have
+// to wait are rejected immediately until a call waits for less than
+// TargetQueueDelay.
+//
+// Zero values mean no limit, except that MaxQueued of zero means that calls
+// are not queued at all and TargetQueueDelay of zero means half of
+// MaxQueueDelay. Calls to reserved methods are not limited.
+type ConcurrencyLimits struct {
+	MaxInFlight       int
+	MethodMaxInFlight map[string]int
+	MaxQueued         int
+	MaxQueueDelay     time.Duration
+	TargetQueueDelay  time.Duration
+}
+
+func (ConcurrencyLimits) RPCServerOpt() {}
+
+// CircuitBreaker enables per-endpoint circuit breakers in a client. Once at
+// least MinRequests calls to an endpoint have been made within Window, and
+// the fraction of them that failed to connect or timed out reaches
+// FailureRate, the endpoint's breaker opens and the endpoint is not used for
+// OpenDuration. After that, a single trial call is allowed to the endpoint:
+// if it succeeds the breaker closes, otherwise it opens again.
+//
+// Zero values are replaced by defaults of 0.5 for FailureRate, 5 for
+// MinRequests, 10 seconds for Window and 5 seconds for OpenDuration.
+type CircuitBreaker struct {
+	FailureRate  float64
+	MinRequests  int
+	Window       time.Duration
+	OpenDuration time.Duration
+}
+
+func (CircuitBreaker) RPCClientOpt() {}
@@ -0,0 +1,47 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"v.io/v23/context"
+)
+
+// ServerHandler invokes the method being intercepted on the server. The
+// args are the decoded positional arguments for the method and the results
+// are the positional results that will be returned to the client.
+type ServerHandler func(ctx *context.T, call StreamServerCall, args []interface{}) ([]interface{}, error)
+
+// UnaryServerInterceptor is invoked around every call of a non-streaming
+// method on a server once the call has been authorized. The method name,
+// suffix and security state are available via call, and args holds the
+// decoded arguments. An interceptor must call handler to continue the
+// chain, or return an error to the client without doing so.
+type UnaryServerInterceptor func(ctx *context.T, call StreamServerCall, args []interface{}, handler ServerHandler) ([]interface{}, error)
+
+// StreamServerHandler invokes the streaming method being intercepted on the
+// server, using stream to send and receive the items on the stream.
+type StreamServerHandler func(ctx *context.T, call ServerCall, stream Stream, args []interface{}) ([]interface{}, error)
+
+// StreamServerInterceptor is invoked around every call of a streaming method
+// on a server once the call has been authorized. An interceptor may pass a
+// wrapped stream to handler in order to observe or modify the items sent or
+// received on the stream.
+type StreamServerInterceptor func(ctx *context.T, call ServerCall, stream Stream, args []interface{}, handler StreamServerHandler) ([]interface{}, error)
+
+// ClientInvoker makes a synchronous call as per Client.Call.
+type ClientInvoker func(ctx *context.T, name, method string, inArgs, outArgs []interface{}, opts ...CallOpt) error
+
+// UnaryClientInterceptor is invoked around every Client.Call. An interceptor
+// must call invoker to continue the chain, or return an error without
+// doing so.
+type UnaryClientInterceptor func(ctx *context.T, name, method string, inArgs, outArgs []interface{}, invoker ClientInvoker, opts ...CallOpt) error
+
+// ClientStarter starts an asynchronous call as per Client.StartCall.
+type ClientStarter func(ctx *context.T, name, method string, args []interface{}, opts ...CallOpt) (ClientCall, error)
+
+// StreamClientInterceptor is invoked around every Client.StartCall. An
+// interceptor may return a wrapped ClientCall in order to observe the
+// items sent or received on the stream and the final call to Finish.
+type StreamClientInterceptor func(ctx *context.T, name, method string, args []interface{}, starter ClientStarter, opts ...CallOpt) (ClientCall, error)
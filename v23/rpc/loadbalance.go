@@ -0,0 +1,30 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/naming"
+)
+
+// LoadBalancer is a client-side load balancing policy that determines the
+// order in which a client attempts the servers that a name resolves to.
+// Implementations must be safe for concurrent use.
+type LoadBalancer interface {
+	// Name returns the name of the policy, it is used to identify the
+	// policy in exported stats.
+	Name() string
+
+	// Order returns the servers that name resolved to in the order in which
+	// they should be tried. The supplied servers are already filtered and
+	// ordered by the client's protocol and network locality preferences
+	// and may be reordered in place.
+	Order(ctx *context.T, name string, servers []naming.MountedServer) []naming.MountedServer
+
+	// Selected is called with the server, and the connection to it, that
+	// was selected for a call.
+	Selected(server string, conn flow.ManagedConn)
+}
@@ -0,0 +1,34 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"v.io/v23/context"
+)
+
+type outgoingMetadataKey struct{}
+
+// WithOutgoingMetadata returns a context that will attach the supplied
+// key/value pairs to the Metadata of every RPC request made using it. The
+// pairs are merged with any metadata already attached to ctx, with the
+// values supplied here taking precedence. Metadata is only sent to servers
+// that support RPCVersion15 or later.
+func WithOutgoingMetadata(ctx *context.T, md map[string]string) *context.T {
+	merged := make(map[string]string, len(md))
+	for k, v := range OutgoingMetadata(ctx) {
+		merged[k] = v
+	}
+	for k, v := range md {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, outgoingMetadataKey{}, merged)
+}
+
+// OutgoingMetadata returns the metadata attached to ctx via
+// WithOutgoingMetadata. The returned map must not be modified.
+func OutgoingMetadata(ctx *context.T) map[string]string {
+	md, _ := ctx.Value(outgoingMetadataKey{}).(map[string]string)
+	return md
+}
@@ -79,6 +79,10 @@ type ClientCall interface {
 
 	// Security returns the security-related state associated with the call.
 	Security() security.Call
+
+	// ResponseMetadata returns the metadata set by the server in its
+	// response to the call. It is only valid after Finish has returned.
+	ResponseMetadata() map[string]string
 }
 
 // Stream defines the interface for a bidirectional FIFO stream of typed values.
@@ -168,6 +172,10 @@ type ListenSpec struct {
 	// The address chooser to use for determining preferred publishing
 	// addresses.
 	AddressChooser
+
+	// Keepalive specifies the keepalive behaviour of the connections
+	// accepted by the listeners created for this ListenSpec.
+	Keepalive flow.KeepaliveOpts
 }
 
 func (l ListenSpec) String() string {
@@ -204,6 +212,15 @@ type Server interface {
 
 	// Closed returns a channel that will be closed after the server is shut down.
 	Closed() <-chan struct{}
+
+	// SetHealth sets the health of the object served under suffix, as
+	// reported by the reserved __Health and __WatchHealth methods. The
+	// health of the empty suffix applies to all objects whose health has not
+	// been set. All objects are reported as HealthStatusLameDuck once the
+	// server starts shutting down. The health of suffixes for which no
+	// object is served, and whose health has not been set, is reported as
+	// an error with verror.ErrUnknownSuffix.
+	SetHealth(suffix string, status HealthStatus)
 }
 
 // ServerState represents the 'state' of the Server.
@@ -545,6 +562,13 @@ type ServerCall interface {
 	GrantedBlessings() security.Blessings
 	// Server returns the Server that this context is associated with.
 	Server() Server
+	// Metadata returns the metadata attached to the request by the client,
+	// see WithOutgoingMetadata. The returned map must not be modified.
+	Metadata() map[string]string
+	// SetResponseMetadata sets the metadata to be returned to the client
+	// in the response to this call. The metadata is dropped for clients
+	// that do not support RPCVersion15 or later.
+	SetResponseMetadata(md map[string]string)
 }
 
 // CallOpt is the interface for all Call options.
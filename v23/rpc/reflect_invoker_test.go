@@ -41,6 +41,8 @@ var _ rpc.StreamServerCall = (*FakeStreamServerCall)(nil)
 
 func (*FakeStreamServerCall) Server() rpc.Server                              { return nil }
 func (*FakeStreamServerCall) GrantedBlessings() security.Blessings            { return security.Blessings{} }
+func (*FakeStreamServerCall) Metadata() map[string]string                     { return nil }
+func (*FakeStreamServerCall) SetResponseMetadata(map[string]string)           {}
 func (*FakeStreamServerCall) Closed() <-chan struct{}                         { return nil }
 func (*FakeStreamServerCall) IsClosed() bool                                  { return false }
 func (*FakeStreamServerCall) Send(item interface{}) error                     { return nil }
@@ -7,6 +7,8 @@
 package reserved
 
 import (
+	"io"
+
 	v23 "v.io/v23"
 	"v.io/v23/context"
 	"v.io/v23/rpc"
@@ -35,3 +37,51 @@ func MethodSignature(ctx *context.T, name, method string, opts ...rpc.CallOpt) (
 	}
 	return sig, nil
 }
+
+// Health invokes the reserved health RPC on the given name, and returns the
+// results.
+func Health(ctx *context.T, name string, opts ...rpc.CallOpt) (rpc.HealthStatus, error) {
+	var status rpc.HealthStatus
+	res := []interface{}{&status}
+	if err := v23.GetClient(ctx).Call(ctx, name, rpc.ReservedHealth, nil, res, opts...); err != nil {
+		return status, err
+	}
+	return status, nil
+}
+
+// WatchHealth invokes the reserved watch health RPC on the given name. The
+// returned channel receives the current health of the object and then every
+// change to it, and is closed when the stream ends, which happens once the
+// server starts shutting down or ctx is canceled. The error, if any, that
+// ended the stream is then available from the returned function.
+func WatchHealth(ctx *context.T, name string, opts ...rpc.CallOpt) (<-chan rpc.HealthStatus, func() error, error) {
+	call, err := v23.GetClient(ctx).StartCall(ctx, name, rpc.ReservedWatchHealth, nil, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch := make(chan rpc.HealthStatus)
+	done := make(chan struct{})
+	var result error
+	go func() {
+		defer close(done)
+		defer close(ch)
+		for {
+			var status rpc.HealthStatus
+			if err := call.Recv(&status); err != nil {
+				if err == io.EOF {
+					result = call.Finish()
+				} else {
+					result = err
+				}
+				return
+			}
+			select {
+			case ch <- status:
+			case <-ctx.Done():
+				result = ctx.Err()
+				return
+			}
+		}
+	}()
+	return ch, func() error { <-done; return result }, nil
+}
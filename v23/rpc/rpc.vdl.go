@@ -9,6 +9,8 @@
 package rpc
 
 import (
+	"fmt"
+
 	"v.io/v23/security"
 	"v.io/v23/vdl"
 	vdltime "v.io/v23/vdlroot/time"
@@ -30,8 +32,10 @@ var (
 	vdlTypeStruct2 *vdl.Type = nil
 	vdlTypeStruct3 *vdl.Type = nil
 	vdlTypeStruct4 *vdl.Type = nil
-	vdlTypeStruct5 *vdl.Type = nil
+	vdlTypeMap5    *vdl.Type = nil
 	vdlTypeStruct6 *vdl.Type = nil
+	vdlTypeStruct7 *vdl.Type = nil
+	vdlTypeEnum8   *vdl.Type = nil
 )
 
 // Type definitions
@@ -72,6 +76,10 @@ type Request struct {
 	// By convention it should be an IETF language tag:
 	// http://en.wikipedia.org/wiki/IETF_language_tag
 	Language string
+	// Metadata is a set of key/value pairs attached to the request by the
+	// client, e.g. request or tenant IDs. It is only sent to servers that
+	// support RPCVersion15 or later.
+	Metadata map[string]string
 }
 
 func (Request) VDLReflect(struct {
@@ -104,6 +112,9 @@ func (x Request) VDLIsZero() bool { //nolint:gocyclo
 	if x.Language != "" {
 		return false
 	}
+	if len(x.Metadata) != 0 {
+		return false
+	}
 	return true
 }
 
@@ -168,12 +179,41 @@ func (x Request) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
 			return err
 		}
 	}
+	if len(x.Metadata) != 0 {
+		if err := enc.NextField(8); err != nil {
+			return err
+		}
+		if err := vdlWriteAnonMap1(enc, x.Metadata); err != nil {
+			return err
+		}
+	}
 	if err := enc.NextField(-1); err != nil {
 		return err
 	}
 	return enc.FinishValue()
 }
 
+func vdlWriteAnonMap1(enc vdl.Encoder, x map[string]string) error {
+	if err := enc.StartValue(vdlTypeMap5); err != nil {
+		return err
+	}
+	if err := enc.SetLenHint(len(x)); err != nil {
+		return err
+	}
+	for key, elem := range x {
+		if err := enc.NextEntryValueString(vdl.StringType, key); err != nil {
+			return err
+		}
+		if err := enc.WriteValueString(vdl.StringType, elem); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextEntry(true); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
 func (x *Request) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 	*x = Request{}
 	if err := dec.StartValue(vdlTypeStruct1); err != nil {
@@ -253,6 +293,41 @@ func (x *Request) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 			default:
 				x.Language = value
 			}
+		case 8:
+			if err := vdlReadAnonMap1(dec, &x.Metadata); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func vdlReadAnonMap1(dec vdl.Decoder, x *map[string]string) error {
+	if err := dec.StartValue(vdlTypeMap5); err != nil {
+		return err
+	}
+	var tmpMap map[string]string
+	if len := dec.LenHint(); len > 0 {
+		tmpMap = make(map[string]string, len)
+	}
+	for {
+		switch done, key, err := dec.NextEntryValueString(); {
+		case err != nil:
+			return err
+		case done:
+			*x = tmpMap
+			return dec.FinishValue()
+		default:
+			var elem string
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				elem = value
+			}
+			if tmpMap == nil {
+				tmpMap = make(map[string]string)
+			}
+			tmpMap[key] = elem
 		}
 	}
 }
@@ -277,6 +352,9 @@ type Response struct {
 	// AckBlessings is true if the server successfully recevied the client's
 	// blessings and stored them in the server's blessings cache.
 	AckBlessings bool
+	// Metadata is a set of key/value pairs attached to the response by the
+	// server. It is only sent to clients that support RPCVersion15 or later.
+	Metadata map[string]string
 }
 
 func (Response) VDLReflect(struct {
@@ -300,11 +378,14 @@ func (x Response) VDLIsZero() bool { //nolint:gocyclo
 	if x.AckBlessings {
 		return false
 	}
+	if len(x.Metadata) != 0 {
+		return false
+	}
 	return true
 }
 
 func (x Response) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
-	if err := enc.StartValue(vdlTypeStruct5); err != nil {
+	if err := enc.StartValue(vdlTypeStruct6); err != nil {
 		return err
 	}
 	if x.Error != nil {
@@ -338,6 +419,14 @@ func (x Response) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
 			return err
 		}
 	}
+	if len(x.Metadata) != 0 {
+		if err := enc.NextField(5); err != nil {
+			return err
+		}
+		if err := vdlWriteAnonMap1(enc, x.Metadata); err != nil {
+			return err
+		}
+	}
 	if err := enc.NextField(-1); err != nil {
 		return err
 	}
@@ -346,7 +435,7 @@ func (x Response) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
 
 func (x *Response) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 	*x = Response{}
-	if err := dec.StartValue(vdlTypeStruct5); err != nil {
+	if err := dec.StartValue(vdlTypeStruct6); err != nil {
 		return err
 	}
 	decType := dec.Type()
@@ -358,8 +447,8 @@ func (x *Response) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 		case index == -1:
 			return dec.FinishValue()
 		}
-		if decType != vdlTypeStruct5 {
-			index = vdlTypeStruct5.FieldIndexByName(decType.Field(index).Name)
+		if decType != vdlTypeStruct6 {
+			index = vdlTypeStruct6.FieldIndexByName(decType.Field(index).Name)
 			if index == -1 {
 				if err := dec.SkipValue(); err != nil {
 					return err
@@ -397,8 +486,92 @@ func (x *Response) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 			default:
 				x.AckBlessings = value
 			}
+		case 5:
+			if err := vdlReadAnonMap1(dec, &x.Metadata); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// HealthStatus is the health of an object served by a server, as reported
+// by the reserved __Health and __WatchHealth methods.
+type HealthStatus int
+
+const (
+	HealthStatusServing HealthStatus = iota
+	HealthStatusNotServing
+	HealthStatusLameDuck
+)
+
+// HealthStatusAll holds all labels for HealthStatus.
+var HealthStatusAll = [...]HealthStatus{HealthStatusServing, HealthStatusNotServing, HealthStatusLameDuck}
+
+// HealthStatusFromString creates a HealthStatus from a string label.
+//
+//nolint:unused
+func HealthStatusFromString(label string) (x HealthStatus, err error) {
+	err = x.Set(label)
+	return
+}
+
+// Set assigns label to x.
+func (x *HealthStatus) Set(label string) error {
+	switch label {
+	case "Serving", "serving":
+		*x = HealthStatusServing
+		return nil
+	case "NotServing", "notserving":
+		*x = HealthStatusNotServing
+		return nil
+	case "LameDuck", "lameduck":
+		*x = HealthStatusLameDuck
+		return nil
+	}
+	*x = -1
+	return fmt.Errorf("unknown label %q in rpc.HealthStatus", label)
+}
+
+// String returns the string label of x.
+func (x HealthStatus) String() string {
+	switch x {
+	case HealthStatusServing:
+		return "Serving"
+	case HealthStatusNotServing:
+		return "NotServing"
+	case HealthStatusLameDuck:
+		return "LameDuck"
+	}
+	return ""
+}
+
+func (HealthStatus) VDLReflect(struct {
+	Name string `vdl:"v.io/v23/rpc.HealthStatus"`
+	Enum struct{ Serving, NotServing, LameDuck string }
+}) {
+}
+
+func (x HealthStatus) VDLIsZero() bool { //nolint:gocyclo
+	return x == HealthStatusServing
+}
+
+func (x HealthStatus) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.WriteValueString(vdlTypeEnum8, x.String()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (x *HealthStatus) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	switch value, err := dec.ReadValueString(); {
+	case err != nil:
+		return err
+	default:
+		if err := x.Set(value); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
 // Const definitions
@@ -408,6 +581,8 @@ func (x *Response) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 const GlobMethod = "__Glob"
 const ReservedSignature = "__Signature"
 const ReservedMethodSignature = "__MethodSignature"
+const ReservedHealth = "__Health"
+const ReservedWatchHealth = "__WatchHealth"
 
 // initializeVDL performs vdl initialization.  It is safe to call multiple times.
 // If you have an init ordering issue, just insert the following line verbatim
@@ -431,14 +606,17 @@ func initializeVDL() struct{} {
 	// Register types.
 	vdl.Register((*Request)(nil))
 	vdl.Register((*Response)(nil))
+	vdl.Register((*HealthStatus)(nil))
 
 	// Initialize type definitions.
 	vdlTypeStruct1 = vdl.TypeOf((*Request)(nil)).Elem()
 	vdlTypeStruct2 = vdl.TypeOf((*vdltime.WireDeadline)(nil)).Elem()
 	vdlTypeStruct3 = vdl.TypeOf((*security.WireBlessings)(nil)).Elem()
 	vdlTypeStruct4 = vdl.TypeOf((*vtrace.Request)(nil)).Elem()
-	vdlTypeStruct5 = vdl.TypeOf((*Response)(nil)).Elem()
-	vdlTypeStruct6 = vdl.TypeOf((*vtrace.Response)(nil)).Elem()
+	vdlTypeMap5 = vdl.TypeOf((*map[string]string)(nil))
+	vdlTypeStruct6 = vdl.TypeOf((*Response)(nil)).Elem()
+	vdlTypeStruct7 = vdl.TypeOf((*vtrace.Response)(nil)).Elem()
+	vdlTypeEnum8 = vdl.TypeOf((*HealthStatus)(nil))
 
 	return struct{}{}
 }
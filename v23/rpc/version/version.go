@@ -48,10 +48,14 @@ const (
 	// connection setup.
 	RPCVersion14
 
+	// RPCVersion15 adds metadata to the request and response headers of
+	// RPCs.
+	RPCVersion15
+
 	// Placeholder for the forthcoming RPC reimplementation. At the very
 	// least the mechanism for how borrowed flow control tokens are
 	// returned to the dialer will change.
-	RPCVersion15
+	RPCVersion16
 )
 
 // RPCVersionRange allows you to optionally specify a range of versions to
@@ -20,6 +20,7 @@ Usage:
 The namespace commands are:
 
 	glob        Returns all matching entries from the namespace
+	watch       Prints changes to matching entries in the namespace
 	mount       Adds a server to the namespace
 	unmount     Removes a server from the namespace
 	resolve     Translates a object name to its object address(es)
@@ -53,6 +54,13 @@ The global flags are:
 	  log level for V logs
 	-v23.credentials=
 	  directory to use for storing security credentials
+	-v23.dial.no-proxy=$NO_PROXY
+	  comma separated list of the destinations that are to be dialed directly
+	  rather than via --v23.dial.proxy
+	-v23.dial.proxy=$ALL_PROXY or $HTTPS_PROXY
+	  egress proxy to use for outbound connections, one of socks5://, socks5h://,
+	  http:// or https://[user:password@]host:port, an http proxy is assumed if
+	  no scheme is given
 	-v23.permissions.file=
 	  specify a perms file as <name>:<permsfile>
 	-v23.permissions.literal=
@@ -132,6 +140,21 @@ The namespace glob flags are:
 	-l=false
 	  Long listing format.
 
+# Namespace watch - Prints changes to matching entries in the namespace
+
+Prints the matching entries in the namespace and then every change to them until
+interrupted.
+
+Usage:
+
+	namespace watch [flags] <pattern>
+
+<pattern> is a glob pattern that is matched against all the names below the
+specified mount name.
+
+Each line printed starts with + followed by the name and its servers when the
+name is added or its servers change, or - and the name when it is removed.
+
 # Namespace mount - Adds a server to the namespace
 
 Adds server <server> to the namespace with name <name>.
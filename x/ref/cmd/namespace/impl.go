@@ -141,6 +141,51 @@ func runGlob(ctx *context.T, env *cmdline.Env, args []string) error {
 	return handleErrors(successes, errors)
 }
 
+var cmdWatch = &cmdline.Command{
+	Runner:   v23cmd.RunnerFunc(runWatch),
+	Name:     "watch",
+	Short:    "Prints changes to matching entries in the namespace",
+	Long:     "Prints the matching entries in the namespace and then every change to them until interrupted.",
+	ArgsName: "<pattern>",
+	ArgsLong: `
+<pattern> is a glob pattern that is matched against all the names below the
+specified mount name.
+
+Each line printed starts with + followed by the name and its servers when the
+name is added or its servers change, or - and the name when it is removed.
+`,
+}
+
+func runWatch(ctx *context.T, env *cmdline.Env, args []string) error {
+	if expected, got := 1, len(args); expected != got {
+		return env.UsageErrorf("watch: incorrect number of arguments, expected %d, got %d", expected, got)
+	}
+	pattern := args[0]
+
+	ns := v23.GetNamespace(ctx)
+
+	c, err := ns.Watch(ctx, pattern)
+	if err != nil {
+		ctx.Infof("ns.Watch(%q) failed: %v", pattern, err)
+		return err
+	}
+	for change := range c {
+		switch {
+		case change.Error != nil:
+			fmt.Fprintf(env.Stderr, "%s: %v\n", change.Name, change.Error)
+		case change.Entry == nil:
+			fmt.Fprintf(env.Stdout, "- %s\n", change.Name)
+		default:
+			fmt.Fprintf(env.Stdout, "+ %s", change.Name)
+			for _, s := range change.Entry.Servers {
+				fmt.Fprintf(env.Stdout, " %s", s.Server)
+			}
+			fmt.Fprintln(env.Stdout)
+		}
+	}
+	return nil
+}
+
 var cmdMount = &cmdline.Command{
 	Runner:   v23cmd.RunnerFunc(runMount),
 	Name:     "mount",
@@ -415,5 +460,5 @@ command line option or from environment variables that have a name starting
 with V23_NAMESPACE, e.g.  V23_NAMESPACE, V23_NAMESPACE_2, V23_NAMESPACE_GOOGLE,
 etc.  The command line options override the environment.
 `,
-	Children: []*cmdline.Command{cmdGlob, cmdMount, cmdUnmount, cmdResolve, cmdResolveToMT, cmdPermissions, cmdDelete},
+	Children: []*cmdline.Command{cmdGlob, cmdWatch, cmdMount, cmdUnmount, cmdResolve, cmdResolveToMT, cmdPermissions, cmdDelete},
 }
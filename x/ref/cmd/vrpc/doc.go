@@ -18,6 +18,7 @@ The vrpc commands are:
 	signature   Describe the interfaces of a Vanadium server
 	call        Call a method of a Vanadium server
 	identify    Reveal blessings presented by a Vanadium server
+	health      Report the health of a Vanadium server
 	help        Display help for commands or topics
 
 The vrpc flags are:
@@ -52,6 +53,13 @@ The global flags are:
 	  log level for V logs
 	-v23.credentials=
 	  directory to use for storing security credentials
+	-v23.dial.no-proxy=$NO_PROXY
+	  comma separated list of the destinations that are to be dialed directly
+	  rather than via --v23.dial.proxy
+	-v23.dial.proxy=$ALL_PROXY or $HTTPS_PROXY
+	  egress proxy to use for outbound connections, one of socks5://, socks5h://,
+	  http:// or https://[user:password@]host:port, an http proxy is assumed if
+	  no scheme is given
 	-v23.permissions.file=
 	  specify a perms file as <name>:<permsfile>
 	-v23.permissions.literal=
@@ -185,6 +193,36 @@ The vrpc identify flags are:
 	-s=false
 	  if true, perform a shallow resolve
 
+# Vrpc health - Report the health of a Vanadium server
+
+Health connects to the Vanadium server identified by <server> and prints the
+health of the object that it names, one of Serving, NotServing or LameDuck, to
+standard output.
+
+If -watch is specified, the health is printed, preceded by the time, whenever
+it changes until the server shuts down or the command is interrupted.
+
+Usage:
+
+	vrpc health [flags] <server>
+
+<server> identifies a Vanadium server.  It can either be the object address of
+the server, or an object name that will be resolved to an end-point.
+
+The vrpc health flags are:
+
+	-insecure=false
+	  If true, skip server authentication. This means that the client will reveal
+	  its blessings to servers that it may not recognize.
+	-watch=false
+	  if true, print the health of the server whenever it changes until the server
+	  shuts down
+
+	-json=false
+	  if true, output a JSON representation of the response
+	-s=false
+	  if true, perform a shallow resolve
+
 # Vrpc help - Display help for commands or topics
 
 Help with no args displays the usage of the parent command.
@@ -39,6 +39,7 @@ var (
 	flagShowReserved   bool
 	flagShallowResolve bool
 	flagJSON           bool
+	flagWatch          bool
 	insecureOpts       = []rpc.CallOpt{
 		options.ServerAuthorizer{Authorizer: security.AllowEveryone()},
 		options.NameResolutionAuthorizer{Authorizer: security.AllowEveryone()},
@@ -58,9 +59,11 @@ func init() {
 	)
 	cmdSignature.Flags.BoolVar(&flagInsecure, insecureName, insecureVal, insecureDesc)
 	cmdIdentify.Flags.BoolVar(&flagInsecure, insecureName, insecureVal, insecureDesc)
+	cmdHealth.Flags.BoolVar(&flagInsecure, insecureName, insecureVal, insecureDesc)
 
 	cmdSignature.Flags.BoolVar(&flagShowReserved, "show-reserved", false, "if true, also show the signatures of reserved methods")
 	cmdVRPC.Flags.BoolVar(&flagShallowResolve, "s", false, "if true, perform a shallow resolve")
+	cmdHealth.Flags.BoolVar(&flagWatch, "watch", false, "if true, print the health of the server whenever it changes until the server shuts down")
 
 	cmdVRPC.Flags.BoolVar(&flagJSON, "json", false, "if true, output a JSON representation of the response")
 }
@@ -75,7 +78,7 @@ a generic client to interact with any Vanadium server.
 	// TODO(toddw): Add cmdServe, which will take an interface as input, and set
 	// up a server capable of handling the given methods.  When a request is
 	// received, it'll allow the user to respond via stdin.
-	Children: []*cmdline.Command{cmdSignature, cmdCall, cmdIdentify},
+	Children: []*cmdline.Command{cmdSignature, cmdCall, cmdIdentify, cmdHealth},
 }
 
 const serverDesc = `
@@ -131,6 +134,22 @@ each argument is implicit and doesn't need to be specified.
 `,
 }
 
+var cmdHealth = &cmdline.Command{
+	Runner: v23cmd.RunnerFunc(runHealth),
+	Name:   "health",
+	Short:  "Report the health of a Vanadium server",
+	Long: `
+Health connects to the Vanadium server identified by <server> and prints the
+health of the object that it names, one of Serving, NotServing or LameDuck, to
+standard output.
+
+If -watch is specified, the health is printed, preceded by the time, whenever
+it changes until the server shuts down or the command is interrupted.
+`,
+	ArgsName: "<server>",
+	ArgsLong: serverDesc,
+}
+
 var cmdIdentify = &cmdline.Command{
 	Runner: v23cmd.RunnerFunc(runIdentify),
 	Name:   "identify",
@@ -157,8 +176,8 @@ func getNamespaceOpts(opts []rpc.CallOpt) []naming.NamespaceOpt {
 func rpcOpts(ctx *context.T, server string) ([]rpc.CallOpt, error) {
 	var opts []rpc.CallOpt
 	if flagInsecure {
-		// Note that this flag is only settable on signature and
-		// identify, as per ashankar@.
+		// Note that this flag is only settable on signature,
+		// identify and health, as per ashankar@.
 		opts = append(opts, insecureOpts...)
 	}
 	if flagShallowResolve {
@@ -346,3 +365,38 @@ func runIdentify(ctx *context.T, env *cmdline.Env, args []string) error {
 	fmt.Fprintf(env.Stdout, "PRESENTED: %v\nVALID:     %v\nPUBLICKEY: %v\n           %v\n", presented, valid, presented.PublicKey(), base64.URLEncoding.EncodeToString(pkey))
 	return nil
 }
+
+func runHealth(ctx *context.T, env *cmdline.Env, args []string) error {
+	if len(args) != 1 {
+		return env.UsageErrorf("wrong number of arguments")
+	}
+	server := args[0]
+	if !flagWatch {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+	}
+	opts, err := rpcOpts(ctx, server)
+	if err != nil {
+		return err
+	}
+	if !flagWatch {
+		status, err := reserved.Health(ctx, server, opts...)
+		if err != nil {
+			return fmt.Errorf("Health failed: %v", err)
+		}
+		fmt.Fprintln(env.Stdout, status)
+		return nil
+	}
+	ch, wait, err := reserved.WatchHealth(ctx, server, opts...)
+	if err != nil {
+		return fmt.Errorf("WatchHealth failed: %v", err)
+	}
+	for status := range ch {
+		fmt.Fprintf(env.Stdout, "%v %v\n", time.Now().Format(time.RFC3339), status)
+	}
+	if err := wait(); err != nil {
+		return fmt.Errorf("WatchHealth failed: %v", err)
+	}
+	return nil
+}
@@ -5,10 +5,13 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+	"time"
 
 	v23 "v.io/v23"
 	"v.io/v23/context"
@@ -177,10 +180,14 @@ type "v.io/x/ref/cmd/vrpc/internal".TypeTester interface {
 type __Reserved interface {
 	// Glob returns all entries matching the pattern.
 	__Glob(pattern string) stream<any, any> error
+	// Health returns the health of the object.
+	__Health() ("v.io/v23/rpc".HealthStatus | error)
 	// MethodSignature returns the signature for the given method.
 	__MethodSignature(method string) ("signature".Method | error)
 	// Signature returns all interface signatures implemented by the object.
 	__Signature() ([]"signature".Interface | error)
+	// WatchHealth streams the health of the object whenever it changes, starting with its current health.
+	__WatchHealth() stream<any, any> error
 }
 
 type "signature".Arg struct {
@@ -213,6 +220,8 @@ type "signature".Method struct {
 	Tags []any
 }
 
+type "v.io/v23/rpc".HealthStatus enum{Serving;NotServing;LameDuck}
+
 type "v.io/x/ref/cmd/vrpc/internal".Array2Int [2]int32
 
 type "v.io/x/ref/cmd/vrpc/internal".Struct struct {
@@ -337,3 +346,81 @@ func TestCall(t *testing.T) {
 		}
 	}
 }
+
+func TestHealth(t *testing.T) {
+	ctx, name, shutdown := initTest(t)
+	defer shutdown()
+
+	var stdout, stderr bytes.Buffer
+	env := &cmdline.Env{Stdout: &stdout, Stderr: &stderr}
+	if err := v23cmd.ParseAndRunForTest(cmdVRPC, ctx, env, []string{"health", name}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stdout.String(), "Serving\n"; got != want {
+		t.Errorf("got stdout %q, want %q", got, want)
+	}
+	if got, want := stderr.String(), ""; got != want {
+		t.Errorf("got stderr %q, want %q", got, want)
+	}
+}
+
+func TestHealthWatch(t *testing.T) {
+	defer func() { flagWatch = false }()
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	sctx, cancel := context.WithCancel(ctx)
+	obj := internal.TypeTesterServer(&server{})
+	_, server, err := v23.WithNewServer(sctx, "", obj, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name := server.Status().Endpoints[0].Name()
+
+	r, w := io.Pipe()
+	var stderr bytes.Buffer
+	env := &cmdline.Env{Stdout: w, Stderr: &stderr}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- v23cmd.ParseAndRunForTest(cmdVRPC, ctx, env, []string{"health", "-watch", name})
+		w.Close()
+	}()
+	lines := bufio.NewScanner(r)
+	// Each change to the health is printed, preceded by the time, until the
+	// server shuts down.
+	next := func() string {
+		if !lines.Scan() {
+			t.Fatalf("watch ended early: %v", lines.Err())
+		}
+		fields := strings.Fields(lines.Text())
+		if len(fields) != 2 {
+			t.Fatalf("unexpected output: %q", lines.Text())
+		}
+		if _, err := time.Parse(time.RFC3339, fields[0]); err != nil {
+			t.Errorf("unexpected time: %v", err)
+		}
+		return fields[1]
+	}
+	if got, want := next(), "Serving"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	server.SetHealth("", rpc.HealthStatusNotServing)
+	if got, want := next(), "NotServing"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cancel()
+	if got, want := next(), "LameDuck"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if lines.Scan() {
+		t.Errorf("unexpected output: %q", lines.Text())
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if got, want := stderr.String(), ""; got != want {
+		t.Errorf("got stderr %q, want %q", got, want)
+	}
+	<-server.Closed()
+}
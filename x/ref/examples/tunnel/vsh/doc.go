@@ -79,6 +79,13 @@ The global flags are:
    log level for V logs
  -v23.credentials=
    directory to use for storing security credentials
+ -v23.dial.no-proxy=$NO_PROXY
+   comma separated list of the destinations that are to be dialed directly
+   rather than via --v23.dial.proxy
+ -v23.dial.proxy=$ALL_PROXY or $HTTPS_PROXY
+   egress proxy to use for outbound connections, one of socks5://, socks5h://,
+   http:// or https://[user:password@]host:port, an http proxy is assumed if
+   no scheme is given
  -v23.namespace.root=[/(dev.v.io:r:vprod:service:mounttabled)@ns.dev.v.io:8101]
    local namespace root; can be repeated to provided multiple roots
  -v23.permissions.file=
@@ -31,6 +31,8 @@ func (s *MockServer) Status() rpc.ServerStatus {
 	}
 }
 
+func (s *MockServer) SetHealth(string, rpc.HealthStatus) {}
+
 func (s *MockServer) UpdateNetwork(eps []naming.Endpoint) {
 	defer s.mu.Unlock()
 	s.mu.Lock()
@@ -260,6 +260,26 @@ func DefaultCredentialsDir() string {
 	return DefaultCredentialsDirNoEnv()
 }
 
+// DefaultDialProxy returns the current defaults for --v23.dial.proxy and
+// --v23.dial.no-proxy as specified by the ALL_PROXY, or failing that
+// HTTPS_PROXY, and NO_PROXY environment variables or their lower case
+// equivalents.
+func DefaultDialProxy() (proxy, noProxy string) {
+	return getenv("ALL_PROXY", "all_proxy", "HTTPS_PROXY", "https_proxy"),
+		getenv("NO_PROXY", "no_proxy")
+}
+
+// getenv returns the value of the first of the named environment variables
+// that is set.
+func getenv(names ...string) string {
+	for _, name := range names {
+		if e := os.Getenv(name); len(e) > 0 {
+			return e
+		}
+	}
+	return ""
+}
+
 // SetDefaultPermissionsLiteral sets the default value for
 // --v23.permissions.literal.
 func SetDefaultPermissionsLiteral(literal string) {
@@ -27,6 +27,8 @@ const (
 	// --v23.vtrace.collect-regexp
 	// --v23.vtrace.enable-aws-xray
 	// --v23.vtrace.root-span-name
+	// --v23.dial.proxy
+	// --v23.dial.no-proxy
 	Runtime FlagGroup = iota
 	// Listen identifies the flags typically required to configure
 	// rpc.ListenSpec. Namely:
@@ -78,6 +80,12 @@ type RuntimeFlags struct {
 	// TODO(cnicolaou): provide flag.Value impl
 	Credentials string `cmdline:"v23.credentials,,directory to use for storing security credentials"`
 
+	// DialProxy and DialNoProxy may be initialized by the ALL_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables. The command line
+	// will override the environment.
+	DialProxy   string `cmdline:"v23.dial.proxy,,'egress proxy to use for outbound connections, one of socks5://, socks5h://, http:// or https://[user:password@]host:port, an http proxy is assumed if no scheme is given'"`
+	DialNoProxy string `cmdline:"v23.dial.no-proxy,,'comma separated list of the destinations that are to be dialed directly rather than via --v23.dial.proxy'"`
+
 	// VtraceFlags control various aspects of Vtrace.
 	VtraceFlags
 }
@@ -135,6 +143,7 @@ func NewRuntimeFlags() (*RuntimeFlags, error) {
 		rf.NamespaceRoots.Roots = roots
 	}
 	rf.Credentials = DefaultCredentialsDir()
+	rf.DialProxy, rf.DialNoProxy = DefaultDialProxy()
 	rf.VtraceFlags = DefaultVtraceFlags()
 	return rf, nil
 }
@@ -142,13 +151,18 @@ func NewRuntimeFlags() (*RuntimeFlags, error) {
 // RegisterRuntimeFlags registers the supplied RuntimeFlags variable with
 // the supplied FlagSet.
 func RegisterRuntimeFlags(fs *flag.FlagSet, f *RuntimeFlags) error {
+	dialProxy, dialNoProxy := DefaultDialProxy()
 	err := flagvar.RegisterFlagsInStruct(fs, "cmdline", f,
 		map[string]interface{}{
-			"v23.credentials": DefaultCredentialsDir(),
+			"v23.credentials":   DefaultCredentialsDir(),
+			"v23.dial.proxy":    dialProxy,
+			"v23.dial.no-proxy": dialNoProxy,
 		},
 		map[string]string{
 			"v23.namespace.root": "[" + strings.Join(DefaultNamespaceRoots(), ",") + "]",
 			"v23.credentials":    "",
+			"v23.dial.proxy":     "$ALL_PROXY or $HTTPS_PROXY",
+			"v23.dial.no-proxy":  "$NO_PROXY",
 		},
 	)
 	return err
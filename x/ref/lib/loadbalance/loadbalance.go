@@ -0,0 +1,171 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package loadbalance provides implementations of rpc.LoadBalancer that can
+// be installed on a client via the options.LoadBalancer option, for example:
+//
+//	ctx, client, err := v23.WithNewClient(ctx, options.LoadBalancer{LoadBalancer: loadbalance.RoundRobin()})
+package loadbalance
+
+import (
+	"container/list"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/naming"
+	"v.io/v23/rpc"
+)
+
+// maxRoundRobinNames is the maximum number of names for which RoundRobin
+// retains the position of the next server to use. The least recently
+// called names are forgotten first.
+const maxRoundRobinNames = 1 << 10
+
+// RoundRobin returns a policy that rotates through the servers that a name
+// resolves to on successive calls to that name.
+func RoundRobin() rpc.LoadBalancer {
+	return &roundRobin{next: make(map[string]*list.Element), lru: list.New()}
+}
+
+type roundRobin struct {
+	mu   sync.Mutex
+	next map[string]*list.Element // keyed by name.
+	lru  *list.List               // of *roundRobinEntry, most recently used first.
+}
+
+type roundRobinEntry struct {
+	name string
+	next int
+}
+
+func (*roundRobin) Name() string {
+	return "round-robin"
+}
+
+func (rr *roundRobin) Order(ctx *context.T, name string, servers []naming.MountedServer) []naming.MountedServer {
+	if len(servers) < 2 {
+		return servers
+	}
+	rr.mu.Lock()
+	e := rr.entry(name)
+	n := e.next % len(servers)
+	e.next = n + 1
+	rr.mu.Unlock()
+	ordered := make([]naming.MountedServer, 0, len(servers))
+	ordered = append(ordered, servers[n:]...)
+	return append(ordered, servers[:n]...)
+}
+
+// entry returns the entry for name, creating it and evicting the least
+// recently used entry if necessary. rr.mu must be held.
+func (rr *roundRobin) entry(name string) *roundRobinEntry {
+	if el, ok := rr.next[name]; ok {
+		rr.lru.MoveToFront(el)
+		return el.Value.(*roundRobinEntry)
+	}
+	if rr.lru.Len() >= maxRoundRobinNames {
+		oldest := rr.lru.Back()
+		rr.lru.Remove(oldest)
+		delete(rr.next, oldest.Value.(*roundRobinEntry).name)
+	}
+	e := &roundRobinEntry{name: name}
+	rr.next[name] = rr.lru.PushFront(e)
+	return e
+}
+
+func (*roundRobin) Selected(string, flow.ManagedConn) {}
+
+// Random returns a policy that orders the servers that a name resolves to
+// randomly on every call.
+func Random() rpc.LoadBalancer {
+	return &random{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+type random struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func (*random) Name() string {
+	return "random"
+}
+
+func (r *random) Order(ctx *context.T, name string, servers []naming.MountedServer) []naming.MountedServer {
+	r.mu.Lock()
+	r.rnd.Shuffle(len(servers), func(i, j int) {
+		servers[i], servers[j] = servers[j], servers[i]
+	})
+	r.mu.Unlock()
+	return servers
+}
+
+func (*random) Selected(string, flow.ManagedConn) {}
+
+// maxLeastRTTServers is the maximum number of servers for which LeastRTT
+// retains the round trip time. The least recently used servers are
+// forgotten first.
+const maxLeastRTTServers = 1 << 10
+
+// LeastRTT returns a policy that prefers the servers with the lowest
+// round trip time as measured by the health checks on the connections
+// used to reach them. Servers whose round trip time is not yet known are
+// preferred over all others so that their round trip time is learned.
+func LeastRTT() rpc.LoadBalancer {
+	return &leastRTT{rtts: make(map[string]*list.Element), lru: list.New()}
+}
+
+type leastRTT struct {
+	mu   sync.Mutex
+	rtts map[string]*list.Element // keyed by server.
+	lru  *list.List               // of *leastRTTEntry, most recently used first.
+}
+
+type leastRTTEntry struct {
+	server string
+	rtt    time.Duration
+}
+
+func (*leastRTT) Name() string {
+	return "least-rtt"
+}
+
+func (l *leastRTT) Order(ctx *context.T, name string, servers []naming.MountedServer) []naming.MountedServer {
+	rtts := make(map[string]time.Duration, len(servers))
+	l.mu.Lock()
+	for _, s := range servers {
+		if el, ok := l.rtts[s.Server]; ok {
+			l.lru.MoveToFront(el)
+			rtts[s.Server] = el.Value.(*leastRTTEntry).rtt
+		}
+	}
+	l.mu.Unlock()
+	sort.SliceStable(servers, func(i, j int) bool {
+		return rtts[servers[i].Server] < rtts[servers[j].Server]
+	})
+	return servers
+}
+
+func (l *leastRTT) Selected(server string, conn flow.ManagedConn) {
+	rtt := conn.RTT()
+	if rtt == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.rtts[server]; ok {
+		l.lru.MoveToFront(el)
+		el.Value.(*leastRTTEntry).rtt = rtt
+		return
+	}
+	if l.lru.Len() >= maxLeastRTTServers {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.rtts, oldest.Value.(*leastRTTEntry).server)
+	}
+	l.rtts[server] = l.lru.PushFront(&leastRTTEntry{server: server, rtt: rtt})
+}
@@ -0,0 +1,114 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loadbalance_test
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"v.io/v23/flow"
+	"v.io/v23/naming"
+	"v.io/x/ref/lib/loadbalance"
+)
+
+func servers(names ...string) []naming.MountedServer {
+	var r []naming.MountedServer
+	for _, n := range names {
+		r = append(r, naming.MountedServer{Server: n})
+	}
+	return r
+}
+
+func names(servers []naming.MountedServer) []string {
+	var r []string
+	for _, s := range servers {
+		r = append(r, s.Server)
+	}
+	return r
+}
+
+func TestRoundRobin(t *testing.T) {
+	lb := loadbalance.RoundRobin()
+	for i, want := range [][]string{
+		{"a", "b", "c"},
+		{"b", "c", "a"},
+		{"c", "a", "b"},
+		{"a", "b", "c"},
+	} {
+		if got := names(lb.Order(nil, "x", servers("a", "b", "c"))); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+	// Each name is rotated independently.
+	if got, want := names(lb.Order(nil, "y", servers("a", "b"))), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The position for names that have not been called recently is
+	// forgotten once many other names have been called.
+	for i := 0; i < 10000; i++ {
+		lb.Order(nil, fmt.Sprint(i), servers("a", "b"))
+	}
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "c"))), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRandom(t *testing.T) {
+	lb := loadbalance.Random()
+	first := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		got := names(lb.Order(nil, "x", servers("a", "b", "c")))
+		first[got[0]] = true
+		sort.Strings(got)
+		if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if got, want := len(first), 3; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+type rttConn struct {
+	flow.ManagedConn
+	rtt time.Duration
+}
+
+func (c *rttConn) RTT() time.Duration { return c.rtt }
+
+func TestLeastRTT(t *testing.T) {
+	lb := loadbalance.LeastRTT()
+	lb.Selected("a", &rttConn{rtt: 30 * time.Millisecond})
+	lb.Selected("b", &rttConn{rtt: 10 * time.Millisecond})
+	lb.Selected("c", &rttConn{rtt: 20 * time.Millisecond})
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "c"))), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Servers with an unknown RTT are tried first.
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "d"))), []string{"d", "b", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// An unavailable RTT does not replace a known one.
+	lb.Selected("b", &rttConn{})
+	lb.Selected("a", &rttConn{rtt: time.Millisecond})
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "c"))), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// The round trip times of servers that have not been used recently are
+	// forgotten once many other servers have been used.
+	for i := 0; i < 10000; i++ {
+		lb.Selected(fmt.Sprint(i), &rttConn{rtt: time.Second})
+	}
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "c"))), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	lb.Selected("a", &rttConn{rtt: 30 * time.Millisecond})
+	if got, want := names(lb.Order(nil, "x", servers("a", "b", "c"))), []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
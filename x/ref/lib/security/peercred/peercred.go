@@ -0,0 +1,90 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package peercred provides security.Authorizers that, in addition to
+// the usual blessings-based authorization, require the client to be a
+// process on the same host running as a specific operating system user or
+// group. The credentials of the client process are those reported by the
+// kernel for connections made using the unix protocol (see
+// v.io/x/ref/runtime/protocols/unix) and are made available to
+// authorizers via flow.GetPeerCredentials. Calls for which no credentials
+// are available, eg. those made over tcp, are always denied.
+//
+// For example, to restrict the methods of an admin endpoint to processes
+// running as root that are also authorized by perms:
+//
+//	auth := peercred.RequireUID(0, access.TypicalTagTypePermissionsAuthorizer(perms))
+package peercred
+
+import (
+	"os/user"
+	"strconv"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+)
+
+// RequireUID returns an authorizer that allows only calls from processes
+// running as uid that are also authorized by next, or by
+// security.DefaultAuthorizer if next is nil.
+func RequireUID(uid int, next security.Authorizer) security.Authorizer {
+	return newAuthorizer(func(creds flow.PeerCredentials) bool {
+		return creds.UID == uid
+	}, "uid "+strconv.Itoa(uid), next)
+}
+
+// RequireGroup returns an authorizer that allows only calls from processes
+// whose primary group is gid, or whose user is a member of the group gid,
+// that are also authorized by next, or by security.DefaultAuthorizer if
+// next is nil.
+func RequireGroup(gid int, next security.Authorizer) security.Authorizer {
+	return newAuthorizer(func(creds flow.PeerCredentials) bool {
+		return creds.GID == gid || isMember(creds.UID, gid)
+	}, "gid "+strconv.Itoa(gid), next)
+}
+
+type authorizer struct {
+	allowed     func(flow.PeerCredentials) bool
+	requirement string
+	next        security.Authorizer
+}
+
+func newAuthorizer(allowed func(flow.PeerCredentials) bool, requirement string, next security.Authorizer) *authorizer {
+	if next == nil {
+		next = security.DefaultAuthorizer()
+	}
+	return &authorizer{allowed: allowed, requirement: requirement, next: next}
+}
+
+func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
+	creds, ok := flow.GetPeerCredentials(ctx)
+	if !ok {
+		return verror.ErrNoAccess.Errorf(ctx, "access denied: the operating system credentials of the caller are not known, %v is required", a.requirement)
+	}
+	if !a.allowed(creds) {
+		return verror.ErrNoAccess.Errorf(ctx, "access denied: caller with uid %v, gid %v does not have %v", creds.UID, creds.GID, a.requirement)
+	}
+	return a.next.Authorize(ctx, call)
+}
+
+// isMember returns true if the user uid is a member of the group gid.
+func isMember(uid, gid int) bool {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return false
+	}
+	gids, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	g := strconv.Itoa(gid)
+	for _, id := range gids {
+		if id == g {
+			return true
+		}
+	}
+	return false
+}
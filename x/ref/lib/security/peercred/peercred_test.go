@@ -0,0 +1,116 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package peercred_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+	"v.io/x/ref/lib/security/peercred"
+	_ "v.io/x/ref/runtime/factories/generic"
+	"v.io/x/ref/services/xproxy/xproxy"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type server struct{}
+
+func (server) Creds(ctx *context.T, _ rpc.ServerCall) (string, error) {
+	creds, ok := flow.GetPeerCredentials(ctx)
+	if !ok {
+		return "", fmt.Errorf("no peer credentials")
+	}
+	return fmt.Sprintf("%v:%v:%v", creds.UID, creds.GID, creds.PID), nil
+}
+
+func serve(t *testing.T, ctx *context.T, protocol, address string, auth security.Authorizer) string {
+	ctx = v23.WithListenSpec(ctx, rpc.ListenSpec{Addrs: rpc.ListenAddrs{{Protocol: protocol, Address: address}}})
+	_, s, err := v23.WithNewServer(ctx, "", server{}, auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testutil.WaitForServerReady(s).Endpoints[0].Name()
+}
+
+func creds(ctx *context.T, name string) (string, error) {
+	var s string
+	err := v23.GetClient(ctx).Call(ctx, name, "Creds", nil, []interface{}{&s})
+	return s, err
+}
+
+func TestPeerCredentials(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	uid, gid := os.Getuid(), os.Getgid()
+	socket := filepath.Join(t.TempDir(), "sock")
+	for i, tc := range []struct {
+		protocol, address string
+		auth              security.Authorizer
+		allowed           bool
+	}{
+		{"unix", socket + "0", peercred.RequireUID(uid, nil), true},
+		{"unix", socket + "1", peercred.RequireGroup(gid, nil), true},
+		{"unix", socket + "2", peercred.RequireUID(uid+1, nil), false},
+		{"unix", socket + "3", peercred.RequireGroup(gid+12345, nil), false},
+		{"unix", socket + "4", peercred.RequireUID(uid, security.EndpointAuthorizer()), false},
+		{"tcp", "127.0.0.1:0", peercred.RequireUID(uid, nil), false},
+	} {
+		name := serve(t, ctx, tc.protocol, tc.address, tc.auth)
+		got, err := creds(ctx, name)
+		if !tc.allowed {
+			if !errors.Is(err, verror.ErrNoAccess) {
+				t.Errorf("%v: got %v, want %v", i, err, verror.ErrNoAccess)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", i, err)
+			continue
+		}
+		if want := fmt.Sprintf("%v:%v:%v", uid, gid, os.Getpid()); got != want {
+			t.Errorf("%v: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestPeerCredentialsViaProxy(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	// The server's connection to the proxy is over a unix socket, but the
+	// credentials of the proxy must not be attributed to the clients whose
+	// connections it forwards.
+	socket := filepath.Join(t.TempDir(), "sock")
+	pctx, cancel := context.WithCancel(v23.WithListenSpec(ctx, rpc.ListenSpec{Addrs: rpc.ListenAddrs{{Protocol: "unix", Address: socket}}}))
+	p, err := xproxy.New(pctx, "", security.AllowEveryone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		<-p.Closed()
+	}()
+	proxyName := p.ListeningEndpoints()[0].Name()
+
+	sctx := v23.WithListenSpec(ctx, rpc.ListenSpec{Proxy: proxyName})
+	_, s, err := v23.WithNewServer(sctx, "", server{}, peercred.RequireUID(os.Getuid(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := testutil.WaitForProxyEndpoints(s, proxyName)
+	if _, err := creds(ctx, status.Endpoints[0].Name()); !errors.Is(err, verror.ErrNoAccess) {
+		t.Errorf("got %v, want %v", err, verror.ErrNoAccess)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package ratelimit provides a security.Authorizer that throttles callers
+// using token-bucket rate limits keyed by their validated blessing names.
+//
+// The limits are specified as a map from blessing patterns to a Limit and
+// may be read from a JSON file, in the same manner as access.Permissions,
+// for example:
+//
+//	{
+//	  "dev.v.io:u:alice": {"Rate": 10, "Burst": 20},
+//	  "dev.v.io:o:app": {"Rate": 100, "Burst": 100, "Shared": true}
+//	}
+//
+// Calls from callers that exceed their limits are rejected with
+// ErrRateLimited, whose parameters include the time after which the call
+// may be retried. The number of calls allowed and rejected for each key are
+// exported via v.io/x/ref/lib/stats under security/ratelimit/<name>.
+package ratelimit
+
+import (
+	"container/list"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+	"v.io/x/ref/lib/stats"
+)
+
+// maxBuckets is the maximum number of buckets retained. The least recently
+// used buckets are discarded first.
+const maxBuckets = 1 << 14
+
+// ReadLimits reads JSON-encoded Limits from r.
+func ReadLimits(r io.Reader) (Limits, error) {
+	var l Limits
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// WriteLimits writes the JSON-encoded representation of l to w.
+func WriteLimits(w io.Writer, l Limits) error {
+	return json.NewEncoder(w).Encode(l)
+}
+
+// Authorizer returns an authorizer that delegates to next, or to
+// security.DefaultAuthorizer if next is nil, and then rejects the calls
+// authorized by next from callers that exceed the specified limits. Stats
+// are exported under security/ratelimit/<name>.
+//
+// A caller is subject to the limit for every pattern matched by any of its
+// validated blessing names, and each call consumes a token from the bucket
+// for each of those limits. Callers that match none of the patterns are not
+// limited.
+func Authorizer(name string, limits Limits, next security.Authorizer) security.Authorizer {
+	return newAuthorizer(name, limits, next)
+}
+
+// AuthorizerFromFile is like Authorizer, with the limits read from a file
+// named filename. Changes to the file affect subsequent calls to Authorize.
+func AuthorizerFromFile(name, filename string, next security.Authorizer) (security.Authorizer, error) {
+	limits, mtime, err := loadLimitsFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &fileAuthorizer{
+		authorizer: newAuthorizer(name, limits, next),
+		filename:   filename,
+		mtime:      mtime,
+	}, nil
+}
+
+type bucketKey struct {
+	pattern security.BlessingPattern
+	key     string
+}
+
+type bucket struct {
+	key    bucketKey
+	tokens float64
+	last   time.Time
+}
+
+type authorizer struct {
+	next security.Authorizer
+	now  func() time.Time
+
+	mu      sync.Mutex
+	limits  Limits
+	buckets map[bucketKey]*list.Element
+	lru     *list.List // of *bucket, most recently used first.
+
+	allowed, rejected *stats.Map // keyed by blessing name or pattern.
+}
+
+func newAuthorizer(name string, limits Limits, next security.Authorizer) *authorizer {
+	if next == nil {
+		next = security.DefaultAuthorizer()
+	}
+	prefix := naming.Join("security", "ratelimit", name)
+	return &authorizer{
+		next:     next,
+		now:      time.Now,
+		limits:   limits,
+		buckets:  make(map[bucketKey]*list.Element),
+		lru:      list.New(),
+		allowed:  stats.NewMap(naming.Join(prefix, "allowed")),
+		rejected: stats.NewMap(naming.Join(prefix, "rejected")),
+	}
+}
+
+// Authorize consumes tokens only for calls authorized by next so that
+// unauthorized callers cannot exhaust the limits of other callers that
+// share their blessing patterns.
+func (a *authorizer) Authorize(ctx *context.T, call security.Call) error {
+	if err := a.next.Authorize(ctx, call); err != nil {
+		return err
+	}
+	names, _ := security.RemoteBlessingNames(ctx, call)
+	return a.take(ctx, names)
+}
+
+// take consumes a token from each of the buckets that apply to a caller
+// with the specified blessing names, or none of them if any is empty.
+func (a *authorizer) take(ctx *context.T, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	var (
+		matched    []*bucket
+		keys       []string
+		limited    string
+		retryAfter time.Duration
+	)
+	for pattern, limit := range a.limits {
+		key := matchingKey(pattern, limit, names)
+		if len(key) == 0 {
+			continue
+		}
+		b := a.bucketLocked(bucketKey{pattern, key}, limit, now)
+		if b.tokens < 1 {
+			var d time.Duration
+			if limit.Rate > 0 {
+				d = time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+			}
+			if len(limited) == 0 || d > retryAfter {
+				limited, retryAfter = key, d
+			}
+			continue
+		}
+		matched = append(matched, b)
+		keys = append(keys, key)
+	}
+	if len(limited) > 0 {
+		a.rejected.Incr(limited, 1)
+		return ErrorfRateLimited(ctx, "rate limit exceeded for %v, retry after %v", limited, retryAfter)
+	}
+	for i, b := range matched {
+		b.tokens--
+		a.allowed.Incr(keys[i], 1)
+	}
+	return nil
+}
+
+// matchingKey returns the key of the bucket for pattern that applies to a
+// caller with the specified (sorted) blessing names, or "" if the pattern
+// matches none of them.
+func matchingKey(pattern security.BlessingPattern, limit Limit, names []string) string {
+	if limit.Shared {
+		if pattern.MatchedBy(names...) {
+			return string(pattern)
+		}
+		return ""
+	}
+	for _, n := range names {
+		if pattern.MatchedBy(n) {
+			return n
+		}
+	}
+	return ""
+}
+
+// bucketLocked returns the bucket for k, refilled as of now, creating it
+// and discarding the least recently used bucket if necessary.
+func (a *authorizer) bucketLocked(k bucketKey, limit Limit, now time.Time) *bucket {
+	if el, ok := a.buckets[k]; ok {
+		a.lru.MoveToFront(el)
+		b := el.Value.(*bucket)
+		refill(b, limit, now)
+		return b
+	}
+	if a.lru.Len() >= maxBuckets {
+		oldest := a.lru.Remove(a.lru.Back()).(*bucket)
+		delete(a.buckets, oldest.key)
+		a.deleteStatsLocked([]string{oldest.key.key})
+	}
+	b := &bucket{key: k, tokens: float64(limit.Burst), last: now}
+	a.buckets[k] = a.lru.PushFront(b)
+	return b
+}
+
+func refill(b *bucket, limit Limit, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * limit.Rate
+		b.last = now
+	}
+	if burst := float64(limit.Burst); b.tokens > burst {
+		b.tokens = burst
+	}
+}
+
+// deleteStatsLocked deletes the stats for those of keys that are no longer
+// used by any bucket. Every bucket is for one of the current limits, so
+// only the buckets for those limits need be checked.
+func (a *authorizer) deleteStatsLocked(keys []string) {
+	var unused []string
+	for _, k := range keys {
+		used := false
+		for pattern := range a.limits {
+			if _, ok := a.buckets[bucketKey{pattern, k}]; ok {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, k)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+	a.allowed.Delete(unused)
+	a.rejected.Delete(unused)
+}
+
+// setLimits replaces the limits, discarding the buckets for patterns whose
+// limit has been changed or removed.
+func (a *authorizer) setLimits(limits Limits) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var discarded []string
+	for k, el := range a.buckets {
+		if old, ok := limits[k.pattern]; !ok || old != a.limits[k.pattern] {
+			a.lru.Remove(el)
+			delete(a.buckets, k)
+			discarded = append(discarded, k.key)
+		}
+	}
+	a.limits = limits
+	a.deleteStatsLocked(discarded)
+}
+
+type fileAuthorizer struct {
+	*authorizer
+	filename string
+
+	mu    sync.Mutex
+	mtime time.Time
+}
+
+func (a *fileAuthorizer) Authorize(ctx *context.T, call security.Call) error {
+	if err := a.reload(); err != nil {
+		ctx.Infof("failed to read rate limits file: %v: %v", a.filename, err)
+		return verror.ErrInternal.Errorf(ctx, "internal error: failed to read rate limits from file")
+	}
+	return a.authorizer.Authorize(ctx, call)
+}
+
+// reload re-reads the limits if the file has been modified since they were
+// last read.
+func (a *fileAuthorizer) reload() error {
+	fi, err := os.Stat(a.filename)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if fi.ModTime().Equal(a.mtime) {
+		return nil
+	}
+	limits, mtime, err := loadLimitsFromFile(a.filename)
+	if err != nil {
+		return err
+	}
+	a.setLimits(limits)
+	a.mtime = mtime
+	return nil
+}
+
+func loadLimitsFromFile(filename string) (Limits, time.Time, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	limits, err := ReadLimits(file)
+	return limits, fi.ModTime(), err
+}
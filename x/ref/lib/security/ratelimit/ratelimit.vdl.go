@@ -0,0 +1,317 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file was auto-generated by the vanadium vdl tool.
+// Package: ratelimit
+//
+//nolint:revive
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/security"
+	"v.io/v23/vdl"
+	_ "v.io/v23/vdlroot/time"
+	"v.io/v23/verror"
+)
+
+var initializeVDLCalled = false
+var _ = initializeVDL() // Must be first; see initializeVDL comments for details.
+
+// Hold type definitions in package-level variables, for better performance.
+// Declare and initialize with default values here so that the initializeVDL
+// method will be considered ready to initialize before any of the type
+// definitions that appear below.
+//
+//nolint:unused
+var (
+	vdlTypeStruct1 *vdl.Type = nil
+	vdlTypeMap2    *vdl.Type = nil
+	vdlTypeString3 *vdl.Type = nil
+)
+
+// Type definitions
+// ================
+// Limit is a token-bucket rate limit: calls are admitted at a sustained rate
+// of Rate calls per second with bursts of up to Burst calls.
+type Limit struct {
+	Rate  float64
+	Burst int32
+	// Shared, if true, specifies that all callers whose blessing names
+	// match the pattern share a single bucket, rather than each of the
+	// caller's blessing names having a bucket of its own.
+	Shared bool
+}
+
+func (Limit) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/lib/security/ratelimit.Limit"`
+}) {
+}
+
+func (x Limit) VDLIsZero() bool { //nolint:gocyclo
+	return x == Limit{}
+}
+
+func (x Limit) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeStruct1); err != nil {
+		return err
+	}
+	if x.Rate != 0 {
+		if err := enc.NextFieldValueFloat(0, vdl.Float64Type, x.Rate); err != nil {
+			return err
+		}
+	}
+	if x.Burst != 0 {
+		if err := enc.NextFieldValueInt(1, vdl.Int32Type, int64(x.Burst)); err != nil {
+			return err
+		}
+	}
+	if x.Shared {
+		if err := enc.NextFieldValueBool(2, vdl.BoolType, x.Shared); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *Limit) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	*x = Limit{}
+	if err := dec.StartValue(vdlTypeStruct1); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != vdlTypeStruct1 {
+			index = vdlTypeStruct1.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueFloat(64); {
+			case err != nil:
+				return err
+			default:
+				x.Rate = value
+			}
+		case 1:
+			switch value, err := dec.ReadValueInt(32); {
+			case err != nil:
+				return err
+			default:
+				x.Burst = int32(value)
+			}
+		case 2:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Shared = value
+			}
+		}
+	}
+}
+
+// Limits maps the blessing patterns of callers to their rate limits.
+type Limits map[security.BlessingPattern]Limit
+
+func (Limits) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/lib/security/ratelimit.Limits"`
+}) {
+}
+
+func (x Limits) VDLIsZero() bool { //nolint:gocyclo
+	return len(x) == 0
+}
+
+func (x Limits) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeMap2); err != nil {
+		return err
+	}
+	if err := enc.SetLenHint(len(x)); err != nil {
+		return err
+	}
+	for key, elem := range x {
+		if err := enc.NextEntryValueString(vdlTypeString3, string(key)); err != nil {
+			return err
+		}
+		if err := elem.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextEntry(true); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *Limits) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	if err := dec.StartValue(vdlTypeMap2); err != nil {
+		return err
+	}
+	var tmpMap Limits
+	if len := dec.LenHint(); len > 0 {
+		tmpMap = make(Limits, len)
+	}
+	for {
+		switch done, key, err := dec.NextEntryValueString(); {
+		case err != nil:
+			return err
+		case done:
+			*x = tmpMap
+			return dec.FinishValue()
+		default:
+			var elem Limit
+			if err := elem.VDLRead(dec); err != nil {
+				return err
+			}
+			if tmpMap == nil {
+				tmpMap = make(Limits)
+			}
+			tmpMap[security.BlessingPattern(key)] = elem
+		}
+	}
+}
+
+// Error definitions
+// =================
+
+var (
+
+	// ErrRateLimited indicates that a call was rejected because the caller
+	// exceeded the rate limit for key, and that it may be retried after
+	// retryAfter.
+	ErrRateLimited = verror.NewIDAction("v.io/x/ref/lib/security/ratelimit.RateLimited", verror.RetryBackoff)
+)
+
+// ErrorfRateLimited calls ErrRateLimited.Errorf with the supplied arguments.
+func ErrorfRateLimited(ctx *context.T, format string, key string, retryAfter time.Duration) error {
+	return ErrRateLimited.Errorf(ctx, format, key, retryAfter)
+}
+
+// MessageRateLimited calls ErrRateLimited.Message with the supplied arguments.
+func MessageRateLimited(ctx *context.T, message string, key string, retryAfter time.Duration) error {
+	return ErrRateLimited.Message(ctx, message, key, retryAfter)
+}
+
+// ParamsErrRateLimited extracts the expected parameters from the error's ParameterList.
+func ParamsErrRateLimited(argumentError error) (verrorComponent string, verrorOperation string, key string, retryAfter time.Duration, returnErr error) {
+	params := verror.Params(argumentError)
+	if params == nil {
+		returnErr = fmt.Errorf("no parameters found in: %T: %v", argumentError, argumentError)
+		return
+	}
+	iter := &paramListIterator{params: params, max: len(params)}
+
+	if verrorComponent, verrorOperation, returnErr = iter.preamble(); returnErr != nil {
+		return
+	}
+
+	var (
+		tmp interface{}
+		ok  bool
+	)
+	tmp, returnErr = iter.next()
+	if key, ok = tmp.(string); !ok {
+		if returnErr != nil {
+			return
+		}
+		returnErr = fmt.Errorf("parameter list contains the wrong type for return value key, has %T and not string", tmp)
+		return
+	}
+	tmp, returnErr = iter.next()
+	if retryAfter, ok = tmp.(time.Duration); !ok {
+		if returnErr != nil {
+			return
+		}
+		returnErr = fmt.Errorf("parameter list contains the wrong type for return value retryAfter, has %T and not time.Duration", tmp)
+		return
+	}
+
+	return
+}
+
+type paramListIterator struct {
+	err      error
+	idx, max int
+	params   []interface{}
+}
+
+func (pl *paramListIterator) next() (interface{}, error) {
+	if pl.err != nil {
+		return nil, pl.err
+	}
+	if pl.idx+1 > pl.max {
+		pl.err = fmt.Errorf("too few parameters: have %v", pl.max)
+		return nil, pl.err
+	}
+	pl.idx++
+	return pl.params[pl.idx-1], nil
+}
+
+func (pl *paramListIterator) preamble() (component, operation string, err error) {
+	var tmp interface{}
+	if tmp, err = pl.next(); err != nil {
+		return
+	}
+	var ok bool
+	if component, ok = tmp.(string); !ok {
+		return "", "", fmt.Errorf("ParamList[0]: component name is not a string: %T", tmp)
+	}
+	if tmp, err = pl.next(); err != nil {
+		return
+	}
+	if operation, ok = tmp.(string); !ok {
+		return "", "", fmt.Errorf("ParamList[1]: operation name is not a string: %T", tmp)
+	}
+	return
+}
+
+// initializeVDL performs vdl initialization.  It is safe to call multiple times.
+// If you have an init ordering issue, just insert the following line verbatim
+// into your source files in this package, right after the "package foo" clause:
+//
+// var _ = initializeVDL()
+//
+// The purpose of this function is to ensure that vdl initialization occurs in
+// the right order, and very early in the init sequence.  In particular, vdl
+// registration and package variable initialization needs to occur before
+// functions like vdl.TypeOf will work properly.
+//
+// This function returns a dummy value, so that it can be used to initialize the
+// first var in the file, to take advantage of Go's defined init order.
+func initializeVDL() struct{} {
+	if initializeVDLCalled {
+		return struct{}{}
+	}
+	initializeVDLCalled = true
+
+	// Register types.
+	vdl.Register((*Limit)(nil))
+	vdl.Register((*Limits)(nil))
+
+	// Initialize type definitions.
+	vdlTypeStruct1 = vdl.TypeOf((*Limit)(nil)).Elem()
+	vdlTypeMap2 = vdl.TypeOf((*Limits)(nil))
+	vdlTypeString3 = vdl.TypeOf((*security.BlessingPattern)(nil))
+
+	return struct{}{}
+}
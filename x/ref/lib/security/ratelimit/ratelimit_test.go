@@ -0,0 +1,259 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+	"v.io/x/ref/lib/stats"
+	"v.io/x/ref/test/testutil"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRateLimits(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	clock := &fakeClock{t: time.Now()}
+	a := newAuthorizer("test", Limits{
+		"alice": {Rate: 1, Burst: 2},
+		"app":   {Rate: 10, Burst: 1, Shared: true},
+	}, nil)
+	a.now = clock.now
+
+	allowed := func(names ...string) error {
+		return a.take(ctx, names)
+	}
+	limited := func(names ...string) time.Duration {
+		err := a.take(ctx, names)
+		if !errors.Is(err, ErrRateLimited) || verror.Action(err) != verror.RetryBackoff {
+			t.Fatalf("%v: unexpected error: %v", names, err)
+		}
+		_, _, _, retryAfter, err := ParamsErrRateLimited(err)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return retryAfter
+	}
+
+	// Alice's bucket allows a burst of two calls.
+	for i := 0; i < 2; i++ {
+		if err := allowed("alice:phone"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := limited("alice:phone"), time.Second; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Each of the blessing names matched by a pattern has its own bucket.
+	if err := allowed("alice:laptop"); err != nil {
+		t.Fatal(err)
+	}
+	// Callers that match no pattern are not limited.
+	for i := 0; i < 5; i++ {
+		if err := allowed("bob"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.advance(500 * time.Millisecond)
+	if got, want := limited("alice:phone"), 500*time.Millisecond; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	clock.advance(500 * time.Millisecond)
+	if err := allowed("alice:phone"); err != nil {
+		t.Fatal(err)
+	}
+
+	// All callers matching a shared pattern share a bucket.
+	if err := allowed("app:a"); err != nil {
+		t.Fatal(err)
+	}
+	limited("app:b")
+	// A caller that exceeds any of its limits consumes none of its tokens.
+	clock.advance(time.Second)
+	if err := allowed("app:a"); err != nil {
+		t.Fatal(err)
+	}
+	limited("app:a", "alice:tablet")
+	for i := 0; i < 2; i++ {
+		if err := allowed("alice:tablet"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for key, want := range map[string]int64{
+		"alice:phone":  3,
+		"alice:laptop": 1,
+		"alice:tablet": 2,
+		"app":          2,
+	} {
+		got, err := stats.Value(naming.Join("security", "ratelimit", "test", "allowed", key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%v: got %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestAuthorizerFromFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "limits.json")
+	write := func(l Limits, mtime time.Time) {
+		f, err := os.Create(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := WriteLimits(f, l); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if err := os.Chtimes(filename, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	first := Limits{"alice": {Rate: 1, Burst: 1}}
+	write(first, now)
+	auth, err := AuthorizerFromFile("file", filename, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := auth.(*fileAuthorizer)
+	if got, want := a.limits, first; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	second := Limits{"bob": {Rate: 2, Burst: 3, Shared: true}}
+	write(second, now.Add(time.Second))
+	if err := a.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := a.limits, second; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	os.Remove(filename)
+	if err := a.reload(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+type denyAll struct{}
+
+func (denyAll) Authorize(ctx *context.T, call security.Call) error {
+	return verror.ErrNoAccess.Errorf(ctx, "denied")
+}
+
+func TestUnauthorizedCallsNotLimited(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	p := testutil.NewPrincipal("alice")
+	blessings, _ := p.BlessingStore().Default()
+	call := security.NewCall(&security.CallParams{
+		LocalPrincipal:  p,
+		RemoteBlessings: blessings,
+	})
+	limits := Limits{"alice": {Rate: 1, Burst: 1}}
+	// Calls that are not authorized consume no tokens.
+	a := newAuthorizer("unauthorized", limits, denyAll{})
+	for i := 0; i < 3; i++ {
+		if err := a.Authorize(ctx, call); !errors.Is(err, verror.ErrNoAccess) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got, want := len(a.buckets), 0; got != want {
+		t.Errorf("got %v buckets, want %v", got, want)
+	}
+	a = newAuthorizer("authorized", limits, security.AllowEveryone())
+	if err := a.Authorize(ctx, call); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Authorize(ctx, call); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	a := newAuthorizer("setlimits", Limits{
+		"alice": {Rate: 1, Burst: 1},
+		"bob":   {Rate: 1, Burst: 1},
+	}, nil)
+	a.now = (&fakeClock{t: time.Now()}).now
+	for _, name := range []string{"alice", "bob"} {
+		if err := a.take(ctx, []string{name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changing alice's limit discards her bucket and her stats, whereas bob's
+	// bucket is unaffected.
+	a.setLimits(Limits{
+		"alice": {Rate: 1, Burst: 2},
+		"bob":   {Rate: 1, Burst: 1},
+	})
+	for i := 0; i < 2; i++ {
+		if err := a.take(ctx, []string{"alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.take(ctx, []string{"bob"}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Removing bob's limit discards his bucket and his stats.
+	a.setLimits(Limits{"alice": {Rate: 1, Burst: 2}})
+	if got, want := a.allowed.Keys(), []string{"alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := len(a.buckets), 1; got != want {
+		t.Errorf("got %v buckets, want %v", got, want)
+	}
+}
+
+func TestMaxBuckets(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	a := newAuthorizer("maxbuckets", Limits{"alice": {Rate: 0, Burst: 1}}, nil)
+	a.now = (&fakeClock{t: time.Now()}).now
+	name := func(i int) string { return fmt.Sprintf("alice:%d", i) }
+	// None of the buckets are full, yet the least recently used are
+	// discarded once there are maxBuckets of them.
+	for i := 0; i < maxBuckets+2; i++ {
+		if err := a.take(ctx, []string{name(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := len(a.buckets), maxBuckets; got != want {
+		t.Errorf("got %v buckets, want %v", got, want)
+	}
+	if got, want := len(a.allowed.Keys()), maxBuckets; got != want {
+		t.Errorf("got stats for %v keys, want %v", got, want)
+	}
+	if err := a.take(ctx, []string{name(maxBuckets + 1)}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := a.take(ctx, []string{name(0)}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(a.buckets), maxBuckets; got != want {
+		t.Errorf("got %v buckets, want %v", got, want)
+	}
+}
@@ -16,6 +16,9 @@ type TimeKeeper interface {
 	// After waits for the duration to elapse and then sends the current
 	// time on the returned channel.
 	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a Timer that sends the current time on its channel
+	// after the duration has elapsed, unless it is stopped first.
+	NewTimer(d time.Duration) Timer
 	// Sleep pauses the current goroutine for at least the duration d. A
 	// negative or zero duration causes Sleep to return immediately.
 	Sleep(d time.Duration)
@@ -23,6 +26,15 @@ type TimeKeeper interface {
 	Now() time.Time
 }
 
+// Timer is a single event that can be stopped, see time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing.  It returns true if the call
+	// stops the timer, false if the timer has already fired or been stopped.
+	Stop() bool
+}
+
 // realTime is the default implementation of TimeKeeper, using the time package.
 type realTime struct{}
 
@@ -33,6 +45,21 @@ func (t *realTime) After(d time.Duration) <-chan time.Time {
 	return time.After(d)
 }
 
+// realTimer implements Timer using a time.Timer.
+type realTimer struct {
+	*time.Timer
+}
+
+// C implements Timer.C.
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// NewTimer implements TimeKeeper.NewTimer.
+func (t *realTime) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
 // Sleep implements TimeKeeper.Sleep.
 func (t *realTime) Sleep(d time.Duration) {
 	time.Sleep(d)
@@ -36,3 +36,16 @@ func TestSleep(t *testing.T) {
 		t.Errorf("Too long: %s", after.Sub(before))
 	}
 }
+
+func TestNewTimer(t *testing.T) {
+	tk := RealTime()
+	timer := tk.NewTimer(time.Hour)
+	if !timer.Stop() {
+		t.Errorf("Expected Stop to stop the timer")
+	}
+	timer = tk.NewTimer(time.Millisecond)
+	<-timer.C()
+	if timer.Stop() {
+		t.Errorf("Expected Stop to find the timer fired")
+	}
+}
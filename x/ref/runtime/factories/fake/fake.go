@@ -18,6 +18,7 @@ import (
 	"v.io/x/ref/runtime/protocols/lib/websocket"
 	_ "v.io/x/ref/runtime/protocols/local" //nolint:revive
 	_ "v.io/x/ref/runtime/protocols/tcp"   //nolint:revive
+	_ "v.io/x/ref/runtime/protocols/tls"   //nolint:revive
 	_ "v.io/x/ref/runtime/protocols/ws"    //nolint:revive
 	_ "v.io/x/ref/runtime/protocols/wsh"   //nolint:revive
 )
@@ -12,9 +12,11 @@ import (
 	"v.io/x/ref/lib/flags"
 	"v.io/x/ref/runtime/factories/library"
 	"v.io/x/ref/runtime/protocols/lib/websocket"
-	_ "v.io/x/ref/runtime/protocols/tcp" // Initialize tcp, ws and wsh.
-	_ "v.io/x/ref/runtime/protocols/ws"
-	_ "v.io/x/ref/runtime/protocols/wsh"
+	_ "v.io/x/ref/runtime/protocols/tcp"  // Initialize tcp.
+	_ "v.io/x/ref/runtime/protocols/tls"  // Initialize tls.
+	_ "v.io/x/ref/runtime/protocols/unix" // Initialize unix.
+	_ "v.io/x/ref/runtime/protocols/ws"   // Initialize ws and wss.
+	_ "v.io/x/ref/runtime/protocols/wsh"  // Initialize wsh.
 )
 
 func init() {
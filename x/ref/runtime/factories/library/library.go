@@ -40,11 +40,14 @@ import (
 	"v.io/x/ref/lib/flags"
 	"v.io/x/ref/lib/pubsub"
 	"v.io/x/ref/runtime/internal"
+	"v.io/x/ref/runtime/internal/flow/manager"
 	"v.io/x/ref/runtime/internal/rt"
 	"v.io/x/ref/runtime/protocols/lib/websocket"
-	_ "v.io/x/ref/runtime/protocols/tcp" // Initialize tcp, ws and wsh.
-	_ "v.io/x/ref/runtime/protocols/ws"
-	_ "v.io/x/ref/runtime/protocols/wsh"
+	_ "v.io/x/ref/runtime/protocols/tcp"  // Initialize tcp.
+	_ "v.io/x/ref/runtime/protocols/tls"  // Initialize tls.
+	_ "v.io/x/ref/runtime/protocols/unix" // Initialize unix.
+	_ "v.io/x/ref/runtime/protocols/ws"   // Initialize ws and wss.
+	_ "v.io/x/ref/runtime/protocols/wsh"  // Initialize wsh.
 	"v.io/x/ref/services/debug/debuglib"
 )
 
@@ -111,6 +114,40 @@ var (
 	// will be considered for eviction from the cache.
 	ConnectionExpiryDuration = 10 * time.Minute
 
+	// ConnectionResumptionTimeout, if non-zero, enables connections to be
+	// resumed over new network connections, for example after a change
+	// of network, provided that this can be done within the timeout.
+	ConnectionResumptionTimeout time.Duration
+
+	// MaxCachedConnections, if non-zero, limits the number of connections
+	// that will be cached. Once the limit is exceeded, idle connections are
+	// evicted from the cache, least recently used first unless
+	// EvictLeastActiveConnections is set.
+	MaxCachedConnections int
+
+	// EvictLeastActiveConnections causes the connections that have been
+	// reused the fewest times to be evicted first once MaxCachedConnections
+	// is exceeded.
+	EvictLeastActiveConnections bool
+
+	// ConnectionCompression enables the compression of the data sent over
+	// connections whose peers enable it too. It is off by default since
+	// compressing data before it is encrypted may reveal some of it to an
+	// attacker who can observe the size of the messages sent.
+	ConnectionCompression bool
+
+	// ConnectionCompressionThreshold, if non-zero, is the size below which
+	// data is not compressed.
+	ConnectionCompressionThreshold uint64
+
+	// ConnectionRekeyBytes and ConnectionRekeyInterval, if non-zero,
+	// determine how often the keys used to encrypt the data sent over
+	// connections are rotated, namely after that many bytes have been
+	// encrypted with a key or that much time has elapsed since it was
+	// first used, whichever comes first.
+	ConnectionRekeyBytes    uint64
+	ConnectionRekeyInterval time.Duration
+
 	state factoryState
 )
 
@@ -233,6 +270,23 @@ func configureLogging() error {
 	return err
 }
 
+func connectionCacheLimit() manager.CacheLimit {
+	limit := manager.CacheLimit{MaxConns: MaxCachedConnections}
+	if EvictLeastActiveConnections {
+		limit.Policy = manager.EvictLeastActive
+	}
+	return limit
+}
+
+func connectionOptions() manager.ConnOptions {
+	return manager.ConnOptions{
+		Compression:          ConnectionCompression,
+		CompressionThreshold: ConnectionCompressionThreshold,
+		RekeyBytes:           ConnectionRekeyBytes,
+		RekeyInterval:        ConnectionRekeyInterval,
+	}
+}
+
 type passthroughAddressChooser struct{}
 
 func (c *passthroughAddressChooser) ChooseAddresses(protocol string, candidates []net.Addr) ([]net.Addr, error) {
@@ -340,7 +394,10 @@ func Init(ctx *context.T) (v23.Runtime, *context.T, v23.Shutdown, error) { //nol
 		runtimeFlags,
 		reservedDispatcher,
 		&PermissionsSpec,
-		ConnectionExpiryDuration)
+		ConnectionExpiryDuration,
+		ConnectionResumptionTimeout,
+		connectionCacheLimit(),
+		connectionOptions())
 	if err != nil {
 		ishutdown(discoveryFactory.Shutdown)
 		return nil, nil, nil, err
@@ -16,6 +16,10 @@
 // The pubsub.Publisher mechanism is used for communicating networking
 // settings to the rpc.Server implementation of the runtime and publishes
 // the Settings it expects.
+//
+// Connections are not resumed over new network connections after such
+// changes unless library.ConnectionResumptionTimeout is set before the
+// runtime is initialized.
 package roaming
 
 import (
@@ -24,9 +28,11 @@ import (
 	"v.io/v23/flow"
 
 	"v.io/x/ref/runtime/protocols/lib/websocket"
-	_ "v.io/x/ref/runtime/protocols/tcp" // Initialize tcp, ws and wsh.
-	_ "v.io/x/ref/runtime/protocols/ws"
-	_ "v.io/x/ref/runtime/protocols/wsh"
+	_ "v.io/x/ref/runtime/protocols/tcp"  // Initialize tcp.
+	_ "v.io/x/ref/runtime/protocols/tls"  // Initialize tls.
+	_ "v.io/x/ref/runtime/protocols/unix" // Initialize unix.
+	_ "v.io/x/ref/runtime/protocols/ws"   // Initialize ws and wss.
+	_ "v.io/x/ref/runtime/protocols/wsh"  // Initialize wsh.
 )
 
 func init() {
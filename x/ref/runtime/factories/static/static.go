@@ -17,9 +17,11 @@ import (
 	"v.io/v23/flow"
 
 	"v.io/x/ref/runtime/protocols/lib/websocket"
-	_ "v.io/x/ref/runtime/protocols/tcp" // Initialize tcp, ws and wsh.
-	_ "v.io/x/ref/runtime/protocols/ws"
-	_ "v.io/x/ref/runtime/protocols/wsh"
+	_ "v.io/x/ref/runtime/protocols/tcp"  // Initialize tcp.
+	_ "v.io/x/ref/runtime/protocols/tls"  // Initialize tls.
+	_ "v.io/x/ref/runtime/protocols/unix" // Initialize unix.
+	_ "v.io/x/ref/runtime/protocols/ws"   // Initialize ws and wss.
+	_ "v.io/x/ref/runtime/protocols/wsh"  // Initialize wsh.
 )
 
 func init() {
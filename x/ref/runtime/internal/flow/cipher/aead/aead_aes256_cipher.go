@@ -12,14 +12,26 @@ import (
 	"encoding/binary"
 
 	"golang.org/x/crypto/curve25519"
+	flowcipher "v.io/x/ref/runtime/internal/flow/cipher"
 )
 
 // T wraps aead.GCM to provide synchronized nonces between the requestor
 // and responder assuming they interact in a strict request/response manner.
+// Separate keys are maintained for each direction so that they may be
+// rotated independently.
 type T struct {
-	gcm            cipher.AEAD
-	channelBinding [64]byte
-	stream         boxStream
+	sealKey, openKey [32]byte
+	sealGCM, openGCM cipher.AEAD
+	channelBinding   [64]byte
+	stream           boxStream
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
 }
 
 // NewCipher returns a Cipher for RPC versions greater than or equal to 15.
@@ -33,14 +45,12 @@ func NewCipher(myPublicKey, myPrivateKey, theirPublicKey *[32]byte) (*T, error)
 	if err != nil {
 		return nil, err
 	}
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, err
-	}
-	c.gcm, err = cipher.NewGCM(block)
-	if err != nil {
+	copy(c.sealKey[:], key)
+	copy(c.openKey[:], key)
+	if c.sealGCM, err = newGCM(key); err != nil {
 		return nil, err
 	}
+	c.openGCM = c.sealGCM
 	if bytes.Compare(myPublicKey[:], theirPublicKey[:]) < 0 {
 		c.stream.InitDirection(true)
 		copy(c.channelBinding[:], (*myPublicKey)[:])
@@ -54,17 +64,17 @@ func NewCipher(myPublicKey, myPrivateKey, theirPublicKey *[32]byte) (*T, error)
 }
 
 func (c *T) Overhead() int {
-	return c.gcm.Overhead()
+	return c.sealGCM.Overhead()
 }
 
 func (c *T) Seal(buf, data []byte) ([]byte, error) {
-	ret := c.gcm.Seal(buf, c.stream.SealNonce(), data, nil)
+	ret := c.sealGCM.Seal(buf, c.stream.SealNonce(), data, nil)
 	c.stream.SealAdvance()
 	return ret, nil
 }
 
 func (c *T) Open(buf, data []byte) ([]byte, bool) {
-	ret, err := c.gcm.Open(buf, c.stream.OpenNonce(), data, nil)
+	ret, err := c.openGCM.Open(buf, c.stream.OpenNonce(), data, nil)
 	if err != nil {
 		// Return without advancing the nonce so that the stream remains in sync.
 		return nil, false
@@ -73,6 +83,37 @@ func (c *T) Open(buf, data []byte) ([]byte, bool) {
 	return ret, true
 }
 
+// RekeySeal replaces the key used by Seal with one derived from the current
+// key and the supplied salt. The peer must call RekeyOpen with the same salt
+// before opening any messages sealed after this call.
+func (c *T) RekeySeal(salt []byte) error {
+	key, err := flowcipher.DeriveKey(&c.sealKey, salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key[:])
+	if err != nil {
+		return err
+	}
+	c.sealKey, c.sealGCM = *key, gcm
+	return nil
+}
+
+// RekeyOpen replaces the key used by Open with one derived from the current
+// key and the supplied salt.
+func (c *T) RekeyOpen(salt []byte) error {
+	key, err := flowcipher.DeriveKey(&c.openKey, salt)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key[:])
+	if err != nil {
+		return err
+	}
+	c.openKey, c.openGCM = *key, gcm
+	return nil
+}
+
 func (c *T) ChannelBinding() []byte {
 	return c.channelBinding[:]
 }
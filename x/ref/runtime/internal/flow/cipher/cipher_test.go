@@ -117,6 +117,61 @@ func testCipherOpenSealRand(t *testing.T, c1, c2 cipher.API, size int) {
 	}
 }
 
+func testCipherRekey(t *testing.T, c1, c2 cipher.API) {
+	seal := func(c cipher.API, s string) []byte {
+		buf, msg := newMessage(c, s)
+		ret, err := c.Seal(buf, msg)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return ret
+	}
+	open := func(c cipher.API, box []byte, want string) bool {
+		got, ok := c.Open(nil, box)
+		if ok && string(got) != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		return ok
+	}
+	if !open(c2, seal(c1, "before"), "before") {
+		t.Fatalf("failed to open message")
+	}
+	salt := []byte("salt")
+	if err := c1.RekeySeal(salt); err != nil {
+		t.Fatal(err)
+	}
+	box := seal(c1, "after")
+	// The message cannot be opened until the receiver has rotated its key.
+	if open(c2, box, "after") {
+		t.Fatalf("message should not have been opened with the old key")
+	}
+	if err := c2.RekeyOpen(salt); err != nil {
+		t.Fatal(err)
+	}
+	if !open(c2, box, "after") {
+		t.Fatalf("failed to open message with the new key")
+	}
+	// The keys for the other direction are unchanged.
+	if !open(c1, seal(c2, "reply"), "reply") {
+		t.Fatalf("failed to open message in the other direction")
+	}
+}
+
+func TestCipherRekey(t *testing.T) {
+	for _, newCiphers := range []func() (cipher.API, cipher.API, error){
+		cipher.NewRPC11Ciphers,
+		cipher.NewRPC16Ciphers,
+	} {
+		c1, c2, err := newCiphers()
+		if err != nil {
+			t.Fatal(err)
+		}
+		testCipherRekey(t, c1, c2)
+		testCipherOpenSeal(t, c1, c2)
+		testCipherOpenSealRand(t, c1, c2, 1024)
+	}
+}
+
 func TestCipherOpenSealRPC11(t *testing.T) {
 	c1, c2, err := cipher.NewRPC11Ciphers()
 	if err != nil {
@@ -135,8 +190,8 @@ func TestCipherOpenSealRPC11MixedKeys(t *testing.T) {
 	testCipherOpenSealRand(t, c1, c2, 1024)
 }
 
-func TestCipherOpenSealRPC15(t *testing.T) {
-	c1, c2, err := cipher.NewRPC15Ciphers()
+func TestCipherOpenSealRPC16(t *testing.T) {
+	c1, c2, err := cipher.NewRPC16Ciphers()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -173,8 +228,8 @@ func benchmarkRPC11(b *testing.B, size int) {
 	benchmarkCipher(b, c1, c2, size)
 }
 
-func benchmarkRPC15(b *testing.B, size int) {
-	c1, c2, err := cipher.NewRPC15Ciphers()
+func benchmarkRPC16(b *testing.B, size int) {
+	c1, c2, err := cipher.NewRPC16Ciphers()
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -213,14 +268,14 @@ func Benchmark_RPC11___1MBB(b *testing.B) {
 	benchmarkRPC11(b, 1000000)
 }
 
-func Benchmark_RPC15____1KB(b *testing.B) {
-	benchmarkRPC15(b, 1000)
+func Benchmark_RPC16____1KB(b *testing.B) {
+	benchmarkRPC16(b, 1000)
 }
 
-func Benchmark_RPC15___10KB(b *testing.B) {
-	benchmarkRPC15(b, 10000)
+func Benchmark_RPC16___10KB(b *testing.B) {
+	benchmarkRPC16(b, 10000)
 }
 
-func Benchmark_RPC15___1MBB(b *testing.B) {
-	benchmarkRPC15(b, 1000000)
+func Benchmark_RPC16___1MBB(b *testing.B) {
+	benchmarkRPC16(b, 1000000)
 }
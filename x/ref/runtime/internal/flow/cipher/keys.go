@@ -5,10 +5,12 @@
 package cipher
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
 
 	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
 )
 
 // GenerateKey is the same as box.GenerateKey but uses X25519 rather than
@@ -37,3 +39,16 @@ func GenerateKey(rand io.Reader) (publicKey, privateKey *[32]byte, err error) {
 	}
 	return
 }
+
+var rekeyInfo = []byte("v.io/flow/rekey")
+
+// DeriveKey returns a new key derived from the supplied key and salt using
+// HKDF with SHA-256. It is used to rotate the keys used by a cipher
+// without requiring a new key exchange.
+func DeriveKey(key *[32]byte, salt []byte) (*[32]byte, error) {
+	derived := new([32]byte)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key[:], salt, rekeyInfo), derived[:]); err != nil {
+		return nil, err
+	}
+	return derived, nil
+}
@@ -13,15 +13,17 @@ import (
 	"golang.org/x/crypto/nacl/box"
 	"golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/crypto/salsa20/salsa"
+	"v.io/x/ref/runtime/internal/flow/cipher"
 )
 
 // T wraps go.crypto/nacl/{box,secretbox} to provide synchronized
 // nonces between the requestor and responder assuming they interact in a
-// strict request/response manner.
+// strict request/response manner. Separate keys are maintained for each
+// direction so that they may be rotated independently.
 type T struct {
-	sharedKey      [32]byte
-	channelBinding [64]byte
-	stream         boxStream
+	sealKey, openKey [32]byte
+	channelBinding   [64]byte
+	stream           boxStream
 }
 
 var zeros [16]byte
@@ -43,9 +45,10 @@ func precompute(sharedKey, peersPublicKey, privateKey *[32]byte) error {
 // The underlying cipher is nacl/secretbox.
 func NewCipher(myPublicKey, myPrivateKey, theirPublicKey *[32]byte) (*T, error) {
 	var c T
-	if err := precompute(&c.sharedKey, theirPublicKey, myPrivateKey); err != nil {
+	if err := precompute(&c.sealKey, theirPublicKey, myPrivateKey); err != nil {
 		return nil, err
 	}
+	c.openKey = c.sealKey
 	if bytes.Compare(myPublicKey[:], theirPublicKey[:]) < 0 {
 		c.stream.InitDirection(true)
 		copy(c.channelBinding[:], (*myPublicKey)[:])
@@ -63,13 +66,13 @@ func (c *T) Overhead() int {
 }
 
 func (c *T) Seal(buf, data []byte) ([]byte, error) {
-	ret := secretbox.Seal(buf, data, c.stream.SealNonce(), &c.sharedKey)
+	ret := secretbox.Seal(buf, data, c.stream.SealNonce(), &c.sealKey)
 	c.stream.SealAdvance()
 	return ret, nil
 }
 
 func (c *T) Open(buf, data []byte) ([]byte, bool) {
-	ret, ok := secretbox.Open(buf, data, c.stream.OpenNonce(), &c.sharedKey)
+	ret, ok := secretbox.Open(buf, data, c.stream.OpenNonce(), &c.openKey)
 	if !ok {
 		// Return without advancing the nonce so that the stream remains in sync.
 		return nil, false
@@ -78,6 +81,29 @@ func (c *T) Open(buf, data []byte) ([]byte, bool) {
 	return ret, ok
 }
 
+// RekeySeal replaces the key used by Seal with one derived from the current
+// key and the supplied salt. The peer must call RekeyOpen with the same salt
+// before opening any messages sealed after this call.
+func (c *T) RekeySeal(salt []byte) error {
+	key, err := cipher.DeriveKey(&c.sealKey, salt)
+	if err != nil {
+		return err
+	}
+	c.sealKey = *key
+	return nil
+}
+
+// RekeyOpen replaces the key used by Open with one derived from the current
+// key and the supplied salt.
+func (c *T) RekeyOpen(salt []byte) error {
+	key, err := cipher.DeriveKey(&c.openKey, salt)
+	if err != nil {
+		return err
+	}
+	c.openKey = *key
+	return nil
+}
+
 func (c *T) ChannelBinding() []byte {
 	return c.channelBinding[:]
 }
@@ -219,6 +219,9 @@ func (c *Conn) createSetupMessage(pk *[32]byte, versions version.RPCVersionRange
 		PeerLocalEndpoint: c.local,
 		Mtu:               c.mtu,
 		SharedTokens:      c.flowControl.bytesBufferedPerFlow,
+		Compression:       c.mp.compressor.algorithms(),
+		Resumable:         c.offerResumption(),
+		Rekey:             true,
 	}
 	copy(lSetup.PeerNaClPublicKey[:], (*pk)[:])
 	if !c.remote.IsZero() {
@@ -262,6 +265,9 @@ func (c *Conn) setup(ctx *context.T, versions version.RPCVersionRange, dialer bo
 	if err := <-errCh; err != nil {
 		return nil, naming.Endpoint{}, rttstart, ErrSend.Errorf(ctx, "conn.setup: remote %v: %v", c.remoteEndpointForError(), err)
 	}
+	if len(rSetup.ResumeID) > 0 && !dialer {
+		return nil, naming.Endpoint{}, rttstart, c.resumeAccepted(ctx, versions, &lSetup, &rSetup)
+	}
 
 	if c.version, err = version.CommonVersion(ctx, versions, rSetup.Versions); err != nil {
 		return nil, naming.Endpoint{}, rttstart, err
@@ -280,6 +286,10 @@ func (c *Conn) setup(ctx *context.T, versions version.RPCVersionRange, dialer bo
 		return nil, naming.Endpoint{}, rttstart, err
 	}
 	c.mp.setMTU(c.mtu, c.flowControl.bytesBufferedPerFlow)
+	c.mp.compressor.configure(&lSetup, &rSetup)
+	if rSetup.Rekey {
+		c.mp.rekey.setSupported()
+	}
 
 	if c.version >= version.RPCVersion14 {
 		// We include the setup messages in the channel binding to prevent attacks
@@ -306,9 +316,58 @@ func (c *Conn) setup(ctx *context.T, versions version.RPCVersionRange, dialer bo
 	// if we're encapsulated in another flow, tell that flow to stop
 	// encrypting now that we've started.
 	c.mp.disableEncryptionOnEncapsulatedFlow()
+	if lSetup.Resumable && rSetup.Resumable {
+		var shared [32]byte
+		box.Precompute(&shared, &rSetup.PeerNaClPublicKey, sk)
+		c.resumable = newResumableConn(ctx, c.mp.rw, &shared, versions, c.resumeTimeout, c.redial, c.resumptions)
+		c.mp.rw = c.resumable
+		c.mp.framer, c.mp.frameOffset = nil, 0
+	}
 	return binding, rSetup.PeerLocalEndpoint, rttstart, nil
 }
 
+// offerResumption returns true if the Conn is able to resume over a new
+// network connection should its current one fail. Conns encapsulated in
+// flows of other Conns cannot be resumed.
+func (c *Conn) offerResumption() bool {
+	if c.resumeTimeout <= 0 || c.mp.isEncapsulated() {
+		return false
+	}
+	return c.redial != nil || c.resumptions != nil
+}
+
+// resumeAccepted is called when the Setup message received by an accepted
+// Conn requests the resumption of an existing Conn. The network connection
+// is handed over to the existing Conn if it can be found and the dialer
+// proves that it is the existing Conn's peer, in which case ErrConnResumed
+// is returned and this Conn must be discarded.
+func (c *Conn) resumeAccepted(ctx *context.T, versions version.RPCVersionRange, lSetup, rSetup *message.Setup) error {
+	var r *resumableConn
+	if c.resumptions != nil {
+		r = c.resumptions.lookup(rSetup.ResumeID)
+	}
+	// The public key sent by this Conn is used as its nonce.
+	acceptorNonce := lSetup.PeerNaClPublicKey[:]
+	if r == nil || !r.authenticate(rSetup.ResumeNonce, acceptorNonce, rSetup.ResumeReceived, rSetup.ResumeProof) {
+		// A Setup message without a proof informs the dialer that the
+		// connection cannot be resumed.
+		if err := c.mp.writeSetup(ctx, message.Setup{Versions: versions}); err != nil {
+			return err
+		}
+		if r == nil {
+			return ErrCannotResume.Errorf(ctx, "conn.setup: no such connection to resume")
+		}
+		return ErrInvalidResumeProof.Errorf(ctx, "conn.setup: the dialer's proof of the shared secret was invalid")
+	}
+	raw := c.mp.rw
+	c.mp.rw = detachedConn{}
+	if err := r.resumeAccepted(raw, rSetup.ResumeNonce, acceptorNonce, rSetup.ResumeReceived); err != nil {
+		raw.Close()
+		return err
+	}
+	return ErrConnResumed.Errorf(ctx, "conn.setup: the network connection was used to resume an existing connection")
+}
+
 // readRemoteAuth is used to read the auth handshake messages from the remote
 // endpoint. This is a sequence of Data messages followed by an Auth message.
 // readRemoteAuth runs asynchronously on the both the dialer and acceptor.
@@ -0,0 +1,165 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"v.io/v23/context"
+	"v.io/v23/flow/message"
+	"v.io/x/ref/lib/stats"
+)
+
+// DefaultCompressionThreshold is the default size below which the payloads
+// of Data messages are not compressed.
+const DefaultCompressionThreshold = 1024
+
+// The totals, across all connections, of the bytes saved by compression.
+var (
+	compressionSavedSent     = stats.NewInteger("rpc/flow/compression/bytes-saved-sent")
+	compressionSavedReceived = stats.NewInteger("rpc/flow/compression/bytes-saved-received")
+)
+
+var (
+	flateWriters = sync.Pool{
+		New: func() interface{} {
+			w, _ := flate.NewWriter(nil, flate.BestSpeed)
+			return w
+		},
+	}
+	flateReaders = sync.Pool{
+		New: func() interface{} {
+			return flate.NewReader(nil)
+		},
+	}
+
+	errNotCompressible = errors.New("payload is not compressible")
+)
+
+// CompressionStats records the effect of compression on the payloads of the
+// Data messages sent and received on a connection. Only compressed payloads
+// are counted.
+type CompressionStats struct {
+	// SentBytes and SentCompressedBytes are the sizes, before and after
+	// compression, of the payloads sent.
+	SentBytes, SentCompressedBytes uint64
+	// ReceivedBytes and ReceivedCompressedBytes are the sizes, after and
+	// before decompression, of the payloads received.
+	ReceivedBytes, ReceivedCompressedBytes uint64
+}
+
+func (s CompressionStats) String() string {
+	return fmt.Sprintf("sent %d bytes as %d, received %d bytes as %d",
+		s.SentBytes, s.SentCompressedBytes, s.ReceivedBytes, s.ReceivedCompressedBytes)
+}
+
+// compressor compresses and decompresses the payloads of Data messages once
+// both ends of a connection have advertised support for compression.
+type compressor struct {
+	// These variables are all set before the connection's setup completes
+	// and never changed after that.
+	disabled  bool
+	threshold int
+	enabled   bool
+
+	stats CompressionStats // accessed atomically.
+}
+
+func (c *compressor) init(threshold uint64, disabled bool) {
+	c.threshold = int(threshold)
+	c.disabled = disabled
+}
+
+// algorithms returns the compression algorithms to be advertised in the
+// Setup message.
+func (c *compressor) algorithms() uint64 {
+	if c.disabled {
+		return 0
+	}
+	return message.CompressionFlate
+}
+
+// configure enables compression if it is supported by both ends of the
+// connection.
+func (c *compressor) configure(lSetup, rSetup *message.Setup) {
+	c.enabled = lSetup.Compression&rSetup.Compression&message.CompressionFlate != 0
+}
+
+// fixedBuffer is an io.Writer that fails rather than grow its buffer.
+type fixedBuffer struct {
+	buf []byte
+}
+
+func (b *fixedBuffer) Write(p []byte) (int, error) {
+	if len(b.buf)+len(p) > cap(b.buf) {
+		return 0, errNotCompressible
+	}
+	b.buf = append(b.buf, p...)
+	return len(p), nil
+}
+
+// compress returns the compressed payload and the flags to be sent with it,
+// along with the netBuf that backs the payload, which must be released
+// once it has been sent. The payload is returned unchanged if it is smaller
+// than the threshold or compression does not reduce its size.
+func (c *compressor) compress(payload []byte, flags uint64) ([]byte, uint64, *netBuf) {
+	if !c.enabled || len(payload) < c.threshold {
+		return payload, flags, nil
+	}
+	nb, buf := getNetBuf(len(payload))
+	out := &fixedBuffer{buf: buf[: 0 : len(payload)-1]}
+	w := flateWriters.Get().(*flate.Writer)
+	defer flateWriters.Put(w)
+	w.Reset(out)
+	if _, err := w.Write(payload); err != nil {
+		return payload, flags, putNetBuf(nb)
+	}
+	if err := w.Close(); err != nil {
+		return payload, flags, putNetBuf(nb)
+	}
+	atomic.AddUint64(&c.stats.SentBytes, uint64(len(payload)))
+	atomic.AddUint64(&c.stats.SentCompressedBytes, uint64(len(out.buf)))
+	compressionSavedSent.Incr(int64(len(payload) - len(out.buf)))
+	return out.buf, flags | message.CompressedFlag, nb
+}
+
+// decompress returns the decompressed payload of a Data message along with
+// the netBuf that backs it. The decompressed payload may be no larger
+// than limit.
+func (c *compressor) decompress(ctx *context.T, payload []byte, limit int) ([]byte, *netBuf, error) {
+	if !c.enabled {
+		return nil, nil, ErrCannotDecompress.Errorf(ctx, "conn.decompress: compression was not negotiated")
+	}
+	nb, buf := getNetBuf(limit + 1)
+	r := flateReaders.Get().(io.ReadCloser)
+	defer flateReaders.Put(r)
+	r.(flate.Resetter).Reset(bytes.NewReader(payload), nil) //nolint:errcheck
+	n, err := io.ReadFull(r, buf[:limit+1])
+	switch {
+	case err == nil:
+		return nil, putNetBuf(nb), ErrCannotDecompress.Errorf(ctx, "conn.decompress: payload exceeds %v bytes", limit)
+	case err != io.EOF && err != io.ErrUnexpectedEOF:
+		return nil, putNetBuf(nb), ErrCannotDecompress.Errorf(ctx, "conn.decompress: %v", err)
+	}
+	atomic.AddUint64(&c.stats.ReceivedBytes, uint64(n))
+	atomic.AddUint64(&c.stats.ReceivedCompressedBytes, uint64(len(payload)))
+	compressionSavedReceived.Incr(int64(n - len(payload)))
+	return buf[:n], nb, nil
+}
+
+func (c *compressor) statistics() CompressionStats {
+	return CompressionStats{
+		SentBytes:               atomic.LoadUint64(&c.stats.SentBytes),
+		SentCompressedBytes:     atomic.LoadUint64(&c.stats.SentCompressedBytes),
+		ReceivedBytes:           atomic.LoadUint64(&c.stats.ReceivedBytes),
+		ReceivedCompressedBytes: atomic.LoadUint64(&c.stats.ReceivedCompressedBytes),
+	}
+}
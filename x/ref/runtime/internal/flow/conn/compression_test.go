@@ -0,0 +1,98 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"bytes"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/flow"
+	"v.io/v23/naming"
+	"v.io/x/ref/runtime/internal/flow/flowtest"
+	"v.io/x/ref/runtime/internal/rpc/version"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/goroutines"
+)
+
+func TestCompression(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	defer netbufsFreed(t)
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+	network, address := "local", ":0"
+	versions := version.Supported
+
+	ridep := naming.Endpoint{Protocol: network, Address: address, RoutingID: naming.FixedRoutingID(191341)}
+	ep := naming.Endpoint{Protocol: network, Address: address}
+	dBlessings, _ := v23.GetPrincipal(ctx).BlessingStore().Default()
+
+	compressible := bytes.Repeat([]byte("compressible "), 4*DefaultMTU)
+	small := compressible[:DefaultCompressionThreshold-1]
+
+	testConn := func(dopts, aopts Opts, disableFlow bool, data []byte, compressed bool) {
+		dmrw, amrw := flowtest.Pipe(t, ctx, network, address)
+		aflows := make(chan flow.Flow, 1)
+		ach := make(chan *Conn)
+		aerrch := make(chan error)
+		go func() {
+			a, err := NewAccepted(ctx, nil, amrw, ridep, versions, fh(aflows), aopts)
+			ach <- a
+			aerrch <- err
+		}()
+		dc, _, _, derr := NewDialed(ctx, dmrw, ep, ep, versions, peerAuthorizer{dBlessings, nil}, nil, dopts)
+		ac, aerr := <-ach, <-aerrch
+		if derr != nil || aerr != nil {
+			t.Fatalf("dial: %v, accept: %v", derr, aerr)
+		}
+		defer dc.Close(ctx, nil)
+		defer ac.Close(ctx, nil)
+
+		// Data sent with the OpenFlow message is never compressed.
+		df, af := oneFlow(t, ctx, dc, aflows, 0)
+		if disableFlow {
+			df.DisableCompression()
+		}
+		errs := make(chan error, 1)
+		go func() {
+			errs <- doWrite(df, data)
+		}()
+		if err := doRead(af, data, nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+
+		dstats, astats := dc.CompressionStats(), ac.CompressionStats()
+		if !compressed {
+			if dstats != (CompressionStats{}) || astats != (CompressionStats{}) {
+				t.Errorf("unexpected compression: dialer: %v, acceptor: %v", dstats, astats)
+			}
+			return
+		}
+		if dstats.SentBytes == 0 || dstats.SentCompressedBytes >= dstats.SentBytes {
+			t.Errorf("data was not compressed: %v", dstats)
+		}
+		if got, want := astats.ReceivedBytes, dstats.SentBytes; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := astats.ReceivedCompressedBytes, dstats.SentCompressedBytes; got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+
+	on := Opts{Compression: true}
+	testConn(on, on, false, compressible, true)
+	testConn(on, on, false, randData[:DefaultMTU], false)
+	testConn(on, on, false, small, false)
+	testConn(on, on, true, compressible, false)
+	// Compression is used only if both ends enable it.
+	testConn(Opts{}, Opts{}, false, compressible, false)
+	testConn(Opts{}, on, false, compressible, false)
+	testConn(on, Opts{}, false, compressible, false)
+	testConn(Opts{Compression: true, CompressionThreshold: 256}, on, false, compressible[:512], true)
+}
@@ -77,6 +77,9 @@ type healthCheckState struct {
 	requestTimer    *time.Timer
 	requestDeadline time.Time
 	lastRTT         time.Duration
+	// missed is the number of consecutive keepalives that have been sent
+	// without a response.
+	missed int
 
 	closeTimer    *time.Timer
 	closeDeadline time.Time
@@ -90,6 +93,7 @@ type Conn struct {
 	version       version.RPCVersion
 	local, remote naming.Endpoint
 	remoteAddr    net.Addr
+	peerCreds     *flow.PeerCredentials
 	closed        chan struct{}
 	lameDucked    chan struct{}
 	blessingsFlow *blessingsFlow
@@ -99,6 +103,17 @@ type Conn struct {
 	handler       FlowHandler
 	mtu           uint64
 
+	// The options used to negotiate resumption and, once it has been
+	// negotiated, the resumable network connection.
+	resumeTimeout time.Duration
+	redial        func(*context.T) (flow.Conn, error)
+	resumptions   *ResumptionTable
+	resumable     *resumableConn
+
+	keepaliveInterval time.Duration
+	missedKeepalives  int
+	rttObserver       func(naming.Endpoint, time.Duration)
+
 	// The following fields for managing flow control and the writeq
 	// are locked independently.
 	flowControl flowControlConnStats
@@ -122,6 +137,9 @@ type Conn struct {
 	flows                             map[uint64]*flw
 	hcstate                           *healthCheckState
 	acceptChannelTimeout              time.Duration
+	// healthCheckResponding is true while a response to a health check
+	// request is being sent.
+	healthCheckResponding bool
 }
 
 type messageSender struct {
@@ -157,6 +175,55 @@ type Opts struct {
 	// BytesBuffered defines the default number of bytes that can be buffered
 	// by a single flow before flow control is invoked.
 	BytesBuffered uint64
+
+	// Compression enables the compression of data written to the
+	// connection's flows, which is used only if the connection's peer
+	// enables it too. It is off by default since compressing data before
+	// it is encrypted allows an attacker who can influence some of the
+	// data to infer the rest from the size of the messages sent.
+	Compression bool
+
+	// CompressionThreshold is the size below which data written to a flow
+	// is not compressed.
+	CompressionThreshold uint64
+
+	// ResumeTimeout, if non-zero, allows the connection to survive the
+	// failure of its underlying network connection by resuming over a new
+	// one, provided that this can be done within the timeout. Resumption
+	// is used only if both ends of the connection support it and requires
+	// Redial to be set for dialed connections and Resumptions to be set for
+	// accepted ones. Note that a connection that remains broken for longer
+	// than the ChannelTimeout will be closed regardless.
+	ResumeTimeout time.Duration
+
+	// Redial is used by a dialed connection to obtain a new network
+	// connection to its peer.
+	Redial func(ctx *context.T) (flow.Conn, error)
+
+	// Resumptions records the accepted connections that may be resumed.
+	Resumptions *ResumptionTable
+
+	// RekeyBytes and RekeyInterval determine how often the key used to
+	// encrypt the messages sent over the connection is rotated, namely
+	// after RekeyBytes have been encrypted with it or RekeyInterval has
+	// elapsed since it was last rotated, whichever comes first. Keys are
+	// rotated only if the connection's peer supports it.
+	RekeyBytes    uint64
+	RekeyInterval time.Duration
+
+	// KeepaliveInterval, if non-zero, is the maximum interval between the
+	// health checks sent over the connection, regardless of the
+	// ChannelTimeout.
+	KeepaliveInterval time.Duration
+
+	// MissedKeepalives, if non-zero, is the number of consecutive health
+	// checks sent at KeepaliveInterval that may go unanswered before the
+	// connection is closed.
+	MissedKeepalives int
+
+	// RTTObserver, if set, is called with every round trip time measured
+	// by the connection's health checks.
+	RTTObserver func(remote naming.Endpoint, rtt time.Duration)
 }
 
 func (co *Opts) initValues(protocol string) error {
@@ -181,6 +248,18 @@ func (co *Opts) initValues(protocol string) error {
 		co.HandshakeTimeout = DefaultHandshakeTimeout
 	}
 
+	if co.CompressionThreshold == 0 {
+		co.CompressionThreshold = DefaultCompressionThreshold
+	}
+
+	if co.RekeyBytes == 0 {
+		co.RekeyBytes = DefaultRekeyBytes
+	}
+
+	if co.RekeyInterval == 0 {
+		co.RekeyInterval = DefaultRekeyInterval
+	}
+
 	if co.ChannelTimeout == 0 {
 		co.ChannelTimeout = DefaultChannelTimeout
 	}
@@ -221,6 +300,7 @@ func NewDialed(
 	if flowConn, ok := conn.(flow.Conn); ok {
 		remoteAddr = flowConn.RemoteAddr()
 	}
+	peerCreds := peerCredentials(conn)
 
 	dctx := ctx
 	ctx, cancel := context.WithRootCancel(ctx)
@@ -237,6 +317,7 @@ func NewDialed(
 		local:                local,
 		remote:               remote,
 		remoteAddr:           remoteAddr,
+		peerCreds:            peerCreds,
 		closed:               make(chan struct{}),
 		lameDucked:           make(chan struct{}),
 		nextFid:              reservedFlows,
@@ -245,10 +326,17 @@ func NewDialed(
 		cancel:               cancel,
 		acceptChannelTimeout: opts.ChannelTimeout,
 		mtu:                  opts.MTU,
+		resumeTimeout:        opts.ResumeTimeout,
+		keepaliveInterval:    opts.KeepaliveInterval,
+		missedKeepalives:     opts.MissedKeepalives,
+		rttObserver:          opts.RTTObserver,
 	}
 
 	c.initWriters()
 	c.flowControl.init(opts.BytesBuffered)
+	c.mp.compressor.init(opts.CompressionThreshold, !opts.Compression)
+	c.mp.rekey.init(opts.RekeyBytes, opts.RekeyInterval)
+	c.redial = opts.Redial
 
 	handshakeCh := make(chan dialHandshakeResult, 1)
 	var handshakeResult dialHandshakeResult
@@ -293,6 +381,7 @@ func NewDialed(
 	}
 
 	c.initializeHealthChecks(ctx, handshakeResult.rtt)
+	c.mp.startRekeying(ctx)
 	// We send discharges asynchronously to prevent making a second RPC while
 	// trying to build up the connection for another. If the two RPCs happen to
 	// go to the same server a deadlock will result.
@@ -342,12 +431,14 @@ func NewAccepted(
 	if flowConn, ok := conn.(flow.Conn); ok {
 		remoteAddr = flowConn.RemoteAddr()
 	}
+	peerCreds := peerCredentials(conn)
 	ctx, cancel := context.WithCancel(ctx)
 	c := &Conn{
 		mp:                   newMessagePipe(conn),
 		handler:              handler,
 		local:                local,
 		remoteAddr:           remoteAddr,
+		peerCreds:            peerCreds,
 		closed:               make(chan struct{}),
 		lameDucked:           make(chan struct{}),
 		nextFid:              reservedFlows + 1,
@@ -356,10 +447,17 @@ func NewAccepted(
 		cancel:               cancel,
 		acceptChannelTimeout: opts.ChannelTimeout,
 		mtu:                  opts.MTU,
+		resumeTimeout:        opts.ResumeTimeout,
+		keepaliveInterval:    opts.KeepaliveInterval,
+		missedKeepalives:     opts.MissedKeepalives,
+		rttObserver:          opts.RTTObserver,
 	}
 
 	c.initWriters()
 	c.flowControl.init(opts.BytesBuffered)
+	c.mp.compressor.init(opts.CompressionThreshold, !opts.Compression)
+	c.mp.rekey.init(opts.RekeyBytes, opts.RekeyInterval)
+	c.resumptions = opts.Resumptions
 
 	handshakeCh := make(chan acceptHandshakeResult, 1)
 	var handshakeResult acceptHandshakeResult
@@ -388,6 +486,7 @@ func NewAccepted(
 		return nil, err
 	}
 	c.initializeHealthChecks(ctx, handshakeResult.rtt)
+	c.mp.startRekeying(ctx)
 	c.loopWG.Add(2)
 	// NOTE: there is a race for refreshTime since it gets set above
 	// in a goroutine but read here without any synchronization.
@@ -458,6 +557,18 @@ func (c *Conn) MTU() uint64 {
 	return c.mtu
 }
 
+// CompressionStats returns the effect of compression on the data sent and
+// received on the connection.
+func (c *Conn) CompressionStats() CompressionStats {
+	return c.mp.compressor.statistics()
+}
+
+// WriteQueueStats returns the statistics for the scheduling of the writes
+// to the flows of priority p on the connection.
+func (c *Conn) WriteQueueStats(p flow.Priority) WriteQueueStats {
+	return c.writeq.statistics(p)
+}
+
 // RTT returns the round trip time of a message to the remote end.
 // Note the initial estimate of the RTT from the accepted side of a connection
 // my be long because we don't fully factor out certificate verification time.
@@ -475,10 +586,20 @@ func (c *Conn) RTT() time.Duration {
 	return rtt
 }
 
+// requestInterval returns the interval at which health checks are sent for
+// the specified channel timeout.
+func (c *Conn) requestInterval(timeout time.Duration) time.Duration {
+	if ki := c.keepaliveInterval; ki > 0 && ki < timeout/2 {
+		return ki
+	}
+	return timeout / 2
+}
+
 func (c *Conn) newHealthChecksLocked(ctx *context.T, firstRTT time.Duration) *healthCheckState {
 	now := time.Now()
+	interval := c.requestInterval(c.acceptChannelTimeout)
 	h := &healthCheckState{
-		requestDeadline: now.Add(c.acceptChannelTimeout / 2),
+		requestDeadline: now.Add(interval),
 
 		closeTimer: time.AfterFunc(c.acceptChannelTimeout, func() {
 			c.internalClose(ctx, false, false, ErrChannelTimeout.Errorf(ctx, "the channel has become unresponsive"))
@@ -486,16 +607,41 @@ func (c *Conn) newHealthChecksLocked(ctx *context.T, firstRTT time.Duration) *he
 		closeDeadline: now.Add(c.acceptChannelTimeout),
 		lastRTT:       firstRTT,
 	}
-	requestTimer := time.AfterFunc(c.acceptChannelTimeout/2, func() {
-		c.sendHealthCheckMessage(ctx, true)
-		c.mu.Lock()
-		h.requestSent = time.Now()
-		c.mu.Unlock()
+	h.requestTimer = time.AfterFunc(interval, func() {
+		c.sendHealthCheckRequest(ctx, h)
 	})
-	h.requestTimer = requestTimer
 	return h
 }
 
+// sendHealthCheckRequest sends a health check request and, if keepalives are
+// enabled, schedules the next one. The connection is closed if too many
+// consecutive keepalives have gone unanswered.
+func (c *Conn) sendHealthCheckRequest(ctx *context.T, h *healthCheckState) {
+	keepalive := c.keepaliveInterval > 0
+	c.mu.Lock()
+	if keepalive && !h.requestSent.IsZero() {
+		h.missed++
+	}
+	missed := h.missed
+	c.mu.Unlock()
+	if keepalive && c.missedKeepalives > 0 && missed >= c.missedKeepalives {
+		c.internalClose(ctx, false, false, ErrChannelTimeout.Errorf(ctx, "the channel has become unresponsive: %v keepalives were missed", missed))
+		return
+	}
+	c.sendHealthCheckMessage(ctx, true) //nolint:errcheck
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// The round trip time is measured from the first of any unanswered
+	// requests.
+	if h.requestSent.IsZero() {
+		h.requestSent = time.Now()
+	}
+	if keepalive && c.status < Closing {
+		h.requestDeadline = time.Now().Add(c.keepaliveInterval)
+		h.requestTimer.Reset(c.keepaliveInterval)
+	}
+}
+
 func (c *Conn) initializeHealthChecks(ctx *context.T, firstRTT time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -520,9 +666,9 @@ func (c *Conn) healthCheckNewFlowLocked(ctx *context.T, timeout time.Duration) {
 			timeout = min
 		}
 		now := time.Now()
-		if rd := now.Add(timeout / 2); rd.Before(c.hcstate.requestDeadline) {
-			c.hcstate.requestDeadline = rd
-			c.hcstate.requestTimer.Reset(timeout / 2)
+		if ri := c.requestInterval(timeout); now.Add(ri).Before(c.hcstate.requestDeadline) {
+			c.hcstate.requestDeadline = now.Add(ri)
+			c.hcstate.requestTimer.Reset(ri)
 		}
 		if cd := now.Add(timeout); cd.Before(c.hcstate.closeDeadline) {
 			c.hcstate.closeDeadline = cd
@@ -607,6 +753,24 @@ func (c *Conn) Dial(ctx *context.T, blessings security.Blessings, discharges []s
 // LocalEndpoint returns the local vanadium Endpoint
 func (c *Conn) LocalEndpoint() naming.Endpoint { return c.local }
 
+// PeerCredentials returns the operating system credentials of the remote
+// process, if they were provided by the underlying protocol.
+func (c *Conn) PeerCredentials() (flow.PeerCredentials, bool) {
+	if c.peerCreds == nil {
+		return flow.PeerCredentials{}, false
+	}
+	return *c.peerCreds, true
+}
+
+func peerCredentials(conn flow.MsgReadWriteCloser) *flow.PeerCredentials {
+	if pc, ok := conn.(flow.PeerCredentialer); ok {
+		if creds, ok := pc.PeerCredentials(); ok {
+			return &creds
+		}
+	}
+	return nil
+}
+
 // RemoteEndpoint returns the remote vanadium Endpoint
 func (c *Conn) RemoteEndpoint() naming.Endpoint {
 	return c.remote
@@ -734,7 +898,11 @@ func (c *Conn) internalClose(ctx *context.T, closedRemotely, closedWhileAcceptin
 }
 
 func (c *Conn) internalCloseAsync(ctx *context.T, flows map[uint64]*flw, closedRemotely, closedWhileAccepting bool, err error) {
-	if !closedRemotely {
+	// There is no point in waiting for a broken network connection to be
+	// resumed just to send a tearDown message, the peer will instead
+	// discover that the connection has been closed when it attempts to
+	// resume it.
+	if !closedRemotely && (c.resumable == nil || !c.resumable.isBroken()) {
 		msg := ""
 		if err != nil {
 			msg = err.Error()
@@ -899,6 +1067,27 @@ func (c *Conn) IsEncapsulated() bool {
 	return c.mp.isEncapsulated()
 }
 
+// Resumptions returns the number of times that the Conn has been resumed
+// over a new network connection.
+func (c *Conn) Resumptions() uint64 {
+	if c.resumable == nil {
+		return 0
+	}
+	return c.resumable.resumptions()
+}
+
+// Reconnect causes a dialed Conn that supports resumption to be resumed
+// over a new network connection, for example, because the network
+// configuration of the local host has changed. It has no effect on other
+// Conns and returns false for them.
+func (c *Conn) Reconnect() bool {
+	if c.resumable == nil || c.redial == nil {
+		return false
+	}
+	c.resumable.reconnect()
+	return true
+}
+
 func (c *Conn) DebugString() string {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -914,6 +1103,14 @@ Version:     %v
 MTU:         %d
 LastUsed:    %v
 #Flows:      %d
+Compression: %v
+Rekeys:      %v
+Resumable:   %v
+Resumptions: %d
+Writes:
+  Control:     %v
+  Interactive: %v
+  Bulk:        %v
 `,
 		c.remote,
 		c.remoteBlessings,
@@ -923,7 +1120,14 @@ LastUsed:    %v
 		c.version,
 		c.mtu,
 		c.lastUsedTime,
-		len(c.flows))
+		len(c.flows),
+		c.mp.compressor.statistics(),
+		&c.mp.rekey,
+		c.resumable != nil,
+		c.Resumptions(),
+		c.writeq.statistics(flow.ControlPriority),
+		c.writeq.statistics(flow.InteractivePriority),
+		c.writeq.statistics(flow.BulkPriority))
 }
 
 func (c *Conn) writeEncodedBlessings(ctx *context.T, w *writer, data []byte) error {
@@ -267,6 +267,72 @@ func TestMinChannelTimeout(t *testing.T) {
 	}
 }
 
+func TestKeepalive(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	defer netbufsFreed(t)
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	var mu sync.Mutex
+	rtts := map[naming.RoutingID]int{}
+	observer := func(remote naming.Endpoint, rtt time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		rtts[remote.RoutingID]++
+	}
+	observed := func() (d, a int) {
+		mu.Lock()
+		defer mu.Unlock()
+		// The dialer's remote endpoint is that of the acceptor.
+		return rtts[naming.FixedRoutingID(191341)], rtts[naming.NullRoutingID]
+	}
+
+	// Keepalives must be sent by both ends even though the channel timeout
+	// is long and each must report the measured round trip times.
+	aflows := make(chan flow.Flow, 1)
+	dc, ac, derr, aerr := setupConnsOpts(t, "local", "", ctx, ctx, nil, aflows, nil, nil, Opts{
+		HandshakeTimeout:  time.Minute,
+		KeepaliveInterval: 5 * time.Millisecond,
+		RTTObserver:       observer,
+	})
+	if derr != nil || aerr != nil {
+		t.Fatal(derr, aerr)
+	}
+	for start := time.Now(); ; time.Sleep(5 * time.Millisecond) {
+		if d, a := observed(); d >= 3 && a >= 3 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			d, a := observed()
+			t.Fatalf("too few keepalives: dialer %v, acceptor %v", d, a)
+		}
+	}
+	dc.Close(ctx, nil)
+	ac.Close(ctx, nil)
+
+	// A conn must be closed once too many keepalives have been missed.
+	dc, ac, derr, aerr = setupConnsOpts(t, "local", "", ctx, ctx, nil, aflows, nil, nil, Opts{
+		HandshakeTimeout:  time.Minute,
+		KeepaliveInterval: 5 * time.Millisecond,
+		MissedKeepalives:  3,
+	})
+	if derr != nil || aerr != nil {
+		t.Fatal(derr, aerr)
+	}
+	defer ac.Close(ctx, nil)
+	// Prevent the acceptor from responding to health checks.
+	ac.healthAndLameDuckSender.Lock()
+	for start := time.Now(); dc.Status() < Closing; time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > 10*time.Second {
+			ac.healthAndLameDuckSender.Unlock()
+			t.Fatalf("conn was not closed after missed keepalives")
+		}
+	}
+	ac.healthAndLameDuckSender.Unlock()
+	<-dc.Closed()
+}
+
 func deadlineInAbout(c *Conn, d time.Duration) error {
 	const slop = 5 * time.Second
 	delta := time.Until(c.healthCheckCloseDeadline())
@@ -33,4 +33,10 @@ var (
 	ErrNoPrivateKey             = verror.NewID("NoPrivateKey")
 	ErrIdleConnKilled           = verror.NewID("IdleConnKilled")
 	ErrRPCVersionMismatch       = verror.NewID("RPCVersionMismatch")
+	ErrCannotDecompress         = verror.NewID("CannotDecompress")
+	ErrConnResumed              = verror.NewID("ConnResumed")
+	ErrCannotResume             = verror.NewID("CannotResume")
+	ErrResumeTimeout            = verror.NewID("ResumeTimeout")
+	ErrInvalidResumeProof       = verror.NewID("InvalidResumeProof")
+	ErrInvalidResumableFrame    = verror.NewID("InvalidResumableFrame")
 )
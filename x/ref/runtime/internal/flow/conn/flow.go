@@ -26,10 +26,12 @@ type flw struct {
 	localDischarges, remoteDischarges security.Discharges
 	noEncrypt                         bool
 	noFragment                        bool
+	noCompress                        bool
 	remote                            naming.Endpoint
 	channelTimeout                    time.Duration
 	sideChannel                       bool
 	encapsulated                      bool
+	priority                          flow.Priority
 
 	// The following fields for managing flow control and the writeq
 	// are locked indepdently.
@@ -122,6 +124,16 @@ func (f *flw) DisableFragmentation() {
 	f.noFragment = true
 }
 
+// DisableCompression should not be called concurrently with Write* methods.
+func (f *flw) DisableCompression() {
+	f.noCompress = true
+}
+
+// SetPriority should not be called concurrently with Write* methods.
+func (f *flw) SetPriority(p flow.Priority) {
+	f.priority = p
+}
+
 // Implement io.Reader.
 // Read and ReadMsg should not be called concurrently with themselves
 // or each other.
@@ -260,7 +272,7 @@ func (f *flw) writeMsg(alsoClose bool, parts [][]byte) (sent int, err error) {
 			if err != nil {
 				return f.writeMsgDone(ctx, sent, alsoClose, err)
 			}
-			if err := f.sendDataMessage(ctx, flowPriority, alsoClose, slice == len(parts), tosend); err != nil {
+			if err := f.sendDataMessage(ctx, writeqPriority(f.priority), alsoClose, slice == len(parts), tosend); err != nil {
 				return f.writeMsgDone(ctx, sent, alsoClose, err)
 			}
 			sent += size
@@ -307,7 +319,13 @@ func (f *flw) sendOpenFlow(ctx *context.T, alsoClose, finalPart bool, payload []
 		return err
 	}
 	flags := f.messageFlags(alsoClose, finalPart)
-	if err := f.writeq.wait(ctx, &f.writeqEntry, flowPriority); err != nil {
+	switch f.priority {
+	case flow.ControlPriority:
+		flags |= message.ControlPriorityFlag
+	case flow.BulkPriority:
+		flags |= message.BulkPriorityFlag
+	}
+	if err := f.writeq.wait(ctx, &f.writeqEntry, writeqPriority(f.priority)); err != nil {
 		if ctx.V(2) {
 			ctx.Infof("writeq.wait: error %v", err)
 		}
@@ -328,6 +346,14 @@ func (f *flw) sendOpenFlow(ctx *context.T, alsoClose, finalPart bool, payload []
 
 func (f *flw) sendDataMessage(ctx *context.T, priority int, alsoClose, finalPart bool, payload []byte) error {
 	flags := f.messageFlags(alsoClose, finalPart)
+	// Compress before waiting on the writeq so as to not hold up other
+	// flows. Flows that carry encapsulated conns or proxied messages are
+	// not compressed since their contents are already encrypted.
+	if !f.noCompress && !f.noEncrypt && !f.noFragment {
+		var nb *netBuf
+		payload, flags, nb = f.conn.mp.compressor.compress(payload, flags)
+		defer putNetBuf(nb)
+	}
 	if err := f.writeq.wait(ctx, &f.writeqEntry, priority); err != nil {
 		if ctx.V(2) {
 			ctx.Infof("writeq.wait: error %v", err)
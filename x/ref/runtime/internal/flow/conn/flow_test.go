@@ -111,6 +111,44 @@ func TestReadAtMost(t *testing.T) {
 	assert(tmpOut, 4, 0, totalSize(input))
 }
 
+func TestFlowPriority(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	dflows, aflows, dc, ac := setupFlows(t, "local", "", ctx, ctx, true, 1)
+	defer dc.Close(ctx, nil)
+	defer ac.Close(ctx, nil)
+
+	// The priority of the dialed flow is adopted by the accepted flow.
+	df := dflows[0]
+	df.SetPriority(flow.BulkPriority)
+	if _, err := df.WriteMsg([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	af := <-aflows
+	if got, want := af.(*flw).priority, flow.BulkPriority; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := af.ReadMsg(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := af.WriteMsg([]byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := df.ReadMsg(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []*Conn{dc, ac} {
+		if got, want := c.WriteQueueStats(flow.BulkPriority).Writes, uint64(1); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := c.WriteQueueStats(flow.ControlPriority).Writes, uint64(0); got != want {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
 func runFlowBenchmark(b *testing.B, ctx *context.T, dialed, accepted flow.Flow, rxbuf []byte, payload []byte) {
 	errCh := make(chan error, 1)
 
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"v.io/v23/context"
+	"v.io/v23/flow"
 	"v.io/v23/flow/message"
 )
 
@@ -107,6 +108,12 @@ func (c *Conn) handleOpenFlow(ctx *context.T, msg message.OpenFlow, nBuf *netBuf
 		c.acceptChannelTimeout,
 		sideChannel,
 		msg.InitialCounters)
+	switch {
+	case msg.Flags&message.ControlPriorityFlag != 0:
+		f.priority = flow.ControlPriority
+	case msg.Flags&message.BulkPriorityFlag != 0:
+		f.priority = flow.BulkPriority
+	}
 	c.flowControl.newCounters(&f.flowControl)
 	c.mu.Unlock()
 
@@ -158,8 +165,8 @@ func (c *Conn) handleAckLameDuck(ctx *context.T, msg message.AckLameDuck) error
 }
 
 func (c *Conn) handleHealthCheckResponse(ctx *context.T) error {
+	var rtt time.Duration
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	if c.status < Closing {
 		timeout := c.acceptChannelTimeout
 		for _, f := range c.flows {
@@ -170,18 +177,45 @@ func (c *Conn) handleHealthCheckResponse(ctx *context.T) error {
 		if min := minChannelTimeout[c.local.Protocol]; timeout < min {
 			timeout = min
 		}
+		ri := c.requestInterval(timeout)
 		c.hcstate.closeTimer.Reset(timeout)
 		c.hcstate.closeDeadline = time.Now().Add(timeout)
-		c.hcstate.requestTimer.Reset(timeout / 2)
-		c.hcstate.requestDeadline = time.Now().Add(timeout / 2)
-		c.hcstate.lastRTT = time.Since(c.hcstate.requestSent)
+		c.hcstate.requestTimer.Reset(ri)
+		c.hcstate.requestDeadline = time.Now().Add(ri)
+		if !c.hcstate.requestSent.IsZero() {
+			rtt = time.Since(c.hcstate.requestSent)
+			c.hcstate.lastRTT = rtt
+		}
 		c.hcstate.requestSent = time.Time{}
+		c.hcstate.missed = 0
+	}
+	c.mu.Unlock()
+	if rtt > 0 && c.rttObserver != nil {
+		c.rttObserver(c.remote, rtt)
 	}
 	return nil
 }
 
 func (c *Conn) handleHealthCheckRequest(ctx *context.T) error {
-	c.sendHealthCheckMessage(ctx, false)
+	// The response is sent asynchronously so that the read loop never
+	// blocks on a write, since the peer's read loop may in turn be blocked
+	// writing its own response. Requests received while a response is
+	// being sent are answered by that response.
+	c.mu.Lock()
+	if c.healthCheckResponding {
+		c.mu.Unlock()
+		return nil
+	}
+	c.healthCheckResponding = true
+	c.mu.Unlock()
+	c.loopWG.Add(1)
+	go func() {
+		defer c.loopWG.Done()
+		c.sendHealthCheckMessage(ctx, false) //nolint:errcheck
+		c.mu.Lock()
+		c.healthCheckResponding = false
+		c.mu.Unlock()
+	}()
 	return nil
 }
 
@@ -64,6 +64,9 @@ type messagePipe struct {
 	naclBoxCipher *naclbox.T
 	aeadCipher    *aead.T
 
+	compressor compressor
+	rekey      rekeyer
+
 	// locks are required to serialize access to the read/write operations since
 	// the messagePipe may be called by different goroutines when connections
 	// are being created or because of the need to send changed blessings
@@ -89,6 +92,7 @@ func (p *messagePipe) setMTU(mtu, bytesBuffered uint64) {
 }
 
 func (p *messagePipe) Close() error {
+	p.stopRekeying()
 	return p.rw.Close()
 }
 
@@ -101,7 +105,7 @@ func (p *messagePipe) enableEncryption(ctx *context.T, publicKey, secretKey, rem
 		return nil, nil
 	}
 	switch {
-	case rpcversion >= version.RPCVersion11 && rpcversion < version.RPCVersion15:
+	case rpcversion >= version.RPCVersion11 && rpcversion < version.RPCVersion16:
 		cipher, err := naclbox.NewCipher(publicKey, secretKey, remotePublicKey)
 		if err != nil {
 			return nil, err
@@ -109,7 +113,7 @@ func (p *messagePipe) enableEncryption(ctx *context.T, publicKey, secretKey, rem
 		p.encrypting = true
 		p.naclBoxCipher = cipher
 		return cipher.ChannelBinding(), nil
-	case rpcversion >= version.RPCVersion15:
+	case rpcversion >= version.RPCVersion16:
 		cipher, err := aead.NewCipher(publicKey, secretKey, remotePublicKey)
 		if err != nil {
 			return nil, err
@@ -169,6 +173,7 @@ func (p *messagePipe) writeCiphertext(ctx *context.T, fn serialize, size int) er
 	if err != nil {
 		return err
 	}
+	p.rekey.sealed += uint64(len(plaintext))
 	return p.writeFrame(ctx, wire, ciphertextBuf)
 }
 
@@ -190,7 +195,10 @@ func (p *messagePipe) writeData(ctx *context.T, m message.Data) error {
 	if err := p.writeCiphertext(ctx, m.Append, size); err != nil {
 		return err
 	}
-	return p.handlePlaintextPayload(m.Flags, m.Payload)
+	if err := p.handlePlaintextPayload(m.Flags, m.Payload); err != nil {
+		return err
+	}
+	return p.maybeRekeyLocked(ctx)
 }
 
 func (p *messagePipe) writeSetup(ctx *context.T, m message.Setup) error {
@@ -213,21 +221,30 @@ func (p *messagePipe) writeOpenFlow(ctx *context.T, m message.OpenFlow) error {
 	if err := p.writeCiphertext(ctx, m.Append, size); err != nil {
 		return err
 	}
-	return p.handlePlaintextPayload(m.Flags, m.Payload)
+	if err := p.handlePlaintextPayload(m.Flags, m.Payload); err != nil {
+		return err
+	}
+	return p.maybeRekeyLocked(ctx)
 }
 
 func (p *messagePipe) writeRelease(ctx *context.T, m message.Release) error {
 	size := (p.counterSizeEstimate) * len(m.Counters)
 	p.writeMu.Lock()
 	defer p.writeMu.Unlock()
-	return p.writeCiphertext(ctx, m.Append, size)
+	if err := p.writeCiphertext(ctx, m.Append, size); err != nil {
+		return err
+	}
+	return p.maybeRekeyLocked(ctx)
 }
 
 func (p *messagePipe) writeAnyMsg(ctx *context.T, fn serialize) error {
 	size := estimatedMessageOverhead
 	p.writeMu.Lock()
 	defer p.writeMu.Unlock()
-	return p.writeCiphertext(ctx, fn, size)
+	if err := p.writeCiphertext(ctx, fn, size); err != nil {
+		return err
+	}
+	return p.maybeRekeyLocked(ctx)
 }
 
 func (p *messagePipe) open(plaintext, ciphertext []byte) ([]byte, bool) {
@@ -257,21 +274,32 @@ func (p *messagePipe) getPlaintextData(ctx *context.T) ([]byte, *netBuf, error)
 	// there is no way of knowing what it's size will be.
 	cnb, ciphertextBuf := getNetBuf(p.mtu + estimatedMessageOverhead + maxCipherOverhead)
 	defer putNetBuf(cnb)
-	ciphertext, err := p.rw.ReadMsg2(ciphertextBuf)
-	if err != nil {
-		return nil, nil, err
-	}
-	// Use the size of the ciphertext as an estimage for the size of the plaintext
-	// allocate a new netBuf for it. The netBut is returned along with the plaintext.
-	pnb, plaintext := getNetBuf(len(ciphertext) - maxCipherOverhead)
-	plaintext, ok := p.open(plaintext[:0], ciphertext)
-	if !ok {
-		return nil, putNetBuf(pnb), message.NewErrInvalidMsg(ctx, 0, uint64(len(ciphertext)), 0, nil)
-	}
-	if len(plaintext) == 0 {
-		return nil, putNetBuf(pnb), message.NewErrInvalidMsg(ctx, message.InvalidType, 0, 0, nil)
+	for {
+		ciphertext, err := p.rw.ReadMsg2(ciphertextBuf)
+		if err != nil {
+			return nil, nil, err
+		}
+		// Use the size of the ciphertext as an estimage for the size of the plaintext
+		// allocate a new netBuf for it. The netBut is returned along with the plaintext.
+		pnb, plaintext := getNetBuf(len(ciphertext) - maxCipherOverhead)
+		plaintext, ok := p.open(plaintext[:0], ciphertext)
+		if !ok {
+			return nil, putNetBuf(pnb), message.NewErrInvalidMsg(ctx, 0, uint64(len(ciphertext)), 0, nil)
+		}
+		if len(plaintext) == 0 {
+			return nil, putNetBuf(pnb), message.NewErrInvalidMsg(ctx, message.InvalidType, 0, 0, nil)
+		}
+		if plaintext[0] != message.RekeyType {
+			return plaintext, pnb, nil
+		}
+		// Rekey messages are handled here since the next message will
+		// be sealed using the new key.
+		err = p.handleRekeyLocked(ctx, plaintext)
+		putNetBuf(pnb)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
-	return plaintext, pnb, nil
 }
 
 // readAnyMsg reads any type of message from the network and returns the parsed
@@ -315,6 +343,17 @@ func (p *messagePipe) handleReadData(ctx *context.T, from []byte, nBuf *netBuf)
 	if err != nil {
 		return m, putNetBuf(nBuf), err
 	}
+	if m.Flags&message.CompressedFlag != 0 {
+		// Compressed payloads are always encrypted.
+		payload, nb, err := p.compressor.decompress(ctx, m.Payload, p.mtu)
+		putNetBuf(nBuf)
+		if err != nil {
+			return m, nil, err
+		}
+		m.Flags &^= message.CompressedFlag
+		m.Payload = payload
+		return m, nb, nil
+	}
 	if m.Flags&message.DisableEncryptionFlag == 0 {
 		return m, nBuf, nil
 	}
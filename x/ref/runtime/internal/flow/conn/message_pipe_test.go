@@ -38,13 +38,13 @@ func (ks *keyset) initUsing(fn func() (pk1, sk1, pk2, sk2 *[32]byte, err error))
 
 var (
 	rpc11Keyset keyset
-	rpc15Keyset keyset
+	rpc16Keyset keyset
 	mixedKeyset keyset
 )
 
 func init() {
 	rpc11Keyset.initUsing(cipher.NewRPC11Keys)
-	rpc15Keyset.initUsing(cipher.NewRPC15Keys)
+	rpc16Keyset.initUsing(cipher.NewRPC16Keys)
 	mixedKeyset.initUsing(cipher.NewMixedKeys)
 }
 
@@ -59,14 +59,14 @@ func TestMessagePipeRPC11(t *testing.T) {
 	testMessagePipesVersioned(t, ctx, "tcp", rpc11Keyset, version.RPCVersion11)
 }
 
-func TestMessagePipesRPC15(t *testing.T) {
+func TestMessagePipesRPC16(t *testing.T) {
 	defer netbufsFreed(t)
 
 	ctx, shutdown := test.V23Init()
 	defer shutdown()
-	testMessagePipesVersioned(t, ctx, "local", rpc15Keyset, version.RPCVersion15)
+	testMessagePipesVersioned(t, ctx, "local", rpc16Keyset, version.RPCVersion16)
 	// framing will be bypassed for the tcp connections.
-	testMessagePipesVersioned(t, ctx, "tcp", rpc15Keyset, version.RPCVersion15)
+	testMessagePipesVersioned(t, ctx, "tcp", rpc16Keyset, version.RPCVersion16)
 }
 
 func newPipes(t *testing.T, ctx *context.T, protocol string) (dialed, accepted *messagePipe) {
@@ -105,13 +105,13 @@ func testMessageEncryption(t *testing.T, ctx *context.T, protocol string, ks key
 	var openFunc func(out, data []byte) ([]byte, bool)
 
 	switch rpcversion {
-	case version.RPCVersion11, version.RPCVersion12, version.RPCVersion13, version.RPCVersion14:
+	case version.RPCVersion11, version.RPCVersion12, version.RPCVersion13, version.RPCVersion14, version.RPCVersion15:
 		cipher, err := naclbox.NewCipher(ks.pk2, ks.sk2, ks.pk1)
 		if err != nil {
 			t.Fatal(err)
 		}
 		openFunc = cipher.Open
-	case version.RPCVersion15:
+	case version.RPCVersion16:
 		cipher, err := aead.NewCipher(ks.pk2, ks.sk2, ks.pk1)
 		if err != nil {
 			t.Fatal(err)
@@ -421,12 +421,12 @@ func BenchmarkMessagePipe__RPC11__UseFramer_____MTU(b *testing.B) {
 	benchmarkMessagePipe(b, false, DefaultMTU, rpc11Keyset, version.RPCVersion11)
 }
 
-func BenchmarkMessagePipe__RPC15__UseFramer_____1KB(b *testing.B) {
-	benchmarkMessagePipe(b, false, 1000, rpc15Keyset, version.RPCVersion15)
+func BenchmarkMessagePipe__RPC16__UseFramer_____1KB(b *testing.B) {
+	benchmarkMessagePipe(b, false, 1000, rpc16Keyset, version.RPCVersion16)
 }
 
-func BenchmarkMessagePipe__RPC15__UseFramer_____MTU(b *testing.B) {
-	benchmarkMessagePipe(b, false, DefaultMTU, rpc15Keyset, version.RPCVersion15)
+func BenchmarkMessagePipe__RPC16__UseFramer_____MTU(b *testing.B) {
+	benchmarkMessagePipe(b, false, DefaultMTU, rpc16Keyset, version.RPCVersion16)
 }
 
 func BenchmarkMessagePipe__RPC11__BypassFramer__1KB(b *testing.B) {
@@ -437,10 +437,10 @@ func BenchmarkMessagePipe__RPC11__BypassFramer__MTU(b *testing.B) {
 	benchmarkMessagePipe(b, true, DefaultMTU, rpc11Keyset, version.RPCVersion11)
 }
 
-func BenchmarkMessagePipe__RPC15__BypassFramer__1KB(b *testing.B) {
-	benchmarkMessagePipe(b, true, 1000, rpc15Keyset, version.RPCVersion15)
+func BenchmarkMessagePipe__RPC16__BypassFramer__1KB(b *testing.B) {
+	benchmarkMessagePipe(b, true, 1000, rpc16Keyset, version.RPCVersion16)
 }
 
-func BenchmarkMessagePipe__RPC15__BypassFramer__MTU(b *testing.B) {
-	benchmarkMessagePipe(b, true, DefaultMTU, rpc15Keyset, version.RPCVersion15)
+func BenchmarkMessagePipe__RPC16__BypassFramer__MTU(b *testing.B) {
+	benchmarkMessagePipe(b, true, DefaultMTU, rpc16Keyset, version.RPCVersion16)
 }
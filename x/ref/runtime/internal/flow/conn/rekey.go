@@ -0,0 +1,178 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/flow/message"
+)
+
+const (
+	// DefaultRekeyBytes is the default value used for Opts.RekeyBytes.
+	DefaultRekeyBytes = 1 << 32
+	// DefaultRekeyInterval is the default value used for Opts.RekeyInterval.
+	DefaultRekeyInterval = 24 * time.Hour
+
+	rekeySaltSize = 32
+)
+
+// rekeyer tracks the use of the key used to seal the messages sent over a
+// messagePipe so that it may be rotated after a configured number of bytes
+// or interval of time. The key is rotated by sending a message.Rekey
+// containing a random salt, sealed using the current key, and then deriving
+// the new key from the current one and the salt. The peer derives the same
+// key when it receives the message.Rekey. Note that the keys for each
+// direction are rotated independently. The interval is enforced by a timer,
+// so that keys are rotated even when no messages are being sent.
+type rekeyer struct {
+	supported bool
+	bytes     uint64
+	interval  time.Duration
+
+	// The following are guarded by messagePipe.writeMu.
+	enabled   bool
+	sealed    uint64
+	lastRekey time.Time
+
+	// timerMu guards timer and stopped, it is not held while writing so
+	// that the timer can be stopped while a write is blocked.
+	timerMu sync.Mutex
+	timer   *time.Timer
+	stopped bool
+
+	sent, received uint64 // accessed atomically.
+}
+
+func (r *rekeyer) init(bytes uint64, interval time.Duration) {
+	r.bytes, r.interval = bytes, interval
+}
+
+// setSupported is called once the peer is known to support rekeying.
+func (r *rekeyer) setSupported() {
+	r.supported = true
+}
+
+func (r *rekeyer) due() bool {
+	if !r.enabled {
+		return false
+	}
+	return (r.bytes > 0 && r.sealed >= r.bytes) ||
+		(r.interval > 0 && time.Since(r.lastRekey) >= r.interval)
+}
+
+// String returns the number of rekeys sent and received.
+func (r *rekeyer) String() string {
+	return fmt.Sprintf("supported: %v, sent: %v, received: %v", r.supported, atomic.LoadUint64(&r.sent), atomic.LoadUint64(&r.received))
+}
+
+// startRekeying enables rekeying if the peer supports it. It is called once
+// the handshake has completed since the handshake messages are exchanged in
+// lockstep and a peer that is writing a handshake message may not be reading
+// the Rekey message.
+func (p *messagePipe) startRekeying(ctx *context.T) {
+	p.writeMu.Lock()
+	p.rekey.enabled = p.rekey.supported
+	p.rekey.lastRekey = time.Now()
+	start := p.rekey.enabled && p.encrypting && p.rekey.interval > 0
+	p.writeMu.Unlock()
+	if start {
+		p.scheduleRekey(ctx, p.rekey.interval)
+	}
+}
+
+// scheduleRekey arranges for the key to be rotated after delay if it has not
+// been rotated by a write in the meantime.
+func (p *messagePipe) scheduleRekey(ctx *context.T, delay time.Duration) {
+	p.rekey.timerMu.Lock()
+	defer p.rekey.timerMu.Unlock()
+	if p.rekey.stopped {
+		return
+	}
+	p.rekey.timer = time.AfterFunc(delay, func() {
+		p.writeMu.Lock()
+		err := p.maybeRekeyLocked(ctx)
+		next := p.rekey.interval - time.Since(p.rekey.lastRekey)
+		p.writeMu.Unlock()
+		if err != nil {
+			// The error will be noticed by the next read or write.
+			ctx.VI(2).Infof("conn.messagePipe: failed to rotate the key used for sealing messages: %v", err)
+			return
+		}
+		p.scheduleRekey(ctx, next)
+	})
+}
+
+// stopRekeying stops the timer used to rotate keys.
+func (p *messagePipe) stopRekeying() {
+	p.rekey.timerMu.Lock()
+	defer p.rekey.timerMu.Unlock()
+	p.rekey.stopped = true
+	if p.rekey.timer != nil {
+		p.rekey.timer.Stop()
+	}
+}
+
+func (p *messagePipe) rekeySeal(salt []byte) error {
+	if p.aeadCipher != nil {
+		return p.aeadCipher.RekeySeal(salt)
+	}
+	return p.naclBoxCipher.RekeySeal(salt)
+}
+
+func (p *messagePipe) rekeyOpen(salt []byte) error {
+	if p.aeadCipher != nil {
+		return p.aeadCipher.RekeyOpen(salt)
+	}
+	return p.naclBoxCipher.RekeyOpen(salt)
+}
+
+// maybeRekeyLocked rotates the key used for sealing messages if it is due
+// to be. It must be called with writeMu held and only once a message and
+// any plaintext payload that follows it have been written.
+func (p *messagePipe) maybeRekeyLocked(ctx *context.T) error {
+	if !p.encrypting || !p.rekey.due() {
+		return nil
+	}
+	salt := make([]byte, rekeySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	// The Rekey message is the last one sealed with the current key.
+	if err := p.writeCiphertext(ctx, message.Rekey{Salt: salt}.Append, rekeySaltSize); err != nil {
+		return err
+	}
+	if err := p.rekeySeal(salt); err != nil {
+		return err
+	}
+	p.rekey.sealed = 0
+	p.rekey.lastRekey = time.Now()
+	atomic.AddUint64(&p.rekey.sent, 1)
+	ctx.VI(2).Infof("conn.messagePipe: rotated the key used for sealing messages")
+	return nil
+}
+
+// handleRekeyLocked is called with readMu held when a Rekey message is
+// received.
+func (p *messagePipe) handleRekeyLocked(ctx *context.T, plaintext []byte) error {
+	m, err := message.Rekey{}.Read(ctx, plaintext[1:])
+	if err != nil {
+		return err
+	}
+	salt := m.(message.Rekey).Salt
+	if len(salt) == 0 {
+		return message.NewErrInvalidMsg(ctx, message.RekeyType, uint64(len(plaintext)), 0, nil)
+	}
+	if err := p.rekeyOpen(salt); err != nil {
+		return err
+	}
+	atomic.AddUint64(&p.rekey.received, 1)
+	return nil
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"v.io/v23/flow"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/goroutines"
+)
+
+func TestRekey(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	defer netbufsFreed(t)
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	rekeys := func(c *Conn) (sent, received uint64) {
+		return atomic.LoadUint64(&c.mp.rekey.sent), atomic.LoadUint64(&c.mp.rekey.received)
+	}
+
+	for _, opts := range []Opts{
+		{RekeyBytes: 4 * DefaultMTU},
+		{RekeyInterval: time.Millisecond},
+	} {
+		aflows := make(chan flow.Flow, 1)
+		dc, ac, derr, aerr := setupConnsOpts(t, "local", "", ctx, ctx, nil, aflows, nil, nil, opts)
+		if derr != nil || aerr != nil {
+			t.Fatal(derr, aerr)
+		}
+		df, af := oneFlow(t, ctx, dc, aflows, 0)
+		for i := 0; i < 8; i++ {
+			exchange(t, df, af, randData[:2*DefaultMTU])
+			time.Sleep(2 * time.Millisecond)
+		}
+		dsent, dreceived := rekeys(dc)
+		asent, areceived := rekeys(ac)
+		if dsent == 0 || asent == 0 {
+			t.Errorf("%+v: keys were not rotated: %v, %v", opts, dsent, asent)
+		}
+		// The most recent rekey may not yet have been received.
+		if areceived == 0 || areceived > dsent || dreceived == 0 || dreceived > asent {
+			t.Errorf("%+v: mismatched rekeys: dialer %v/%v, acceptor %v/%v", opts, dsent, dreceived, asent, areceived)
+		}
+		df.Close()
+		af.Close()
+		dc.Close(ctx, nil)
+		ac.Close(ctx, nil)
+		<-dc.Closed()
+		<-ac.Closed()
+	}
+}
+
+func TestRekeyIdle(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	// Keys are rotated at the interval even if nothing is written.
+	dc, ac, derr, aerr := setupConnsOpts(t, "local", "", ctx, ctx, nil, nil, nil, nil, Opts{RekeyInterval: 10 * time.Millisecond})
+	if derr != nil || aerr != nil {
+		t.Fatal(derr, aerr)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if atomic.LoadUint64(&dc.mp.rekey.sent) > 0 && atomic.LoadUint64(&ac.mp.rekey.received) > 0 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatalf("keys were not rotated: dialer %v, acceptor %v", &dc.mp.rekey, &ac.mp.rekey)
+		}
+	}
+	dc.Close(ctx, nil)
+	ac.Close(ctx, nil)
+	<-dc.Closed()
+	<-ac.Closed()
+}
@@ -0,0 +1,591 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/flow/message"
+	"v.io/v23/rpc/version"
+)
+
+const (
+	// resumableAckInterval is the number of frames that may be received
+	// before an acknowledgement is sent for them if there are no other
+	// frames to carry it.
+	resumableAckInterval = 32
+
+	minRedialDelay = 50 * time.Millisecond
+	maxRedialDelay = 5 * time.Second
+)
+
+// The kinds of frame sent over a resumable connection.
+const (
+	resumableData = iota
+	resumableAck
+)
+
+var (
+	resumeIDTag       = []byte("ResumeID\x00")
+	resumeKeyTag      = []byte("ResumeKey\x00")
+	resumeDialerTag   = []byte("ResumeDial\x00")
+	resumeAcceptorTag = []byte("ResumeAcpt\x00")
+)
+
+// resumptionSecrets returns the identifier of a resumable connection and
+// the key used to prove knowledge of the secret shared by the two ends of
+// the connection by virtue of their key exchange.
+func resumptionSecrets(shared *[32]byte) (id, key []byte) {
+	derive := func(tag []byte) []byte {
+		sum := sha256.Sum256(append(append([]byte{}, tag...), shared[:]...))
+		return sum[:]
+	}
+	return derive(resumeIDTag), derive(resumeKeyTag)
+}
+
+// resumeProof returns the proof sent by one end of a connection that is
+// being resumed, which is bound to the nonces chosen by both ends for the
+// new network connection and to the number of frames received by the
+// sender.
+func resumeProof(key, tag, dialerNonce, acceptorNonce []byte, received uint64) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(tag)
+	mac.Write(dialerNonce)
+	mac.Write(acceptorNonce)
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], received)
+	mac.Write(buf[:])
+	return mac.Sum(nil)
+}
+
+// ResumptionTable records the resumable Conns accepted by a flow manager
+// so that their dialers can resume them over new network connections.
+type ResumptionTable struct {
+	mu    sync.Mutex
+	conns map[string]*resumableConn
+}
+
+// NewResumptionTable returns a new, empty, ResumptionTable.
+func NewResumptionTable() *ResumptionTable {
+	return &ResumptionTable{conns: map[string]*resumableConn{}}
+}
+
+func (t *ResumptionTable) add(r *resumableConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conns[string(r.id)] = r
+}
+
+func (t *ResumptionTable) remove(r *resumableConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[string(r.id)] == r {
+		delete(t.conns, string(r.id))
+	}
+}
+
+func (t *ResumptionTable) lookup(id []byte) *resumableConn {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.conns[string(id)]
+}
+
+// detachedConn replaces the network connection of a messagePipe once
+// that network connection has been handed over to a resumed Conn.
+type detachedConn struct{}
+
+func (detachedConn) WriteMsg(...[]byte) (int, error) { return 0, io.EOF }
+func (detachedConn) ReadMsg() ([]byte, error)        { return nil, io.EOF }
+func (detachedConn) ReadMsg2([]byte) ([]byte, error) { return nil, io.EOF }
+func (detachedConn) Close() error                    { return nil }
+
+// resumableConn is a flow.MsgReadWriteCloser whose underlying network
+// connection may be replaced should it fail. It is used below the
+// messagePipe and hence the frames it carries are already encrypted. Each
+// frame is prefixed with the number of frames received from the peer so far,
+// which serves to acknowledge them, and frames are retained until they are
+// acknowledged. When a network connection fails, the dialer redials its
+// peer and the two ends exchange the number of frames that they have
+// received so that any frames lost along with the network connection can be
+// retransmitted over the new one. If this cannot be done within the
+// timeout, the resumableConn fails and returns errors from all of its
+// methods.
+//
+// The connection to be resumed is identified by an ID that is sent in the
+// clear. Each end must also prove that it knows the secret negotiated by
+// the key exchange when the connection was first established, using a
+// proof that is bound to nonces chosen by both ends for the new network
+// connection and to the number of frames that it has received. Hence the
+// Setup messages used to resume a connection cannot be replayed or
+// altered to resume it over another network connection. The acceptor's
+// nonce is the NaCl public key in the Setup message that it sends on
+// every new network connection, which the dialer reads before sending
+// its own.
+type resumableConn struct {
+	// These variables are all set before the resumableConn is used and
+	// never changed after that.
+	ctx      *context.T
+	id       []byte
+	key      []byte
+	versions version.RPCVersionRange
+	timeout  time.Duration
+	redial   func(*context.T) (flow.Conn, error) // nil for accepted conns.
+	table    *ResumptionTable                    // nil for dialed conns.
+
+	// writeMu serializes writes to the network connection, including the
+	// retransmission of frames when the connection is resumed.
+	writeMu sync.Mutex
+
+	// mu guards all of the following fields.
+	mu sync.Mutex
+	rw flow.MsgReadWriteCloser
+	// gen is incremented whenever the frames read from rw are to be
+	// discarded, ie. when the number of frames received is sent to the
+	// peer for a resumption or when rw is replaced.
+	gen uint64
+	// changed is closed when rw is replaced or the resumableConn fails.
+	changed chan struct{}
+	// broken is true from the time that rw fails until it is replaced and
+	// epoch is incremented every time that rw fails.
+	broken bool
+	epoch  uint64
+	err    error
+
+	sent       uint64   // the number of frames sent.
+	unacked    [][]byte // frames [sent-len(unacked), sent).
+	received   uint64   // the number of frames received.
+	ackSent    uint64   // the value of received last sent to the peer.
+	ackPending bool
+	resumed    uint64 // the number of times rw has been replaced.
+}
+
+func newResumableConn(ctx *context.T, rw flow.MsgReadWriteCloser, shared *[32]byte, versions version.RPCVersionRange, timeout time.Duration, redial func(*context.T) (flow.Conn, error), table *ResumptionTable) *resumableConn {
+	r := &resumableConn{
+		ctx:      ctx,
+		rw:       rw,
+		versions: versions,
+		timeout:  timeout,
+		redial:   redial,
+		table:    table,
+		changed:  make(chan struct{}),
+	}
+	r.id, r.key = resumptionSecrets(shared)
+	if table != nil {
+		table.add(r)
+	}
+	return r
+}
+
+func writeResumableFrame(rw flow.MsgWriter, kind byte, ack uint64, payload []byte) error {
+	var hdr [1 + binary.MaxVarintLen64]byte
+	hdr[0] = kind
+	n := binary.PutUvarint(hdr[1:], ack)
+	_, err := rw.WriteMsg(hdr[:1+n], payload)
+	return err
+}
+
+func readResumableFrame(frame []byte) (kind byte, ack uint64, payload []byte, ok bool) {
+	if len(frame) < 2 {
+		return
+	}
+	ack, n := binary.Uvarint(frame[1:])
+	if n <= 0 {
+		return
+	}
+	return frame[0], ack, frame[1+n:], true
+}
+
+// WriteMsg implements flow.MsgWriter. If the network connection fails,
+// WriteMsg waits until it has been replaced before returning.
+func (r *resumableConn) WriteMsg(data ...[]byte) (int, error) {
+	var frame []byte
+	for _, d := range data {
+		frame = append(frame, d...)
+	}
+	r.writeMu.Lock()
+	r.mu.Lock()
+	if r.err != nil {
+		r.mu.Unlock()
+		r.writeMu.Unlock()
+		return 0, r.err
+	}
+	r.unacked = append(r.unacked, frame)
+	r.sent++
+	rw, broken, ack := r.rw, r.broken, r.received
+	r.ackSent = ack
+	r.mu.Unlock()
+	var err error
+	if !broken {
+		err = writeResumableFrame(rw, resumableData, ack, frame)
+	}
+	r.writeMu.Unlock()
+	if broken || err != nil {
+		// The frame will be retransmitted once the network connection
+		// has been replaced.
+		if _, _, err := r.wait(rw); err != nil {
+			return 0, err
+		}
+	}
+	return len(frame), nil
+}
+
+// ReadMsg implements flow.MsgReader.
+func (r *resumableConn) ReadMsg() ([]byte, error) {
+	return r.ReadMsg2(nil)
+}
+
+// ReadMsg2 implements flow.MsgReader. If the network connection fails,
+// ReadMsg2 waits until it has been replaced and then reads from the new
+// network connection.
+func (r *resumableConn) ReadMsg2(buf []byte) ([]byte, error) {
+	r.mu.Lock()
+	rw, gen, broken, err := r.rw, r.gen, r.broken, r.err
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if broken {
+		if rw, gen, err = r.wait(rw); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		frame, err := rw.ReadMsg2(buf)
+		if err != nil {
+			if rw, gen, err = r.wait(rw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		kind, ack, payload, ok := readResumableFrame(frame)
+		if !ok {
+			return nil, ErrInvalidResumableFrame.Errorf(r.ctx, "conn.resumableConn: invalid frame of %v bytes", len(frame))
+		}
+		r.mu.Lock()
+		if r.gen != gen {
+			// The frame will be retransmitted over the new network
+			// connection.
+			r.mu.Unlock()
+			if rw, gen, err = r.wait(rw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r.ackedLocked(ack)
+		if kind == resumableAck {
+			r.mu.Unlock()
+			continue
+		}
+		r.received++
+		sendAck := !r.ackPending && r.received-r.ackSent >= resumableAckInterval
+		if sendAck {
+			r.ackPending = true
+		}
+		r.mu.Unlock()
+		if sendAck {
+			go r.sendAck()
+		}
+		return payload, nil
+	}
+}
+
+// Close implements io.Closer.
+func (r *resumableConn) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.failLocked(io.EOF)
+	}
+	return nil
+}
+
+// ackedLocked discards the frames that have been acknowledged by the peer.
+func (r *resumableConn) ackedLocked(ack uint64) {
+	base := r.sent - uint64(len(r.unacked))
+	if ack <= base {
+		return
+	}
+	n := ack - base
+	if n > uint64(len(r.unacked)) {
+		n = uint64(len(r.unacked))
+	}
+	for i := uint64(0); i < n; i++ {
+		r.unacked[i] = nil
+	}
+	r.unacked = r.unacked[n:]
+}
+
+func (r *resumableConn) sendAck() {
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	r.mu.Lock()
+	r.ackPending = false
+	if r.broken || r.err != nil {
+		r.mu.Unlock()
+		return
+	}
+	rw, ack := r.rw, r.received
+	r.ackSent = ack
+	r.mu.Unlock()
+	// Any error will be noticed by the next read or write.
+	writeResumableFrame(rw, resumableAck, ack, nil) //nolint:errcheck
+}
+
+// wait is called when rw has failed. It waits until rw has been replaced,
+// returning its replacement, or until the resumableConn has failed.
+func (r *resumableConn) wait(rw flow.MsgReadWriteCloser) (flow.MsgReadWriteCloser, uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		switch {
+		case r.err != nil:
+			return nil, 0, r.err
+		case !r.broken && r.rw != rw:
+			return r.rw, r.gen, nil
+		case !r.broken:
+			r.breakLocked()
+		}
+		ch := r.changed
+		r.mu.Unlock()
+		<-ch
+		r.mu.Lock()
+	}
+}
+
+// breakLocked is called when the network connection has failed, or is
+// about to be replaced. It closes the network connection and arranges for
+// the resumableConn to fail if it is not resumed within the timeout.
+func (r *resumableConn) breakLocked() {
+	r.broken = true
+	r.epoch++
+	r.rw.Close()
+	epoch := r.epoch
+	time.AfterFunc(r.timeout, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.err == nil && r.broken && r.epoch == epoch {
+			r.failLocked(ErrResumeTimeout.Errorf(r.ctx, "conn.resumableConn: not resumed within %v", r.timeout))
+		}
+	})
+	if r.redial != nil {
+		go r.redialLoop(epoch)
+	}
+}
+
+func (r *resumableConn) failLocked(err error) {
+	r.err = err
+	r.rw.Close()
+	r.unacked = nil
+	close(r.changed)
+	if r.table != nil {
+		r.table.remove(r)
+	}
+}
+
+func (r *resumableConn) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.failLocked(err)
+	}
+}
+
+// reconnect replaces the network connection of a dialed resumableConn even
+// though it has not failed.
+func (r *resumableConn) reconnect() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.redial != nil && r.err == nil && !r.broken {
+		r.breakLocked()
+	}
+}
+
+// detach prepares for the network connection to be replaced, returning the
+// number of frames received so far. Frames subsequently read from the
+// current network connection are discarded.
+func (r *resumableConn) detach() (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, r.err
+	}
+	if !r.broken {
+		r.breakLocked()
+	}
+	r.gen++
+	return r.received, nil
+}
+
+func (r *resumableConn) redialLoop(epoch uint64) {
+	delay := minRedialDelay
+	for {
+		r.mu.Lock()
+		done := r.err != nil || r.epoch != epoch
+		r.mu.Unlock()
+		if done {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.ctx, r.timeout)
+		raw, err := r.redial(ctx)
+		cancel()
+		if err == nil {
+			if err = r.resumeDialed(raw); err == nil {
+				return
+			}
+			raw.Close()
+			if errors.Is(err, ErrCannotResume) {
+				r.fail(err)
+				return
+			}
+		}
+		r.ctx.VI(2).Infof("conn.resumableConn: failed to resume: %v", err)
+		time.Sleep(delay)
+		if delay *= 2; delay > maxRedialDelay {
+			delay = maxRedialDelay
+		}
+	}
+}
+
+// resumeDialed resumes a dialed resumableConn over the supplied network
+// connection.
+func (r *resumableConn) resumeDialed(raw flow.Conn) error {
+	// Ensure that the exchange of Setup messages cannot block forever.
+	timer := time.AfterFunc(r.timeout, func() { raw.Close() })
+	defer timer.Stop()
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	received, err := r.detach()
+	if err != nil {
+		return err
+	}
+	mp := newMessagePipe(raw)
+	// The acceptor sends a Setup message for a new connection before it
+	// reads ours, the freshly generated public key in which serves as its
+	// nonce, and then a second one in reply to ours.
+	first, nBuf, err := mp.readSetup(r.ctx)
+	if err != nil {
+		return err
+	}
+	putNetBuf(nBuf)
+	acceptorNonce := first.PeerNaClPublicKey[:]
+	if first.PeerNaClPublicKey == emptyNaClPublicKey {
+		return ErrMissingSetupOption.Errorf(r.ctx, "conn.resumableConn: missing required setup option: peerNaClPublicKey")
+	}
+	dialerNonce := make([]byte, 32)
+	if _, err := rand.Read(dialerNonce); err != nil {
+		return err
+	}
+	if err := mp.writeSetup(r.ctx, message.Setup{
+		Versions:       r.versions,
+		ResumeID:       r.id,
+		ResumeNonce:    dialerNonce,
+		ResumeReceived: received,
+		ResumeProof:    resumeProof(r.key, resumeDialerTag, dialerNonce, acceptorNonce, received),
+	}); err != nil {
+		return err
+	}
+	reply, nBuf, err := mp.readSetup(r.ctx)
+	if err != nil {
+		return err
+	}
+	putNetBuf(nBuf)
+	if len(reply.ResumeProof) == 0 {
+		return ErrCannotResume.Errorf(r.ctx, "conn.resumableConn: the peer refused to resume the connection")
+	}
+	if !hmac.Equal(reply.ResumeProof, resumeProof(r.key, resumeAcceptorTag, dialerNonce, acceptorNonce, reply.ResumeReceived)) {
+		return ErrInvalidResumeProof.Errorf(r.ctx, "conn.resumableConn: the peer's proof of the shared secret was invalid")
+	}
+	if !timer.Stop() {
+		return ErrResumeTimeout.Errorf(r.ctx, "conn.resumableConn: timed out resuming the connection")
+	}
+	return r.replaceLocked(raw, reply.ResumeReceived)
+}
+
+// authenticate returns true if proof, sent by the dialer along with its
+// nonce and the number of frames it has received, proves that the dialer
+// knows the secret shared by the two ends of the connection.
+func (r *resumableConn) authenticate(dialerNonce, acceptorNonce []byte, peerReceived uint64, proof []byte) bool {
+	return len(dialerNonce) > 0 && hmac.Equal(proof, resumeProof(r.key, resumeDialerTag, dialerNonce, acceptorNonce, peerReceived))
+}
+
+// resumeAccepted resumes an accepted resumableConn over the supplied
+// network connection on which its dialer has sent an authenticated Setup
+// message with its nonce and the number of frames it has received.
+func (r *resumableConn) resumeAccepted(raw flow.MsgReadWriteCloser, dialerNonce, acceptorNonce []byte, peerReceived uint64) error {
+	// Close the current network connection before acquiring writeMu so
+	// that any writes blocked on it give up writeMu. No frames are
+	// received once it has been detached.
+	received, err := r.detach()
+	if err != nil {
+		return err
+	}
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+	mp := newMessagePipe(raw)
+	if err := mp.writeSetup(r.ctx, message.Setup{
+		Versions:       r.versions,
+		ResumeReceived: received,
+		ResumeProof:    resumeProof(r.key, resumeAcceptorTag, dialerNonce, acceptorNonce, received),
+	}); err != nil {
+		return err
+	}
+	return r.replaceLocked(raw, peerReceived)
+}
+
+// replaceLocked replaces the network connection and retransmits the frames
+// that the peer has not received. It must be called with writeMu held.
+func (r *resumableConn) replaceLocked(raw flow.MsgReadWriteCloser, peerReceived uint64) error {
+	r.mu.Lock()
+	if r.err != nil {
+		r.mu.Unlock()
+		return r.err
+	}
+	base := r.sent - uint64(len(r.unacked))
+	if peerReceived < base || peerReceived > r.sent {
+		err := ErrCannotResume.Errorf(r.ctx, "conn.resumableConn: the peer has received %v frames, but only frames %v to %v are available", peerReceived, base, r.sent)
+		r.failLocked(err)
+		r.mu.Unlock()
+		return err
+	}
+	r.ackedLocked(peerReceived)
+	pending := r.unacked
+	r.rw = raw
+	r.gen++
+	r.broken = false
+	r.resumed++
+	close(r.changed)
+	r.changed = make(chan struct{})
+	ack := r.received
+	r.ackSent = ack
+	r.mu.Unlock()
+	for _, frame := range pending {
+		// Any error will be noticed by the next read or write.
+		if err := writeResumableFrame(raw, resumableData, ack, frame); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (r *resumableConn) isBroken() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.broken
+}
+
+func (r *resumableConn) resumptions() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resumed
+}
@@ -0,0 +1,296 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package conn
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/v23/flow/message"
+	"v.io/v23/naming"
+	"v.io/x/ref/runtime/internal/flow/flowtest"
+	"v.io/x/ref/runtime/internal/rpc/version"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/goroutines"
+)
+
+// resumablePipes provides the network connections used by a pair of
+// resumable Conns and allows the current one to be broken.
+type resumablePipes struct {
+	t      *testing.T
+	ctx    *context.T
+	table  *ResumptionTable
+	aflows chan flow.Flow
+
+	mu      sync.Mutex
+	current flow.Conn
+	accepts sync.WaitGroup
+	errs    []error
+}
+
+func (p *resumablePipes) redial(ctx *context.T) (flow.Conn, error) {
+	dmrw, amrw, err := flowtest.NewPipe(p.ctx, "local", ":0")
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.current = dmrw
+	p.mu.Unlock()
+	p.accepts.Add(1)
+	go func() {
+		defer p.accepts.Done()
+		ridep := naming.Endpoint{Protocol: "local", Address: ":0", RoutingID: naming.FixedRoutingID(191341)}
+		_, err := NewAccepted(p.ctx, nil, amrw, ridep, version.Supported, fh(p.aflows), Opts{
+			ResumeTimeout: time.Minute,
+			Resumptions:   p.table,
+		})
+		p.mu.Lock()
+		p.errs = append(p.errs, err)
+		p.mu.Unlock()
+	}()
+	return dmrw, nil
+}
+
+func (p *resumablePipes) breakConn() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current.Close()
+}
+
+func (p *resumablePipes) acceptErrors() []error {
+	p.accepts.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.errs
+}
+
+func setupResumableConns(t *testing.T, ctx *context.T, p *resumablePipes, dopts, aopts Opts) (dc, ac *Conn) {
+	dmrw, amrw := flowtest.Pipe(t, ctx, "local", ":0")
+	p.mu.Lock()
+	p.current = dmrw
+	p.mu.Unlock()
+	versions := version.Supported
+	ridep := naming.Endpoint{Protocol: "local", Address: ":0", RoutingID: naming.FixedRoutingID(191341)}
+	ep := naming.Endpoint{Protocol: "local", Address: ":0"}
+	dBlessings, _ := v23.GetPrincipal(ctx).BlessingStore().Default()
+	ach := make(chan *Conn)
+	aerrch := make(chan error)
+	go func() {
+		a, err := NewAccepted(ctx, nil, amrw, ridep, versions, fh(p.aflows), aopts)
+		ach <- a
+		aerrch <- err
+	}()
+	dc, _, _, derr := NewDialed(ctx, dmrw, ep, ep, versions, peerAuthorizer{dBlessings, nil}, nil, dopts)
+	ac, aerr := <-ach, <-aerrch
+	if derr != nil || aerr != nil {
+		t.Fatalf("dial: %v, accept: %v", derr, aerr)
+	}
+	return dc, ac
+}
+
+func exchange(t *testing.T, df, af flow.Flow, data []byte) {
+	errs := make(chan error, 2)
+	go func() {
+		_, err := df.WriteMsg(data)
+		errs <- err
+	}()
+	go func() {
+		_, err := af.WriteMsg(data)
+		errs <- err
+	}()
+	for _, f := range []flow.Flow{af, df} {
+		var got []byte
+		for len(got) < len(data) {
+			msg, err := f.ReadMsg()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, msg...)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("got %v bytes, want %v bytes", len(got), len(data))
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResumption(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	p := &resumablePipes{t: t, ctx: ctx, table: NewResumptionTable(), aflows: make(chan flow.Flow, 1)}
+	dopts := Opts{ResumeTimeout: time.Minute, Redial: p.redial}
+	aopts := Opts{ResumeTimeout: time.Minute, Resumptions: p.table}
+	dc, ac := setupResumableConns(t, ctx, p, dopts, aopts)
+	if dc.resumable == nil || ac.resumable == nil {
+		t.Fatalf("resumption was not negotiated")
+	}
+	df, af := oneFlow(t, ctx, dc, p.aflows, 0)
+	exchange(t, df, af, []byte("before"))
+
+	// Data written while the network connection is broken must be
+	// delivered once the conn has been resumed.
+	for i := 0; i < 3; i++ {
+		p.breakConn()
+		exchange(t, df, af, randData[:4*DefaultMTU])
+	}
+	// An explicit reconnection also resumes the conn.
+	if !dc.Reconnect() || ac.Reconnect() {
+		t.Errorf("only the dialed conn should be reconnectable")
+	}
+	exchange(t, df, af, []byte("after"))
+
+	if got, want := dc.Resumptions(), uint64(4); got != want {
+		t.Errorf("got %v dialer resumptions, want %v", got, want)
+	}
+	if got, want := ac.Resumptions(), uint64(4); got != want {
+		t.Errorf("got %v acceptor resumptions, want %v", got, want)
+	}
+	for _, err := range p.acceptErrors() {
+		if !errors.Is(err, ErrConnResumed) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	df.Close()
+	af.Close()
+	dc.Close(ctx, nil)
+	ac.Close(ctx, nil)
+	<-dc.Closed()
+	<-ac.Closed()
+}
+
+func TestResumptionRefused(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	p := &resumablePipes{t: t, ctx: ctx, table: NewResumptionTable(), aflows: make(chan flow.Flow, 1)}
+	dopts := Opts{ResumeTimeout: time.Minute, Redial: p.redial}
+	aopts := Opts{ResumeTimeout: time.Minute, Resumptions: NewResumptionTable()}
+	dc, ac := setupResumableConns(t, ctx, p, dopts, aopts)
+	df, af := oneFlow(t, ctx, dc, p.aflows, 0)
+	exchange(t, df, af, []byte("before"))
+
+	// The acceptor used for the redial does not know of the conn and
+	// hence it must be closed rather than resumed.
+	p.breakConn()
+	<-dc.Closed()
+	if _, err := df.WriteMsg([]byte("after")); err == nil {
+		t.Errorf("expected an error")
+	}
+	for _, err := range p.acceptErrors() {
+		if !errors.Is(err, ErrCannotResume) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	ac.Close(ctx, nil)
+	<-ac.Closed()
+}
+
+func TestResumptionReplayed(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	p := &resumablePipes{t: t, ctx: ctx, table: NewResumptionTable(), aflows: make(chan flow.Flow, 1)}
+	dopts := Opts{ResumeTimeout: time.Minute, Redial: p.redial}
+	aopts := Opts{ResumeTimeout: time.Minute, Resumptions: p.table}
+	dc, ac := setupResumableConns(t, ctx, p, dopts, aopts)
+	df, af := oneFlow(t, ctx, dc, p.aflows, 0)
+	exchange(t, df, af, []byte("before"))
+
+	// A Setup message whose proof was computed for another network
+	// connection, as would be the case if it were replayed, must not
+	// affect the conn, even though it names the conn and the proof was
+	// computed with the right key.
+	dmrw, amrw, err := flowtest.NewPipe(ctx, "local", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aerr := make(chan error, 1)
+	go func() {
+		ridep := naming.Endpoint{Protocol: "local", Address: ":0", RoutingID: naming.FixedRoutingID(191341)}
+		_, err := NewAccepted(ctx, nil, amrw, ridep, version.Supported, fh(p.aflows), aopts)
+		aerr <- err
+	}()
+	mp := newMessagePipe(dmrw)
+	if _, nBuf, err := mp.readSetup(ctx); err != nil {
+		t.Fatal(err)
+	} else {
+		putNetBuf(nBuf)
+	}
+	r := ac.resumable
+	nonce, otherNonce := []byte("dialer nonce"), []byte("another acceptor nonce")
+	if err := mp.writeSetup(ctx, message.Setup{
+		Versions:       version.Supported,
+		ResumeID:       r.id,
+		ResumeNonce:    nonce,
+		ResumeReceived: 0,
+		ResumeProof:    resumeProof(r.key, resumeDialerTag, nonce, otherNonce, 0),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	reply, nBuf, err := mp.readSetup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putNetBuf(nBuf)
+	if len(reply.ResumeProof) != 0 {
+		t.Errorf("the resumption should have been refused")
+	}
+	dmrw.Close()
+	if err := <-aerr; !errors.Is(err, ErrInvalidResumeProof) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	exchange(t, df, af, []byte("after"))
+	if got, want := ac.Resumptions(), uint64(0); got != want {
+		t.Errorf("got %v acceptor resumptions, want %v", got, want)
+	}
+	df.Close()
+	af.Close()
+	dc.Close(ctx, nil)
+	ac.Close(ctx, nil)
+	<-dc.Closed()
+	<-ac.Closed()
+}
+
+func TestResumptionNotNegotiated(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	p := &resumablePipes{t: t, ctx: ctx, table: NewResumptionTable(), aflows: make(chan flow.Flow, 1)}
+	dc, ac := setupResumableConns(t, ctx, p, Opts{ResumeTimeout: time.Minute, Redial: p.redial}, Opts{})
+	defer ac.Close(ctx, nil)
+	defer dc.Close(ctx, nil)
+	if dc.resumable != nil || ac.resumable != nil {
+		t.Errorf("resumption should not have been negotiated")
+	}
+	if dc.Reconnect() {
+		t.Errorf("conn should not be reconnectable")
+	}
+	df, af := oneFlow(t, ctx, dc, p.aflows, 0)
+	exchange(t, df, af, []byte("data"))
+	p.breakConn()
+	if _, err := af.ReadMsg(); err == nil {
+		t.Errorf("expected an error")
+	}
+}
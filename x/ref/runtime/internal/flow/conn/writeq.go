@@ -9,20 +9,60 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"v.io/v23/context"
+	"v.io/v23/flow"
 	"v.io/x/lib/vlog"
 )
 
 const (
 	expressPriority = iota
+	controlFlowPriority
 	flowPriority
+	bulkFlowPriority
 	tearDownPriority
 
 	// Must be last.
 	numPriorities
 )
 
+// priorityWeights determines how writers of different priorities are
+// scheduled. Writers of priorities with a zero weight are always scheduled
+// ahead of those of lower priorities. Writers of adjacent priorities with
+// non-zero weights are scheduled in proportion to those weights when they
+// are all waiting, so that lower priority writers are not starved.
+var priorityWeights = [numPriorities]int{
+	flowPriority:     4,
+	bulkFlowPriority: 1,
+}
+
+// writeqPriority returns the writeq priority used for flows of priority p.
+func writeqPriority(p flow.Priority) int {
+	switch p {
+	case flow.ControlPriority:
+		return controlFlowPriority
+	case flow.BulkPriority:
+		return bulkFlowPriority
+	}
+	return flowPriority
+}
+
+// WriteQueueStats records how the writes to the flows of a given priority
+// on a connection were scheduled.
+type WriteQueueStats struct {
+	// Writes is the total number of writes.
+	Writes uint64
+	// Queued is the number of writes that had to wait for those of other
+	// flows, and Delay the total time that they spent waiting.
+	Queued uint64
+	Delay  time.Duration
+}
+
+func (s WriteQueueStats) String() string {
+	return fmt.Sprintf("%d writes, %d queued for %v", s.Writes, s.Queued, s.Delay)
+}
+
 // writeq implements a set of LIFO queues used to order and prioritize
 // writing to a connection's underlying message pipe. The intention is for
 // writers to block until their turn to transmit comes up. This approach,
@@ -53,6 +93,11 @@ const (
 //	    that the supplied writer is not the active one. An error is returned
 //	    either for context cancelation or attempting to wait on the same
 //	    writer more than once.
+//
+// Writers are scheduled according to priorityWeights. Note that flows only
+// wait on the writeq once they have obtained the flow control tokens needed
+// for their writes, so flows that are blocked on flow control never hold up
+// others, regardless of their priority.
 type writeq struct {
 	mu sync.Mutex
 
@@ -68,6 +113,13 @@ type writeq struct {
 	// to an empty writeq (via wait) will return immediately since there is
 	// no need for it to wait.
 	active *writer
+
+	// credits is the number of writers of each priority with a non-zero
+	// weight that may be scheduled before those of the priorities are
+	// replenished.
+	credits [numPriorities]int
+
+	stats [numPriorities]WriteQueueStats
 }
 
 type writer struct {
@@ -88,6 +140,9 @@ type writer struct {
 	prev, next *writer
 
 	notify chan struct{}
+
+	// queued is the time at which the writer was added to the writeq.
+	queued time.Time
 }
 
 func (q *writeq) String() string {
@@ -154,24 +209,59 @@ func (q *writeq) addWriterLocked(w *writer, p int) bool {
 	return true
 }
 
-// nextLocked returns the next writer in the q by priority and LIFO
-// order. If a writer is found it is removed from the queue.
-func (q *writeq) nextLocked() (*writer, int) {
+// priorityLocked returns the priority of the next writer to be
+// scheduled, or -1 if there are none.
+func (q *writeq) priorityLocked() int {
+	exhausted := -1
 	for p, head := range q.activeWriters {
-		if head != nil {
-			prv, nxt := head.prev, head.next
-			if nxt == head {
-				q.activeWriters[p] = nil
-				head.prev, head.next = nil, nil
-				return head, p
+		if head == nil {
+			continue
+		}
+		if priorityWeights[p] == 0 {
+			if exhausted >= 0 {
+				break
 			}
-			q.activeWriters[p] = head.next
-			nxt.prev, prv.next = prv, nxt
-			head.prev, head.next = nil, nil
-			return head, p
+			return p
+		}
+		if q.credits[p] > 0 {
+			q.credits[p]--
+			return p
 		}
+		if exhausted < 0 {
+			exhausted = p
+		}
+	}
+	if exhausted < 0 {
+		return -1
+	}
+	// All of the waiting writers with non-zero weights have used their
+	// credits, so replenish them.
+	for p, weight := range priorityWeights {
+		q.credits[p] = weight
+	}
+	q.credits[exhausted]--
+	return exhausted
+}
+
+// nextLocked returns the next writer in the q by priority and LIFO
+// order. If a writer is found it is removed from the queue.
+func (q *writeq) nextLocked() (*writer, int) {
+	p := q.priorityLocked()
+	if p < 0 {
+		return nil, -1
+	}
+	head := q.activeWriters[p]
+	q.stats[p].Delay += time.Since(head.queued)
+	prv, nxt := head.prev, head.next
+	if nxt == head {
+		q.activeWriters[p] = nil
+		head.prev, head.next = nil, nil
+		return head, p
 	}
-	return nil, -1
+	q.activeWriters[p] = head.next
+	nxt.prev, prv.next = prv, nxt
+	head.prev, head.next = nil, nil
+	return head, p
 }
 
 func (q *writeq) handleCancel(w *writer, p int) {
@@ -231,6 +321,9 @@ func (q *writeq) wait(ctx *context.T, w *writer, p int) error {
 			vlog.Infof("writer %p, priority %v already exists in the writeq", w, p)
 			return fmt.Errorf("writer %p, priority %v already exists in the writeq", w, p)
 		}
+		w.queued = time.Now()
+		q.stats[p].Writes++
+		q.stats[p].Queued++
 		q.mu.Unlock()
 		return q.signalWait(ctx, w, p)
 	}
@@ -238,6 +331,7 @@ func (q *writeq) wait(ctx *context.T, w *writer, p int) error {
 	// another writer waiting so it's safe to make the new writer the active one
 	// and just return immediately with no need to signal the writer.
 	q.active = w
+	q.stats[p].Writes++
 	q.mu.Unlock()
 	return nil
 
@@ -258,3 +352,11 @@ func (q *writeq) done(w *writer) {
 	}
 	vlog.Infof("%p.writeq(%p): unexpected active writer, should be %p, not %p", q, w, w, q.active)
 }
+
+// statistics returns the statistics for the writes of the flows of
+// priority p.
+func (q *writeq) statistics(p flow.Priority) WriteQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.stats[writeqPriority(p)]
+}
@@ -179,6 +179,36 @@ func TestWriteqLists(t *testing.T) {
 
 }
 
+func TestWriteqWeightedPriorities(t *testing.T) {
+	wq := &writeq{}
+
+	interactive := []*writeqEntry{newEntry(), newEntry(), newEntry(), newEntry(), newEntry(), newEntry()}
+	bulk := []*writeqEntry{newEntry(), newEntry(), newEntry()}
+	control, tearDown := newEntry(), newEntry()
+
+	addWriteq(wq, tearDownPriority, tearDown)
+	addWriteq(wq, bulkFlowPriority, bulk...)
+	addWriteq(wq, flowPriority, interactive...)
+	addWriteq(wq, controlFlowPriority, control)
+
+	// Control writers are always first and tear down writers always last.
+	// Interactive and bulk writers are interleaved according to their
+	// weights whilst both are waiting.
+	for _, w := range []*writeqEntry{
+		control,
+		interactive[0], interactive[1], interactive[2], interactive[3],
+		bulk[0],
+		interactive[4], interactive[5],
+		bulk[1], bulk[2],
+		tearDown,
+	} {
+		cmpWriteqNext(t, wq, w)
+	}
+	if w, _ := wq.nextLocked(); w != nil {
+		t.Errorf("unexpected writer: %p", w)
+	}
+}
+
 func TestWriteqErrors(t *testing.T) {
 	defer goroutines.NoLeaks(t, leakWaitTime)()
 	wq := &writeq{}
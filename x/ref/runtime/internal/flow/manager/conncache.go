@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"v.io/v23/context"
@@ -34,6 +35,67 @@ type ConnCache struct {
 	reserved map[interface{}]*Reservation
 
 	idleExpiry time.Duration
+	limit      CacheLimit
+
+	// The following are reported by Stats.
+	hits, misses, evictions uint64
+}
+
+// EvictionPolicy determines the order in which conns are evicted from a
+// ConnCache that holds more than CacheLimit.MaxConns conns.
+type EvictionPolicy int
+
+const (
+	// EvictLRU evicts the least recently used conns first.
+	EvictLRU EvictionPolicy = iota
+	// EvictLeastActive evicts the conns that have been found in the cache
+	// the fewest times first, with ties broken by evicting the least
+	// recently used conn.
+	EvictLeastActive
+)
+
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "lru"
+	case EvictLeastActive:
+		return "least-active"
+	}
+	return "EvictionPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// CacheLimit bounds the number of conns held by a ConnCache.
+type CacheLimit struct {
+	// MaxConns is the maximum number of conns that the cache will hold,
+	// not counting conns that are encapsulated within other conns and
+	// hence do not use a file descriptor. Once it is exceeded, idle conns
+	// are evicted in the order given by Policy. Conns with open flows,
+	// including those held open by a flow.PinnedConn, are never evicted
+	// and hence MaxConns may be exceeded if all of the cached conns are
+	// in use. If zero, the number of conns is unbounded.
+	MaxConns int
+	// Policy determines the order in which conns are evicted.
+	Policy EvictionPolicy
+}
+
+// CacheStats contains statistics on the use of a ConnCache.
+type CacheStats struct {
+	// Hits is the number of times that a conn was found in the cache.
+	Hits uint64
+	// Misses is the number of times that a conn was not found in the
+	// cache.
+	Misses uint64
+	// Evictions is the number of conns that were closed and removed from
+	// the cache in order to respect CacheLimit.MaxConns.
+	Evictions uint64
+}
+
+// HitRate returns the fraction of lookups that found a conn in the cache.
+func (s CacheStats) HitRate() float64 {
+	if total := s.Hits + s.Misses; total > 0 {
+		return float64(s.Hits) / float64(total)
+	}
+	return 0
 }
 
 type connEntry struct {
@@ -48,6 +110,8 @@ type connEntry struct {
 	// needed.  In our case that's when we eject the context from the cache.
 	cancel context.CancelFunc
 	keys   []interface{}
+	// hits is the number of times that this conn has been found in the cache.
+	hits uint64
 }
 
 type dialError struct {
@@ -110,6 +174,7 @@ func (r *Reservation) Unreserve(conn, proxyConn CachedConn, err error) error {
 	if conn != nil {
 		c.insertConnLocked(r.remote, conn, proxyConn != nil, proxyConn == nil, r.cancel)
 		r.cancel = nil
+		c.evictLocked(r.ctx, conn, proxyConn)
 	} else if err != nil {
 		e := dialError{
 			err:  err,
@@ -144,40 +209,50 @@ type CachedConn interface {
 	RTT() time.Duration
 	LastUsed() time.Time
 	DebugString() string
+	Reconnect() bool
 }
 
 // NewConnCache creates a ConnCache with an idleExpiry for connections.
 // If idleExpiry is zero, connections will never expire.
 func NewConnCache(idleExpiry time.Duration) *ConnCache {
+	return NewBoundedConnCache(idleExpiry, CacheLimit{})
+}
+
+// NewBoundedConnCache creates a ConnCache with an idleExpiry for connections
+// that will evict connections as per limit.
+func NewBoundedConnCache(idleExpiry time.Duration, limit CacheLimit) *ConnCache {
 	return &ConnCache{
 		conns:      make(map[CachedConn]*connEntry),
 		cache:      make(map[interface{}][]*connEntry),
 		errors:     make(map[interface{}]dialError),
 		reserved:   make(map[interface{}]*Reservation),
 		idleExpiry: idleExpiry,
+		limit:      limit,
 	}
 }
 
 // Insert adds conn to the cache, keyed by both (protocol, address) and (routingID).
 // An error will be returned iff the cache has been closed.
-func (c *ConnCache) Insert(conn CachedConn, proxy bool) error {
+func (c *ConnCache) Insert(ctx *context.T, conn CachedConn, proxy bool) error {
 	defer c.mu.Unlock()
 	c.mu.Lock()
 	if c.conns == nil {
-		return errCacheClosed.Errorf(nil, "cache is closed")
+		return errCacheClosed.Errorf(ctx, "cache is closed")
 	}
 	c.insertConnLocked(conn.RemoteEndpoint(), conn, proxy, true, nil)
+	c.evictLocked(ctx, conn)
 	return nil
 }
 
 // InsertWithRoutingID adds conn to the cache keyed only by conn's RoutingID.
-func (c *ConnCache) InsertWithRoutingID(conn CachedConn, proxy bool) error {
+func (c *ConnCache) InsertWithRoutingID(ctx *context.T, conn CachedConn, proxy bool) error {
 	defer c.mu.Unlock()
 	c.mu.Lock()
 	if c.conns == nil {
-		return errCacheClosed.Errorf(nil, "cache is closed")
+		return errCacheClosed.Errorf(ctx, "cache is closed")
 	}
 	c.insertConnLocked(conn.RemoteEndpoint(), conn, proxy, false, nil)
+	c.evictLocked(ctx, conn)
 	return nil
 }
 
@@ -192,12 +267,13 @@ func (c *ConnCache) Find(
 	auth flow.PeerAuthorizer,
 ) (conn CachedConn, names []string, rejected []security.RejectedBlessing, err error) {
 	var keys []interface{}
-	if keys, conn, names, rejected, _ = c.internalFindCached(ctx, remote, auth); conn != nil {
-		return conn, names, rejected, nil
+	if keys, conn, names, rejected, _ = c.internalFindCached(ctx, remote, auth); conn == nil {
+		// Finally try waiting for any outstanding dials to complete, a
+		// conn obtained by doing so also counts as a hit.
+		conn, names, rejected, err = c.internalFind(ctx, remote, keys, auth, true)
 	}
-	// Finally try waiting for any outstanding dials to complete.
-	nc, n, r, err := c.internalFind(ctx, remote, keys, auth, true)
-	return nc, n, r, err
+	c.recordLookup(conn)
+	return conn, names, rejected, err
 }
 
 // FindCached returns a Conn only if it's already in the cache.
@@ -206,6 +282,7 @@ func (c *ConnCache) FindCached(
 	remote naming.Endpoint,
 	auth flow.PeerAuthorizer) (conn CachedConn, names []string, rejected []security.RejectedBlessing, err error) {
 	_, conn, names, rejected, err = c.internalFindCached(ctx, remote, auth)
+	c.recordLookup(conn)
 	return
 }
 
@@ -533,6 +610,21 @@ func (c *ConnCache) EnterLameDuckMode(ctx *context.T) {
 	}
 }
 
+// Reconnect causes all of the dialed connections in the cache that support
+// resumption to be resumed over new network connections. It returns the
+// number of connections that will be resumed.
+func (c *ConnCache) Reconnect(ctx *context.T) int {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	n := 0
+	for _, e := range c.conns {
+		if e.conn.Reconnect() {
+			n++
+		}
+	}
+	return n
+}
+
 // Close closes all connections in the cache.
 func (c *ConnCache) Close(ctx *context.T) {
 	defer c.mu.Unlock()
@@ -555,6 +647,13 @@ func (c *ConnCache) Close(ctx *context.T) {
 	c.errors = nil
 }
 
+// Stats returns statistics on the use of the cache.
+func (c *ConnCache) Stats() CacheStats {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
+
 // String returns a user friendly representation of the connections in the cache.
 func (c *ConnCache) String() string {
 	defer c.mu.Unlock()
@@ -578,6 +677,80 @@ func (c *ConnCache) String() string {
 func (c *ConnCache) ExportStats(prefix string) {
 	stats.NewStringFunc(naming.Join(prefix, "cache"), c.debugStringForCache)
 	stats.NewStringFunc(naming.Join(prefix, "reserved"), c.debugStringForDialing)
+	stats.NewIntegerFunc(naming.Join(prefix, "hits"), func() int64 { return int64(c.Stats().Hits) })
+	stats.NewIntegerFunc(naming.Join(prefix, "misses"), func() int64 { return int64(c.Stats().Misses) })
+	stats.NewIntegerFunc(naming.Join(prefix, "evictions"), func() int64 { return int64(c.Stats().Evictions) })
+	stats.NewFloatFunc(naming.Join(prefix, "hit-rate"), func() float64 { return c.Stats().HitRate() })
+}
+
+// recordLookup updates the cache statistics for a lookup that found conn,
+// which is nil if no conn was found.
+func (c *ConnCache) recordLookup(conn CachedConn) {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	if conn == nil {
+		c.misses++
+		return
+	}
+	c.hits++
+	if e := c.conns[conn]; e != nil {
+		e.hits++
+	}
+}
+
+// evictLocked closes and removes idle connections, in the order determined
+// by the cache's eviction policy, until the cache holds no more than the
+// maximum number of connections allowed. The connections in keep, which
+// have typically just been inserted, are never evicted.
+func (c *ConnCache) evictLocked(ctx *context.T, keep ...CachedConn) {
+	if c.limit.MaxConns <= 0 {
+		return
+	}
+	entries := make([]*connEntry, 0, len(c.conns))
+	for _, e := range c.conns {
+		// Encapsulated connections do not consume a file descriptor.
+		if !e.conn.IsEncapsulated() {
+			entries = append(entries, e)
+		}
+	}
+	excess := len(entries) - c.limit.MaxConns
+	if excess <= 0 {
+		return
+	}
+	switch c.limit.Policy {
+	case EvictLeastActive:
+		sort.Sort(activityEntries(entries))
+	default:
+		sort.Sort(lruEntries(entries))
+	}
+	for _, e := range entries {
+		if excess <= 0 {
+			break
+		}
+		if isKept(e.conn, keep) {
+			continue
+		}
+		switch {
+		case e.conn.Status() >= conn.Closing:
+			c.removeEntryLocked(e)
+			excess--
+		case e.conn.CloseIfIdle(ctx, 0):
+			// Connections with open flows, such as those that are
+			// pinned, are never idle and hence never evicted.
+			c.removeEntryLocked(e)
+			c.evictions++
+			excess--
+		}
+	}
+}
+
+func isKept(conn CachedConn, keep []CachedConn) bool {
+	for _, k := range keep {
+		if k == conn {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *ConnCache) insertConnLocked(remote naming.Endpoint, conn CachedConn, proxy bool, keyByAddr bool, cancel context.CancelFunc) bool {
@@ -789,6 +962,23 @@ func (e lruEntries) Swap(i, j int) {
 	e[i], e[j] = e[j], e[i]
 }
 
+type activityEntries []*connEntry
+
+func (e activityEntries) Len() int {
+	return len(e)
+}
+
+func (e activityEntries) Less(i, j int) bool {
+	if e[i].hits != e[j].hits {
+		return e[i].hits < e[j].hits
+	}
+	return e[i].conn.LastUsed().Before(e[j].conn.LastUsed())
+}
+
+func (e activityEntries) Swap(i, j int) {
+	e[i], e[j] = e[j], e[i]
+}
+
 func resolve(ctx *context.T, p flow.Protocol, protocol, address string) (string, []string, error) {
 	if p != nil {
 		net, addrs, err := p.Resolve(ctx, protocol, address)
@@ -161,6 +161,36 @@ func TestCacheReserve(t *testing.T) {
 	}
 }
 
+func TestCacheFindAfterDial(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	c := NewConnCache(0)
+	defer c.Close(ctx)
+	ep, _, _, _, _, _ := makeEPs(ctx, "address")
+	r := c.Reserve(ctx, ep)
+	found := make(chan CachedConn, 1)
+	go func() {
+		conn, _, _, _ := c.Find(ctx, ep, flowtest.NewPeerAuthorizer(ep.BlessingNames()))
+		found <- conn
+	}()
+	// A conn that is found once the outstanding dial completes counts as
+	// a hit.
+	time.Sleep(50 * time.Millisecond)
+	caf := makeConnAndFlow(t, ctx, ep)
+	defer caf.stop(ctx)
+	if err := r.Unreserve(caf.c, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-found, caf.c; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 1}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
 func TestCacheFind(t *testing.T) { //nolint:gocyclo
 	defer goroutines.NoLeaks(t, leakWaitTime)()
 	ctx, shutdown := test.V23Init()
@@ -173,7 +203,7 @@ func TestCacheFind(t *testing.T) { //nolint:gocyclo
 	caf := makeConnAndFlow(t, ctx, ep)
 	defer caf.stop(ctx)
 	conn := caf.c
-	if err := c.Insert(conn, false); err != nil {
+	if err := c.Insert(ctx, conn, false); err != nil {
 		t.Fatal(err)
 	}
 	// We should be able to find the conn in the cache.
@@ -210,7 +240,7 @@ func TestCacheFind(t *testing.T) { //nolint:gocyclo
 	caf = makeConnAndFlow(t, ctx, proxyep)
 	defer caf.stop(ctx)
 	proxyConn := caf.c
-	if err := c.Insert(proxyConn, true); err != nil {
+	if err := c.Insert(ctx, proxyConn, true); err != nil {
 		t.Fatal(err)
 	}
 	// Wrong blessingNames should still work
@@ -225,7 +255,7 @@ func TestCacheFind(t *testing.T) { //nolint:gocyclo
 	caf = makeConnAndFlow(t, ctx, ridep)
 	defer caf.stop(ctx)
 	ridConn := caf.c
-	if err := c.InsertWithRoutingID(ridConn, false); err != nil {
+	if err := c.InsertWithRoutingID(ctx, ridConn, false); err != nil {
 		t.Fatal(err)
 	}
 	if got, _, _, err := c.Find(ctx, nullridep, ridauth); err == nil || got != nil {
@@ -240,7 +270,7 @@ func TestCacheFind(t *testing.T) { //nolint:gocyclo
 	caf = makeConnAndFlow(t, ctx, ep)
 	defer caf.stop(ctx)
 	dupConn := caf.c
-	if err := c.Insert(dupConn, false); err != nil {
+	if err := c.Insert(ctx, dupConn, false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -271,7 +301,7 @@ func TestLRU(t *testing.T) { //nolint:gocyclo
 	conns, stop := nConnAndFlows(t, ctx, 10)
 	defer stop()
 	for _, conn := range conns {
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -302,7 +332,7 @@ func TestLRU(t *testing.T) { //nolint:gocyclo
 	conns, stop = nConnAndFlows(t, ctx, 10)
 	defer stop()
 	for _, conn := range conns {
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -335,7 +365,7 @@ func TestLRU(t *testing.T) { //nolint:gocyclo
 	conns, stop = nConnAndFlows(t, ctx, 10)
 	defer stop()
 	for _, conn := range conns {
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -378,7 +408,7 @@ func TestIdleConns(t *testing.T) { //nolint:gocyclo
 	for _, conn := range conns {
 		// close the flows so the conns aren't kept alive due to ongoing flows.
 		conn.f.Close()
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -416,7 +446,7 @@ func TestIdleConns(t *testing.T) { //nolint:gocyclo
 	for _, conn := range conns {
 		// close the flows so the conns aren't kept alive due to ongoing flows.
 		conn.f.Close()
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -440,7 +470,7 @@ func TestIdleConns(t *testing.T) { //nolint:gocyclo
 	defer stop()
 	for _, conn := range conns {
 		// don't close the flows so that the conns are kept alive.
-		if err := c.Insert(conn.c, false); err != nil {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -458,6 +488,103 @@ func TestIdleConns(t *testing.T) { //nolint:gocyclo
 	}
 }
 
+func TestEviction(t *testing.T) { //nolint:gocyclo
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	idle := func(conns []connAndFlow) {
+		for _, conn := range conns {
+			conn.f.Close()
+			for conn.c.HasActiveFlows() {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+
+	// Ensure that the least recently used idle conns are evicted.
+	c := NewBoundedConnCache(0, CacheLimit{MaxConns: 5})
+	defer c.Close(ctx)
+	conns, stop := nConnAndFlows(t, ctx, 10)
+	defer stop()
+	idle(conns)
+	for _, conn := range conns {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.Stats().Evictions, uint64(5); got != want {
+		t.Errorf("got %v evictions, want %v", got, want)
+	}
+	for _, conn := range conns[:5] {
+		<-conn.c.Closed()
+		if isInCache(ctx, c, conn.c) {
+			t.Errorf("conn %v should not be in cache", conn)
+		}
+	}
+	for _, conn := range conns[5:] {
+		if !isInCache(ctx, c, conn.c) {
+			t.Errorf("conn %v should still be in cache", conn)
+		}
+	}
+
+	// Ensure that conns with open flows are never evicted.
+	c = NewBoundedConnCache(0, CacheLimit{MaxConns: 2})
+	defer c.Close(ctx)
+	conns, stop = nConnAndFlows(t, ctx, 4)
+	defer stop()
+	for _, conn := range conns {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := c.Stats().Evictions, uint64(0); got != want {
+		t.Errorf("got %v evictions, want %v", got, want)
+	}
+	for _, conn := range conns {
+		if status := conn.c.Status(); status >= connpackage.Closing {
+			t.Errorf("conn %v should not have been closed", conn)
+		}
+	}
+
+	// Ensure that the least active conns are evicted first and that
+	// hits and misses are counted.
+	c = NewBoundedConnCache(0, CacheLimit{MaxConns: 3, Policy: EvictLeastActive})
+	defer c.Close(ctx)
+	conns, stop = nConnAndFlows(t, ctx, 4)
+	defer stop()
+	idle(conns)
+	for _, conn := range conns[:3] {
+		if err := c.Insert(ctx, conn.c, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range []connAndFlow{conns[0], conns[0], conns[1]} {
+		rep := conn.c.RemoteEndpoint()
+		if got, _, _, err := c.FindCached(ctx, rep, flowtest.NewPeerAuthorizer(rep.BlessingNames())); err != nil || got != conn.c {
+			t.Errorf("got %v, want %v, err: %v", got, conn.c, err)
+		}
+	}
+	if got, _, _, _ := c.FindCached(ctx, makeEP(ctx, "local", "nowhere", 1000), nil); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+	if err := c.Insert(ctx, conns[3].c, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Stats(), (CacheStats{Hits: 3, Misses: 1, Evictions: 1}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got, want := c.Stats().HitRate(), 0.75; got != want {
+		t.Errorf("got hit rate %v, want %v", got, want)
+	}
+	<-conns[2].c.Closed()
+	for i, conn := range conns {
+		if got, want := isInCache(ctx, c, conn.c), i != 2; got != want {
+			t.Errorf("conn %v: got in cache %v, want %v", i, got, want)
+		}
+	}
+}
+
 func TestMultiRTTConns(t *testing.T) {
 	defer goroutines.NoLeaks(t, leakWaitTime)()
 	ctx, shutdown := test.V23Init()
@@ -470,7 +597,7 @@ func TestMultiRTTConns(t *testing.T) {
 	slow, med, fast := 3*time.Millisecond, 2*time.Millisecond, 1*time.Millisecond
 	// Add a slow connection into the cache and ensure it is found.
 	slowConn := newRTTConn(ctx, remote, slow)
-	if err := c.Insert(slowConn, false); err != nil {
+	if err := c.Insert(ctx, slowConn, false); err != nil {
 		t.Fatal(err)
 	}
 	if got, _, _, err := c.Find(ctx, remote, auth); err != nil || got != slowConn {
@@ -479,7 +606,7 @@ func TestMultiRTTConns(t *testing.T) {
 
 	// Add a fast connection into the cache and ensure it is found over the slow one.
 	fastConn := newRTTConn(ctx, remote, fast)
-	if err := c.Insert(fastConn, false); err != nil {
+	if err := c.Insert(ctx, fastConn, false); err != nil {
 		t.Fatal(err)
 	}
 	if got, _, _, err := c.Find(ctx, remote, auth); err != nil || got != fastConn {
@@ -488,7 +615,7 @@ func TestMultiRTTConns(t *testing.T) {
 
 	// Add a med connection into the cache and ensure that the fast one is still found.
 	medConn := newRTTConn(ctx, remote, med)
-	if err := c.Insert(medConn, false); err != nil {
+	if err := c.Insert(ctx, medConn, false); err != nil {
 		t.Fatal(err)
 	}
 	if got, _, _, err := c.Find(ctx, remote, auth); err != nil || got != fastConn {
@@ -552,6 +679,7 @@ func (c *rttConn) RemoteDischarges() security.Discharges { return nil }
 func (c *rttConn) RTT() time.Duration                    { return c.rtt }
 func (c *rttConn) LastUsed() time.Time                   { return time.Now() }
 func (c *rttConn) DebugString() string                   { return "" }
+func (c *rttConn) Reconnect() bool                       { return false }
 
 func isInCache(ctx *context.T, c *ConnCache, conn *connpackage.Conn) bool {
 	rep := conn.RemoteEndpoint()
@@ -49,7 +49,27 @@ type manager struct {
 	ctx                  *context.T
 	acceptChannelTimeout time.Duration
 	idleExpiry           time.Duration // time after which idle connections will be closed.
+	resumeTimeout        time.Duration // time allowed for broken connections to be resumed.
+	resumptions          *conn.ResumptionTable
 	cacheTicker          *time.Ticker
+	rtts                 *rttStats
+	connOpts             ConnOptions
+}
+
+// ConnOptions configures the conns created by a manager.
+type ConnOptions struct {
+	// Compression enables the compression of the data sent over conns
+	// whose peers enable it too. See conn.Opts.Compression for why it is
+	// off by default.
+	Compression bool
+	// CompressionThreshold is the size below which data is not compressed,
+	// conn.DefaultCompressionThreshold is used if it is zero.
+	CompressionThreshold uint64
+	// RekeyBytes and RekeyInterval determine how often the keys used to
+	// encrypt the data sent over conns are rotated, conn.DefaultRekeyBytes
+	// and conn.DefaultRekeyInterval are used if they are zero.
+	RekeyBytes    uint64
+	RekeyInterval time.Duration
 }
 
 type listenState struct {
@@ -79,21 +99,31 @@ type endpointState struct {
 	roaming      bool
 }
 
-// New creates a new flow manager.
+// New creates a new flow manager. If resumeTimeout is non-zero, connections
+// whose underlying network connections fail are resumed over new network
+// connections, provided that this can be done within resumeTimeout and that
+// the peer also supports resumption. cacheLimit bounds the number of
+// connections that are cached by the manager and connOpts configures the
+// connections that it creates.
 func New(
 	ctx *context.T,
 	rid naming.RoutingID,
 	dhcpPublisher *pubsub.Publisher,
 	channelTimeout time.Duration,
 	idleExpiry time.Duration,
+	resumeTimeout time.Duration,
+	cacheLimit CacheLimit,
+	connOpts ConnOptions,
 	authorizedPeers []security.BlessingPattern) flow.Manager {
 	m := &manager{
 		rid:                  rid,
 		closed:               make(chan struct{}),
-		cache:                NewConnCache(idleExpiry),
+		cache:                NewBoundedConnCache(idleExpiry, cacheLimit),
 		ctx:                  ctx,
 		acceptChannelTimeout: channelTimeout,
 		idleExpiry:           idleExpiry,
+		resumeTimeout:        resumeTimeout,
+		connOpts:             connOpts,
 	}
 
 	var valid <-chan struct{}
@@ -123,8 +153,16 @@ func New(
 	}
 	m.cacheTicker = time.NewTicker(cacheInterval)
 
+	if resumeTimeout > 0 {
+		if rid != naming.NullRoutingID {
+			m.resumptions = conn.NewResumptionTable()
+		}
+		go m.reconnectOnNetworkChanges(ctx)
+	}
+
 	statsPrefix := naming.Join("rpc", "flow", rid.String())
 	m.cache.ExportStats(naming.Join(statsPrefix, "conn-cache"))
+	m.rtts = newRTTStats(naming.Join(statsPrefix, "rtt-ms"))
 	go func() {
 		for {
 			select {
@@ -151,6 +189,16 @@ func New(
 	return m
 }
 
+// newConnOpts returns opts with the options that the manager applies to
+// all of its conns added.
+func (m *manager) newConnOpts(opts conn.Opts) conn.Opts {
+	opts.Compression = m.connOpts.Compression
+	opts.CompressionThreshold = m.connOpts.CompressionThreshold
+	opts.RekeyBytes = m.connOpts.RekeyBytes
+	opts.RekeyInterval = m.connOpts.RekeyInterval
+	return opts
+}
+
 func (m *manager) stopListening() {
 	if m.ls == nil {
 		return
@@ -282,6 +330,27 @@ func (m *manager) monitorNetworkChanges(ctx *context.T, done chan<- struct{}) {
 	}
 }
 
+// reconnectOnNetworkChanges resumes the dialed connections over new network
+// connections whenever the network configuration changes since the
+// addresses they are bound to may no longer be usable.
+func (m *manager) reconnectOnNetworkChanges(ctx *context.T) {
+	for {
+		change, err := netconfig.NotifyChange()
+		if err != nil {
+			ctx.Errorf("connections will not be resumed if the network configuration changes, failed to monitor network changes: %v", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-change:
+			if n := m.cache.Reconnect(ctx); n > 0 {
+				ctx.Infof("Network configuration may have changed, resuming %v connections (routing id: %v)", n, m.rid)
+			}
+		}
+	}
+}
+
 func (m *manager) updateRoamingEndpoints(ctx *context.T) {
 	ctx.Infof("Network configuration may have changed, adjusting the addresses to listen on (routing id: %v)", m.rid)
 	changed := false
@@ -534,6 +603,7 @@ func (m *manager) lnAcceptLoop(ctx *context.T, ln flow.Listener, local naming.En
 		m.ls.mu.Unlock()
 	}()
 	const killConnectionsRetryDelay = 5 * time.Millisecond
+	keepalive := flow.Keepalive(ctx)
 	for {
 		flowConn, err := ln.Accept(ctx)
 		for tokill := 1; isTemporaryError(err); tokill *= 2 {
@@ -583,10 +653,18 @@ func (m *manager) lnAcceptLoop(ctx *context.T, ln flow.Listener, local naming.En
 				local,
 				version.Supported,
 				fh,
-				conn.Opts{
-					HandshakeTimeout: handshakeTimeout,
-					ChannelTimeout:   m.acceptChannelTimeout})
-			if err != nil {
+				m.newConnOpts(conn.Opts{
+					HandshakeTimeout:  handshakeTimeout,
+					ChannelTimeout:    m.acceptChannelTimeout,
+					ResumeTimeout:     m.resumeTimeout,
+					Resumptions:       m.resumptions,
+					KeepaliveInterval: keepalive.Interval,
+					MissedKeepalives:  keepalive.MissedThreshold,
+					RTTObserver:       m.rtts.record}))
+			if errors.Is(err, conn.ErrConnResumed) {
+				// The network connection now belongs to an existing conn.
+				ctx.VI(1).Infof("resumed conn on localEP %v", local)
+			} else if err != nil {
 				// We don't want probing from load balancers or Prometheus to cause
 				// the error log to be noisy so we skip logging an err in the following
 				// cases:
@@ -630,7 +708,7 @@ func (m *manager) lnAcceptLoop(ctx *context.T, ln flow.Listener, local naming.En
 					}
 				}
 				flowConn.Close()
-			} else if err = m.cache.InsertWithRoutingID(c, false); err != nil {
+			} else if err = m.cache.InsertWithRoutingID(ctx, c, false); err != nil {
 				ctx.Errorf("failed to cache conn %v: %v", c, err)
 				c.Close(ctx, err)
 			}
@@ -706,12 +784,13 @@ func (h *proxyFlowHandler) HandleFlow(f flow.Flow) error {
 			f.LocalEndpoint(),
 			version.Supported,
 			fh,
-			conn.Opts{
+			h.m.newConnOpts(conn.Opts{
 				HandshakeTimeout: handshakeTimeout,
-				ChannelTimeout:   h.m.acceptChannelTimeout})
+				ChannelTimeout:   h.m.acceptChannelTimeout,
+				RTTObserver:      h.m.rtts.record}))
 		if err != nil {
 			h.m.ctx.Errorf("failed to create accepted conn: %v", err)
-		} else if err = h.m.cache.InsertWithRoutingID(c, false); err != nil {
+		} else if err = h.m.cache.InsertWithRoutingID(h.m.ctx, c, false); err != nil {
 			h.m.ctx.Errorf("failed to create accepted conn: %v", err)
 		}
 		close(fh.cached)
@@ -970,6 +1049,7 @@ func (m *manager) dialConn(
 		}
 		m.ls.mu.Unlock()
 	}
+	keepalive := flow.Keepalive(ctx)
 	c, _, _, err := conn.NewDialed(
 		ctx,
 		flowConn,
@@ -978,7 +1058,16 @@ func (m *manager) dialConn(
 		version.Supported,
 		auth,
 		fh,
-		conn.Opts{HandshakeTimeout: handshakeTimeout},
+		m.newConnOpts(conn.Opts{
+			HandshakeTimeout: handshakeTimeout,
+			ResumeTimeout:    m.resumeTimeout,
+			Redial: func(ctx *context.T) (flow.Conn, error) {
+				return dial(ctx, protocol, remote.Protocol, remote.Address)
+			},
+			KeepaliveInterval: keepalive.Interval,
+			MissedKeepalives:  keepalive.MissedThreshold,
+			RTTObserver:       m.rtts.record,
+		}),
 	)
 	if errors.Is(err, verror.ErrCanceled) {
 		// If the connection was canceled, it may still be dialed, so
@@ -1018,7 +1107,7 @@ func (m *manager) dialProxyConn(
 		version.Supported,
 		auth,
 		fh,
-		conn.Opts{Proxy: true, HandshakeTimeout: handshakeTimeout},
+		m.newConnOpts(conn.Opts{Proxy: true, HandshakeTimeout: handshakeTimeout, RTTObserver: m.rtts.record}),
 	)
 	if err != nil {
 		return nil, names, rejected, iflow.MaybeWrapError(flow.ErrDialFailed, ctx, err)
@@ -27,11 +27,11 @@ func TestDirectConnection(t *testing.T) {
 	ctx, shutdown := test.V23Init()
 	ctx, cancel := context.WithCancel(ctx)
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		t.Fatal(err)
 	}
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 
 	testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
 
@@ -46,12 +46,12 @@ func TestDialCachedConn(t *testing.T) {
 	ctx, shutdown := test.V23Init()
 	ctx, cancel := context.WithCancel(ctx)
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		t.Fatal(err)
 	}
 
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	// At first the cache should be empty.
 	if got, want := len(dm.(*manager).cache.conns), 0; got != want {
 		t.Fatalf("got cache size %v, want %v", got, want)
@@ -83,12 +83,12 @@ func TestBidirectionalListeningEndpoint(t *testing.T) {
 	ctx, shutdown := test.V23Init()
 	ctx, cancel := context.WithCancel(ctx)
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		t.Fatal(err)
 	}
 
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
 	// Now am should be able to make a flow to dm even though dm is not listening.
 	testFlows(t, ctx, am, dm, flowtest.AllowAllPeersAuthorizer{})
@@ -104,18 +104,18 @@ func TestPublicKeyOnlyClientBlessings(t *testing.T) {
 	ctx, shutdown := test.V23Init()
 	ctx, cancel := context.WithCancel(ctx)
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		t.Fatal(err)
 	}
-	nulldm := New(ctx, naming.NullRoutingID, nil, 0, 0, nil)
+	nulldm := New(ctx, naming.NullRoutingID, nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	_, af := testFlows(t, ctx, nulldm, am, flowtest.AllowAllPeersAuthorizer{})
 	// Ensure that the remote blessings of the underlying conn of the accepted blessings
 	// only has the public key of the client and no certificates.
 	if rBlessings := af.Conn().(*conn.Conn).RemoteBlessings(); len(rBlessings.String()) > 0 || rBlessings.PublicKey() == nil {
 		t.Errorf("got %v, want no-cert blessings", rBlessings)
 	}
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	_, af = testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
 	// Ensure that the remote blessings of the underlying conn of the accepted flow are
 	// non-zero if we did specify a RoutingID.
@@ -135,11 +135,11 @@ func TestStopListening(t *testing.T) {
 	ctx, shutdown := test.V23Init()
 	ctx, cancel := context.WithCancel(ctx)
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		t.Fatal(err)
 	}
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
 
 	lameEP := am.Status().Endpoints[0]
@@ -155,6 +155,75 @@ func TestStopListening(t *testing.T) {
 	shutdown()
 }
 
+func TestConnectionResumption(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	ctx, shutdown := test.V23Init()
+	ctx, cancel := context.WithCancel(ctx)
+
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, time.Minute, CacheLimit{}, ConnOptions{}, nil)
+	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, time.Minute, CacheLimit{}, ConnOptions{}, nil)
+
+	df, af := testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
+	// Only the dialed conn can be reconnected and the flow must survive
+	// the reconnection.
+	if got, want := am.(*manager).cache.Reconnect(ctx), 0; got != want {
+		t.Errorf("got %v reconnections, want %v", got, want)
+	}
+	if got, want := dm.(*manager).cache.Reconnect(ctx), 1; got != want {
+		t.Errorf("got %v reconnections, want %v", got, want)
+	}
+	want := "are you still there?"
+	if err := writeLine(df, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readLine(af); err != nil || got != want {
+		t.Errorf("got %v, %v, want %v", got, err, want)
+	}
+	c := dm.(*manager).cache.cache[am.RoutingID()][0].conn.(*conn.Conn)
+	if got, want := c.Resumptions(), uint64(1); got != want {
+		t.Errorf("got %v resumptions, want %v", got, want)
+	}
+
+	cancel()
+	<-am.Closed()
+	<-dm.Closed()
+	shutdown()
+}
+
+func TestConnCompression(t *testing.T) {
+	defer goroutines.NoLeaks(t, leakWaitTime)()
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	line := strings.Repeat("compressible ", 100)
+	for _, compression := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(ctx)
+		opts := ConnOptions{Compression: compression, CompressionThreshold: 16}
+		am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, opts, nil)
+		if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, opts, nil)
+		df, af := testFlows(t, ctx, dm, am, flowtest.AllowAllPeersAuthorizer{})
+		if err := writeLine(df, line); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := readLine(af); err != nil || got != line {
+			t.Fatalf("got %q, %v", got, err)
+		}
+		stats := df.Conn().(*conn.Conn).CompressionStats()
+		if got, want := stats.SentBytes > 0, compression; got != want {
+			t.Errorf("compression %v: unexpected stats: %v", compression, stats)
+		}
+		cancel()
+		<-am.Closed()
+		<-dm.Closed()
+	}
+}
+
 func testFlows(t testing.TB, ctx *context.T, dm, am flow.Manager, auth flow.PeerAuthorizer) (df, af flow.Flow) {
 	ep := am.Status().Endpoints[0]
 	var err error
@@ -206,11 +275,11 @@ func BenchmarkDialCachedConn(b *testing.B) {
 	defer goroutines.NoLeaks(b, leakWaitTime)()
 	ctx, shutdown := test.V23Init()
 
-	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, nil)
+	am := New(ctx, naming.FixedRoutingID(0x5555), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	if _, err := am.Listen(ctx, "tcp", "127.0.0.1:0"); err != nil {
 		b.Fatal(err)
 	}
-	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, nil)
+	dm := New(ctx, naming.FixedRoutingID(0x1111), nil, 0, 0, 0, CacheLimit{}, ConnOptions{}, nil)
 	// At first the cache should be empty.
 	if got, want := len(dm.(*manager).cache.conns), 0; got != want {
 		b.Fatalf("got cache size %v, want %v", got, want)
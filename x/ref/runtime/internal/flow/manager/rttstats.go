@@ -0,0 +1,82 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manager
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+
+	"v.io/v23/naming"
+	"v.io/x/ref/lib/stats"
+	"v.io/x/ref/lib/stats/histogram"
+)
+
+// maxRTTPeers bounds the number of peers for which round trip times are
+// recorded, the least recently measured peer is forgotten once it is
+// exceeded.
+const maxRTTPeers = 256
+
+// rttStats records the distribution of the round trip times measured by the
+// health checks of the connections to each peer. The distributions are
+// exported as <prefix>/<peer>, where peer is the peer's routing id, or its
+// protocol and host if it has no routing id.
+type rttStats struct {
+	prefix string
+
+	mu    sync.Mutex
+	peers map[string]*list.Element // of *rttPeerStats
+	lru   *list.List               // most recently measured first
+}
+
+type rttPeerStats struct {
+	peer      string
+	histogram *histogram.Histogram
+}
+
+func newRTTStats(prefix string) *rttStats {
+	return &rttStats{
+		prefix: prefix,
+		peers:  make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+func (s *rttStats) record(remote naming.Endpoint, rtt time.Duration) {
+	peer := rttPeer(remote)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.peers[peer]
+	if e != nil {
+		s.lru.MoveToFront(e)
+	} else {
+		h := stats.NewHistogram(naming.Join(s.prefix, peer), histogram.Options{
+			NumBuckets:         25,
+			GrowthFactor:       1,
+			SmallestBucketSize: 1,
+			MinValue:           0,
+		})
+		e = s.lru.PushFront(&rttPeerStats{peer: peer, histogram: h})
+		s.peers[peer] = e
+		if s.lru.Len() > maxRTTPeers {
+			oldest := s.lru.Remove(s.lru.Back()).(*rttPeerStats)
+			delete(s.peers, oldest.peer)
+			stats.Delete(naming.Join(s.prefix, oldest.peer)) //nolint:errcheck
+		}
+	}
+	e.Value.(*rttPeerStats).histogram.Add(int64(rtt / time.Millisecond)) //nolint:errcheck
+}
+
+func rttPeer(remote naming.Endpoint) string {
+	if remote.RoutingID != naming.NullRoutingID {
+		return remote.RoutingID.String()
+	}
+	host := remote.Address
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return naming.EncodeAsNameElement(remote.Protocol + "," + host)
+}
@@ -0,0 +1,38 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"v.io/v23/naming"
+	"v.io/x/ref/lib/stats"
+)
+
+func TestRTTStatsBounded(t *testing.T) {
+	s := newRTTStats("rtt-stats-test")
+	defer stats.Delete("rtt-stats-test") //nolint:errcheck
+	exported := func(i int) bool {
+		ep := naming.Endpoint{RoutingID: naming.FixedRoutingID(uint64(i + 1))}
+		_, err := stats.Value(naming.Join("rtt-stats-test", rttPeer(ep)))
+		return err == nil
+	}
+	for i := 0; i <= maxRTTPeers; i++ {
+		s.record(naming.Endpoint{RoutingID: naming.FixedRoutingID(uint64(i + 1))}, time.Millisecond)
+		if i == 1 {
+			// The first peer remains the most recently measured.
+			s.record(naming.Endpoint{RoutingID: naming.FixedRoutingID(1)}, time.Millisecond)
+		}
+	}
+	if got, want := len(s.peers), maxRTTPeers; got != want {
+		t.Errorf("got %v peers, want %v", got, want)
+	}
+	for i, want := range map[int]bool{0: true, 1: false, 2: true, maxRTTPeers: true} {
+		if got := exported(i); got != want {
+			t.Errorf("peer %v: got exported %v, want %v", i, got, want)
+		}
+	}
+}
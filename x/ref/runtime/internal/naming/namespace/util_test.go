@@ -0,0 +1,18 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package namespace
+
+import "time"
+
+// SetWatchIntervals sets how often Watch polls mount tables that can't be
+// watched and retries failed watches.  It returns a function that restores
+// the previous values.
+func SetWatchIntervals(poll, retry time.Duration) func() {
+	oldPoll, oldRetry := watchPollInterval, watchRetryInterval
+	watchPollInterval, watchRetryInterval = poll, retry
+	return func() {
+		watchPollInterval, watchRetryInterval = oldPoll, oldRetry
+	}
+}
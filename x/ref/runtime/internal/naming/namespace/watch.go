@@ -0,0 +1,386 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package namespace
+
+import (
+	"io"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/glob"
+	vnamespace "v.io/v23/namespace"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/services/watch"
+	"v.io/v23/verror"
+)
+
+var (
+	// watchPollInterval is how often mount tables that don't support
+	// watching are globbed.
+	watchPollInterval = 30 * time.Second
+	// watchRetryInterval is how long to wait before watching a mount table
+	// again after a failure.
+	watchRetryInterval = 5 * time.Second
+)
+
+// watcher is the state shared by all the mount tables watched by a Watch.
+type watcher struct {
+	ns     *namespace
+	ctx    *context.T // the context passed to Watch.
+	prefix string
+	opts   []rpc.CallOpt
+	out    chan vnamespace.Change
+}
+
+// tableWatch watches a single mount table and starts a tableWatch for each
+// matching mount table mounted in it.  It is the Watch counterpart of task.
+type tableWatch struct {
+	w       *watcher
+	me      *naming.MountEntry // the mount table, me.Name is the name it is mounted at.
+	pattern *glob.Glob         // pattern to match in the mount table.
+	depth   int                // number of mount tables traversed recursively
+	marker  watch.ResumeMarker // where to resume watching, empty for the initial state.
+
+	entries  map[string]naming.MountEntry // the names in the mount table, relative to it.
+	children map[string]*childWatch       // watches of the mount tables mounted on entries.
+}
+
+type childWatch struct {
+	t      *tableWatch
+	cancel func()
+	done   chan struct{}
+}
+
+// update is a change to a name in a mount table; entry is nil if the name
+// no longer exists.
+type update struct {
+	name  string
+	entry *naming.MountEntry
+}
+
+func (w *watcher) send(c vnamespace.Change) {
+	select {
+	case w.out <- c:
+	case <-w.ctx.Done():
+	}
+}
+
+func (w *watcher) newTableWatch(me *naming.MountEntry, pattern *glob.Glob, depth int) *tableWatch {
+	return &tableWatch{
+		w:        w,
+		me:       me,
+		pattern:  pattern,
+		depth:    depth,
+		entries:  make(map[string]naming.MountEntry),
+		children: make(map[string]*childWatch),
+	}
+}
+
+// run watches the mount table until ctx is done and then reports every name
+// it reported as removed.
+func (t *tableWatch) run(ctx *context.T) {
+	defer t.stop()
+	for {
+		received, err := t.watch(ctx)
+		if !received && cannotWatch(err) && ctx.Err() == nil {
+			// Fall back to globbing mount tables that can't be watched.
+			err = t.poll(ctx)
+			if notAnMT(err) && ctx.Err() == nil {
+				// As with Glob, ignore servers that aren't mount tables.
+				<-ctx.Done()
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if verror.ErrorID(err) == watch.ErrUnknownResumeMarker.ID {
+			// Start again from the initial state.
+			t.marker = nil
+			continue
+		}
+		ctx.VI(2).Infof("watch of %v failed: %v", t.me.Name, err)
+		if err != io.EOF {
+			t.w.send(vnamespace.Change{Name: naming.Join(t.w.prefix, t.me.Name), Error: err})
+		}
+		select {
+		case <-time.After(watchRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stop stops the watches of the mount tables below this one and reports
+// all the names as removed.
+func (t *tableWatch) stop() {
+	var removed []update
+	for name := range t.entries {
+		removed = append(removed, update{name: name})
+	}
+	t.apply(removed, false)
+}
+
+// cannotWatch returns true if err, returned by a WatchGlob call before any
+// changes were received, indicates that the server doesn't support watching.
+// Other errors, such as ErrBadProtocol, may also be returned part way through
+// the stream, and so only cause WatchGlob to be called again.
+func cannotWatch(err error) bool {
+	switch verror.ErrorID(err) {
+	case verror.ErrUnknownMethod.ID, verror.ErrUnknownSuffix.ID:
+		return true
+	}
+	return false
+}
+
+// watch calls WatchGlob on the mount table and applies the changes until
+// the call fails. received is true if any changes were received.
+func (t *tableWatch) watch(ctx *context.T) (received bool, err error) {
+	// As in globAtServer, the name has already been matched.
+	me := *t.me
+	me.Name = ""
+	req := watch.GlobRequest{Pattern: t.pattern.String(), ResumeMarker: t.marker}
+	call, err := watch.GlobWatcherClient("").WatchGlob(ctx, req, append(t.w.opts, options.Preresolved{Resolution: &me})...)
+	if err != nil {
+		return false, err
+	}
+	initial := len(t.marker) == 0
+	var group []update
+	stream := call.RecvStream()
+	for stream.Advance() {
+		received = true
+		c := stream.Value()
+		switch c.State {
+		case watch.Exists:
+			var entry naming.MountEntry
+			if err := c.Value.ToValue(&entry); err != nil {
+				return received, err
+			}
+			group = append(group, update{name: c.Name, entry: &entry})
+		case watch.DoesNotExist:
+			group = append(group, update{name: c.Name})
+		}
+		if c.Continued {
+			continue
+		}
+		t.apply(group, initial)
+		t.marker, group, initial = c.ResumeMarker, nil, false
+	}
+	if err := stream.Err(); err != nil {
+		return received, err
+	}
+	if err := call.Finish(); err != nil {
+		return received, err
+	}
+	return received, io.EOF
+}
+
+// poll globs the mount table every watchPollInterval until a Glob fails.
+func (t *tableWatch) poll(ctx *context.T) error {
+	client := v23.GetClient(ctx)
+	for {
+		me := *t.me
+		me.Name = ""
+		timeoutCtx, cancel := withTimeout(ctx)
+		call, err := client.StartCall(timeoutCtx, "", rpc.GlobMethod, []interface{}{t.pattern.String()}, append(t.w.opts, options.Preresolved{Resolution: &me})...)
+		if err != nil {
+			cancel()
+			return err
+		}
+		var all []update
+		for {
+			var gr naming.GlobReply
+			err := call.Recv(&gr)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				cancel()
+				return err
+			}
+			if v, ok := gr.(naming.GlobReplyEntry); ok {
+				entry := v.Value
+				all = append(all, update{name: entry.Name, entry: &entry})
+			}
+		}
+		err = call.Finish()
+		cancel()
+		if err != nil {
+			return err
+		}
+		t.apply(all, true)
+		select {
+		case <-time.After(watchPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// apply applies a group of updates to the names in the mount table.  If all
+// is true, the group contains every name in the mount table.
+func (t *tableWatch) apply(group []update, all bool) {
+	if all {
+		seen := make(map[string]bool, len(group))
+		for _, u := range group {
+			seen[u.name] = true
+		}
+		for name := range t.entries {
+			if !seen[name] {
+				group = append(group, update{name: name})
+			}
+		}
+	}
+	for _, u := range group {
+		t.applyOne(u)
+	}
+}
+
+func (t *tableWatch) applyOne(u update) {
+	// The root of a mount table below the first is the mount point in
+	// its parent, which is already taken care of.
+	if u.name == "" && t.depth > 0 {
+		return
+	}
+	old, existed := t.entries[u.name]
+	if u.entry == nil && !existed {
+		return
+	}
+	name := naming.Join(t.me.Name, u.name)
+
+	// Get the pattern elements below the name.
+	suffix := t.pattern
+	for i := depth(u.name) - 1; i >= 0; i-- {
+		suffix = suffix.Tail()
+	}
+
+	if existed && (u.entry == nil || !sameServers(old.Servers, u.entry.Servers)) {
+		t.forget(u.name)
+	}
+	if u.entry == nil {
+		delete(t.entries, u.name)
+		t.stopChild(u.name)
+		if suffix.Len() == 0 {
+			t.w.send(vnamespace.Change{Name: naming.Join(t.w.prefix, name)})
+		}
+		return
+	}
+	t.entries[u.name] = *u.entry
+	if suffix.Len() == 0 {
+		x := *u.entry
+		x.Name = naming.Join(t.w.prefix, name)
+		t.w.send(vnamespace.Change{Name: x.Name, Entry: &x})
+	}
+
+	// Watch the mount tables mounted here if the pattern goes below them.
+	descend := u.name != "" && u.entry.ServesMountTable && len(u.entry.Servers) > 0 && !suffix.Empty()
+	if descend && suffix.Len() == 0 && t.depth+1 > t.w.ns.maxRecursiveGlobDepth {
+		descend = false
+	}
+	if c := t.children[u.name]; c != nil {
+		if descend && overlap(c.t.me.Servers, u.entry.Servers) {
+			return
+		}
+		t.stopChild(u.name)
+	}
+	if !descend {
+		return
+	}
+	me := *u.entry
+	me.Name = name
+	child := t.w.newTableWatch(&me, suffix, t.depth+1)
+	ctx, cancel := context.WithCancel(t.w.ctx)
+	c := &childWatch{t: child, cancel: cancel, done: make(chan struct{})}
+	t.children[u.name] = c
+	go func() {
+		defer close(c.done)
+		child.run(ctx)
+	}()
+}
+
+func (t *tableWatch) stopChild(name string) {
+	if c := t.children[name]; c != nil {
+		c.cancel()
+		<-c.done
+		delete(t.children, name)
+	}
+}
+
+// forget flushes the resolution cache entries for a name in the mount
+// table.
+func (t *tableWatch) forget(name string) {
+	var names []string
+	for _, s := range t.me.Servers {
+		names = append(names, naming.JoinAddressName(s.Server, name))
+	}
+	t.w.ns.RLock()
+	c := t.w.ns.resolutionCache
+	t.w.ns.RUnlock()
+	c.forget(t.w.ctx, names)
+}
+
+func sameServers(a, b []naming.MountedServer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	servers := make(map[string]bool, len(a))
+	for _, s := range a {
+		servers[s.Server] = true
+	}
+	for _, s := range b {
+		if !servers[s.Server] {
+			return false
+		}
+	}
+	return true
+}
+
+func overlap(a, b []naming.MountedServer) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.Server == y.Server {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Watch implements namespace.T.Watch.
+func (ns *namespace) Watch(ctx *context.T, pattern string, opts ...naming.NamespaceOpt) (<-chan vnamespace.Change, error) {
+	// Root the pattern.  If we have no servers to query, give up.
+	e, patternWasRooted := ns.rootMountEntry(pattern)
+	if len(e.Servers) == 0 {
+		return nil, naming.ErrNoMountTable.Errorf(ctx, "no mounttable")
+	}
+
+	// If the name doesn't parse, give up.
+	g, err := glob.Parse(e.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// As with Glob, names are rooted if the pattern was.
+	var prefix string
+	if patternWasRooted {
+		prefix = e.Servers[0].Server
+	}
+	e.Name = ""
+	w := &watcher{
+		ns:     ns,
+		ctx:    ctx,
+		prefix: prefix,
+		opts:   getCallOpts(opts),
+		out:    make(chan vnamespace.Change, 100),
+	}
+	go func() {
+		defer close(w.out)
+		w.newTableWatch(e, g, 0).run(ctx)
+	}()
+	return w.out, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package namespace_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/namespace"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/security"
+	"v.io/v23/services/mounttable"
+	"v.io/v23/services/watch"
+	"v.io/v23/verror"
+	"v.io/v23/vom"
+	inamespace "v.io/x/ref/runtime/internal/naming/namespace"
+)
+
+// waitForChange waits for a change to name, ignoring changes to other names,
+// and returns the servers in it, or nil if name was removed.
+func waitForChange(t *testing.T, changes <-chan namespace.Change, name string) []string {
+	for {
+		select {
+		case c, ok := <-changes:
+			if !ok {
+				boom(t, "watch ended while waiting for %v", name)
+			}
+			if c.Error != nil {
+				boom(t, "watch failed at %v: %v", c.Name, c.Error)
+			}
+			if c.Name != name {
+				continue
+			}
+			if c.Entry == nil {
+				return nil
+			}
+			servers := []string{}
+			for _, s := range c.Entry.Servers {
+				servers = append(servers, s.Server)
+			}
+			return servers
+		case <-time.After(time.Minute):
+			boom(t, "timed out waiting for a change to %v", name)
+		}
+	}
+}
+
+func startWatch(t *testing.T, ctx *context.T, ns namespace.T, pattern string) (<-chan namespace.Change, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	changes, err := ns.Watch(ctx, pattern)
+	if err != nil {
+		cancel()
+		boom(t, "Watch(%v): %v", pattern, err)
+	}
+	return changes, func() {
+		cancel()
+		for range changes {
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	defer inamespace.SetWatchIntervals(100*time.Millisecond, 100*time.Millisecond)()
+	_, c, cleanup := createContexts(t)
+	defer cleanup()
+
+	_, mts, _, stopper := createNamespace(t, c)
+	defer stopper()
+	ns := v23.GetNamespace(c)
+
+	// The initial state includes the mounted servers, in order.
+	changes, stop := startWatch(t, c, ns, "*")
+	for _, mp := range []string{j1MP, mt1MP, mt2MP} {
+		if got := waitForChange(t, changes, mp); len(got) != 1 {
+			boom(t, "%v: got %v, want one server", mp, got)
+		}
+	}
+	stop()
+
+	// Changes are followed across mount tables.
+	changes, stop = startWatch(t, c, ns, "mt1/*")
+	defer stop()
+	if err := ns.Mount(c, "mt1/x", "/127.0.0.1:1111", ttl); err != nil {
+		boom(t, "Mount: %v", err)
+	}
+	compare(t, "Watch", "mt1/x", waitForChange(t, changes, "mt1/x"), []string{"/127.0.0.1:1111"})
+
+	// The resolution cache is invalidated when a mount changes.
+	ns.CacheCtl(naming.DisableCache(false))
+	defer ns.CacheCtl(naming.DisableCache(true))
+	testResolve(t, c, ns, "mt1/x", "/127.0.0.1:1111")
+	// Mount directly on the mount table, as another client would, since the
+	// namespace flushes its own mounts from the cache.
+	mt1x := naming.JoinAddressName(mts[mt1MP].name, "x")
+	if err := mounttable.MountTableClient(mt1x).Mount(c, "/127.0.0.1:2222", uint32(ttl.Seconds()), naming.Replace, options.Preresolved{}); err != nil {
+		boom(t, "Mount: %v", err)
+	}
+	compare(t, "Watch", "mt1/x", waitForChange(t, changes, "mt1/x"), []string{"/127.0.0.1:2222"})
+	if me, err := ns.Resolve(c, "mt1/x"); err != nil || len(me.Servers) != 1 || me.Servers[0].Server != "/127.0.0.1:2222" {
+		boom(t, "Resolve(mt1/x): got %v, %v, want /127.0.0.1:2222", me, err)
+	}
+
+	if err := ns.Unmount(c, "mt1/x", ""); err != nil {
+		boom(t, "Unmount: %v", err)
+	}
+	if got := waitForChange(t, changes, "mt1/x"); got != nil {
+		boom(t, "mt1/x: got %v, want it removed", got)
+	}
+
+	// Mount tables that can't be watched are polled.
+	fake := run(t, c, &dispatcher{}, "fake", true)
+	defer fake.stop()
+	changes, stop = startWatch(t, c, ns, "fake/*")
+	defer stop()
+	waitForChange(t, changes, "fake/level1")
+}
+
+// flakyWatcher is a mount table whose first WatchGlob stream fails after
+// sending a single name, and whose later streams send a different name.
+type flakyWatcher struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (w *flakyWatcher) Lookup(_ *context.T, suffix string) (interface{}, security.Authorizer, error) {
+	return watch.GlobWatcherServer(w), security.AllowEveryone(), nil
+}
+
+func (w *flakyWatcher) WatchGlob(ctx *context.T, call watch.GlobWatcherWatchGlobServerCall, req watch.GlobRequest) error {
+	w.mu.Lock()
+	w.calls++
+	first := w.calls == 1
+	w.mu.Unlock()
+	name := "b"
+	if first {
+		name = "a"
+	}
+	entry := naming.MountEntry{Name: name, Servers: []naming.MountedServer{{Server: "/127.0.0.1:1111"}}}
+	if err := call.SendStream().Send(watch.Change{Name: name, State: watch.Exists, Value: vom.RawBytesOf(entry), ResumeMarker: []byte(name)}); err != nil {
+		return err
+	}
+	if first {
+		return verror.ErrBadProtocol.Errorf(ctx, "stream failed")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestWatchRetriesAfterStreamErrors(t *testing.T) {
+	defer inamespace.SetWatchIntervals(time.Hour, 100*time.Millisecond)()
+	_, c, cleanup := createContexts(t)
+	defer cleanup()
+
+	_, _, _, stopper := createNamespace(t, c)
+	defer stopper()
+	ns := v23.GetNamespace(c)
+
+	flaky := run(t, c, &flakyWatcher{}, "flaky", true)
+	defer flaky.stop()
+	changes, stop := startWatch(t, c, ns, "flaky/*")
+	defer stop()
+	// The error part way through the first stream is reported, and the
+	// mount table watched again rather than polled.
+	want := map[string]bool{"flaky/a": true, "flaky/b": true}
+	failed := false
+	for len(want) > 0 || !failed {
+		select {
+		case c, ok := <-changes:
+			if !ok {
+				boom(t, "watch ended")
+			}
+			if c.Error != nil {
+				failed = true
+				continue
+			}
+			delete(want, c.Name)
+		case <-time.After(time.Minute):
+			boom(t, "timed out waiting for %v", want)
+		}
+	}
+}
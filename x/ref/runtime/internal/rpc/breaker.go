@@ -0,0 +1,217 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/verror"
+	"v.io/x/ref/lib/stats"
+)
+
+const (
+	defaultBreakerFailureRate  = 0.5
+	defaultBreakerMinRequests  = 5
+	defaultBreakerWindow       = 10 * time.Second
+	defaultBreakerOpenDuration = 5 * time.Second
+)
+
+var errAllCircuitsOpen = errors.New("the circuit breakers for all servers are open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("unknown(%d)", int(s))
+}
+
+// breaker is the circuit breaker for a single endpoint.
+type breaker struct {
+	state              breakerState
+	requests, failures int
+	windowStart        time.Time
+	// openedAt is the time at which the breaker last opened, or at which
+	// the last trial call was allowed when half-open.
+	openedAt time.Time
+}
+
+// circuitBreakers implements options.CircuitBreaker for a client. The
+// breakers are keyed by endpoint address.
+type circuitBreakers struct {
+	opts   options.CircuitBreaker
+	prefix string
+	now    func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func newCircuitBreakers(prefix string, opts options.CircuitBreaker) *circuitBreakers {
+	if opts.FailureRate <= 0 {
+		opts.FailureRate = defaultBreakerFailureRate
+	}
+	if opts.MinRequests <= 0 {
+		opts.MinRequests = defaultBreakerMinRequests
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaultBreakerWindow
+	}
+	if opts.OpenDuration <= 0 {
+		opts.OpenDuration = defaultBreakerOpenDuration
+	}
+	cb := &circuitBreakers{
+		opts:     opts,
+		prefix:   prefix,
+		now:      time.Now,
+		breakers: make(map[string]*breaker),
+	}
+	stats.NewStringFunc(prefix, cb.String)
+	return cb
+}
+
+func (cb *circuitBreakers) close() {
+	stats.Delete(cb.prefix) //nolint:errcheck
+}
+
+// filter returns the servers whose breakers allow a call to be made to them.
+// Servers whose breakers have been open for long enough become half-open,
+// and are allowed a single trial call.
+func (cb *circuitBreakers) filter(servers []naming.MountedServer) []naming.MountedServer {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.now()
+	allowed := make([]naming.MountedServer, 0, len(servers))
+	for _, s := range servers {
+		address, _ := naming.SplitAddressName(s.Server)
+		b, ok := cb.breakers[address]
+		if !ok || b.state == breakerClosed {
+			allowed = append(allowed, s)
+			continue
+		}
+		// The trial call made when half-open may never report its
+		// result, e.g. if another server is selected instead, so another
+		// is allowed after a further OpenDuration.
+		if now.Sub(b.openedAt) >= cb.opts.OpenDuration {
+			b.state = breakerHalfOpen
+			b.openedAt = now
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed
+}
+
+// record records whether a call to, or an attempt to connect to, the server
+// with the specified name failed.
+func (cb *circuitBreakers) record(server string, failed bool) {
+	address, _ := naming.SplitAddressName(server)
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	now := cb.now()
+	b, ok := cb.breakers[address]
+	if !ok {
+		if !failed {
+			return
+		}
+		b = &breaker{windowStart: now}
+		cb.breakers[address] = b
+	}
+	switch b.state {
+	case breakerHalfOpen:
+		if !failed {
+			delete(cb.breakers, address)
+			return
+		}
+		b.state, b.openedAt = breakerOpen, now
+		return
+	case breakerOpen:
+		return
+	}
+	if cb.expiredLocked(b, now) {
+		// A closed breaker whose window has expired is equivalent to no
+		// breaker at all, unless this call failed.
+		if !failed {
+			delete(cb.breakers, address)
+			return
+		}
+		b.requests, b.failures, b.windowStart = 0, 0, now
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= cb.opts.MinRequests && float64(b.failures)/float64(b.requests) >= cb.opts.FailureRate {
+		b.state, b.openedAt = breakerOpen, now
+		b.requests, b.failures = 0, 0
+	}
+}
+
+// expiredLocked returns true if b is closed and its window has expired.
+func (cb *circuitBreakers) expiredLocked(b *breaker, now time.Time) bool {
+	return b.state == breakerClosed && now.Sub(b.windowStart) >= cb.opts.Window
+}
+
+// isBreakerFailure returns true if err, returned by a call, indicates that
+// the server could not be reached or did not respond in time, rather than
+// that the server responded with an error.
+func isBreakerFailure(err error) bool {
+	switch verror.ErrorID(err) {
+	case verror.ErrTimeout.ID, verror.ErrBadProtocol.ID, verror.ErrNoServers.ID:
+		return true
+	case verror.ErrCanceled.ID:
+		return false
+	}
+	return verror.Action(err) == verror.RetryConnection
+}
+
+func (cb *circuitBreakers) String() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	// Closed breakers whose window has expired are discarded here too, so
+	// that those for servers that are no longer called are not retained.
+	now := cb.now()
+	for a, b := range cb.breakers {
+		if cb.expiredLocked(b, now) {
+			delete(cb.breakers, a)
+		}
+	}
+	if len(cb.breakers) == 0 {
+		return "No circuit breakers."
+	}
+	addresses := make([]string, 0, len(cb.breakers))
+	for a := range cb.breakers {
+		addresses = append(addresses, a)
+	}
+	sort.Strings(addresses)
+	buf := &bytes.Buffer{}
+	for _, a := range addresses {
+		b := cb.breakers[a]
+		fmt.Fprintf(buf, "%s state:%v failures:%d/%d", a, b.state, b.failures, b.requests)
+		if b.state != breakerClosed {
+			fmt.Fprintf(buf, " since:%v", b.openedAt.Format(time.RFC3339))
+		}
+		fmt.Fprintln(buf)
+	}
+	return buf.String()
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"v.io/v23/naming"
+	"v.io/v23/options"
+)
+
+func TestCircuitBreakers(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreakers(fmt.Sprintf("rpc/test/breakers/%p", t), options.CircuitBreaker{
+		MinRequests:  4,
+		Window:       time.Minute,
+		OpenDuration: time.Second,
+	})
+	defer cb.close()
+	cb.now = func() time.Time { return now }
+
+	var servers []naming.MountedServer
+	for _, a := range []string{"127.0.0.1:1", "127.0.0.1:2"} {
+		servers = append(servers, naming.MountedServer{
+			Server: naming.JoinAddressName(naming.FormatEndpoint("tcp", a), "suffix"),
+		})
+	}
+	bad := servers[0].Server
+	filtered := func() []string {
+		return servers2names(cb.filter(servers))
+	}
+
+	// Two failures out of four requests trip the breaker.
+	cb.record(bad, true)
+	cb.record(bad, false)
+	cb.record(bad, false)
+	if got, want := filtered(), servers2names(servers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cb.record(bad, true)
+	if got, want := filtered(), servers2names(servers[1:]); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := cb.String(), "state:open"; !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+
+	// Once half-open, a failed trial call opens the breaker again.
+	now = now.Add(time.Second)
+	if got, want := filtered(), servers2names(servers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := filtered(), servers2names(servers[1:]); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cb.record(bad, true)
+	now = now.Add(time.Second / 2)
+	if got, want := filtered(), servers2names(servers[1:]); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// And a successful trial call closes it.
+	now = now.Add(time.Second)
+	if got, want := filtered(), servers2names(servers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	cb.record(bad, false)
+	if got, want := filtered(), servers2names(servers); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := cb.String(), "No circuit breakers."; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCircuitBreakersExpire(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreakers(fmt.Sprintf("rpc/test/breakers/%p", t), options.CircuitBreaker{
+		MinRequests: 4,
+		Window:      time.Minute,
+	})
+	defer cb.close()
+	cb.now = func() time.Time { return now }
+
+	// A closed breaker is discarded by a successful call once its window
+	// has expired.
+	cb.record("/127.0.0.1:1", true)
+	now = now.Add(time.Minute)
+	cb.record("/127.0.0.1:1", false)
+	if got, want := len(cb.breakers), 0; got != want {
+		t.Errorf("got %v breakers, want %v", got, want)
+	}
+
+	// Or when the stats are read, if the server is not called again.
+	cb.record("/127.0.0.1:2", true)
+	if got, want := cb.String(), "127.0.0.1:2 state:closed failures:1/1"; !strings.Contains(got, want) {
+		t.Errorf("got %v, want it to contain %v", got, want)
+	}
+	now = now.Add(time.Minute)
+	if got, want := cb.String(), "No circuit breakers."; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFlowClientDoneOnce(t *testing.T) {
+	// The outcome of a call is recorded once, even if the call is closed
+	// again, e.g. by a second call to Finish, so that the breaker does not
+	// record the error returned for the second close as a success.
+	calls := 0
+	fc := &flowClient{done: func(error) { calls++ }}
+	fc.close(nil)
+	fc.close(nil)
+	if got, want := calls, 1; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
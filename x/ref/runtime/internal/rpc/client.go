@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"sync"
 	"time"
@@ -17,7 +16,9 @@ import (
 	"v.io/v23/context"
 	"v.io/v23/flow"
 	"v.io/v23/naming"
+	"v.io/v23/options"
 	"v.io/v23/rpc"
+	"v.io/v23/rpc/version"
 	"v.io/v23/security"
 	vtime "v.io/v23/vdlroot/time"
 	"v.io/v23/verror"
@@ -81,6 +82,23 @@ type client struct {
 	// typeCache maintains a cache of type encoders and decoders.
 	typeCache *typeCache
 
+	// interceptors are invoked around every Call and StartCall.
+	interceptors clientInterceptors
+
+	// loadBalancer, if set, orders the servers that a name resolves to.
+	loadBalancer rpc.LoadBalancer
+	lbStats      *loadBalancerStats
+
+	// hedge records the state used to send hedged requests.
+	hedge *hedgeState
+
+	// retry is the policy used for calls that do not specify their own.
+	retry      options.RetryPolicy
+	retryStats *retryStats
+
+	// breakers, if set, exclude servers that are failing from calls.
+	breakers *circuitBreakers
+
 	wg      sync.WaitGroup
 	mu      sync.Mutex
 	closing bool
@@ -98,9 +116,14 @@ func NewClient(ctx *context.T, opts ...rpc.ClientOpt) rpc.Client {
 		stop:        cancel,
 		closed:      make(chan struct{}),
 		outstanding: newOutstandingStats(statsPrefix),
+		hedge:       newHedgeState(),
+		retry:       defaultRetryPolicy,
+		retryStats:  newRetryStats(fmt.Sprintf("rpc/client/retries/%p", ctx)),
 	}
 
-	connIdleExpiry := time.Duration(0)
+	connIdleExpiry, connResumeTimeout := time.Duration(0), time.Duration(0)
+	var connCacheLimit manager.CacheLimit
+	var connOpts manager.ConnOptions
 	for _, opt := range opts {
 		switch v := opt.(type) {
 		case PreferredProtocols:
@@ -109,11 +132,28 @@ func NewClient(ctx *context.T, opts ...rpc.ClientOpt) rpc.Client {
 			c.flowMgr = v.mgr
 		case IdleConnectionExpiry:
 			connIdleExpiry = time.Duration(v)
+		case ConnectionResumptionTimeout:
+			connResumeTimeout = time.Duration(v)
+		case ConnectionCacheLimit:
+			connCacheLimit = manager.CacheLimit(v)
+		case ConnectionOptions:
+			connOpts = manager.ConnOptions(v)
+		case options.ClientInterceptors:
+			c.interceptors.add(v)
+		case options.LoadBalancer:
+			c.loadBalancer = v.LoadBalancer
+		case options.RetryPolicy:
+			c.retry = v
+		case options.CircuitBreaker:
+			c.breakers = newCircuitBreakers(fmt.Sprintf("rpc/client/breakers/%p", ctx), v)
 		}
 	}
+	if c.loadBalancer != nil {
+		c.lbStats = newLoadBalancerStats(fmt.Sprintf("rpc/client/loadbalance/%p", ctx), c.loadBalancer.Name())
+	}
 
 	if c.flowMgr == nil {
-		c.flowMgr = manager.New(ctx, naming.NullRoutingID, nil, 0, connIdleExpiry, nil)
+		c.flowMgr = manager.New(ctx, naming.NullRoutingID, nil, 0, connIdleExpiry, connResumeTimeout, connCacheLimit, connOpts, nil)
 	}
 
 	go func() {
@@ -125,6 +165,13 @@ func NewClient(ctx *context.T, opts ...rpc.ClientOpt) rpc.Client {
 		<-c.flowMgr.Closed()
 		c.wg.Wait()
 		c.outstanding.close()
+		c.retryStats.close()
+		if c.lbStats != nil {
+			c.lbStats.close()
+		}
+		if c.breakers != nil {
+			c.breakers.close()
+		}
 		close(c.closed)
 		c.typeCache.close()
 	}()
@@ -136,18 +183,34 @@ func (c *client) StartCall(ctx *context.T, name, method string, args []interface
 	if !ctx.Initialized() {
 		return nil, verror.ErrBadArg.Errorf(ctx, "context not initialized")
 	}
+	return c.interceptors.starter(c.start)(ctx, name, method, args, opts...)
+}
+
+func (c *client) start(ctx *context.T, name, method string, args []interface{}, opts ...rpc.CallOpt) (rpc.ClientCall, error) {
 	connOpts := getConnectionOptions(ctx, opts)
 	return c.startCall(ctx, name, method, args, connOpts, opts)
 }
 
 func (c *client) Call(ctx *context.T, name, method string, inArgs, outArgs []interface{}, opts ...rpc.CallOpt) error {
+	return c.interceptors.invoker(c.call)(ctx, name, method, inArgs, outArgs, opts...)
+}
+
+func (c *client) call(ctx *context.T, name, method string, inArgs, outArgs []interface{}, opts ...rpc.CallOpt) error {
+	if opt, ok := getHedgedRequestOpt(opts); ok {
+		return c.hedgedCall(ctx, opt, name, method, inArgs, outArgs, opts)
+	}
+	return c.retryingCall(ctx, name, method, inArgs, outArgs, opts...)
+}
 
+func (c *client) retryingCall(ctx *context.T, name, method string, inArgs, outArgs []interface{}, opts ...rpc.CallOpt) error {
 	tr := trace.New("Sent."+name, method)
 	defer tr.Finish()
 
 	connOpts := getConnectionOptions(ctx, opts)
+	policy := c.retryPolicy(method, opts)
+	connOpts.retry = policy
 	var prevErr error
-	for retries := uint(0); ; retries++ {
+	for {
 		call, err := c.startCall(ctx, name, method, inArgs, connOpts, opts)
 		if err != nil {
 			// See explanation in connectToName.
@@ -158,13 +221,13 @@ func (c *client) Call(ctx *context.T, name, method string, inArgs, outArgs []int
 		switch err := call.Finish(outArgs...); {
 		case err == nil:
 			return nil
-		case !shouldRetryBackoff(verror.Action(err), connOpts):
+		case !policy.shouldRetryCall(err, connOpts):
 			ctx.VI(4).Infof("Cannot retry after error: %s", err)
 			// See explanation in connectToName.
 			tr.LazyPrintf("%s\n", err)
 			tr.SetError()
 			return preferNonTimeout(err, prevErr)
-		case !backoff(retries, connOpts.connDeadline):
+		case !policy.backoff(connOpts.connDeadline):
 			tr.LazyPrintf("%s\n", err)
 			tr.SetError()
 			return err
@@ -192,7 +255,13 @@ func (c *client) startCall(ctx *context.T, name, method string, args []interface
 	}
 
 	removeStat := c.outstanding.start(method, r.flow.RemoteEndpoint())
-	fc, err := newFlowClient(ctx, removeStat, r.flow, r.typeEnc, r.typeDec)
+	done := func(err error) {
+		removeStat()
+		if c.breakers != nil {
+			c.breakers.record(r.server, isBreakerFailure(err))
+		}
+	}
+	fc, err := newFlowClient(ctx, done, r.flow, r.typeEnc, r.typeDec)
 	if err != nil {
 		return nil, err
 	}
@@ -280,13 +349,17 @@ type serverStatus struct {
 // Once connOpts.connDeadline is reached, it will stop retrying.
 func (c *client) connectToName(ctx *context.T, name, method string, args []interface{}, connOpts *connectionOpts, opts []rpc.CallOpt) (*serverStatus, error) {
 	span := vtrace.GetSpan(ctx)
+	policy := connOpts.retry
+	if policy == nil {
+		policy = c.retryPolicy(method, opts)
+	}
 	var prevErr error
-	for retries := uint(0); ; retries++ {
+	for {
 		r, action, requireResolve, err := c.tryConnectToName(ctx, name, method, args, connOpts, opts)
 		switch {
 		case err == nil:
 			return r, nil
-		case !shouldRetry(action, requireResolve, connOpts, opts):
+		case !policy.shouldRetryConnect(action, err, requireResolve, connOpts, opts):
 			span.Annotatef("Cannot retry after error: %s", err)
 			span.Finish(err)
 			// If the latest error is a timeout, prefer the
@@ -296,7 +369,7 @@ func (c *client) connectToName(ctx *context.T, name, method string, args []inter
 			// the client (since the current timeout error is likely
 			// just a result of the context timing out).
 			return nil, preferNonTimeout(err, prevErr)
-		case !backoff(retries, connOpts.connDeadline):
+		case !policy.backoff(connOpts.connDeadline):
 			span.Annotatef("Retries exhausted")
 			span.Finish(err)
 			return nil, err
@@ -336,9 +409,29 @@ func (c *client) tryConnectToName(ctx *context.T, name, method string, args []in
 	if resolved.Servers, err = filterAndOrderServers(resolved.Servers, c.preferredProtocols); err != nil {
 		return nil, verror.RetryRefetch, true, verror.ErrNoServers.Errorf(ctx, "no usable servers found for: %v: %v", name, err)
 	}
+	if c.breakers != nil {
+		// Avoid filtering an entry that may be shared with the caller,
+		// e.g. via options.Preresolved.
+		filtered := *resolved
+		if filtered.Servers = c.breakers.filter(resolved.Servers); len(filtered.Servers) == 0 {
+			return nil, verror.RetryRefetch, false, verror.ErrNoServers.Errorf(ctx, "no usable servers found for: %v: %v", name, errAllCircuitsOpen)
+		}
+		resolved = &filtered
+	}
+	if c.loadBalancer != nil {
+		// Avoid reordering an entry that may be shared with the caller,
+		// e.g. via options.Preresolved.
+		balanced := *resolved
+		balanced.Servers = c.loadBalancer.Order(ctx, name, resolved.Servers)
+		resolved = &balanced
+	}
 
 	// servers is now ordered by the priority heurestic implemented in
-	// filterAndOrderServers.
+	// filterAndOrderServers, or by the load balancing policy.
+	//
+	// When a load balancing policy is in use, its order is strictly
+	// honoured: a server is only used once all of the servers that
+	// precede it have failed.
 	//
 	// Try to connect to all servers in parallel.  Provide sufficient
 	// buffering for all of the connections to finish instantaneously. This
@@ -391,11 +484,20 @@ func (c *client) tryConnectToName(ctx *context.T, name, method string, args []in
 			if r != nil {
 				numResponses++
 			}
+		}
+		for _, r := range responses {
+			if r == nil && c.loadBalancer != nil {
+				break
+			}
 			if r == nil || r.flow == nil {
 				continue
 			}
 			// We must ensure that all flows other than r.flow are closed.
 			go cleanupTryConnectToName(r, responses, ch)
+			if c.loadBalancer != nil {
+				c.loadBalancer.Selected(r.server, r.flow.Conn())
+				c.lbStats.selected(r.flow.RemoteEndpoint())
+			}
 			return r, verror.NoRetry, false, nil
 		}
 		if numResponses == len(responses) {
@@ -427,6 +529,9 @@ func (c *client) tryConnectToServer(
 	span.Annotate(server)
 	annotateClientSpan(span, name, "tryConnectToServer")
 	defer func() {
+		if c.breakers != nil && status.serverErr != nil && ctx.Err() == nil {
+			c.breakers.record(server, true)
+		}
 		ch <- status
 		span.Finish(nil)
 	}()
@@ -459,13 +564,18 @@ func (c *client) tryConnectToServer(
 			return
 		}
 	} else {
-		flw, err = c.flowMgr.Dial(ctx, ep, auth, connOpts.channelTimeout)
+		dctx := ctx
+		if connOpts.keepalive != nil {
+			dctx = flow.WithKeepalive(ctx, *connOpts.keepalive)
+		}
+		flw, err = c.flowMgr.Dial(dctx, ep, auth, connOpts.channelTimeout)
 		if err != nil {
 			ctx.VI(2).Infof("rpc: failed to create Flow with %v: %v", server, err)
 			status.serverErr = suberr(err)
 			return
 		}
 	}
+	flw.SetPriority(connOpts.priority)
 	if write := c.typeCache.writer(flw.Conn()); write != nil {
 		// Create the type flow with a root-cancellable context.
 		// This flow must outlive the flow we're currently creating.
@@ -478,11 +588,16 @@ func (c *client) tryConnectToServer(
 			ctx.Infof("Existing: %p, new side channel: %p: %v", flw.Conn(), tflow.Conn(), flw.Conn().RemoteEndpoint())
 			tflow.Close()
 			write(nil, tcancel)
-		} else if _, err = tflow.Write([]byte{typeFlow}); err != nil {
-			tflow.Close()
-			write(nil, tcancel)
 		} else {
-			write(tflow, tcancel)
+			// Type messages must be received before the data that refers
+			// to them, so they are not queued behind that of other flows.
+			tflow.SetPriority(flow.ControlPriority)
+			if _, err = tflow.Write([]byte{typeFlow}); err != nil {
+				tflow.Close()
+				write(nil, tcancel)
+			} else {
+				write(tflow, tcancel)
+			}
 		}
 	}
 
@@ -617,25 +732,28 @@ type flowClient struct {
 	sendClosedMu sync.Mutex
 	sendClosed   bool // is the send side already closed? GUARDED_BY(sendClosedMu)
 	finished     bool // has Finish() already been called?
-	removeStat   func()
+	done         func(error)
+	doneOnce     sync.Once // done is called at most once.
 }
 
 var _ rpc.ClientCall = (*flowClient)(nil)
 var _ rpc.Stream = (*flowClient)(nil)
 
-func newFlowClient(ctx *context.T, removeStat func(), flow flow.Flow, typeEnc *vom.TypeEncoder, typeDec *vom.TypeDecoder) (*flowClient, error) {
+// newFlowClient returns a flowClient for a call over flow. done is called
+// with the call's final error, or nil, once the call is complete.
+func newFlowClient(ctx *context.T, done func(error), flow flow.Flow, typeEnc *vom.TypeEncoder, typeDec *vom.TypeDecoder) (*flowClient, error) {
 	bf := conn.NewBufferingFlow(ctx, flow)
 	if _, err := bf.Write([]byte{dataFlow}); err != nil {
 		flow.Close()
-		removeStat()
+		done(err)
 		return nil, err
 	}
 	fc := &flowClient{
-		ctx:        ctx,
-		flow:       bf,
-		dec:        vom.NewDecoderWithTypeDecoder(bf, typeDec),
-		enc:        vom.NewEncoderWithTypeEncoder(bf, typeEnc),
-		removeStat: removeStat,
+		ctx:  ctx,
+		flow: bf,
+		dec:  vom.NewDecoderWithTypeDecoder(bf, typeDec),
+		enc:  vom.NewEncoderWithTypeEncoder(bf, typeEnc),
+		done: done,
 	}
 	return fc, nil
 }
@@ -651,7 +769,12 @@ func (fc *flowClient) Conn() flow.ManagedConn {
 // a timeout can lead to any other number of errors due to the underlying
 // network connection being shutdown abruptly.
 func (fc *flowClient) close(err error) error {
-	fc.removeStat()
+	err = fc.closeErr(err)
+	fc.doneOnce.Do(func() { fc.done(err) })
+	return err
+}
+
+func (fc *flowClient) closeErr(err error) error {
 	if err == nil {
 		return nil
 	}
@@ -707,6 +830,9 @@ func (fc *flowClient) start(suffix, method string, args []interface{}, opts []rp
 		GrantedBlessings: grantedB,
 		TraceRequest:     vtrace.GetRequest(fc.ctx),
 	}
+	if supportsMetadata(fc.flow.Conn()) {
+		req.Metadata = rpc.OutgoingMetadata(fc.ctx)
+	}
 	if err := fc.enc.Encode(req); err != nil {
 		berr := errRequestEncoding.Errorf(fc.ctx, "failed to encode request %#v: %v", req, err)
 		return fc.close(berr)
@@ -926,6 +1052,16 @@ func (fc *flowClient) Security() security.Call {
 	return fc.secCall
 }
 
+func (fc *flowClient) ResponseMetadata() map[string]string {
+	return fc.response.Metadata
+}
+
+// supportsMetadata returns true if the RPC version negotiated for conn
+// allows metadata to be sent in request and response headers.
+func supportsMetadata(conn flow.ManagedConn) bool {
+	return conn.CommonVersion() >= version.RPCVersion15
+}
+
 type typeFlowAuthorizer struct{}
 
 func (a typeFlowAuthorizer) AuthorizePeer(
@@ -978,67 +1114,6 @@ func (x peerAuthorizer) BlessingsForPeer(ctx *context.T, peerNames []string) (
 	return clientB, dis, nil
 }
 
-func shouldRetryBackoff(action verror.ActionCode, connOpts *connectionOpts) bool {
-	switch {
-	case connOpts.noRetry:
-		return false
-	case action != verror.RetryBackoff:
-		return false
-	case time.Now().After(connOpts.connDeadline):
-		return false
-	}
-	return true
-}
-
-func shouldRetry(action verror.ActionCode, requireResolve bool, connOpts *connectionOpts, opts []rpc.CallOpt) bool {
-	switch {
-	case connOpts.noRetry:
-		return false
-	case connOpts.useOnlyCached:
-		// If we should only used cached connections, it doesn't make sense to retry
-		// looking in the cache.
-		return false
-	case action != verror.RetryConnection && action != verror.RetryRefetch:
-		return false
-	case time.Now().After(connOpts.connDeadline):
-		return false
-	case requireResolve && getNoNamespaceOpt(opts):
-		// If we're skipping resolution and there are no servers for
-		// this call retrying is not going to help, we can't come up
-		// with new servers if there is no resolution.
-		return false
-	}
-	return true
-}
-
-// A randomized exponential backoff. The randomness deters error convoys
-// from forming.  The first time you retry n should be 0, then 1 etc.
-func backoff(n uint, deadline time.Time) bool {
-	// This is ((100 to 200) * 2^n) ms.
-	b := time.Duration((100+rand.Intn(100))<<n) * time.Millisecond
-	if b > maxBackoff {
-		b = maxBackoff
-	}
-	r := time.Until(deadline)
-	// We need to budget some time for the call to have a chance to complete
-	// lest we'll timeout before we actually do anything.  If we just don't
-	// have enough time left, give up.
-	//
-	// The value should cover a sensible call duration (which includes name
-	// resolution and the actual server RPC) on most supported platforms;
-	// use https://vanadium.github.io/performance.html for inspiration.
-	const reserveTime = 100 * time.Millisecond
-	if r <= reserveTime {
-		return false
-	}
-	r -= reserveTime
-	if b > r {
-		b = r
-	}
-	time.Sleep(b)
-	return true
-}
-
 func suberrName(server, name, method string) string {
 	// In the case the client directly dialed an endpoint we want to avoid printing
 	// the endpoint twice.
@@ -0,0 +1,78 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"strings"
+	"sync"
+
+	"v.io/v23/rpc"
+)
+
+// healthState records the health of the objects served by a server, as
+// reported by the reserved __Health and __WatchHealth methods.
+type healthState struct {
+	mu       sync.Mutex
+	statuses map[string]rpc.HealthStatus // keyed by suffix.
+	lameDuck bool
+	// changed is closed, and replaced, whenever any status changes.
+	changed chan struct{}
+}
+
+func newHealthState() *healthState {
+	return &healthState{
+		statuses: make(map[string]rpc.HealthStatus),
+		changed:  make(chan struct{}),
+	}
+}
+
+func (h *healthState) set(suffix string, status rpc.HealthStatus) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	suffix = strings.TrimLeft(suffix, "/")
+	if old, ok := h.statuses[suffix]; ok && old == status {
+		return
+	}
+	h.statuses[suffix] = status
+	h.notifyLocked()
+}
+
+// setLameDuck reports all objects as rpc.HealthStatusLameDuck.
+func (h *healthState) setLameDuck() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.lameDuck {
+		h.lameDuck = true
+		h.notifyLocked()
+	}
+}
+
+func (h *healthState) notifyLocked() {
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+// has returns true if the health of the object served under suffix has
+// been set explicitly.
+func (h *healthState) has(suffix string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.statuses[strings.TrimLeft(suffix, "/")]
+	return ok
+}
+
+// status returns the health of the object served under suffix and a channel
+// that is closed when it may have changed.
+func (h *healthState) status(suffix string) (rpc.HealthStatus, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lameDuck {
+		return rpc.HealthStatusLameDuck, h.changed
+	}
+	if status, ok := h.statuses[strings.TrimLeft(suffix, "/")]; ok {
+		return status, h.changed
+	}
+	return h.statuses[""], h.changed
+}
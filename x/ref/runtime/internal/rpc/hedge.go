@@ -0,0 +1,285 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	"v.io/v23/vdlroot/signature"
+)
+
+const (
+	// The number of recent latencies retained for each method in order to
+	// compute the delay before a hedged request is sent.
+	hedgeLatencySamples = 100
+	// The number of latencies that must be observed for a method before
+	// they are used to compute the hedging delay.
+	minHedgeLatencySamples = 10
+	// The maximum number of methods for which hedging state is retained.
+	maxHedgeCacheSize = 1 << 11
+)
+
+var readTag = vdl.ValueOf(access.Read)
+
+// hedgeState records, for each server and method called with the
+// options.HedgedRequest option, whether the method is tagged as
+// access.Read and the latencies recently observed for it.
+type hedgeState struct {
+	mu        sync.Mutex
+	readOnly  map[string]bool           // keyed by hedgeKey
+	latencies map[string]*latencyWindow // keyed by hedgeKey
+}
+
+func newHedgeState() *hedgeState {
+	return &hedgeState{
+		readOnly:  make(map[string]bool),
+		latencies: make(map[string]*latencyWindow),
+	}
+}
+
+func (h *hedgeState) isReadOnly(key string) (readOnly, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	readOnly, ok = h.readOnly[key]
+	return
+}
+
+func (h *hedgeState) setReadOnly(key string, readOnly bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.readOnly) >= maxHedgeCacheSize {
+		for k := range h.readOnly {
+			delete(h.readOnly, k)
+			break
+		}
+	}
+	h.readOnly[key] = readOnly
+}
+
+func (h *hedgeState) record(key string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.latencies[key]
+	if !ok {
+		if len(h.latencies) >= maxHedgeCacheSize {
+			for k := range h.latencies {
+				delete(h.latencies, k)
+				break
+			}
+		}
+		w = &latencyWindow{}
+		h.latencies[key] = w
+	}
+	w.add(latency)
+}
+
+// delay returns the time to wait before sending a hedged request.
+func (h *hedgeState) delay(key string, opt options.HedgedRequest) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	w, ok := h.latencies[key]
+	if !ok || len(w.samples) < minHedgeLatencySamples {
+		return opt.Delay
+	}
+	if d := w.percentile(opt.Percentile); d > opt.Delay {
+		return d
+	}
+	return opt.Delay
+}
+
+// latencyWindow is a ring buffer of the most recently observed latencies.
+type latencyWindow struct {
+	samples []time.Duration
+	next    int
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	if len(w.samples) < hedgeLatencySamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % hedgeLatencySamples
+}
+
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	sorted := make([]time.Duration, len(w.samples))
+	copy(sorted, w.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	i := int(p / 100 * float64(len(sorted)))
+	switch {
+	case i < 0:
+		i = 0
+	case i >= len(sorted):
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func getHedgedRequestOpt(opts []rpc.CallOpt) (options.HedgedRequest, bool) {
+	for _, o := range opts {
+		if h, ok := o.(options.HedgedRequest); ok {
+			return h, true
+		}
+	}
+	return options.HedgedRequest{}, false
+}
+
+// withResolution returns opts with any options.HedgedRequest or
+// options.Preresolved options replaced by a Preresolved option for entry.
+func withResolution(opts []rpc.CallOpt, entry *naming.MountEntry) []rpc.CallOpt {
+	r := make([]rpc.CallOpt, 0, len(opts)+1)
+	for _, o := range opts {
+		switch o.(type) {
+		case options.HedgedRequest, options.Preresolved:
+			continue
+		}
+		r = append(r, o)
+	}
+	return append(r, options.Preresolved{Resolution: entry})
+}
+
+// hedgeKey returns the key used to record the hedging state for method on
+// the given servers. The key is derived from the resolved names rather than
+// the name being called since the latter is empty for calls made with the
+// options.Preresolved option.
+func hedgeKey(entry *naming.MountEntry, servers []naming.MountedServer, method string) string {
+	names := make([]string, len(servers))
+	for i, s := range servers {
+		names[i] = naming.JoinAddressName(s.Server, entry.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",") + "." + method
+}
+
+type hedgedAttempt struct {
+	results []interface{}
+	err     error
+	latency time.Duration
+}
+
+// hedgedCall implements Client.Call for calls made with the
+// options.HedgedRequest option. Calls to methods that are not tagged with
+// access.Read, or to names that resolve to a single server, are not
+// hedged.
+func (c *client) hedgedCall(ctx *context.T, opt options.HedgedRequest, name, method string, inArgs, outArgs []interface{}, opts []rpc.CallOpt) error {
+	_, rname := security.SplitPatternName(name)
+	resolved, err := v23.GetNamespace(ctx).Resolve(ctx, rname, getNamespaceOpts(opts)...)
+	if err != nil {
+		return c.retryingCall(ctx, name, method, inArgs, outArgs, opts...)
+	}
+	opts = withResolution(opts, resolved)
+	servers, err := filterAndOrderServers(resolved.Servers, c.preferredProtocols)
+	if err != nil || len(servers) < 2 || !resultsArePointers(outArgs) {
+		return c.retryingCall(ctx, name, method, inArgs, outArgs, opts...)
+	}
+	key := hedgeKey(resolved, servers, method)
+	if !c.isReadOnlyMethod(ctx, key, name, method, opts) {
+		return c.retryingCall(ctx, name, method, inArgs, outArgs, opts...)
+	}
+
+	// The primary call prefers the first server, the hedged call the
+	// second, but both may fail over to any of the servers.
+	orders := [][]naming.MountedServer{
+		servers,
+		append(append([]naming.MountedServer{}, servers[1:]...), servers[0]),
+	}
+	ch := make(chan hedgedAttempt, len(orders))
+	var cancels []context.CancelFunc
+	defer func() {
+		// Cancel the loser, if any.
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+	start := func(servers []naming.MountedServer) {
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		entry := *resolved
+		entry.Servers = servers
+		results := make([]interface{}, len(outArgs))
+		for i, r := range outArgs {
+			results[i] = reflect.New(reflect.TypeOf(r).Elem()).Interface()
+		}
+		go func() {
+			start := time.Now()
+			err := c.retryingCall(actx, name, method, inArgs, results, withResolution(opts, &entry)...)
+			ch <- hedgedAttempt{results: results, err: err, latency: time.Since(start)}
+		}()
+	}
+
+	start(orders[0])
+	timer := time.NewTimer(c.hedge.delay(key, opt))
+	defer timer.Stop()
+	timeout := timer.C
+	pending := 1
+	for pending > 0 {
+		select {
+		case <-timeout:
+			ctx.VI(2).Infof("Sending hedged request for %v.%v", name, method)
+			start(orders[1])
+			timeout = nil
+			pending++
+		case a := <-ch:
+			pending--
+			if a.err == nil {
+				c.hedge.record(key, a.latency)
+				for i, r := range outArgs {
+					reflect.ValueOf(r).Elem().Set(reflect.ValueOf(a.results[i]).Elem())
+				}
+				return nil
+			}
+			err = a.err
+			if timeout != nil {
+				// The primary call failed before the hedged call was sent.
+				return err
+			}
+		}
+	}
+	return err
+}
+
+// isReadOnlyMethod returns true if the method's signature is tagged with
+// access.Read. The result is cached, but failures to obtain the signature
+// are not.
+func (c *client) isReadOnlyMethod(ctx *context.T, key, name, method string, opts []rpc.CallOpt) bool {
+	if readOnly, ok := c.hedge.isReadOnly(key); ok {
+		return readOnly
+	}
+	var sig signature.Method
+	if err := c.retryingCall(ctx, name, rpc.ReservedMethodSignature, []interface{}{method}, []interface{}{&sig}, opts...); err != nil {
+		ctx.VI(2).Infof("Failed to obtain signature of %v.%v: %v", name, method, err)
+		return false
+	}
+	readOnly := false
+	for _, tag := range sig.Tags {
+		if vdl.EqualValue(tag, readTag) {
+			readOnly = true
+		}
+	}
+	c.hedge.setReadOnly(key, readOnly)
+	return readOnly
+}
+
+func resultsArePointers(results []interface{}) bool {
+	for _, r := range results {
+		if r == nil || reflect.TypeOf(r).Kind() != reflect.Ptr {
+			return false
+		}
+	}
+	return true
+}
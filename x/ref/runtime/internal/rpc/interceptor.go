@@ -0,0 +1,114 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"v.io/v23/context"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+)
+
+// serverInterceptors holds the interceptor chains installed on a server
+// via options.ServerInterceptors.
+type serverInterceptors struct {
+	unary  []rpc.UnaryServerInterceptor
+	stream []rpc.StreamServerInterceptor
+}
+
+func (si *serverInterceptors) add(opt options.ServerInterceptors) {
+	si.unary = append(si.unary, opt.Unary...)
+	si.stream = append(si.stream, opt.Stream...)
+}
+
+func (si *serverInterceptors) empty() bool {
+	return len(si.unary) == 0 && len(si.stream) == 0
+}
+
+// intercept invokes handler via the unary or stream interceptor chain as
+// determined by the signature of the method being invoked.
+func (si *serverInterceptors) intercept(ctx *context.T, call rpc.StreamServerCall, invoker rpc.Invoker, method string, args []interface{}, handler rpc.ServerHandler) ([]interface{}, error) {
+	if si.empty() {
+		return handler(ctx, call, args)
+	}
+	if isStreamingMethod(ctx, call, invoker, method) {
+		return si.chainStream(handler)(ctx, call, call, args)
+	}
+	return chainServerInterceptors(si.unary, handler)(ctx, call, args)
+}
+
+func chainServerInterceptors(interceptors []rpc.UnaryServerInterceptor, handler rpc.ServerHandler) rpc.ServerHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, icpt := handler, interceptors[i]
+		handler = func(ctx *context.T, call rpc.StreamServerCall, args []interface{}) ([]interface{}, error) {
+			return icpt(ctx, call, args, next)
+		}
+	}
+	return handler
+}
+
+// chainStream returns a StreamServerHandler that invokes handler via the
+// stream interceptor chain, with the stream passed to handler being that
+// returned by the innermost interceptor.
+func (si *serverInterceptors) chainStream(handler rpc.ServerHandler) rpc.StreamServerHandler {
+	h := func(ctx *context.T, call rpc.ServerCall, stream rpc.Stream, args []interface{}) ([]interface{}, error) {
+		return handler(ctx, streamServerCall{call, stream}, args)
+	}
+	for i := len(si.stream) - 1; i >= 0; i-- {
+		next, icpt := h, si.stream[i]
+		h = func(ctx *context.T, call rpc.ServerCall, stream rpc.Stream, args []interface{}) ([]interface{}, error) {
+			return icpt(ctx, call, stream, args, next)
+		}
+	}
+	return h
+}
+
+// streamServerCall combines a ServerCall with a, possibly wrapped, Stream.
+type streamServerCall struct {
+	rpc.ServerCall
+	rpc.Stream
+}
+
+// isStreamingMethod returns true if the method has either an input or
+// output stream. Methods whose signature cannot be obtained are treated as
+// non-streaming.
+func isStreamingMethod(ctx *context.T, call rpc.ServerCall, invoker rpc.Invoker, method string) bool {
+	sig, err := invoker.MethodSignature(ctx, call, method)
+	if err != nil {
+		return false
+	}
+	return sig.InStream != nil || sig.OutStream != nil
+}
+
+// clientInterceptors holds the interceptor chains installed on a client
+// via options.ClientInterceptors.
+type clientInterceptors struct {
+	unary  []rpc.UnaryClientInterceptor
+	stream []rpc.StreamClientInterceptor
+}
+
+func (ci *clientInterceptors) add(opt options.ClientInterceptors) {
+	ci.unary = append(ci.unary, opt.Unary...)
+	ci.stream = append(ci.stream, opt.Stream...)
+}
+
+func (ci *clientInterceptors) invoker(invoker rpc.ClientInvoker) rpc.ClientInvoker {
+	for i := len(ci.unary) - 1; i >= 0; i-- {
+		next, icpt := invoker, ci.unary[i]
+		invoker = func(ctx *context.T, name, method string, inArgs, outArgs []interface{}, opts ...rpc.CallOpt) error {
+			return icpt(ctx, name, method, inArgs, outArgs, next, opts...)
+		}
+	}
+	return invoker
+}
+
+func (ci *clientInterceptors) starter(starter rpc.ClientStarter) rpc.ClientStarter {
+	for i := len(ci.stream) - 1; i >= 0; i-- {
+		next, icpt := starter, ci.stream[i]
+		starter = func(ctx *context.T, name, method string, args []interface{}, opts ...rpc.CallOpt) (rpc.ClientCall, error) {
+			return icpt(ctx, name, method, args, next, opts...)
+		}
+	}
+	return starter
+}
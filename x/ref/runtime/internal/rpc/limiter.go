@@ -0,0 +1,182 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/verror"
+	"v.io/x/ref/lib/stats"
+)
+
+var errServerOverloaded = verror.NewIDAction("errServerOverloaded", verror.RetryBackoff)
+
+// shedInterval is the time for which every call must wait for longer than
+// the target queue delay before calls start to be shed.
+const shedInterval = 100 * time.Millisecond
+
+// callLimiter implements options.ConcurrencyLimits for a server.
+type callLimiter struct {
+	limits options.ConcurrencyLimits
+	target time.Duration // the target queue delay.
+
+	mu       sync.Mutex
+	inFlight int
+	methods  map[string]int // in-flight calls, keyed by method.
+	queue    []*queuedCall
+	// queueDelay is a moving average of the time spent by calls in the
+	// queue, including those that were not queued at all.
+	queueDelay time.Duration
+	// aboveTarget is the time since which every call has spent longer
+	// than the target delay in the queue, or zero if the last call did
+	// not.
+	aboveTarget time.Time
+
+	rejected *stats.Map // keyed by method.
+}
+
+type queuedCall struct {
+	method  string
+	ready   chan struct{}
+	granted bool
+}
+
+func newCallLimiter(prefix string, limits options.ConcurrencyLimits) *callLimiter {
+	l := &callLimiter{
+		limits:   limits,
+		target:   limits.TargetQueueDelay,
+		methods:  make(map[string]int),
+		rejected: stats.NewMap(naming.Join(prefix, "rejected")),
+	}
+	if l.target <= 0 {
+		l.target = limits.MaxQueueDelay / 2
+	}
+	stats.NewIntegerFunc(naming.Join(prefix, "in-flight"), func() int64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return int64(l.inFlight)
+	})
+	stats.NewIntegerFunc(naming.Join(prefix, "queued"), func() int64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return int64(len(l.queue))
+	})
+	stats.NewIntegerFunc(naming.Join(prefix, "queue-delay-ms"), func() int64 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return int64(l.queueDelay / time.Millisecond)
+	})
+	return l
+}
+
+// acquire waits until a call to method may proceed and returns a function
+// that must be called once the call is complete, or an error if the call
+// is to be rejected.
+func (l *callLimiter) acquire(ctx *context.T, method string) (func(), error) {
+	release := func() { l.release(method) }
+	l.mu.Lock()
+	if l.canRunLocked(method) {
+		l.startLocked(method)
+		l.recordLocked(0)
+		l.mu.Unlock()
+		return release, nil
+	}
+	if len(l.queue) >= l.limits.MaxQueued || (len(l.queue) > 0 && l.sheddingLocked()) {
+		l.mu.Unlock()
+		return nil, l.reject(ctx, method)
+	}
+	q := &queuedCall{method: method, ready: make(chan struct{})}
+	l.queue = append(l.queue, q)
+	l.mu.Unlock()
+
+	start := time.Now()
+	var timeout <-chan time.Time
+	if l.limits.MaxQueueDelay > 0 {
+		timer := time.NewTimer(l.limits.MaxQueueDelay)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-q.ready:
+	case <-timeout:
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.recordLocked(time.Since(start))
+	if q.granted {
+		return release, nil
+	}
+	for i, c := range l.queue {
+		if c == q {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			break
+		}
+	}
+	return nil, l.reject(ctx, method)
+}
+
+func (l *callLimiter) reject(ctx *context.T, method string) error {
+	l.rejected.Incr(method, 1)
+	return errServerOverloaded.Errorf(ctx, "server is overloaded, rejected call to %v", method)
+}
+
+func (l *callLimiter) release(method string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if l.methods[method]--; l.methods[method] == 0 {
+		delete(l.methods, method)
+	}
+	// Start as many of the queued calls, in order, as the limits allow.
+	queue := l.queue[:0]
+	for _, q := range l.queue {
+		if l.canRunLocked(q.method) {
+			l.startLocked(q.method)
+			q.granted = true
+			close(q.ready)
+			continue
+		}
+		queue = append(queue, q)
+	}
+	l.queue = queue
+}
+
+func (l *callLimiter) canRunLocked(method string) bool {
+	if max := l.limits.MaxInFlight; max > 0 && l.inFlight >= max {
+		return false
+	}
+	if max := l.limits.MethodMaxInFlight[method]; max > 0 && l.methods[method] >= max {
+		return false
+	}
+	return true
+}
+
+func (l *callLimiter) startLocked(method string) {
+	l.inFlight++
+	l.methods[method]++
+}
+
+func (l *callLimiter) recordLocked(d time.Duration) {
+	l.queueDelay = l.queueDelay*7/8 + d/8
+	switch {
+	case l.target <= 0 || d <= l.target:
+		l.aboveTarget = time.Time{}
+	case l.aboveTarget.IsZero():
+		l.aboveTarget = time.Now()
+	}
+}
+
+// sheddingLocked returns true if every call for at least shedInterval has
+// spent longer than the target delay in the queue, that is, if the queue is
+// not draining and calls that would have to wait should be rejected
+// immediately.
+func (l *callLimiter) sheddingLocked() bool {
+	return !l.aboveTarget.IsZero() && time.Since(l.aboveTarget) >= shedInterval
+}
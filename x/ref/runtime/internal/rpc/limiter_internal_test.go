@@ -0,0 +1,165 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/options"
+	"v.io/v23/verror"
+)
+
+func TestCallLimiter(t *testing.T) {
+	ctx, shutdown := initForTest()
+	defer shutdown()
+
+	l := newCallLimiter(fmt.Sprintf("rpc/test/limiter/%p", t), options.ConcurrencyLimits{
+		MaxInFlight:       2,
+		MethodMaxInFlight: map[string]int{"Slow": 1},
+		MaxQueued:         1,
+	})
+	mustAcquire := func(method string) func() {
+		release, err := l.acquire(ctx, method)
+		if err != nil {
+			t.Fatalf("%v: %v", method, err)
+		}
+		return release
+	}
+	mustReject := func(method string) {
+		if _, err := l.acquire(ctx, method); !errors.Is(err, errServerOverloaded) || verror.Action(err) != verror.RetryBackoff {
+			t.Fatalf("%v: unexpected error: %v", method, err)
+		}
+	}
+
+	releaseSlow := mustAcquire("Slow")
+	// Slow is limited to a single call, so a second call is queued and a
+	// third rejected since the queue is full.
+	acquired := make(chan func())
+	go func() {
+		release, err := l.acquire(ctx, "Slow")
+		if err != nil {
+			t.Error(err)
+		}
+		acquired <- release
+	}()
+	for {
+		l.mu.Lock()
+		queued := len(l.queue)
+		l.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mustReject("Slow")
+	// Other methods are subject only to the global limit.
+	releaseFast := mustAcquire("Fast")
+	mustReject("Fast")
+	releaseFast()
+
+	releaseSlow()
+	releaseSlow = <-acquired
+	releaseSlow()
+	if got, want := l.inFlight, 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCallLimiterQueueDelay(t *testing.T) {
+	ctx, shutdown := initForTest()
+	defer shutdown()
+
+	l := newCallLimiter(fmt.Sprintf("rpc/test/limiter/%p", t), options.ConcurrencyLimits{
+		MaxInFlight:   1,
+		MaxQueued:     10,
+		MaxQueueDelay: 10 * time.Millisecond,
+	})
+	release, err := l.acquire(ctx, "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	// A queued call is rejected once it has waited for MaxQueueDelay.
+	if _, err := l.acquire(ctx, "M"); !errors.Is(err, errServerOverloaded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// As is a queued call whose context is canceled.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := l.acquire(cctx, "M"); !errors.Is(err, errServerOverloaded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := len(l.queue), 0; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCallLimiterShedding(t *testing.T) {
+	ctx, shutdown := initForTest()
+	defer shutdown()
+
+	l := newCallLimiter(fmt.Sprintf("rpc/test/limiter/%p", t), options.ConcurrencyLimits{
+		MaxInFlight:      1,
+		MaxQueued:        10,
+		MaxQueueDelay:    50 * time.Millisecond,
+		TargetQueueDelay: 5 * time.Millisecond,
+	})
+	release, err := l.acquire(ctx, "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every call waits for longer than the target delay until it times
+	// out.
+	start := time.Now()
+	for time.Since(start) < 2*shedInterval {
+		if _, err := l.acquire(ctx, "M"); !errors.Is(err, errServerOverloaded) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// And so, with a call already queued, further calls are rejected
+	// without waiting.
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := l.acquire(ctx, "M")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+	for {
+		l.mu.Lock()
+		queued := len(l.queue)
+		l.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	start = time.Now()
+	if _, err := l.acquire(ctx, "M"); !errors.Is(err, errServerOverloaded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= l.limits.MaxQueueDelay {
+		t.Errorf("call was queued for %v before being rejected", elapsed)
+	}
+	release()
+	<-acquired
+	// A call that does not have to wait ends the shedding.
+	release, err = l.acquire(ctx, "M")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	l.mu.Lock()
+	shedding := l.sheddingLocked()
+	l.mu.Unlock()
+	if shedding {
+		t.Errorf("calls are still being shed")
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"time"
 
 	"v.io/v23/context"
+	"v.io/v23/flow"
 	"v.io/v23/naming"
 	"v.io/v23/options"
 	"v.io/v23/rpc"
+	"v.io/x/ref/runtime/internal/flow/manager"
 )
 
 // PreferredProtocols instructs the Runtime implementation to select
@@ -46,11 +48,45 @@ func (IdleConnectionExpiry) RPCClientOpt() {
 func (IdleConnectionExpiry) RPCServerOpt() {
 }
 
+// ConnectionResumptionTimeout is the amount of time allowed for a connection
+// whose underlying network connection has failed to be resumed over a new
+// one. Resumption is disabled if it is zero or not specified.
+type ConnectionResumptionTimeout time.Duration
+
+func (ConnectionResumptionTimeout) RPCClientOpt() {
+}
+func (ConnectionResumptionTimeout) RPCServerOpt() {
+}
+
+// ConnectionCacheLimit bounds the number of connections cached by the
+// client or server, the connection cache is unbounded if it is not specified.
+type ConnectionCacheLimit manager.CacheLimit
+
+func (ConnectionCacheLimit) RPCClientOpt() {
+}
+func (ConnectionCacheLimit) RPCServerOpt() {
+}
+
+// ConnectionOptions configures the connections created by the client or
+// server, compression is disabled and the default rekeying intervals are
+// used if it is not specified.
+type ConnectionOptions manager.ConnOptions
+
+func (ConnectionOptions) RPCClientOpt() {
+}
+func (ConnectionOptions) RPCServerOpt() {
+}
+
 type connectionOpts struct {
 	connDeadline   time.Time
 	channelTimeout time.Duration
 	useOnlyCached  bool
 	noRetry        bool
+	priority       flow.Priority
+	keepalive      *flow.KeepaliveOpts
+	// retry, if set, is the policy shared by all of the attempts made
+	// for a call.
+	retry *retryPolicy
 }
 
 func getConnectionOptions(ctx *context.T, opts []rpc.CallOpt) *connectionOpts {
@@ -73,6 +109,11 @@ func getConnectionOptions(ctx *context.T, opts []rpc.CallOpt) *connectionOpts {
 			}
 		case options.NoRetry:
 			copts.noRetry = true
+		case options.Priority:
+			copts.priority = flow.Priority(t)
+		case options.Keepalive:
+			ka := flow.KeepaliveOpts(t)
+			copts.keepalive = &ka
 		}
 	}
 	// If the context deadline is sooner than connection deadline, use it instead.
@@ -22,8 +22,8 @@ import (
 // reservedInvoker returns a special invoker for reserved methods.  This invoker
 // has access to the internal dispatchers, which allows it to perform special
 // handling for methods like Glob and Signature.
-func reservedInvoker(dispNormal, dispReserved rpc.Dispatcher) rpc.Invoker {
-	methods := &reservedMethods{dispNormal: dispNormal, dispReserved: dispReserved}
+func reservedInvoker(dispNormal, dispReserved rpc.Dispatcher, health *healthState) rpc.Invoker {
+	methods := &reservedMethods{dispNormal: dispNormal, dispReserved: dispReserved, health: health}
 	invoker := rpc.ReflectInvokerOrDie(methods)
 	methods.selfInvoker = invoker
 	return invoker
@@ -39,6 +39,7 @@ type reservedMethods struct {
 	dispNormal   rpc.Dispatcher
 	dispReserved rpc.Dispatcher
 	selfInvoker  rpc.Invoker
+	health       *healthState
 }
 
 //nolint:revive // API change required.
@@ -71,6 +72,18 @@ func (r *reservedMethods) Describe__() []rpc.InterfaceDesc {
 					Doc: "All interface signatures implemented by the object.",
 				}},
 			},
+			{
+				Name: "Health",
+				Doc:  "Health returns the health of the object.",
+				OutArgs: []rpc.ArgDesc{{
+					Doc: "The health of the object.",
+				}},
+			},
+			{
+				Name:      "WatchHealth",
+				Doc:       "WatchHealth streams the health of the object whenever it changes, starting with its current health.",
+				OutStream: rpc.ArgDesc{Doc: "Streams the health of the object back to the client."},
+			},
 		},
 	}}
 }
@@ -143,6 +156,64 @@ func (r *reservedMethods) MethodSignature(ctx *context.T, call rpc.ServerCall, m
 	return invoker.MethodSignature(ctx, call, method)
 }
 
+func (r *reservedMethods) Health(ctx *context.T, call rpc.ServerCall) (rpc.HealthStatus, error) {
+	if err := r.healthExists(ctx, call.Suffix()); err != nil {
+		return rpc.HealthStatusServing, err
+	}
+	status, _ := r.health.status(call.Suffix())
+	return status, nil
+}
+
+func (r *reservedMethods) WatchHealth(ctx *context.T, call rpc.StreamServerCall) error {
+	if err := r.healthExists(ctx, call.Suffix()); err != nil {
+		return err
+	}
+	var last rpc.HealthStatus
+	for sent := false; ; sent = true {
+		status, changed := r.health.status(call.Suffix())
+		if !sent || status != last {
+			if err := call.Send(status); err != nil {
+				return err
+			}
+			last = status
+		}
+		if status == rpc.HealthStatusLameDuck {
+			// The server is shutting down, end the stream so as not to
+			// delay it.
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// healthExists returns an error if there is no object with the specified
+// suffix, that is, if no health has been set for the suffix and the
+// dispatcher does not serve an object for it.
+func (r *reservedMethods) healthExists(ctx *context.T, suffix string) error {
+	if r.health.has(suffix) {
+		return nil
+	}
+	disp := r.dispNormal
+	if naming.IsReserved(suffix) {
+		disp = r.dispReserved
+	}
+	if disp == nil {
+		return verror.ErrUnknownSuffix.Errorf(ctx, "suffix does not exist: %v", suffix)
+	}
+	obj, _, err := disp.Lookup(ctx, suffix)
+	switch {
+	case err != nil:
+		return err
+	case obj == nil:
+		return verror.ErrUnknownSuffix.Errorf(ctx, "suffix does not exist: %v", suffix)
+	}
+	return nil
+}
+
 func (r *reservedMethods) Glob(ctx *context.T, call rpc.StreamServerCall, pattern string) error {
 	// Copy the original call to shield ourselves from changes the flowServer makes.
 	glob := globInternal{r.dispNormal, r.dispReserved, call.Suffix()}
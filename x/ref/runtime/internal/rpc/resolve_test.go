@@ -18,6 +18,7 @@ import (
 	"v.io/v23/security/access"
 	"v.io/x/ref/lib/flags"
 	"v.io/x/ref/runtime/factories/fake"
+	"v.io/x/ref/runtime/internal/flow/manager"
 	irpc "v.io/x/ref/runtime/internal/rpc"
 	grt "v.io/x/ref/runtime/internal/rt"
 	"v.io/x/ref/test"
@@ -50,7 +51,10 @@ func setupRuntime() {
 		commonFlags.RuntimeFlags(),
 		nil,
 		&access.PermissionsSpec{},
-		0)
+		0,
+		0,
+		manager.CacheLimit{},
+		manager.ConnOptions{})
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,178 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rpc
+
+import (
+	"math/rand"
+	"time"
+
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/verror"
+)
+
+const (
+	// The client uses this as the delay before the first retry of a call
+	// unless a RetryPolicy specifies otherwise.
+	defaultInitialBackoff = 100 * time.Millisecond
+)
+
+// defaultRetryPolicy is used by clients that are not created with a
+// RetryPolicy option. It retries until the call's deadline with a
+// randomized exponential backoff of ((100 to 200) * 2^n) ms.
+var defaultRetryPolicy = options.RetryPolicy{
+	InitialBackoff: defaultInitialBackoff,
+	MaxBackoff:     maxBackoff,
+	Jitter:         1,
+}
+
+// retryPolicy is the options.RetryPolicy that applies to a single call. It
+// counts the retries made while connecting to the servers for the call as
+// well as those made for the call itself so that both are subject to the
+// same MaxAttempts.
+type retryPolicy struct {
+	options.RetryPolicy
+	method  string
+	stats   *retryStats
+	retries uint
+}
+
+// retryPolicy returns the policy for a call to method, which is the client's
+// policy unless overridden by a RetryPolicy CallOpt, and then overridden by
+// any per-method policy.
+func (c *client) retryPolicy(method string, opts []rpc.CallOpt) *retryPolicy {
+	policy := c.retry
+	for _, o := range opts {
+		if p, ok := o.(options.RetryPolicy); ok {
+			policy = p
+		}
+	}
+	if p, ok := policy.Methods[method]; ok {
+		policy = p
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = maxBackoff
+	}
+	return &retryPolicy{RetryPolicy: policy, method: method, stats: c.retryStats}
+}
+
+// matches returns true if an error with the specified action and ID is one
+// of the policy's Actions or IDs.
+func (p *retryPolicy) matches(action verror.ActionCode, err error) bool {
+	for _, a := range p.Actions {
+		if action.RetryAction() == a {
+			return true
+		}
+	}
+	id := verror.ErrorID(err)
+	for _, i := range p.IDs {
+		if id == i {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableCallError returns true if err, returned by a call once started,
+// matches the policy. Errors with the RetryBackoff action are retried when
+// the policy specifies neither Actions nor IDs.
+func (p *retryPolicy) retryableCallError(err error) bool {
+	action := verror.Action(err)
+	if len(p.Actions) == 0 && len(p.IDs) == 0 {
+		return action.RetryAction() == verror.RetryBackoff
+	}
+	return p.matches(action, err)
+}
+
+// shouldRetryCall returns true if a call that failed with err once started
+// should be retried.
+func (p *retryPolicy) shouldRetryCall(err error, connOpts *connectionOpts) bool {
+	switch {
+	case connOpts.noRetry:
+		return false
+	case !p.retryableCallError(err):
+		return false
+	case time.Now().After(connOpts.connDeadline):
+		return false
+	}
+	return true
+}
+
+// shouldRetryConnect returns true if an attempt to connect to the servers
+// for a call that failed with the specified action and error should be
+// retried. Errors with the RetryConnection or RetryRefetch actions are
+// always retried, in addition to those that match the policy's Actions and
+// IDs, so that a policy intended for the errors returned by servers does
+// not prevent the client from connecting to them.
+func (p *retryPolicy) shouldRetryConnect(action verror.ActionCode, err error, requireResolve bool, connOpts *connectionOpts, opts []rpc.CallOpt) bool {
+	switch {
+	case connOpts.noRetry:
+		return false
+	case connOpts.useOnlyCached:
+		// If we should only used cached connections, it doesn't make sense to retry
+		// looking in the cache.
+		return false
+	case action.RetryAction() != verror.RetryConnection &&
+		action.RetryAction() != verror.RetryRefetch &&
+		!p.matches(action, err):
+		return false
+	case time.Now().After(connOpts.connDeadline):
+		return false
+	case requireResolve && getNoNamespaceOpt(opts):
+		// If we're skipping resolution and there are no servers for
+		// this call retrying is not going to help, we can't come up
+		// with new servers if there is no resolution.
+		return false
+	}
+	return true
+}
+
+// backoff waits before the next retry and returns false if the retry should
+// not be attempted because the policy's MaxAttempts would be exceeded or
+// there is not enough time left before the deadline.
+func (p *retryPolicy) backoff(deadline time.Time) bool {
+	n := p.retries
+	if p.MaxAttempts > 0 && n+1 >= uint(p.MaxAttempts) {
+		p.stats.exhausted(p.method)
+		return false
+	}
+	b := p.MaxBackoff
+	if n < 32 {
+		if d := p.InitialBackoff << n; d > 0 && d < b {
+			b = d
+		}
+	}
+	// The randomness deters error convoys from forming.
+	if p.Jitter > 0 {
+		b += time.Duration(rand.Float64() * p.Jitter * float64(b))
+	}
+	if b > p.MaxBackoff {
+		b = p.MaxBackoff
+	}
+	r := time.Until(deadline)
+	// We need to budget some time for the call to have a chance to complete
+	// lest we'll timeout before we actually do anything.  If we just don't
+	// have enough time left, give up.
+	//
+	// The value should cover a sensible call duration (which includes name
+	// resolution and the actual server RPC) on most supported platforms;
+	// use https://vanadium.github.io/performance.html for inspiration.
+	const reserveTime = 100 * time.Millisecond
+	if r <= reserveTime {
+		p.stats.exhausted(p.method)
+		return false
+	}
+	r -= reserveTime
+	if b > r {
+		b = r
+	}
+	time.Sleep(b)
+	p.retries++
+	p.stats.retried(p.method)
+	return true
+}
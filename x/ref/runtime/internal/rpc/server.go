@@ -65,8 +65,11 @@ type server struct {
 	isLeaf             bool
 	lameDuckTimeout    time.Duration // the time to wait for inflight operations to finish on shutdown
 
-	stats       *rpcStats // stats for this server.
-	outstanding *outstandingStats
+	stats        *rpcStats // stats for this server.
+	outstanding  *outstandingStats
+	interceptors serverInterceptors // interceptors invoked around every call.
+	limiter      *callLimiter       // limits the number of concurrent calls, if set.
+	health       *healthState       // the health reported by __Health.
 }
 
 func WithNewServer(ctx *context.T,
@@ -114,9 +117,12 @@ func WithNewDispatchingServer(ctx *context.T,
 		lameDuckTimeout:   5 * time.Second, // TODO(cnicolaou): make this an option
 		closed:            make(chan struct{}),
 		outstanding:       newOutstandingStats(naming.Join("rpc", "server", "outstanding", rid.String())),
+		health:            newHealthState(),
 	}
 	channelTimeout := time.Duration(0)
-	connIdleExpiry := time.Duration(0)
+	connIdleExpiry, connResumeTimeout := time.Duration(0), time.Duration(0)
+	var connCacheLimit manager.CacheLimit
+	var connOpts manager.ConnOptions
 	var authorizedPeers []security.BlessingPattern
 	for _, opt := range opts {
 		switch opt := opt.(type) {
@@ -153,6 +159,16 @@ func WithNewDispatchingServer(ctx *context.T,
 
 		case IdleConnectionExpiry:
 			connIdleExpiry = time.Duration(opt)
+		case ConnectionResumptionTimeout:
+			connResumeTimeout = time.Duration(opt)
+		case ConnectionCacheLimit:
+			connCacheLimit = manager.CacheLimit(opt)
+		case ConnectionOptions:
+			connOpts = manager.ConnOptions(opt)
+		case options.ServerInterceptors:
+			s.interceptors.add(opt)
+		case options.ConcurrencyLimits:
+			s.limiter = newCallLimiter(naming.Join(statsPrefix, "load"), opt)
 		}
 	}
 
@@ -171,7 +187,7 @@ func WithNewDispatchingServer(ctx *context.T,
 		}
 	}
 
-	s.flowMgr = manager.New(s.ctx, rid, settingsPublisher, channelTimeout, connIdleExpiry, authorizedPeers)
+	s.flowMgr = manager.New(s.ctx, rid, settingsPublisher, channelTimeout, connIdleExpiry, connResumeTimeout, connCacheLimit, connOpts, authorizedPeers)
 	s.ctx, _, err = v23.WithNewClient(s.ctx,
 		clientFlowManagerOpt{s.flowMgr},
 		PreferredProtocols(s.preferredProtocols))
@@ -213,6 +229,7 @@ func WithNewDispatchingServer(ctx *context.T,
 			}
 		}
 		s.setState(rpc.ServerStopping)
+		s.health.setLameDuck()
 		serverDebug := fmt.Sprintf("Dispatcher: %T, Status:[%v]", s.disp, s.Status())
 		s.ctx.VI(1).Infof("Stop: %s", serverDebug)
 		defer s.ctx.VI(1).Infof("Stop done: %s", serverDebug)
@@ -279,6 +296,10 @@ func (s *server) monitorPubStatus(ctx *context.T) {
 	}
 }
 
+func (s *server) SetHealth(suffix string, status rpc.HealthStatus) {
+	s.health.set(suffix, status)
+}
+
 func (s *server) Status() rpc.ServerStatus {
 	status := rpc.ServerStatus{}
 	status.ServesMountTable = s.servesMountTable
@@ -362,6 +383,7 @@ func (s *server) proxyListen(ctx *context.T, name string, ep naming.Endpoint) (<
 func (s *server) listen(ctx *context.T, listenSpec rpc.ListenSpec) {
 	defer s.Unlock()
 	s.Lock()
+	ctx = flow.WithKeepalive(ctx, listenSpec.Keepalive)
 	var lctx *context.T
 	lctx, s.stopListens = context.WithCancel(ctx)
 	if len(listenSpec.Proxy) > 0 {
@@ -591,6 +613,8 @@ type flowServer struct {
 	enc              *vom.Encoder // to encode responses and results to the client
 	grantedBlessings security.Blessings
 	method, suffix   string
+	metadata         map[string]string // metadata sent by the client.
+	responseMetadata map[string]string // metadata to be sent to the client.
 	tags             []*vdl.Value
 	discharges       map[string]security.Discharge
 	starttime        time.Time
@@ -610,6 +634,7 @@ func newXFlowServer(flow flow.Flow, server *server) (*flowServer, error) {
 	}
 	ctx := WithRequestID(server.ctx, requestID)
 	ctx = context.WithLoggingPrefix(ctx, requestID)
+	ctx = withPeerCredentials(ctx, flow)
 	fs := &flowServer{
 		ctx:        ctx,
 		server:     server,
@@ -620,6 +645,19 @@ func newXFlowServer(flow flow.Flow, server *server) (*flowServer, error) {
 	return fs, nil
 }
 
+// withPeerCredentials makes the operating system credentials of the client,
+// if known, available to authorizers via flow.GetPeerCredentials. They are
+// only known for connections made directly over a protocol that reports
+// them, and never for those tunnelled through a flow, eg. via a proxy.
+func withPeerCredentials(ctx *context.T, f flow.Flow) *context.T {
+	if pc, ok := f.Conn().(flow.PeerCredentialer); ok {
+		if creds, ok := pc.PeerCredentials(); ok {
+			return flow.WithPeerCredentials(ctx, creds)
+		}
+	}
+	return ctx
+}
+
 // authorizeVtrace works by simulating a call to __debug/vtrace.Trace.  That
 // rpc is essentially equivalent in power to the data we are attempting to
 // attach here.
@@ -683,6 +721,9 @@ func (fs *flowServer) serve() error {
 		NumPosResults:    uint64(len(results)),
 		TraceResponse:    traceResponse,
 	}
+	if supportsMetadata(fs.flow.Conn()) {
+		response.Metadata = fs.responseMetadata
+	}
 	if err := fs.enc.Encode(response); err != nil {
 		if err == io.EOF {
 			return err
@@ -841,6 +882,7 @@ func (fs *flowServer) processRequest() (*context.T, []interface{}, error) {
 	// after this point, and before we actually decode the arguments.
 	fs.method = req.Method
 	fs.suffix = strings.TrimLeft(req.Suffix, "/")
+	fs.metadata = req.Metadata
 
 	// TODO(mattr): Currently this allows users to trigger trace collection
 	// on the server even if they will not be allowed to collect the
@@ -851,6 +893,18 @@ func (fs *flowServer) processRequest() (*context.T, []interface{}, error) {
 
 	ctx = fs.flow.SetDeadlineContext(ctx, req.Deadline.Time)
 
+	// Shed load before doing any further work for the call.
+	if fs.server.limiter != nil && !naming.IsReserved(fs.method) {
+		release, err := fs.server.limiter.acquire(ctx, fs.method)
+		if err != nil {
+			fs.drainDecoderArgs(int(req.NumPosArgs)) //nolint:errcheck
+			tr.LazyPrintf("%s\n", err)
+			tr.SetError()
+			return ctx, nil, err
+		}
+		defer release()
+	}
+
 	if err := fs.readGrantedBlessings(ctx, req); err != nil {
 		fs.drainDecoderArgs(int(req.NumPosArgs)) //nolint:errcheck
 
@@ -917,8 +971,12 @@ func (fs *flowServer) processRequest() (*context.T, []interface{}, error) {
 		}
 	}()
 
-	// Invoke the method.
-	results, err := invoker.Invoke(ctx, fs, strippedMethod, argptrs)
+	// Invoke the method via any interceptors installed on the server. The
+	// interceptors only see calls that have already been authorized.
+	handler := func(ctx *context.T, call rpc.StreamServerCall, args []interface{}) ([]interface{}, error) {
+		return invoker.Invoke(ctx, call, strippedMethod, args)
+	}
+	results, err := fs.server.interceptors.intercept(ctx, fs, invoker, strippedMethod, argptrs, handler)
 	fs.server.stats.record(fs.method, time.Since(fs.starttime))
 	return ctx, results, err
 }
@@ -945,7 +1003,7 @@ func (fs *flowServer) drainDecoderArgs(n int) error {
 // value may be modified to match the actual suffix and method to use.
 func (fs *flowServer) lookup(ctx *context.T, suffix string, method string) (rpc.Invoker, security.Authorizer, error) {
 	if naming.IsReserved(method) {
-		return reservedInvoker(fs.disp, fs.server.dispReserved), security.AllowEveryone(), nil
+		return reservedInvoker(fs.disp, fs.server.dispReserved, fs.server.health), security.AllowEveryone(), nil
 	}
 	disp := fs.disp
 	if naming.IsReserved(suffix) {
@@ -1061,6 +1119,12 @@ func (fs *flowServer) RemoteEndpoint() naming.Endpoint {
 func (fs *flowServer) RemoteAddr() net.Addr {
 	return fs.flow.RemoteAddr()
 }
+func (fs *flowServer) Metadata() map[string]string {
+	return fs.metadata
+}
+func (fs *flowServer) SetResponseMetadata(md map[string]string) {
+	fs.responseMetadata = md
+}
 
 type leafDispatcher struct {
 	invoker rpc.Invoker
@@ -176,3 +176,53 @@ func (s *blessingsCacheStats) incr(hit bool) {
 		s.cacheHits.Incr(1)
 	}
 }
+
+// loadBalancerStats counts the number of calls for which each server was
+// selected by a client's load balancing policy.
+type loadBalancerStats struct {
+	prefix     string
+	selections *stats.Map
+}
+
+func newLoadBalancerStats(prefix, policy string) *loadBalancerStats {
+	return &loadBalancerStats{
+		prefix:     prefix,
+		selections: stats.NewMap(naming.Join(prefix, policy, "selected")),
+	}
+}
+
+func (s *loadBalancerStats) selected(ep naming.Endpoint) {
+	s.selections.Incr(ep.Address, 1)
+}
+
+func (s *loadBalancerStats) close() {
+	stats.Delete(s.prefix) //nolint:errcheck
+}
+
+// retryStats counts, per method, the number of calls retried by a client and
+// the number of calls that were not retried because the client's retry
+// policy was exhausted.
+type retryStats struct {
+	prefix            string
+	retries, failures *stats.Map
+}
+
+func newRetryStats(prefix string) *retryStats {
+	return &retryStats{
+		prefix:   prefix,
+		retries:  stats.NewMap(naming.Join(prefix, "retried")),
+		failures: stats.NewMap(naming.Join(prefix, "exhausted")),
+	}
+}
+
+func (s *retryStats) retried(method string) {
+	s.retries.Incr(method, 1)
+}
+
+func (s *retryStats) exhausted(method string) {
+	s.failures.Incr(method, 1)
+}
+
+func (s *retryStats) close() {
+	stats.Delete(s.prefix) //nolint:errcheck
+}
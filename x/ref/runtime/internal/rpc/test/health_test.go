@@ -0,0 +1,109 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/rpc"
+	"v.io/v23/rpc/reserved"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type healthServer struct{}
+
+func (healthServer) Ping(*context.T, rpc.ServerCall) error {
+	return nil
+}
+
+// healthDispatcher serves healthServer under the empty suffix and "b".
+type healthDispatcher struct{}
+
+func (healthDispatcher) Lookup(_ *context.T, suffix string) (interface{}, security.Authorizer, error) {
+	switch suffix {
+	case "", "b":
+		return healthServer{}, security.AllowEveryone(), nil
+	}
+	return nil, nil, nil
+}
+
+func TestHealth(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	sctx, cancel := context.WithCancel(ctx)
+	_, server, err := v23.WithNewDispatchingServer(sctx, "", healthDispatcher{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name := server.Status().Endpoints[0].Name()
+
+	health := func(suffix string) rpc.HealthStatus {
+		status, err := reserved.Health(ctx, naming.Join(name, suffix))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+	if got, want := health(""), rpc.HealthStatusServing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	ch, wait, err := reserved.WatchHealth(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := <-ch, rpc.HealthStatusServing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Suffixes without a status of their own share that of the server.
+	server.SetHealth("a", rpc.HealthStatusServing)
+	server.SetHealth("", rpc.HealthStatusNotServing)
+	if got, want := <-ch, rpc.HealthStatusNotServing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := health("a"), rpc.HealthStatusServing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := health("b"), rpc.HealthStatusNotServing; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// Suffixes that are not served, and have no status of their own, do
+	// not exist.
+	if _, err := reserved.Health(ctx, naming.Join(name, "c")); !errors.Is(err, verror.ErrUnknownSuffix) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cch, cwait, err := reserved.WatchHealth(ctx, naming.Join(name, "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range cch {
+	}
+	if err := cwait(); !errors.Is(err, verror.ErrUnknownSuffix) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// All objects are reported as lame ducks once the server starts to
+	// shut down, which also ends the watch.
+	cancel()
+	if got, want := <-ch, rpc.HealthStatusLameDuck; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for range ch {
+	}
+	if err := wait(); err != nil {
+		t.Error(err)
+	}
+	<-server.Closed()
+}
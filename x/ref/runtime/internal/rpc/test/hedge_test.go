@@ -0,0 +1,164 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+// hedgeGate blocks the first call to Get, and every call to Put, until
+// release is closed. The id of the server is sent on calls as each call
+// starts.
+type hedgeGate struct {
+	calls   chan string
+	release chan struct{}
+	once    sync.Once
+	started int32
+}
+
+func newHedgeGate() *hedgeGate {
+	return &hedgeGate{calls: make(chan string, 10), release: make(chan struct{})}
+}
+
+func (g *hedgeGate) releaseAll() {
+	g.once.Do(func() { close(g.release) })
+}
+
+type hedgeServer struct {
+	id   string
+	gate *hedgeGate
+}
+
+func (s *hedgeServer) Get(*context.T, rpc.ServerCall) (string, error) {
+	s.gate.calls <- s.id
+	if atomic.CompareAndSwapInt32(&s.gate.started, 0, 1) {
+		<-s.gate.release
+	}
+	return s.id, nil
+}
+
+func (s *hedgeServer) Put(*context.T, rpc.ServerCall) error {
+	s.gate.calls <- s.id
+	<-s.gate.release
+	return nil
+}
+
+func (s *hedgeServer) Describe__() []rpc.InterfaceDesc {
+	return []rpc.InterfaceDesc{{
+		Name: "Hedge",
+		Methods: []rpc.MethodDesc{
+			{Name: "Get", Tags: []*vdl.Value{vdl.ValueOf(access.Read)}},
+			{Name: "Put", Tags: []*vdl.Value{vdl.ValueOf(access.Write)}},
+		},
+	}}
+}
+
+// writeHedgeServer is a hedgeServer whose Get method is tagged as
+// access.Write. It is a distinct type since the server caches the
+// description of each type.
+type writeHedgeServer struct {
+	*hedgeServer
+}
+
+func (s writeHedgeServer) Describe__() []rpc.InterfaceDesc {
+	return []rpc.InterfaceDesc{{
+		Name: "Hedge",
+		Methods: []rpc.MethodDesc{
+			{Name: "Get", Tags: []*vdl.Value{vdl.ValueOf(access.Write)}},
+			{Name: "Put", Tags: []*vdl.Value{vdl.ValueOf(access.Write)}},
+		},
+	}}
+}
+
+func startHedgeServers(t *testing.T, ctx *context.T, newServer func(*hedgeServer) interface{}, gate *hedgeGate) *naming.MountEntry {
+	entry := &naming.MountEntry{}
+	for _, id := range []string{"a", "b"} {
+		_, server, err := v23.WithNewServer(ctx, "", newServer(&hedgeServer{id: id, gate: gate}), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.WaitForServerReady(server)
+		entry.Servers = append(entry.Servers, naming.MountedServer{
+			Server: server.Status().Endpoints[0].Name(),
+		})
+	}
+	return entry
+}
+
+// expectNoHedge fails the test if a second server is called before the
+// hedging delay has passed several times over.
+func expectNoHedge(t *testing.T, gate *hedgeGate, hedge options.HedgedRequest) {
+	<-gate.calls
+	select {
+	case id := <-gate.calls:
+		t.Errorf("unexpected hedged request to %v", id)
+	case <-time.After(10 * hedge.Delay):
+	}
+}
+
+func TestHedgedRequest(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	gate := newHedgeGate()
+	defer gate.releaseAll()
+	entry := startHedgeServers(t, ctx, func(s *hedgeServer) interface{} { return s }, gate)
+	client := v23.GetClient(ctx)
+	hedge := options.HedgedRequest{Percentile: 95, Delay: 20 * time.Millisecond}
+
+	// Get is tagged as access.Read and so the hedged request to the second
+	// server wins while the first server is blocked.
+	var id string
+	if err := client.Call(ctx, "", "Get", nil, []interface{}{&id}, hedge, options.Preresolved{Resolution: entry}); err != nil {
+		t.Fatal(err)
+	}
+	primary, hedged := <-gate.calls, <-gate.calls
+	if primary == hedged {
+		t.Errorf("both requests were sent to %v", primary)
+	}
+	if got, want := id, hedged; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Put is not tagged as access.Read and so is not hedged even though
+	// it takes longer than the hedging delay.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- client.Call(ctx, "", "Put", nil, nil, hedge, options.Preresolved{Resolution: entry})
+	}()
+	expectNoHedge(t, gate, hedge)
+	gate.releaseAll()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// Another service, also called via an empty preresolved name, does not
+	// share the state recorded for the first. Its Get method is not tagged
+	// as access.Read and so is not hedged.
+	gate = newHedgeGate()
+	defer gate.releaseAll()
+	entry = startHedgeServers(t, ctx, func(s *hedgeServer) interface{} { return writeHedgeServer{s} }, gate)
+	go func() {
+		errCh <- client.Call(ctx, "", "Get", nil, []interface{}{&id}, hedge, options.Preresolved{Resolution: entry})
+	}()
+	expectNoHedge(t, gate, hedge)
+	gate.releaseAll()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/verror"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type interceptLog struct {
+	sync.Mutex
+	entries []string
+}
+
+func (l *interceptLog) add(format string, args ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	l.entries = append(l.entries, fmt.Sprintf(format, args...))
+}
+
+func (l *interceptLog) reset() []string {
+	l.Lock()
+	defer l.Unlock()
+	r := l.entries
+	l.entries = nil
+	return r
+}
+
+func unaryLogger(l *interceptLog, id string) rpc.UnaryServerInterceptor {
+	return func(ctx *context.T, call rpc.StreamServerCall, args []interface{}, handler rpc.ServerHandler) ([]interface{}, error) {
+		l.add("%s:unary:%s:%v", id, call.Security().Method(), len(args))
+		return handler(ctx, call, args)
+	}
+}
+
+type countingStream struct {
+	rpc.Stream
+	l *interceptLog
+}
+
+func (c *countingStream) Recv(itemptr interface{}) error {
+	err := c.Stream.Recv(itemptr)
+	if err == nil {
+		c.l.add("recv")
+	}
+	return err
+}
+
+func TestServerInterceptors(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	log := &interceptLog{}
+	errDenied := verror.NewID("interceptor_test.denied")
+	deny := func(ctx *context.T, call rpc.StreamServerCall, args []interface{}, handler rpc.ServerHandler) ([]interface{}, error) {
+		if call.Security().Method() == "Echo" && *args[0].(*string) == "deny" {
+			return nil, errDenied.Errorf(ctx, "denied")
+		}
+		return handler(ctx, call, args)
+	}
+	stream := func(ctx *context.T, call rpc.ServerCall, stream rpc.Stream, args []interface{}, handler rpc.StreamServerHandler) ([]interface{}, error) {
+		log.add("stream:%s", call.Security().Method())
+		return handler(ctx, call, &countingStream{stream, log}, args)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	_, server, err := v23.WithNewServer(ctx, "", &simple{done}, nil,
+		options.ServerInterceptors{Unary: []rpc.UnaryServerInterceptor{unaryLogger(log, "a")}},
+		options.ServerInterceptors{
+			Unary:  []rpc.UnaryServerInterceptor{unaryLogger(log, "b"), deny},
+			Stream: []rpc.StreamServerInterceptor{stream},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name := server.Status().Endpoints[0].Name()
+	client := v23.GetClient(ctx)
+
+	var got string
+	if err := client.Call(ctx, name, "PingWithArgs", []interface{}{"x", "y", "z"}, []interface{}{&got}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.reset(), []string{"a:unary:PingWithArgs:3", "b:unary:PingWithArgs:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if err := client.Call(ctx, name, "Echo", []interface{}{"deny"}, []interface{}{&got}); !errors.Is(err, errDenied) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	log.reset()
+
+	call, err := client.StartCall(ctx, name, "Sink", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := call.Send(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var result int
+	if err := call.Finish(&result); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.reset(), []string{"stream:Sink", "recv", "recv", "recv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// Interceptors must not see calls that fail authorization.
+	_, server, err = v23.WithNewServer(ctx, "", &simple{done}, denyAllAuthorizer{},
+		options.ServerInterceptors{Unary: []rpc.UnaryServerInterceptor{unaryLogger(log, "a")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name = server.Status().Endpoints[0].Name()
+	if err := client.Call(ctx, name, "Echo", []interface{}{"hi"}, []interface{}{&got}); !errors.Is(err, verror.ErrNoAccess) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got := log.reset(); len(got) != 0 {
+		t.Errorf("interceptors invoked for an unauthorized call: %v", got)
+	}
+}
+
+type finishLogger struct {
+	rpc.ClientCall
+	l      *interceptLog
+	method string
+}
+
+func (f *finishLogger) Finish(resultptrs ...interface{}) error {
+	err := f.ClientCall.Finish(resultptrs...)
+	f.l.add("finish:%s:%v", f.method, err)
+	return err
+}
+
+func TestClientInterceptors(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+	name, fn := startSimpleServer(t, ctx)
+	defer fn()
+
+	log := &interceptLog{}
+	unary := func(id string) rpc.UnaryClientInterceptor {
+		return func(ctx *context.T, name, method string, inArgs, outArgs []interface{}, invoker rpc.ClientInvoker, opts ...rpc.CallOpt) error {
+			log.add("%s:unary:%s:%v", id, method, inArgs)
+			return invoker(ctx, name, method, inArgs, outArgs, opts...)
+		}
+	}
+	stream := func(ctx *context.T, name, method string, args []interface{}, starter rpc.ClientStarter, opts ...rpc.CallOpt) (rpc.ClientCall, error) {
+		log.add("stream:%s", method)
+		call, err := starter(ctx, name, method, args, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &finishLogger{call, log, method}, nil
+	}
+	ctx, client, err := v23.WithNewClient(ctx, options.ClientInterceptors{
+		Unary:  []rpc.UnaryClientInterceptor{unary("a"), unary("b")},
+		Stream: []rpc.StreamClientInterceptor{stream},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got string
+	if err := client.Call(ctx, name, "Echo", []interface{}{"hi"}, []interface{}{&got}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.reset(), []string{"a:unary:Echo:[hi]", "b:unary:Echo:[hi]"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	call, err := client.StartCall(ctx, name, "Ping", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := call.Finish(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := log.reset(), []string{"stream:Ping", "finish:Ping:<nil>"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
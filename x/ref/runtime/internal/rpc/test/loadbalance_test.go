@@ -0,0 +1,57 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/x/ref/lib/loadbalance"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type idServer string
+
+func (s idServer) ID(*context.T, rpc.ServerCall) (string, error) {
+	return string(s), nil
+}
+
+func TestRoundRobinLoadBalancer(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	entry := &naming.MountEntry{}
+	for _, id := range []string{"a", "b", "c"} {
+		_, server, err := v23.WithNewServer(ctx, "", idServer(id), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testutil.WaitForServerReady(server)
+		entry.Servers = append(entry.Servers, naming.MountedServer{
+			Server: server.Status().Endpoints[0].Name(),
+		})
+	}
+	ctx, client, err := v23.WithNewClient(ctx, options.LoadBalancer{LoadBalancer: loadbalance.RoundRobin()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 6; i++ {
+		var id string
+		if err := client.Call(ctx, "", "ID", nil, []interface{}{&id}, options.Preresolved{Resolution: entry}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if got, want := ids, []string{"a", "b", "c", "a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
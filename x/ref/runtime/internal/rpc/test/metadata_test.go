@@ -0,0 +1,103 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"reflect"
+	"testing"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/rpc"
+	"v.io/v23/rpc/version"
+	iversion "v.io/x/ref/runtime/internal/rpc/version"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type metadataServer struct{}
+
+func (metadataServer) Get(_ *context.T, call rpc.ServerCall, key string) (string, error) {
+	call.SetResponseMetadata(map[string]string{"echo": key})
+	return call.Metadata()[key], nil
+}
+
+func TestRequestMetadata(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	_, server, err := v23.WithNewServer(ctx, "", metadataServer{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name := server.Status().Endpoints[0].Name()
+	client := v23.GetClient(ctx)
+
+	get := func(ctx *context.T, key string) string {
+		call, err := client.StartCall(ctx, name, "Get", []interface{}{key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var value string
+		if err := call.Finish(&value); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := call.ResponseMetadata(), map[string]string{"echo": key}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		return value
+	}
+
+	if got, want := get(ctx, "tenant"), ""; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	mctx := rpc.WithOutgoingMetadata(ctx, map[string]string{"tenant": "a", "request": "1"})
+	mctx = rpc.WithOutgoingMetadata(mctx, map[string]string{"tenant": "b"})
+	if got, want := get(mctx, "tenant"), "b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := get(mctx, "request"), "1"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := rpc.OutgoingMetadata(ctx), map[string]string(nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRequestMetadataOldVersion(t *testing.T) {
+	// Metadata is neither sent nor returned over conns that negotiate an
+	// RPC version that predates it.
+	defer func(supported version.RPCVersionRange) {
+		iversion.Supported = supported
+	}(iversion.Supported)
+	iversion.Supported.Max = version.RPCVersion14
+
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	_, server, err := v23.WithNewServer(ctx, "", metadataServer{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	name := server.Status().Endpoints[0].Name()
+
+	ctx = rpc.WithOutgoingMetadata(ctx, map[string]string{"tenant": "a"})
+	call, err := v23.GetClient(ctx).StartCall(ctx, name, "Get", []interface{}{"tenant"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value string
+	if err := call.Finish(&value); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := value, ""; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := call.ResponseMetadata(); len(got) != 0 {
+		t.Errorf("got %v, want no metadata", got)
+	}
+}
@@ -5,15 +5,20 @@
 package test
 
 import (
+	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	v23 "v.io/v23"
 	"v.io/v23/context"
+	"v.io/v23/naming"
 	"v.io/v23/options"
 	"v.io/v23/rpc"
 	"v.io/v23/security"
 	"v.io/v23/verror"
 	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
 )
 
 var errRetryThis = verror.NewIDAction("retry_test.retryThis", verror.RetryBackoff)
@@ -78,3 +83,101 @@ func TestRetryCall(t *testing.T) {
 		t.Errorf("retryServer have been called once, instead called %d times", rs.called)
 	}
 }
+
+var errFlaky = verror.NewID("flaky")
+
+type flakyServer struct {
+	calls int32
+}
+
+func (s *flakyServer) Get(ctx *context.T, _ rpc.ServerCall) error {
+	atomic.AddInt32(&s.calls, 1)
+	return errFlaky.Errorf(ctx, "flaky")
+}
+
+func (s *flakyServer) Put(ctx *context.T, _ rpc.ServerCall) error {
+	atomic.AddInt32(&s.calls, 1)
+	return errFlaky.Errorf(ctx, "flaky")
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	s := &flakyServer{}
+	_, server, err := v23.WithNewServer(ctx, "", s, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(server)
+	entry := &naming.MountEntry{
+		Servers: []naming.MountedServer{{Server: server.Status().Endpoints[0].Name()}},
+	}
+	ctx, client, err := v23.WithNewClient(ctx, options.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		IDs:            []verror.ID{errFlaky.ID},
+		Methods: map[string]options.RetryPolicy{
+			"Put": {MaxAttempts: 2, InitialBackoff: time.Millisecond, IDs: []verror.ID{errFlaky.ID}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		method string
+		opts   []rpc.CallOpt
+		calls  int32
+	}{
+		// The client's policy.
+		{"Get", nil, 3},
+		// A per-method override.
+		{"Put", nil, 2},
+		// A policy for a single call, the default policy does not retry
+		// errors with the NoRetry action.
+		{"Get", []rpc.CallOpt{options.RetryPolicy{}}, 1},
+		{"Get", []rpc.CallOpt{options.RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond, Actions: []verror.ActionCode{verror.NoRetry}}}, 4},
+		{"Get", []rpc.CallOpt{options.NoRetry{}}, 1},
+	} {
+		atomic.StoreInt32(&s.calls, 0)
+		opts := append(tc.opts, options.Preresolved{Resolution: entry})
+		if err := client.Call(ctx, "", tc.method, nil, nil, opts...); !errors.Is(err, errFlaky) {
+			t.Errorf("%v: unexpected error: %v", i, err)
+		}
+		if got, want := atomic.LoadInt32(&s.calls), tc.calls; got != want {
+			t.Errorf("%v: got %v calls, want %v", i, got, want)
+		}
+	}
+}
+
+func TestRetryPolicyConnect(t *testing.T) {
+	ctx, shutdown := test.V23InitWithMounttable()
+	defer shutdown()
+
+	// The policy only lists the ID of the error returned by the server, but
+	// the failure to resolve the name before the server is mounted is still
+	// retried. Those retries count towards MaxAttempts, so once the server
+	// has been reached the call is only attempted twice.
+	ctx, client, err := v23.WithNewClient(ctx, options.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		IDs:            []verror.ID{errFlaky.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &flakyServer{}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if _, _, err := v23.WithNewServer(ctx, "flaky", s, nil); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err := client.Call(ctx, "flaky", "Get", nil, nil); !errors.Is(err, errFlaky) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got, want := atomic.LoadInt32(&s.calls), int32(2); got != want {
+		t.Errorf("got %v calls, want %v", got, want)
+	}
+}
@@ -134,7 +134,7 @@ func TestSignature(t *testing.T) {
 	if got, want := sig[1].Name, "__Reserved"; got != want {
 		t.Errorf("got sig[1].Name %q, want %q", got, want)
 	}
-	if got, want := signature.MethodNames(sig[1:2]), []string{"__Glob", "__MethodSignature", "__Signature"}; !reflect.DeepEqual(got, want) {
+	if got, want := signature.MethodNames(sig[1:2]), []string{"__Glob", "__Health", "__MethodSignature", "__Signature", "__WatchHealth"}; !reflect.DeepEqual(got, want) {
 		t.Fatalf("got sig[1] methods %v, want %v", got, want)
 	}
 }
@@ -19,7 +19,7 @@ import (
 //
 // Min is incremented whenever we want to remove support for old protocol
 // versions.
-var Supported = version.RPCVersionRange{Min: version.RPCVersion10, Max: version.RPCVersion14}
+var Supported = version.RPCVersionRange{Min: version.RPCVersion10, Max: version.RPCVersion15}
 
 func init() {
 	metadata.Insert("v23.RPCVersionMax", fmt.Sprint(Supported.Max))
@@ -37,6 +37,7 @@ import (
 	"v.io/x/ref/runtime/internal/lib/dependency"
 	inamespace "v.io/x/ref/runtime/internal/naming/namespace"
 	irpc "v.io/x/ref/runtime/internal/rpc"
+	"v.io/x/ref/runtime/protocols/lib/tcputil"
 )
 
 type clientKey struct{}
@@ -62,6 +63,9 @@ type initData struct {
 	protocols         []string
 	settingsPublisher *pubsub.Publisher
 	connIdleExpiry    time.Duration
+	connResumeTimeout time.Duration
+	connCacheLimit    manager.CacheLimit
+	connOpts          manager.ConnOptions
 }
 
 type vtraceDependency struct{}
@@ -85,7 +89,10 @@ func Init(
 	flags flags.RuntimeFlags,
 	reservedDispatcher rpc.Dispatcher,
 	permissionsSpec *access.PermissionsSpec,
-	connIdleExpiry time.Duration) (*Runtime, *context.T, v23.Shutdown, error) {
+	connIdleExpiry time.Duration,
+	connResumeTimeout time.Duration,
+	connCacheLimit manager.CacheLimit,
+	connOpts manager.ConnOptions) (*Runtime, *context.T, v23.Shutdown, error) {
 	r := &Runtime{deps: dependency.NewGraph()}
 
 	r.flags = flags
@@ -95,6 +102,9 @@ func Init(
 		protocols:         protocols,
 		settingsPublisher: settingsPublisher,
 		connIdleExpiry:    connIdleExpiry,
+		connResumeTimeout: connResumeTimeout,
+		connCacheLimit:    connCacheLimit,
+		connOpts:          connOpts,
 	})
 
 	if listenSpec != nil {
@@ -125,6 +135,11 @@ func Init(
 		ctx.VI(1).Infof("Root span name: %v", rsn)
 	}
 	ctx, _ = vtrace.WithNewTrace(ctx, flags.VtraceFlags.RootSpanName, nil)
+
+	ctx, err = withDialProxy(ctx, flags)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 	err = r.addChild(ctx, vtraceDependency{}, func() {
 		vtrace.FormatTraces(os.Stderr, vtrace.GetStore(ctx).TraceRecords(), nil)
 	})
@@ -191,6 +206,29 @@ func (r *Runtime) shutdown() {
 	r.ctx.FlushLog()
 }
 
+// withDialProxy configures ctx to use the egress proxy specified by
+// --v23.dial.proxy. An invalid proxy that was specified on the command line
+// is an error, whereas one obtained from the environment, which is shared
+// with other programs that may support different proxy URLs, is logged and
+// ignored.
+func withDialProxy(ctx *context.T, rf flags.RuntimeFlags) (*context.T, error) {
+	if len(rf.DialProxy) == 0 {
+		return ctx, nil
+	}
+	pctx, err := tcputil.WithProxyConfig(ctx, tcputil.ProxyConfig{
+		URL:     rf.DialProxy,
+		NoProxy: rf.DialNoProxy,
+	})
+	if err != nil {
+		if envProxy, _ := flags.DefaultDialProxy(); rf.DialProxy == envProxy {
+			ctx.Errorf("ignoring the proxy specified by the environment: %v", err)
+			return ctx, nil
+		}
+		return nil, err
+	}
+	return pctx, nil
+}
+
 func (r *Runtime) initSignalHandling(ctx *context.T) {
 	// TODO(caprita): Given that our device manager implementation is to
 	// kill all child apps when the device manager dies, we should
@@ -283,13 +321,19 @@ func getInitData(ctx *context.T) (*initData, error) {
 
 func (r *Runtime) WithNewClient(ctx *context.T, opts ...rpc.ClientOpt) (*context.T, rpc.Client, error) {
 	otherOpts := []rpc.ClientOpt{}
-	hasProtocol, hasExpiration := false, false
+	hasProtocol, hasExpiration, hasResumption, hasCacheLimit, hasConnOpts := false, false, false, false, false
 	for _, o := range opts {
 		switch o.(type) {
 		case irpc.PreferredProtocols:
 			hasProtocol = true
 		case irpc.IdleConnectionExpiry:
 			hasExpiration = true
+		case irpc.ConnectionResumptionTimeout:
+			hasResumption = true
+		case irpc.ConnectionCacheLimit:
+			hasCacheLimit = true
+		case irpc.ConnectionOptions:
+			hasConnOpts = true
 		}
 	}
 	id, err := getInitData(ctx)
@@ -309,6 +353,15 @@ func (r *Runtime) WithNewClient(ctx *context.T, opts ...rpc.ClientOpt) (*context
 	if !hasExpiration && id.connIdleExpiry > 0 {
 		otherOpts = append(otherOpts, irpc.IdleConnectionExpiry(id.connIdleExpiry))
 	}
+	if !hasResumption && id.connResumeTimeout > 0 {
+		otherOpts = append(otherOpts, irpc.ConnectionResumptionTimeout(id.connResumeTimeout))
+	}
+	if !hasCacheLimit && id.connCacheLimit.MaxConns > 0 {
+		otherOpts = append(otherOpts, irpc.ConnectionCacheLimit(id.connCacheLimit))
+	}
+	if !hasConnOpts {
+		otherOpts = append(otherOpts, irpc.ConnectionOptions(id.connOpts))
+	}
 	otherOpts = append(otherOpts, opts...)
 	deps := []interface{}{vtraceDependency{}}
 	client := irpc.NewClient(ctx, otherOpts...)
@@ -439,7 +492,7 @@ func (r *Runtime) NewFlowManager(ctx *context.T, channelTimeout time.Duration) (
 	if err != nil {
 		return nil, err
 	}
-	return manager.New(ctx, rid, id.settingsPublisher, channelTimeout, id.connIdleExpiry, nil), nil
+	return manager.New(ctx, rid, id.settingsPublisher, channelTimeout, id.connIdleExpiry, id.connResumeTimeout, id.connCacheLimit, id.connOpts, nil), nil
 }
 
 func (r *Runtime) commonServerInit(ctx *context.T, opts ...rpc.ServerOpt) (*pubsub.Publisher, []rpc.ServerOpt, error) {
@@ -459,6 +512,13 @@ func (r *Runtime) commonServerInit(ctx *context.T, opts ...rpc.ServerOpt) (*pubs
 	if id.connIdleExpiry > 0 {
 		otherOpts = append(otherOpts, irpc.IdleConnectionExpiry(id.connIdleExpiry))
 	}
+	if id.connResumeTimeout > 0 {
+		otherOpts = append(otherOpts, irpc.ConnectionResumptionTimeout(id.connResumeTimeout))
+	}
+	if id.connCacheLimit.MaxConns > 0 {
+		otherOpts = append(otherOpts, irpc.ConnectionCacheLimit(id.connCacheLimit))
+	}
+	otherOpts = append(otherOpts, irpc.ConnectionOptions(id.connOpts))
 	return id.settingsPublisher, otherOpts, nil
 }
 
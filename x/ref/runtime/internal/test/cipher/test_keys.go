@@ -39,6 +39,14 @@ type API interface {
 
 	// Overhead is the max difference between the plaintext and ciphertext sizes.
 	Overhead() int
+
+	// RekeySeal replaces the key used by Seal with one derived from the
+	// current key and the supplied salt.
+	RekeySeal(salt []byte) error
+
+	// RekeyOpen replaces the key used by Open with one derived from the
+	// current key and the supplied salt.
+	RekeyOpen(salt []byte) error
 }
 
 func NewRPC11Keys() (pk1, sk1, pk2, sk2 *[32]byte, err error) {
@@ -69,7 +77,7 @@ func NewRPC11Ciphers() (c1, c2 API, err error) {
 	return
 }
 
-func NewRPC15Keys() (pk1, sk1, pk2, sk2 *[32]byte, err error) {
+func NewRPC16Keys() (pk1, sk1, pk2, sk2 *[32]byte, err error) {
 	pk1, sk1, err = cipher.GenerateKey(rand.Reader)
 	if err != nil {
 		return nil, nil, nil, nil, fmt.Errorf("can't generate key")
@@ -81,8 +89,8 @@ func NewRPC15Keys() (pk1, sk1, pk2, sk2 *[32]byte, err error) {
 	return
 }
 
-func NewRPC15Ciphers() (c1, c2 API, err error) {
-	pk1, sk1, pk2, sk2, err := NewRPC15Keys()
+func NewRPC16Ciphers() (c1, c2 API, err error) {
+	pk1, sk1, pk2, sk2, err := NewRPC16Keys()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create keys: %v", err)
 	}
@@ -151,6 +151,10 @@ func (ns *namespaceMock) Glob(ctx *context.T, pattern string, opts ...naming.Nam
 	panic("Glob not implemented")
 }
 
+func (ns *namespaceMock) Watch(ctx *context.T, pattern string, opts ...naming.NamespaceOpt) (<-chan namespace.Change, error) {
+	panic("Watch not implemented")
+}
+
 func (ns *namespaceMock) SetRoots(args ...string) error {
 	if len(args) > 0 {
 		panic("Calling SetRoots with arguments on a mock namespace.  This is not supported.")
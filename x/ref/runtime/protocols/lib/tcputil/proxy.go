@@ -0,0 +1,249 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tcputil
+
+import (
+	"bufio"
+	gocontext "context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+
+	"v.io/v23/context"
+)
+
+// ProxyConfig specifies an egress proxy to be used for dialing outbound
+// connections.
+type ProxyConfig struct {
+	// URL is the URL of the proxy, it must be of the form
+	// socks5://[user:password@]host:port for a SOCKS5 proxy or
+	// http://[user:password@]host:port for an HTTP proxy that supports the
+	// CONNECT method. The socks5h scheme is accepted as a synonym for
+	// socks5, since host names are always resolved by the proxy, and the
+	// https scheme selects an HTTP proxy that is connected to using TLS.
+	// As for the ALL_PROXY and HTTPS_PROXY environment variables, a URL
+	// without a scheme, eg. proxy.example.com:3128, is treated as an HTTP
+	// proxy.
+	URL string
+
+	// NoProxy is a comma separated list of the destinations that should be
+	// dialed directly rather than via the proxy. It uses the same format as
+	// the NO_PROXY environment variable, ie. each entry is an IP address,
+	// a CIDR range, a domain name that also matches its subdomains, or '*'
+	// to match all destinations, each optionally followed by a port.
+	// Connections to localhost and loopback addresses are always dialed
+	// directly.
+	NoProxy string
+}
+
+type proxyKey struct{}
+
+type proxyDialer struct {
+	proxyFor func(*url.URL) (*url.URL, error)
+}
+
+// WithProxyConfig returns a context that will cause connections dialed using
+// it by the tcp based protocols to be dialed via the proxy specified by
+// config. The proxy is disabled if config.URL is empty.
+func WithProxyConfig(ctx *context.T, config ProxyConfig) (*context.T, error) {
+	if len(config.URL) == 0 {
+		return context.WithValue(ctx, proxyKey{}, (*proxyDialer)(nil)), nil
+	}
+	u, err := parseProxyURL(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	pc := &httpproxy.Config{HTTPSProxy: u.String(), NoProxy: config.NoProxy}
+	return context.WithValue(ctx, proxyKey{}, &proxyDialer{proxyFor: pc.ProxyFunc()}), nil
+}
+
+// proxyPorts are the ports used for proxy URLs that do not specify one.
+var proxyPorts = map[string]string{
+	"http":   "80",
+	"https":  "443",
+	"socks5": "1080",
+}
+
+// parseProxyURL parses a proxy URL, treating one without a scheme as an
+// HTTP proxy and socks5h as socks5.
+func parseProxyURL(s string) (*url.URL, error) {
+	raw := s
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %v", s, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	case "socks5h":
+		u.Scheme = "socks5"
+	default:
+		return nil, fmt.Errorf("unsupported proxy URL %q: the scheme must be socks5, socks5h, http or https", s)
+	}
+	if len(u.Host) == 0 {
+		return nil, fmt.Errorf("invalid proxy URL %q: no host specified", s)
+	}
+	if len(u.Port()) == 0 {
+		u.Host = net.JoinHostPort(u.Hostname(), proxyPorts[u.Scheme])
+	}
+	return u, nil
+}
+
+// ProxyFor returns the URL of the proxy that will be used to dial address
+// using ctx, or nil if it will be dialed directly.
+func ProxyFor(ctx *context.T, address string) (*url.URL, error) {
+	pd, _ := ctx.Value(proxyKey{}).(*proxyDialer)
+	if pd == nil {
+		return nil, nil
+	}
+	// The https scheme is used to select the HTTPSProxy configured above.
+	return pd.proxyFor(&url.URL{Scheme: "https", Host: address})
+}
+
+// DialTimeout dials address either directly or via the proxy configured for
+// ctx using WithProxyConfig. TCP keep alives are enabled for the connection
+// to the destination, or to the proxy, using the period returned by
+// KeepAlivePeriod.
+func DialTimeout(ctx *context.T, network, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: KeepAlivePeriod(ctx),
+	}
+	proxyURL, err := ProxyFor(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL == nil {
+		return dialer.Dial(network, address)
+	}
+	dctx := gocontext.Context(ctx)
+	if timeout > 0 {
+		var cancel gocontext.CancelFunc
+		dctx, cancel = gocontext.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	ctx.VI(2).Infof("dialing %v %v via proxy %v", network, address, proxyURL.Redacted())
+	// The proxy would otherwise be free to connect to a destination host
+	// name using either address family.
+	address, err = resolveForNetwork(dctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "socks5":
+		return dialSOCKS5(dctx, dialer, proxyURL, network, address)
+	case "http", "https":
+		return dialHTTPConnect(dctx, dialer, proxyURL, address)
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+}
+
+// resolveForNetwork returns address with its host resolved to an IP address
+// of the family required by network if it is tcp4 or tcp6, and unchanged
+// otherwise.
+func resolveForNetwork(ctx gocontext.Context, network, address string) (string, error) {
+	var ipNetwork string
+	switch network {
+	case "tcp4":
+		ipNetwork = "ip4"
+	case "tcp6":
+		ipNetwork = "ip6"
+	default:
+		return address, nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	ips, err := net.DefaultResolver.LookupIP(ctx, ipNetwork, host)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ips[0].String(), port), nil
+}
+
+func dialSOCKS5(ctx gocontext.Context, dialer *net.Dialer, proxyURL *url.URL, network, address string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if u := proxyURL.User; u != nil {
+		auth = &proxy.Auth{User: u.Username()}
+		auth.Password, _ = u.Password()
+	}
+	d, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialer)
+	if err != nil {
+		return nil, err
+	}
+	return d.(proxy.ContextDialer).DialContext(ctx, network, address)
+}
+
+func dialHTTPConnect(ctx gocontext.Context, dialer *net.Dialer, proxyURL *url.URL, address string) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(dl) //nolint:errcheck
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if u := proxyURL.User; u != nil {
+		password, _ := u.Password()
+		creds := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+creds)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	// The response body is not read or closed since, for a successful
+	// response, it is the tunnelled connection.
+	resp, err := http.ReadResponse(br, req) //nolint:bodyclose
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %v refused to connect to %v: %v", proxyURL.Host, address, resp.Status)
+	}
+	conn.SetDeadline(time.Time{}) //nolint:errcheck
+	if br.Buffered() > 0 {
+		// The proxy has already forwarded data from the destination.
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn whose reads are first satisfied from r.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
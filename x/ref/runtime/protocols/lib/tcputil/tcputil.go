@@ -29,8 +29,14 @@ const keepAlivePeriod = 30 * time.Second
 // dies, falls off the network, or when there is packet loss. So, it is best to
 // enable this option for all TCP connections.
 func EnableTCPKeepAlive(conn net.Conn) error {
+	return EnableTCPKeepAlivePeriod(conn, keepAlivePeriod)
+}
+
+// EnableTCPKeepAlivePeriod is like EnableTCPKeepAlive but uses the specified
+// keep alive period.
+func EnableTCPKeepAlivePeriod(conn net.Conn, period time.Duration) error {
 	if tcpconn, ok := conn.(*net.TCPConn); ok {
-		if err := tcpconn.SetKeepAlivePeriod(keepAlivePeriod); err != nil {
+		if err := tcpconn.SetKeepAlivePeriod(period); err != nil {
 			return err
 		}
 		return tcpconn.SetKeepAlive(true)
@@ -38,17 +44,24 @@ func EnableTCPKeepAlive(conn net.Conn) error {
 	return nil
 }
 
+// KeepAlivePeriod returns the TCP keep alive period to be used for connections
+// dialed or accepted using ctx, as specified via flow.WithKeepalive.
+func KeepAlivePeriod(ctx *context.T) time.Duration {
+	if p := flow.Keepalive(ctx).TCPPeriod; p > 0 {
+		return p
+	}
+	return keepAlivePeriod
+}
+
 type TCP struct{}
 
-// Dial dials a net.Conn to a the specific address and adds framing to the connection.
+// Dial dials a net.Conn to a the specific address, via any proxy configured
+// using WithProxyConfig, and adds framing to the connection.
 func (TCP) Dial(ctx *context.T, network, address string, timeout time.Duration) (flow.Conn, error) {
-	conn, err := net.DialTimeout(network, address, timeout)
+	conn, err := DialTimeout(ctx, network, address, timeout)
 	if err != nil {
 		return nil, err
 	}
-	if err := EnableTCPKeepAlive(conn); err != nil {
-		return nil, err
-	}
 	return NewTCPConn(conn), nil
 }
 
@@ -65,13 +78,14 @@ func (TCP) Listen(ctx *context.T, network, address string) (flow.Listener, error
 	if err != nil {
 		return nil, err
 	}
-	return &tcpListener{ln}, nil
+	return &tcpListener{ln, KeepAlivePeriod(ctx)}, nil
 }
 
 // tcpListener is a wrapper around net.Listener that sets KeepAlive on all
 // accepted connections and returns framed flow.Conns.
 type tcpListener struct {
-	netLn net.Listener
+	netLn           net.Listener
+	keepAlivePeriod time.Duration
 }
 
 func (ln *tcpListener) Accept(ctx *context.T) (flow.Conn, error) {
@@ -79,7 +93,7 @@ func (ln *tcpListener) Accept(ctx *context.T) (flow.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := EnableTCPKeepAlive(conn); err != nil {
+	if err := EnableTCPKeepAlivePeriod(conn, ln.keepAlivePeriod); err != nil {
 		return nil, err
 	}
 	return NewTCPConn(conn), nil
@@ -0,0 +1,166 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tlsutil provides a flow.Protocol that runs over TLS 1.3 sessions
+// layered on TCP connections and the functions used to configure the
+// certificates and server names used by it and by the other TLS based
+// protocols, such as wss.
+//
+// TLS is used purely as a transport, Vanadium's own authentication and
+// encryption are run over the TLS session. Its intended use is for
+// traversing firewalls and other middleboxes that only allow TLS traffic
+// to pass, typically on port 443.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/x/ref/runtime/protocols/lib/tcputil"
+)
+
+var mapTLSToTCP = map[string]string{"tls": "tcp", "tls4": "tcp4", "tls6": "tcp6"}
+
+type configKey struct{}
+
+// WithConfig returns a context that will cause connections dialed and
+// listeners created using it with a TLS based protocol to use config.
+// Listeners require config to contain at least one certificate. For dialed
+// connections, config.ServerName defaults to the host being dialed and is
+// sent as the SNI server name.
+func WithConfig(ctx *context.T, config *tls.Config) *context.T {
+	return context.WithValue(ctx, configKey{}, config)
+}
+
+// Config returns the TLS configuration attached to ctx via WithConfig, or
+// nil if there is none.
+func Config(ctx *context.T) *tls.Config {
+	config, _ := ctx.Value(configKey{}).(*tls.Config)
+	return config
+}
+
+// ClientConfig returns the TLS configuration to be used for dialing address
+// using ctx. It is a copy of the configuration attached to ctx, or of an
+// empty configuration if there is none, that requires TLS 1.3 and whose
+// ServerName defaults to the host portion of address.
+func ClientConfig(ctx *context.T, address string) *tls.Config {
+	config := &tls.Config{}
+	if c := Config(ctx); c != nil {
+		config = c.Clone()
+	}
+	config.MinVersion = tls.VersionTLS13
+	if len(config.ServerName) == 0 {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			host = address
+		}
+		config.ServerName = host
+	}
+	return config
+}
+
+// ServerConfig returns the TLS configuration to be used by listeners
+// created using ctx. It is a copy of the configuration attached to ctx that
+// requires TLS 1.3, an error is returned if there is no configuration or it
+// has no certificates.
+func ServerConfig(ctx *context.T) (*tls.Config, error) {
+	c := Config(ctx)
+	if c == nil || (len(c.Certificates) == 0 && c.GetCertificate == nil && c.GetConfigForClient == nil) {
+		return nil, fmt.Errorf("no TLS certificates have been configured for listening")
+	}
+	config := c.Clone()
+	config.MinVersion = tls.VersionTLS13
+	return config, nil
+}
+
+// TLS implements flow.Protocol for the tls, tls4 and tls6 protocols.
+type TLS struct{}
+
+// Dial dials address over TCP, via any proxy configured using
+// tcputil.WithProxyConfig, performs a TLS handshake and returns a
+// framed connection over the resulting TLS session.
+func (TLS) Dial(ctx *context.T, protocol, address string, timeout time.Duration) (flow.Conn, error) {
+	conn, err := tcputil.DialTimeout(ctx, mapTLSToTCP[protocol], address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout)) //nolint:errcheck
+	}
+	tlsConn := tls.Client(conn, ClientConfig(ctx, address))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{}) //nolint:errcheck
+	return tcputil.NewTCPConn(tlsConn), nil
+}
+
+// Resolve performs a DNS resolution on the provided address.
+func (TLS) Resolve(ctx *context.T, protocol, address string) (string, []string, error) {
+	addrs, err := tcputil.TCPResolveAddrs(ctx, address)
+	return protocol, addrs, err
+}
+
+// Listen returns a listener whose accepted connections are framed
+// connections over TLS sessions. The certificates used are those
+// configured via WithConfig.
+func (TLS) Listen(ctx *context.T, protocol, address string) (flow.Listener, error) {
+	config, err := ServerConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen(mapTLSToTCP[protocol], address)
+	if err != nil {
+		return nil, err
+	}
+	return &tlsListener{
+		netLn:           ln,
+		config:          config,
+		protocol:        protocol,
+		keepAlivePeriod: tcputil.KeepAlivePeriod(ctx),
+	}, nil
+}
+
+// tlsListener is a wrapper around net.Listener that sets KeepAlive on all
+// accepted connections and returns framed flow.Conns over TLS sessions.
+type tlsListener struct {
+	netLn           net.Listener
+	config          *tls.Config
+	protocol        string
+	keepAlivePeriod time.Duration
+}
+
+// Accept returns the next accepted connection. The TLS handshake is
+// performed lazily on the first read or write of the returned connection
+// so that a slow or malicious client cannot block other connections from
+// being accepted.
+func (ln *tlsListener) Accept(ctx *context.T) (flow.Conn, error) {
+	conn, err := ln.netLn.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if err := tcputil.EnableTCPKeepAlivePeriod(conn, ln.keepAlivePeriod); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tcputil.NewTCPConn(tls.Server(conn, ln.config)), nil
+}
+
+func (ln *tlsListener) Addr() net.Addr {
+	return addr{ln.protocol, ln.netLn.Addr().String()}
+}
+
+func (ln *tlsListener) Close() error {
+	return ln.netLn.Close()
+}
+
+type addr struct{ n, a string }
+
+func (a addr) Network() string { return a.n }
+func (a addr) String() string  { return a.a }
@@ -0,0 +1,144 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tlsutil_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/x/ref/runtime/protocols/lib/tlsutil"
+)
+
+func selfSignedConfig(t *testing.T, host string) (server, client *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	client = &tls.Config{RootCAs: pool}
+	return
+}
+
+func TestTLS(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+
+	serverConfig, clientConfig := selfSignedConfig(t, "example.com")
+	sni := make(chan string, 1)
+	serverConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		sni <- hello.ServerName
+		return nil, nil
+	}
+
+	if _, err := (tlsutil.TLS{}).Listen(ctx, "tls", "127.0.0.1:0"); err == nil {
+		t.Fatalf("expected an error when listening without any certificates")
+	}
+
+	ln, err := tlsutil.TLS{}.Listen(tlsutil.WithConfig(ctx, serverConfig), "tls", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if got, want := ln.Addr().Network(), "tls"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The TLS handshake is performed by the accepted connection's first read.
+	received := make(chan []byte, 1)
+	go func() {
+		c, err := ln.Accept(ctx)
+		if err != nil {
+			t.Error(err)
+			close(received)
+			return
+		}
+		defer c.Close()
+		msg, err := c.ReadMsg()
+		if err != nil {
+			t.Error(err)
+		}
+		received <- msg
+	}()
+
+	// The certificate is for example.com, not the address being dialed, so
+	// the server name must be specified for the handshake to succeed.
+	clientConfig.ServerName = "example.com"
+	dialed, err := tlsutil.TLS{}.Dial(tlsutil.WithConfig(ctx, clientConfig), "tls", ln.Addr().String(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dialed.Close()
+
+	msg := []byte("hello")
+	if _, err := dialed.WriteMsg(msg); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; !bytes.Equal(got, msg) {
+		t.Errorf("got %q, want %q", got, msg)
+	}
+	if got, want := <-sni, "example.com"; got != want {
+		t.Errorf("got SNI %q, want %q", got, want)
+	}
+
+	// Dialing with the wrong server name must fail.
+	go func() {
+		if c, err := ln.Accept(ctx); err == nil {
+			c.ReadMsg() //nolint:errcheck
+			c.Close()
+		}
+	}()
+	clientConfig.ServerName = "example.org"
+	if _, err := (tlsutil.TLS{}).Dial(tlsutil.WithConfig(ctx, clientConfig), "tls", ln.Addr().String(), time.Minute); err == nil {
+		t.Errorf("expected an error when dialing with the wrong server name")
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	config := tlsutil.ClientConfig(ctx, "example.com:443")
+	if got, want := config.ServerName, "example.com"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := config.MinVersion, uint16(tls.VersionTLS13); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	config = tlsutil.ClientConfig(tlsutil.WithConfig(ctx, &tls.Config{ServerName: "front.example.com"}), "example.com:443")
+	if got, want := config.ServerName, "front.example.com"; got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -8,6 +8,7 @@
 package websocket
 
 import (
+	"crypto/tls"
 	"io"
 	"net"
 	"net/http"
@@ -35,9 +36,12 @@ type wsTCPListener struct {
 	netLn   net.Listener     // The underlying listener
 	httpReq sync.WaitGroup   // Number of active HTTP requests
 	hybrid  bool             // true if running in 'hybrid' mode
+	tls     *tls.Config      // non-nil if running over TLS, ie. wss
+
+	keepAlivePeriod time.Duration // TCP keep alive period for accepted connections
 }
 
-func listener(protocol, address string, hybrid bool) (flow.Listener, error) {
+func listener(ctx *context.T, protocol, address string, hybrid bool, tlsConfig *tls.Config) (flow.Listener, error) {
 	netLn, err := net.Listen(mapWebSocketToTCP[protocol], address)
 	if err != nil {
 		return nil, err
@@ -47,6 +51,9 @@ func listener(protocol, address string, hybrid bool) (flow.Listener, error) {
 		httpQ:   make(chan net.Conn),
 		netLn:   netLn,
 		hybrid:  hybrid,
+		tls:     tlsConfig,
+
+		keepAlivePeriod: tcputil.KeepAlivePeriod(ctx),
 	}
 	go ln.netAcceptLoop()
 	httpsrv := http.Server{Handler: ln}
@@ -76,6 +83,9 @@ func (ln *wsTCPListener) Addr() net.Addr {
 	if ln.hybrid {
 		protocol = "wsh"
 	}
+	if ln.tls != nil {
+		protocol = "wss"
+	}
 	return addr{protocol, ln.netLn.Addr().String()}
 }
 
@@ -125,9 +135,13 @@ func (ln *wsTCPListener) netAcceptLoop() {
 			continue
 		}
 		logger.Global().VI(2).Infof("New net.Conn accepted from %s (local address: %s)", conn.RemoteAddr(), conn.LocalAddr())
-		if err := tcputil.EnableTCPKeepAlive(conn); err != nil {
+		if err := tcputil.EnableTCPKeepAlivePeriod(conn, ln.keepAlivePeriod); err != nil {
 			logger.Global().Errorf("Failed to enable TCP keep alive for connection from %s (local address %s): %v", conn.RemoteAddr(), conn.LocalAddr(), err)
 		}
+		if ln.tls != nil {
+			// The TLS handshake is performed by the http server.
+			conn = tls.Server(conn, ln.tls)
+		}
 		classifications.Add(1)
 		go ln.classify(conn, &classifications)
 	}
@@ -8,7 +8,6 @@
 package websocket
 
 import (
-	"net"
 	"net/http"
 	"net/url"
 	"time"
@@ -22,7 +21,7 @@ import (
 )
 
 // TODO(jhahn): Figure out a way for this mapping to be shared.
-var mapWebSocketToTCP = map[string]string{"ws": "tcp", "ws4": "tcp4", "ws6": "tcp6", "wsh": "tcp", "wsh4": "tcp4", "wsh6": "tcp6", "tcp": "tcp", "tcp4": "tcp4", "tcp6": "tcp6"}
+var mapWebSocketToTCP = map[string]string{"ws": "tcp", "ws4": "tcp4", "ws6": "tcp6", "wsh": "tcp", "wsh4": "tcp4", "wsh6": "tcp6", "wss": "tcp", "wss4": "tcp4", "wss6": "tcp6", "tcp": "tcp", "tcp4": "tcp4", "tcp6": "tcp6"}
 
 const bufferSize = 4096
 
@@ -34,14 +33,11 @@ func (WS) Dial(ctx *context.T, protocol, address string, timeout time.Duration)
 		deadline = time.Now().Add(timeout)
 	}
 	tcp := mapWebSocketToTCP[protocol]
-	conn, err := net.DialTimeout(tcp, address, timeout)
+	conn, err := tcputil.DialTimeout(ctx, tcp, address, timeout)
 	if err != nil {
 		return nil, err
 	}
 	conn.SetReadDeadline(deadline) //nolint:errcheck
-	if err := tcputil.EnableTCPKeepAlive(conn); err != nil {
-		return nil, err
-	}
 	u, err := url.Parse("ws://" + address)
 	if err != nil {
 		return nil, err
@@ -63,5 +59,5 @@ func (WS) Resolve(ctx *context.T, protocol, address string) (string, []string, e
 }
 
 func (WS) Listen(ctx *context.T, protocol, address string) (flow.Listener, error) {
-	return listener(protocol, address, false)
+	return listener(ctx, protocol, address, false, nil)
 }
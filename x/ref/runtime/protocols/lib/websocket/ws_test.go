@@ -6,13 +6,20 @@ package websocket_test
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"math/big"
+	"net"
 	"sync"
 	"testing"
 	"time"
 
 	"v.io/x/ref/runtime/protocols/lib/tcputil"
+	"v.io/x/ref/runtime/protocols/lib/tlsutil"
 	websocket "v.io/x/ref/runtime/protocols/lib/websocket"
 
 	"v.io/v23/context"
@@ -35,6 +42,47 @@ func TestTCPToWSH(t *testing.T) {
 	runTest(t, tcputil.TCP{}, websocket.WSH{}, "tcp", "wsh")
 }
 
+func TestWSSToWSS(t *testing.T) {
+	ctx, cancel := context.RootContext()
+	defer cancel()
+	if _, err := (websocket.WSS{}).Listen(ctx, "wss", "127.0.0.1:0"); err == nil {
+		t.Fatalf("expected an error when listening without any certificates")
+	}
+	ctx = tlsutil.WithConfig(ctx, selfSignedConfig(t))
+	runTestWithContext(ctx, t, websocket.WSS{}, websocket.WSS{}, "wss", "wss")
+}
+
+// selfSignedConfig returns a TLS config containing a self-signed certificate
+// for 127.0.0.1 that is also trusted by the config.
+func selfSignedConfig(t *testing.T) *tls.Config {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		RootCAs:      pool,
+	}
+}
+
 var randData []byte
 
 const (
@@ -52,6 +100,10 @@ func init() {
 func runTest(t *testing.T, dialObj, listenObj flow.Protocol, dialP, listenP string) {
 	ctx, cancel := context.RootContext()
 	defer cancel()
+	runTestWithContext(ctx, t, dialObj, listenObj, dialP, listenP)
+}
+
+func runTestWithContext(ctx *context.T, t *testing.T, dialObj, listenObj flow.Protocol, dialP, listenP string) {
 	address := "127.0.0.1:0"
 	timeout := 5 * time.Second
 	acceptCh := make(chan flow.Conn)
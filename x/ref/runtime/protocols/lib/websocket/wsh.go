@@ -8,7 +8,6 @@
 package websocket
 
 import (
-	"net"
 	"time"
 
 	"v.io/x/ref/runtime/protocols/lib/tcputil"
@@ -25,13 +24,10 @@ type WSH struct{}
 // 'tcp' as its Network.
 func (WSH) Dial(ctx *context.T, network, address string, timeout time.Duration) (flow.Conn, error) {
 	tcp := mapWebSocketToTCP[network]
-	conn, err := net.DialTimeout(tcp, address, timeout)
+	conn, err := tcputil.DialTimeout(ctx, tcp, address, timeout)
 	if err != nil {
 		return nil, err
 	}
-	if err := tcputil.EnableTCPKeepAlive(conn); err != nil {
-		return nil, err
-	}
 	return tcputil.NewTCPConn(conn), nil
 }
 
@@ -51,5 +47,5 @@ func (WSH) Resolve(ctx *context.T, network, address string) (string, []string, e
 // websockets, all other protocols must guarantee to not send 'GET ' as the
 // first four bytes of the payload.
 func (WSH) Listen(ctx *context.T, protocol, address string) (flow.Listener, error) {
-	return listener(protocol, address, true)
+	return listener(ctx, protocol, address, true, nil)
 }
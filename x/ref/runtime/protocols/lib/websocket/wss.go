@@ -0,0 +1,59 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !nacl
+// +build !nacl
+
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"v.io/x/ref/runtime/protocols/lib/tcputil"
+	"v.io/x/ref/runtime/protocols/lib/tlsutil"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+)
+
+// WSS implements the wss protocol, ie. websockets over TLS 1.3. The
+// certificates and server names used are configured via tlsutil.WithConfig.
+type WSS struct{}
+
+func (WSS) Dial(ctx *context.T, protocol, address string, timeout time.Duration) (flow.Conn, error) {
+	tcp := mapWebSocketToTCP[protocol]
+	dialer := &websocket.Dialer{
+		NetDial: func(_, addr string) (net.Conn, error) {
+			return tcputil.DialTimeout(ctx, tcp, addr, timeout)
+		},
+		TLSClientConfig:  tlsutil.ClientConfig(ctx, address),
+		HandshakeTimeout: timeout,
+		ReadBufferSize:   bufferSize,
+		WriteBufferSize:  bufferSize,
+	}
+	ws, _, err := dialer.DialContext(ctx, "wss://"+address, http.Header{})
+	if err != nil {
+		return nil, err
+	}
+	return WebsocketConn(ws), nil
+}
+
+func (WSS) Resolve(ctx *context.T, protocol, address string) (string, []string, error) {
+	addrs, err := tcputil.TCPResolveAddrs(ctx, address)
+	return "wss", addrs, err
+}
+
+// Listen returns a listener that accepts websocket connections over TLS
+// using the certificates configured via tlsutil.WithConfig.
+func (WSS) Listen(ctx *context.T, protocol, address string) (flow.Listener, error) {
+	config, err := tlsutil.ServerConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listener(ctx, protocol, address, false, config)
+}
@@ -0,0 +1,20 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tls registers the tls, tls4 and tls6 protocols which run over
+// TLS 1.3 sessions layered on TCP. The certificates and server names used
+// are configured via tlsutil.WithConfig.
+package tls
+
+import (
+	"v.io/v23/flow"
+	"v.io/x/ref/runtime/protocols/lib/tlsutil"
+)
+
+func init() {
+	protocol := tlsutil.TLS{}
+	flow.RegisterProtocol("tls", protocol, "tls4", "tls6")
+	flow.RegisterProtocol("tls4", protocol)
+	flow.RegisterProtocol("tls6", protocol)
+}
@@ -0,0 +1,35 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package unix
+
+import (
+	"net"
+	"syscall"
+
+	"v.io/v23/flow"
+)
+
+// peerCredentials returns the credentials of the process at the other end
+// of c, as recorded by the kernel when the connection was established.
+func peerCredentials(c *net.UnixConn) (flow.PeerCredentials, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return flow.PeerCredentials{}, err
+	}
+	var ucred *syscall.Ucred
+	var serr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, serr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return flow.PeerCredentials{}, err
+	}
+	if serr != nil {
+		return flow.PeerCredentials{}, serr
+	}
+	return flow.PeerCredentials{UID: int(ucred.Uid), GID: int(ucred.Gid), PID: int(ucred.Pid)}, nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package unix
+
+import (
+	"fmt"
+	"net"
+	"runtime"
+
+	"v.io/v23/flow"
+)
+
+// peerCredentials is not supported on this operating system.
+func peerCredentials(c *net.UnixConn) (flow.PeerCredentials, error) {
+	return flow.PeerCredentials{}, fmt.Errorf("peer credentials are not supported on %v", runtime.GOOS)
+}
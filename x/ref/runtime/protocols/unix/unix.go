@@ -0,0 +1,93 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package unix registers the unix protocol, which uses unix domain sockets.
+package unix
+
+import (
+	"net"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/flow"
+	"v.io/x/ref/runtime/protocols/lib/framer"
+)
+
+func init() {
+	flow.RegisterProtocol("unix", protocol{})
+}
+
+// protocol uses unix domain sockets, whose addresses are file system paths.
+// The connections it creates implement flow.PeerCredentialer on systems that
+// support obtaining the credentials of the peer process from the kernel.
+type protocol struct{}
+
+func (protocol) Dial(ctx *context.T, network, address string, timeout time.Duration) (flow.Conn, error) {
+	c, err := net.DialTimeout("unix", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newUnixConn(c.(*net.UnixConn)), nil
+}
+
+func (protocol) Resolve(ctx *context.T, network, address string) (string, []string, error) {
+	return network, []string{address}, nil
+}
+
+func (protocol) Listen(ctx *context.T, network, address string) (flow.Listener, error) {
+	ln, err := net.Listen("unix", address)
+	if err != nil {
+		return nil, err
+	}
+	return &unixListener{ln.(*net.UnixListener)}, nil
+}
+
+type unixListener struct {
+	ln *net.UnixListener
+}
+
+func (l *unixListener) Accept(ctx *context.T) (flow.Conn, error) {
+	c, err := l.ln.AcceptUnix()
+	if err != nil {
+		return nil, err
+	}
+	return newUnixConn(c), nil
+}
+
+func (l *unixListener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+func (l *unixListener) Close() error {
+	return l.ln.Close()
+}
+
+type unixConn struct {
+	framer.T
+	localAddr, remoteAddr net.Addr
+	creds                 *flow.PeerCredentials
+}
+
+func newUnixConn(c *net.UnixConn) *unixConn {
+	uc := &unixConn{
+		T:          framer.New(c),
+		localAddr:  c.LocalAddr(),
+		remoteAddr: c.RemoteAddr(),
+	}
+	if creds, err := peerCredentials(c); err == nil {
+		uc.creds = &creds
+	}
+	return uc
+}
+
+func (c *unixConn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *unixConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+// PeerCredentials implements flow.PeerCredentialer.
+func (c *unixConn) PeerCredentials() (flow.PeerCredentials, bool) {
+	if c.creds == nil {
+		return flow.PeerCredentials{}, false
+	}
+	return *c.creds, true
+}
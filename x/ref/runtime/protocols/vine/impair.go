@@ -0,0 +1,164 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vine
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"v.io/v23/flow"
+)
+
+// impairedWriter applies the latency, jitter, bandwidth, message loss and
+// connection reset characteristics of a PeerBehavior to the messages written
+// to a flow.Conn. Messages that are delayed are written, in order, by a
+// goroutine that is started on demand.
+type impairedWriter struct {
+	base flow.Conn
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	queue    []delayedMsg
+	running  bool      // true if the delivery goroutine is running.
+	closing  bool      // true once close has been called.
+	err      error     // the first error encountered by the delivery goroutine.
+	linkFree time.Time // the time at which the emulated link will be idle.
+	last     time.Time // the delivery time of the most recently queued message.
+	done     chan struct{}
+}
+
+type delayedMsg struct {
+	at  time.Time
+	msg []byte
+}
+
+// closeGrace bounds the additional time that close will wait for queued
+// messages to be delivered.
+const closeGrace = time.Second
+
+func newImpairedWriter(base flow.Conn) *impairedWriter {
+	w := &impairedWriter{base: base}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+func (w *impairedWriter) write(behavior PeerBehavior, data ...[]byte) (int, error) {
+	size := 0
+	for _, d := range data {
+		size += len(d)
+	}
+	if p := behavior.ResetProbability; p > 0 && rand.Float64() < p { //nolint:gosec
+		w.base.Close()
+		return 0, ErrorfConnectionReset(nil, "connection reset")
+	}
+	if p := behavior.MessageLoss; p > 0 && rand.Float64() < p { //nolint:gosec
+		return size, nil
+	}
+	now := time.Now()
+	w.mu.Lock()
+	if err := w.err; err != nil {
+		w.mu.Unlock()
+		return 0, err
+	}
+	if w.closing {
+		w.mu.Unlock()
+		return w.base.WriteMsg(data...)
+	}
+	sent := now
+	if behavior.Bandwidth > 0 {
+		// The writer is blocked until the emulated link has transmitted the
+		// message, which provides back pressure.
+		if w.linkFree.After(sent) {
+			sent = w.linkFree
+		}
+		sent = sent.Add(time.Duration(uint64(size) * uint64(time.Second) / behavior.Bandwidth))
+		w.linkFree = sent
+	}
+	delay := behavior.Latency
+	if behavior.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(2*behavior.Jitter+1))) - behavior.Jitter //nolint:gosec
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	at := sent.Add(delay)
+	if at.Before(w.last) {
+		// Messages are never reordered.
+		at = w.last
+	}
+	if !at.After(now) && len(w.queue) == 0 && !w.running {
+		w.mu.Unlock()
+		return w.base.WriteMsg(data...)
+	}
+	msg := make([]byte, 0, size)
+	for _, d := range data {
+		msg = append(msg, d...)
+	}
+	w.last = at
+	w.queue = append(w.queue, delayedMsg{at: at, msg: msg})
+	if !w.running {
+		w.running = true
+		w.done = make(chan struct{})
+		go w.deliver(w.done)
+	}
+	w.cond.Signal()
+	w.mu.Unlock()
+	if d := time.Until(sent); d > 0 {
+		time.Sleep(d)
+	}
+	return size, nil
+}
+
+func (w *impairedWriter) deliver(done chan struct{}) {
+	defer close(done)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for {
+		for len(w.queue) == 0 {
+			if w.closing {
+				w.running = false
+				return
+			}
+			w.cond.Wait()
+		}
+		m := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		if d := time.Until(m.at); d > 0 {
+			time.Sleep(d)
+		}
+		_, err := w.base.WriteMsg(m.msg)
+		w.mu.Lock()
+		if err != nil {
+			if w.err == nil {
+				w.err = err
+			}
+			w.queue = nil
+			w.running = false
+			return
+		}
+	}
+}
+
+// close waits for any messages that have already been written to be
+// delivered, unless doing so takes more than closeGrace longer than
+// expected, in which case they may be lost once the base connection is closed.
+func (w *impairedWriter) close() {
+	w.mu.Lock()
+	w.closing = true
+	done, last := w.done, w.last
+	w.cond.Signal()
+	w.mu.Unlock()
+	if done == nil {
+		return
+	}
+	timer := time.NewTimer(time.Until(last) + closeGrace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+}
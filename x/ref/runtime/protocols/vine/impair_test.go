@@ -0,0 +1,126 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vine
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingConn is a flow.Conn that records the messages written to it.
+type recordingConn struct {
+	mu     sync.Mutex
+	msgs   []string
+	times  []time.Time
+	closed bool
+}
+
+func (c *recordingConn) WriteMsg(data ...[]byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, errors.New("closed")
+	}
+	var msg []byte
+	for _, d := range data {
+		msg = append(msg, d...)
+	}
+	c.msgs = append(c.msgs, string(msg))
+	c.times = append(c.times, time.Now())
+	return len(msg), nil
+}
+
+func (c *recordingConn) ReadMsg() ([]byte, error)        { return nil, nil }
+func (c *recordingConn) ReadMsg2([]byte) ([]byte, error) { return nil, nil }
+func (c *recordingConn) LocalAddr() net.Addr             { return addr("local") }
+func (c *recordingConn) RemoteAddr() net.Addr            { return addr("remote") }
+
+func (c *recordingConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *recordingConn) state() ([]string, []time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.msgs...), append([]time.Time{}, c.times...), c.closed
+}
+
+func TestImpairedLatency(t *testing.T) {
+	base := &recordingConn{}
+	w := newImpairedWriter(base)
+	behavior := PeerBehavior{Latency: 50 * time.Millisecond, Jitter: 20 * time.Millisecond}
+	start := time.Now()
+	for _, m := range []string{"a", "b", "c", "d"} {
+		if _, err := w.write(behavior, []byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Writes are not blocked by latency.
+	if took := time.Since(start); took > 25*time.Millisecond {
+		t.Errorf("writes took %v", took)
+	}
+	w.close()
+	msgs, times, _ := base.state()
+	if got, want := len(msgs), 4; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i, m := range []string{"a", "b", "c", "d"} {
+		if msgs[i] != m {
+			t.Errorf("%v: got %v, want %v", i, msgs[i], m)
+		}
+		if d := times[i].Sub(start); d < 30*time.Millisecond {
+			t.Errorf("%v: message was delivered after only %v", i, d)
+		}
+	}
+}
+
+func TestImpairedBandwidth(t *testing.T) {
+	base := &recordingConn{}
+	w := newImpairedWriter(base)
+	// 10 messages of 1KB at 100KB/s should take about 100ms.
+	behavior := PeerBehavior{Bandwidth: 100 * 1024}
+	msg := make([]byte, 1024)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if _, err := w.write(behavior, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if took := time.Since(start); took < 90*time.Millisecond {
+		t.Errorf("writes took only %v", took)
+	}
+	w.close()
+	if msgs, _, _ := base.state(); len(msgs) != 10 {
+		t.Errorf("got %v messages, want 10", len(msgs))
+	}
+}
+
+func TestImpairedLossAndReset(t *testing.T) {
+	base := &recordingConn{}
+	w := newImpairedWriter(base)
+	if n, err := w.write(PeerBehavior{MessageLoss: 1}, []byte("lost")); n != 4 || err != nil {
+		t.Errorf("got %v, %v", n, err)
+	}
+	if _, err := w.write(PeerBehavior{}, []byte("sent")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.write(PeerBehavior{ResetProbability: 1}, []byte("reset")); !errors.Is(err, ErrConnectionReset) {
+		t.Errorf("got %v, want %v", err, ErrConnectionReset)
+	}
+	w.close()
+	msgs, _, closed := base.state()
+	if len(msgs) != 1 || msgs[0] != "sent" {
+		t.Errorf("got %v", msgs)
+	}
+	if !closed {
+		t.Errorf("connection was not reset")
+	}
+}
@@ -5,7 +5,9 @@
 // Package vine contains Vanadium's Implementation of Network Emulation (VINE).
 // VINE provides the ability to dynamically specific a network topology
 // (e.g. A can reach B, but A cannot reach C) with various network
-// charcteristics (e.g. A can reach B with latency of 500ms).
+// charcteristics (e.g. A can reach B with latency of 500ms), see PeerBehavior.
+// The characteristics are applied to the messages sent over a connection by
+// the process that sends them.
 // This can be useful for testing Vanadium applications under unpredictable and
 // unfriendly network conditions.
 package vine
@@ -183,6 +185,7 @@ func (v *vine) Dial(ctx *context.T, protocol, address string, timeout time.Durat
 		addr: addr(createDialingAddress(laddr.Network(), laddr.String(), localTag)),
 		key:  key,
 		vine: v,
+		w:    newImpairedWriter(c),
 	}
 	v.insertConn(conn)
 	return conn, nil
@@ -243,6 +246,12 @@ func (v *vine) insertConn(c *conn) {
 	v.mu.Unlock()
 }
 
+func (v *vine) behavior(key PeerKey) PeerBehavior {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.behaviors[key]
+}
+
 func (v *vine) removeConn(c *conn) {
 	key := c.key
 	v.mu.Lock()
@@ -257,12 +266,14 @@ type conn struct {
 	addr addr
 	key  PeerKey
 	vine *vine
+	w    *impairedWriter
 }
 
 // WriteMsg wraps the base flow.Conn's WriteMsg method to allow injection of
-// various network characteristics.
+// various network characteristics. The current behavior for the connection's
+// PeerKey is applied to each message.
 func (c *conn) WriteMsg(data ...[]byte) (int, error) {
-	return c.base.WriteMsg(data...)
+	return c.w.write(c.vine.behavior(c.key), data...)
 }
 
 // ReadMsg wraps the base flow.Conn's ReadMsg method to allow injection of
@@ -277,6 +288,7 @@ func (c *conn) ReadMsg2(buf []byte) ([]byte, error) {
 
 func (c *conn) Close() error {
 	c.vine.removeConn(c)
+	c.w.close()
 	return c.base.Close()
 }
 
@@ -316,6 +328,7 @@ func (l *listener) Accept(ctx *context.T) (flow.Conn, error) {
 		addr: l.addr,
 		key:  key,
 		vine: l.vine,
+		w:    newImpairedWriter(c),
 	}
 	l.vine.insertConn(conn)
 	return conn, nil
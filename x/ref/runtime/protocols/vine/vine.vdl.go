@@ -10,11 +10,13 @@ package vine
 
 import (
 	"fmt"
+	"time"
 
 	v23 "v.io/v23"
 	"v.io/v23/context"
 	"v.io/v23/rpc"
 	"v.io/v23/vdl"
+	vdltime "v.io/v23/vdlroot/time"
 	"v.io/v23/verror"
 )
 
@@ -30,6 +32,7 @@ var _ = initializeVDL() // Must be first; see initializeVDL comments for details
 var (
 	vdlTypeStruct1 *vdl.Type = nil
 	vdlTypeStruct2 *vdl.Type = nil
+	vdlTypeStruct3 *vdl.Type = nil
 )
 
 // Type definitions
@@ -122,6 +125,21 @@ type PeerBehavior struct {
 	// TODO(suharshs): Discoverable should always be bidirectional. It is unrealistic for
 	// A to discover B, but not vice versa.
 	Discoverable bool
+	// Latency is the delay added to each message sent over the connection.
+	Latency time.Duration
+	// Jitter is the maximum random variation, in either direction, applied to
+	// Latency for each message. Messages are never reordered.
+	Jitter time.Duration
+	// Bandwidth, if non-zero, is the maximum rate, in bytes per second, at
+	// which messages are sent over the connection.
+	Bandwidth uint64
+	// MessageLoss is the probability, from 0 to 1, that a message sent over the
+	// connection is dropped. Note that Vanadium connections do not recover
+	// from lost messages and hence they will typically be closed as a result.
+	MessageLoss float64
+	// ResetProbability is the probability, from 0 to 1, that the connection
+	// is reset, ie. closed, rather than sending a message over it.
+	ResetProbability float64
 }
 
 func (PeerBehavior) VDLReflect(struct {
@@ -147,6 +165,45 @@ func (x PeerBehavior) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
 			return err
 		}
 	}
+	if x.Latency != 0 {
+		if err := enc.NextField(2); err != nil {
+			return err
+		}
+		var wire vdltime.Duration
+		if err := vdltime.DurationFromNative(&wire, x.Latency); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.Jitter != 0 {
+		if err := enc.NextField(3); err != nil {
+			return err
+		}
+		var wire vdltime.Duration
+		if err := vdltime.DurationFromNative(&wire, x.Jitter); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.Bandwidth != 0 {
+		if err := enc.NextFieldValueUint(4, vdl.Uint64Type, x.Bandwidth); err != nil {
+			return err
+		}
+	}
+	if x.MessageLoss != 0 {
+		if err := enc.NextFieldValueFloat(5, vdl.Float64Type, x.MessageLoss); err != nil {
+			return err
+		}
+	}
+	if x.ResetProbability != 0 {
+		if err := enc.NextFieldValueFloat(6, vdl.Float64Type, x.ResetProbability); err != nil {
+			return err
+		}
+	}
 	if err := enc.NextField(-1); err != nil {
 		return err
 	}
@@ -191,6 +248,43 @@ func (x *PeerBehavior) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
 			default:
 				x.Discoverable = value
 			}
+		case 2:
+			var wire vdltime.Duration
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := vdltime.DurationToNative(wire, &x.Latency); err != nil {
+				return err
+			}
+		case 3:
+			var wire vdltime.Duration
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := vdltime.DurationToNative(wire, &x.Jitter); err != nil {
+				return err
+			}
+		case 4:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Bandwidth = value
+			}
+		case 5:
+			switch value, err := dec.ReadValueFloat(64); {
+			case err != nil:
+				return err
+			default:
+				x.MessageLoss = value
+			}
+		case 6:
+			switch value, err := dec.ReadValueFloat(64); {
+			case err != nil:
+				return err
+			default:
+				x.ResetProbability = value
+			}
 		}
 	}
 }
@@ -203,6 +297,7 @@ var (
 	ErrAddressNotReachable  = verror.NewIDAction("v.io/x/ref/runtime/protocols/vine.AddressNotReachable", verror.NoRetry)
 	ErrNoRegisteredProtocol = verror.NewIDAction("v.io/x/ref/runtime/protocols/vine.NoRegisteredProtocol", verror.NoRetry)
 	ErrCantAcceptFromTag    = verror.NewIDAction("v.io/x/ref/runtime/protocols/vine.CantAcceptFromTag", verror.NoRetry)
+	ErrConnectionReset      = verror.NewIDAction("v.io/x/ref/runtime/protocols/vine.ConnectionReset", verror.NoRetry)
 )
 
 // ErrorfInvalidAddress calls ErrInvalidAddress.Errorf with the supplied arguments.
@@ -361,6 +456,32 @@ func ParamsErrCantAcceptFromTag(argumentError error) (verrorComponent string, ve
 	return
 }
 
+// ErrorfConnectionReset calls ErrConnectionReset.Errorf with the supplied arguments.
+func ErrorfConnectionReset(ctx *context.T, format string) error {
+	return ErrConnectionReset.Errorf(ctx, format)
+}
+
+// MessageConnectionReset calls ErrConnectionReset.Message with the supplied arguments.
+func MessageConnectionReset(ctx *context.T, message string) error {
+	return ErrConnectionReset.Message(ctx, message)
+}
+
+// ParamsErrConnectionReset extracts the expected parameters from the error's ParameterList.
+func ParamsErrConnectionReset(argumentError error) (verrorComponent string, verrorOperation string, returnErr error) {
+	params := verror.Params(argumentError)
+	if params == nil {
+		returnErr = fmt.Errorf("no parameters found in: %T: %v", argumentError, argumentError)
+		return
+	}
+	iter := &paramListIterator{params: params, max: len(params)}
+
+	if verrorComponent, verrorOperation, returnErr = iter.preamble(); returnErr != nil {
+		return
+	}
+
+	return
+}
+
 type paramListIterator struct {
 	err      error
 	idx, max int
@@ -542,6 +663,7 @@ func initializeVDL() struct{} {
 	// Initialize type definitions.
 	vdlTypeStruct1 = vdl.TypeOf((*PeerKey)(nil)).Elem()
 	vdlTypeStruct2 = vdl.TypeOf((*PeerBehavior)(nil)).Elem()
+	vdlTypeStruct3 = vdl.TypeOf((*vdltime.Duration)(nil)).Elem()
 
 	return struct{}{}
 }
@@ -228,6 +228,52 @@ func TestDiscovery(t *testing.T) {
 	}
 }
 
+func TestLatency(t *testing.T) {
+	ctx, shutdown := test.V23InitWithMounttable()
+	defer shutdown()
+
+	ctx, shutdown, err := vine.Init(ctx, "vineserver", security.AllowEveryone(), "client", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown()
+	sctx := vine.WithLocalTag(ctx, "server")
+	sctx, cancel := context.WithCancel(sctx)
+	_, server, err := v23.WithNewServer(sctx, "server", &testService{}, security.AllowEveryone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cancel()
+		<-server.Closed()
+	}()
+	vineClient := vine.VineClient("vineserver")
+	if err := vineClient.SetBehaviors(ctx, map[vine.PeerKey]vine.PeerBehavior{
+		{"client", "server"}: {Reachable: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	client := v23.GetClient(ctx)
+	// Establish the connection before adding latency.
+	if err := client.Call(ctx, "server", "Foo", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	latency := 100 * time.Millisecond
+	if err := vineClient.SetBehaviors(ctx, map[vine.PeerKey]vine.PeerBehavior{
+		{"client", "server"}: {Reachable: true, Latency: latency},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := client.Call(ctx, "server", "Foo", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Both the request and the response are delayed.
+	if took := time.Since(start); took < 2*latency {
+		t.Errorf("call took %v, expected at least %v", took, 2*latency)
+	}
+}
+
 type testService struct{}
 
 func (*testService) Foo(*context.T, rpc.ServerCall) error {
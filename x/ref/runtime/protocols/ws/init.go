@@ -16,4 +16,10 @@ func init() {
 	flow.RegisterProtocol("ws", protocol, "ws4", "ws6")
 	flow.RegisterProtocol("ws4", protocol)
 	flow.RegisterProtocol("ws6", protocol)
+
+	// wss, wss4, wss6 represent websocket over TLS protocol instances.
+	secure := websocket.WSS{}
+	flow.RegisterProtocol("wss", secure, "wss4", "wss6")
+	flow.RegisterProtocol("wss4", secure)
+	flow.RegisterProtocol("wss6", secure)
 }
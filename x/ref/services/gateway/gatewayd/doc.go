@@ -0,0 +1,129 @@
+// Copyright 2018 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// This file was auto-generated via go generate.
+// DO NOT UPDATE MANUALLY
+
+/*
+Command gatewayd is a daemon that accepts HTTP requests with JSON encoded
+arguments, calls the Vanadium methods that they identify and returns the results
+as JSON, so that clients that do not speak VOM may use Vanadium services.
+
+A request's path is the object name of the service followed by the method name;
+rooted names are specified with a leading double slash, eg:
+
+	curl -d '["arg"]' http://localhost:8080//host:port/service/Method
+
+The body of the request is a JSON array of the method's arguments, which are
+converted to the types declared in the method's signature. The response is a
+JSON object with either a "results" or an "error" field. Streaming methods use
+newline-delimited JSON for the items sent and received.
+
+Calls are made using the gateway's principal, or using blessings granted to it
+by the client and included, base64url-VOM-encoded, in the X-Vanadium-Blessings
+header of the request.
+
+Usage:
+
+	gatewayd [flags]
+
+The gatewayd flags are:
+
+	-http=localhost:8080
+	  Network address on which the HTTP server listens.
+	-timeout=1m0s
+	  The maximum time allowed for each call, or 0 for no limit.
+
+The global flags are:
+
+	-alsologtostderr=true
+	  log to standard error as well as files
+	-log_backtrace_at=:0
+	  when logging hits line file:N, emit a stack trace
+	-log_dir=
+	  if non-empty, write log files to this directory
+	-logtostderr=false
+	  log to standard error instead of files
+	-max_stack_buf_size=4292608
+	  max size in bytes of the buffer to use for logging stack traces
+	-metadata=<just specify -metadata to activate>
+	  Displays metadata for the program and exits.
+	-stderrthreshold=2
+	  logs at or above this threshold go to stderr
+	-time=false
+	  Dump timing information to stderr before exiting the program.
+	-v=0
+	  log level for V logs
+	-v23.credentials=
+	  directory to use for storing security credentials
+	-v23.dial.no-proxy=$NO_PROXY
+	  comma separated list of the destinations that are to be dialed directly
+	  rather than via --v23.dial.proxy
+	-v23.dial.proxy=$ALL_PROXY or $HTTPS_PROXY
+	  egress proxy to use for outbound connections, one of socks5://, socks5h://,
+	  http:// or https://[user:password@]host:port, an http proxy is assumed if
+	  no scheme is given
+	-v23.namespace.root=[/(dev.v.io:r:vprod:service:mounttabled)@ns.dev.v.io:8101]
+	  local namespace root; can be repeated to provided multiple roots
+	-v23.permissions.file=
+	  specify a perms file as <name>:<permsfile>
+	-v23.permissions.literal=
+	  explicitly specify the runtime perms as a JSON-encoded access.Permissions.
+	  Overrides all --v23.permissions.file flags
+	-v23.proxy=
+	  object name of proxy service to use to export services across network
+	  boundaries
+	-v23.proxy.limit=0
+	  max number of proxies to connect to when the policy is to connect to all
+	  proxies; 0 implies all proxies
+	-v23.proxy.policy=
+	  policy for choosing from a set of available proxy instances
+	-v23.tcp.address=
+	  address to listen on
+	-v23.tcp.protocol=
+	  protocol to listen with
+	-v23.virtualized.advertise-private-addresses=
+	  if set the process will also advertise its private addresses
+	-v23.virtualized.disallow-native-fallback=false
+	  if set, a failure to detect the requested virtualization provider will result
+	  in an error, otherwise, native mode is used
+	-v23.virtualized.dns.public-name=
+	  if set the process will use the supplied dns name (and port) without
+	  resolution for its entry in the mounttable
+	-v23.virtualized.docker=
+	  set if the process is running in a docker container and needs to configure
+	  itself differently therein
+	-v23.virtualized.provider=
+	  the name of the virtualization/cloud provider hosting this process if the
+	  process needs to configure itself differently therein
+	-v23.virtualized.tcp.public-address=
+	  if set the process will use this address (resolving via dns if appropriate)
+	  for its entry in the mounttable
+	-v23.virtualized.tcp.public-protocol=
+	  if set the process will use this protocol for its entry in the mounttable
+	-v23.vtrace.cache-size=1024
+	  The number of vtrace traces to store in memory
+	-v23.vtrace.collect-regexp=
+	  Spans and annotations that match this regular expression will trigger trace
+	  collection
+	-v23.vtrace.dump-on-shutdown=true
+	  If true, dump all stored traces on runtime shutdown
+	-v23.vtrace.enable-aws-xray=false
+	  Enable the use of AWS x-ray integration with vtrace
+	-v23.vtrace.root-span-name=
+	  Set the name of the root vtrace span created by the runtime at startup
+	-v23.vtrace.sample-rate=0
+	  Rate (from 0.0 to 1.0) to sample vtrace traces
+	-v23.vtrace.v=0
+	  The verbosity level of the log messages to be captured in traces
+	-vmodule=
+	  comma-separated list of globpattern=N settings for filename-filtered logging
+	  (without the .go suffix).  E.g. foo/bar/baz.go is matched by patterns baz or
+	  *az or b* but not by bar/baz or baz.go or az or b.*
+	-vpath=
+	  comma-separated list of regexppattern=N settings for file pathname-filtered
+	  logging (without the .go suffix).  E.g. foo/bar/baz.go is matched by patterns
+	  foo/bar/baz or fo.*az or oo/ba or b.z but not by foo/bar/baz.go or fo*az
+*/
+package main
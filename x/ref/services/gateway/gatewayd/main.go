@@ -0,0 +1,82 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// The following enables go generate to generate the doc.go file.
+//go:generate go run v.io/x/lib/cmdline/gendoc . -help
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/x/lib/cmdline"
+	"v.io/x/ref/lib/signals"
+	"v.io/x/ref/lib/v23cmd"
+	_ "v.io/x/ref/runtime/factories/roaming"
+	"v.io/x/ref/services/gateway/gatewaylib"
+)
+
+var (
+	httpAddr string
+	timeout  time.Duration
+)
+
+func main() {
+	cmdGatewayD.Flags.StringVar(&httpAddr, "http", "localhost:8080", "Network address on which the HTTP server listens.")
+	cmdGatewayD.Flags.DurationVar(&timeout, "timeout", time.Minute, "The maximum time allowed for each call, or 0 for no limit.")
+
+	cmdline.HideGlobalFlagsExcept()
+	cmdline.Main(cmdGatewayD)
+}
+
+var cmdGatewayD = &cmdline.Command{
+	Runner: v23cmd.RunnerFunc(runGatewayD),
+	Name:   "gatewayd",
+	Short:  "Calls Vanadium services on behalf of HTTP clients",
+	Long: `
+Command gatewayd is a daemon that accepts HTTP requests with JSON encoded
+arguments, calls the Vanadium methods that they identify and returns the
+results as JSON, so that clients that do not speak VOM may use Vanadium
+services.
+
+A request's path is the object name of the service followed by the method
+name; rooted names are specified with a leading double slash, eg:
+
+   curl -d '["arg"]' http://localhost:8080//host:port/service/Method
+
+The body of the request is a JSON array of the method's arguments, which
+are converted to the types declared in the method's signature. The response
+is a JSON object with either a "results" or an "error" field. Streaming
+methods use newline-delimited JSON for the items sent and received.
+
+Calls are made using the gateway's principal, or using blessings granted to
+it by the client and included, base64url-VOM-encoded, in the
+X-Vanadium-Blessings header of the request.
+`,
+}
+
+func runGatewayD(ctx *context.T, env *cmdline.Env, args []string) error {
+	ctx, handler := signals.ShutdownOnSignalsWithCancel(ctx)
+	defer handler.WaitForSignal()
+
+	ln, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: gatewaylib.NewHandler(ctx, timeout)}
+	handler.RegisterCancel(func() {
+		server.Close() //nolint:errcheck
+	})
+	fmt.Fprintf(env.Stdout, "HTTP=%s\n", ln.Addr())
+	go func() {
+		if err := server.Serve(ln); err != http.ErrServerClosed {
+			ctx.Errorf("HTTP server failed: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,394 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gatewaylib implements an HTTP gateway that allows clients that
+// speak JSON, rather than VOM, to call the methods of arbitrary Vanadium
+// services.
+//
+// The method to be called is identified by the path of the request, which
+// is the object name of the service followed by the name of the method, eg:
+//
+//	POST /some/mounted/service/Method
+//	POST //host:port/rooted/name/Method
+//
+// GET may be used instead of POST only for methods tagged with access.Read,
+// since browsers allow other sites to cause GET requests to be made, and
+// those calls would otherwise be made with the gateway's principal.
+//
+// The body of the request is a JSON array of the method's positional
+// arguments, which are converted to the types declared in the method's
+// signature, as returned by its __MethodSignature reserved method. The
+// response is a JSON object with either a "results" field, holding the JSON
+// array of the method's results, or an "error" field. For methods with an
+// input stream, the arguments are followed in the body by the items to be
+// sent on the stream, one JSON value per line. For methods with an output
+// stream, the response is newline-delimited JSON, with one object with an
+// "item" field per item received, followed by the object holding the
+// results or error.
+//
+// Methods are called using the gateway's principal. Alternatively, a client
+// may grant blessings to the gateway's public key, which are then used for
+// its request, by including them, base64url-VOM-encoded, in the
+// X-Vanadium-Blessings header.
+package gatewaylib
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/rpc"
+	"v.io/v23/rpc/reserved"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	"v.io/v23/vdlroot/signature"
+	"v.io/v23/verror"
+	seclib "v.io/x/ref/lib/security"
+)
+
+// BlessingsHeader is the HTTP header used to grant blessings to the gateway
+// for use with a single request.
+const BlessingsHeader = "X-Vanadium-Blessings"
+
+const ndjsonContentType = "application/x-ndjson"
+
+const (
+	// sigCacheSize bounds the number of method signatures that are cached.
+	sigCacheSize = 1024
+	// sigCacheTTL is how long a cached method signature is used before it
+	// is fetched again, so that changes to a server are eventually seen.
+	sigCacheTTL = time.Minute
+)
+
+var readTag = vdl.ValueOf(access.Read)
+
+type gateway struct {
+	ctx     *context.T
+	timeout time.Duration
+
+	mu   sync.Mutex
+	sigs map[sigKey]sigEntry // GUARDED_BY(mu)
+}
+
+type sigKey struct {
+	name, method string
+}
+
+type sigEntry struct {
+	sig     signature.Method
+	expires time.Time
+}
+
+// NewHandler returns an http.Handler that calls Vanadium methods as
+// described in the package documentation. Calls are made using ctx and,
+// if timeout is non-zero, must complete within timeout.
+func NewHandler(ctx *context.T, timeout time.Duration) http.Handler {
+	return &gateway{ctx: ctx, timeout: timeout, sigs: map[sigKey]sigEntry{}}
+}
+
+// methodSignature returns the signature of method on the object name,
+// calling its __MethodSignature reserved method only if the signature is
+// not already cached.
+func (g *gateway) methodSignature(ctx *context.T, name, method string) (signature.Method, error) {
+	key := sigKey{name, method}
+	now := time.Now()
+	g.mu.Lock()
+	entry, ok := g.sigs[key]
+	g.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.sig, nil
+	}
+	sig, err := reserved.MethodSignature(ctx, name, method)
+	if err != nil {
+		return sig, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.sigs[key]; !ok && len(g.sigs) >= sigCacheSize {
+		// Evict expired entries, or an arbitrary one if none have expired.
+		for k, e := range g.sigs {
+			if now.After(e.expires) {
+				delete(g.sigs, k)
+			}
+		}
+		for k := range g.sigs {
+			if len(g.sigs) < sigCacheSize {
+				break
+			}
+			delete(g.sigs, k)
+		}
+	}
+	g.sigs[key] = sigEntry{sig: sig, expires: now.Add(sigCacheTTL)}
+	return sig, nil
+}
+
+// The JSON objects written in response to a request, or as the lines of a
+// streaming response.
+type (
+	itemResponse struct {
+		Item interface{} `json:"item"`
+	}
+	resultsResponse struct {
+		Results []interface{} `json:"results"`
+	}
+	errorResponse struct {
+		Error *jsonError `json:"error"`
+	}
+)
+
+type jsonError struct {
+	ID      verror.ID         `json:"id,omitempty"`
+	Action  verror.ActionCode `json:"action"`
+	Message string            `json:"message"`
+}
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v is not allowed", r.Method))
+		return
+	}
+	name, method := splitPath(r.URL.Path)
+	if len(method) == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("no method specified in %q", r.URL.Path))
+		return
+	}
+	ctx, cancel := context.WithCancel(g.ctx)
+	defer cancel()
+	// Cancel the call if the HTTP client goes away.
+	go func(done <-chan struct{}) {
+		select {
+		case <-r.Context().Done():
+			cancel()
+		case <-done:
+		}
+	}(ctx.Done())
+	if g.timeout > 0 {
+		var cancelTimeout func()
+		ctx, cancelTimeout = context.WithTimeout(ctx, g.timeout)
+		defer cancelTimeout()
+	}
+	if header := r.Header.Get(BlessingsHeader); len(header) > 0 {
+		var err error
+		if ctx, err = withGrantedBlessings(ctx, header); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	sig, err := g.methodSignature(ctx, name, method)
+	if err != nil {
+		writeError(w, httpStatus(err), err)
+		return
+	}
+	if r.Method == http.MethodGet && !isReadOnly(sig) {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %v is not allowed for %v, which is not tagged with %v", r.Method, method, access.Read))
+		return
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	inargs, err := decodeArgs(dec, sig)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	call, err := v23.GetClient(ctx).StartCall(ctx, name, method, inargs)
+	if err != nil {
+		writeError(w, httpStatus(err), err)
+		return
+	}
+	if sig.InStream != nil {
+		// The request body is read in full before any streamed results are
+		// written, since HTTP/1.x servers cannot do both at once.
+		if err := sendStream(ctx, dec, call, sig.InStream.Type); err != nil {
+			writeError(w, httpStatus(err), err)
+			return
+		}
+	}
+	stream := &streamWriter{w: w, streaming: sig.OutStream != nil}
+	if stream.streaming {
+		for {
+			var item *vdl.Value
+			if err := call.Recv(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				stream.writeError(err)
+				return
+			}
+			stream.writeItem(toJSON(item))
+		}
+	}
+	outargs := make([]*vdl.Value, len(sig.OutArgs))
+	outptrs := make([]interface{}, len(outargs))
+	for i := range outargs {
+		outptrs[i] = &outargs[i]
+	}
+	if err := call.Finish(outptrs...); err != nil {
+		stream.writeError(err)
+		return
+	}
+	results := make([]interface{}, len(outargs))
+	for i, arg := range outargs {
+		results[i] = toJSON(arg)
+	}
+	stream.write(resultsResponse{Results: results})
+}
+
+// isReadOnly returns true if the method's signature is tagged with
+// access.Read.
+func isReadOnly(sig signature.Method) bool {
+	for _, tag := range sig.Tags {
+		if vdl.EqualValue(tag, readTag) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPath splits the path of a request into an object name and a method.
+// A single leading slash is removed from the path, so that rooted names
+// are specified with two.
+func splitPath(path string) (name, method string) {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
+
+// withGrantedBlessings returns a context whose principal uses the blessings
+// encoded in header, which must have been granted to the gateway's public
+// key, for all peers.
+func withGrantedBlessings(ctx *context.T, header string) (*context.T, error) {
+	blessings, err := seclib.DecodeBlessingsBase64(header)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v header: %v", BlessingsHeader, err)
+	}
+	p := v23.GetPrincipal(ctx)
+	forked, err := seclib.ForkPrincipal(p, seclib.FixedBlessingsStore(blessings, nil), p.Roots())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v header: %v", BlessingsHeader, err)
+	}
+	return v23.WithPrincipal(ctx, forked)
+}
+
+// decodeArgs decodes the JSON array of positional arguments from dec; an
+// empty body is accepted for methods without arguments.
+func decodeArgs(dec *json.Decoder, sig signature.Method) ([]interface{}, error) {
+	var args []interface{}
+	if err := dec.Decode(&args); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid arguments: %v", err)
+	}
+	if got, want := len(args), len(sig.InArgs); got != want {
+		return nil, fmt.Errorf("%v takes %d arguments, %d were provided", sig.Name, want, got)
+	}
+	inargs := make([]interface{}, len(args))
+	for i, arg := range args {
+		v, err := fromJSON(sig.InArgs[i].Type, arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %d: %v", i, err)
+		}
+		inargs[i] = v
+	}
+	return inargs, nil
+}
+
+// sendStream sends the JSON values remaining in dec, converted to t, on the
+// input stream of call.
+func sendStream(ctx *context.T, dec *json.Decoder, call rpc.ClientCall, t *vdl.Type) error {
+	for {
+		var item interface{}
+		if err := dec.Decode(&item); err == io.EOF {
+			break
+		} else if err != nil {
+			return verror.ErrBadArg.Errorf(ctx, "invalid stream item: %v", err)
+		}
+		v, err := fromJSON(t, item)
+		if err != nil {
+			return verror.ErrBadArg.Errorf(ctx, "invalid stream item: %v", err)
+		}
+		if err := call.Send(v); err != nil {
+			return err
+		}
+	}
+	return call.CloseSend()
+}
+
+// streamWriter writes either a single JSON response or, for methods with an
+// output stream, newline-delimited JSON responses.
+type streamWriter struct {
+	w         http.ResponseWriter
+	streaming bool
+	written   bool
+}
+
+func (s *streamWriter) writeItem(item interface{}) {
+	s.write(itemResponse{Item: item})
+}
+
+func (s *streamWriter) write(r interface{}) {
+	if s.streaming {
+		s.w.Header().Set("Content-Type", ndjsonContentType)
+	} else {
+		s.w.Header().Set("Content-Type", "application/json")
+	}
+	s.written = true
+	json.NewEncoder(s.w).Encode(r) //nolint:errcheck
+	if f, ok := s.w.(http.Flusher); ok && s.streaming {
+		f.Flush()
+	}
+}
+
+// writeError writes err with a matching HTTP status if nothing has been
+// written yet, or as the final line of a streaming response otherwise.
+func (s *streamWriter) writeError(err error) {
+	if s.written {
+		s.write(errorResponse{Error: toJSONError(err)})
+		return
+	}
+	writeError(s.w, httpStatus(err), err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: toJSONError(err)}) //nolint:errcheck
+}
+
+func toJSONError(err error) *jsonError {
+	return &jsonError{
+		ID:      verror.ErrorID(err),
+		Action:  verror.Action(err),
+		Message: err.Error(),
+	}
+}
+
+// httpStatus returns the HTTP status code that best describes err.
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, verror.ErrBadArg):
+		return http.StatusBadRequest
+	case errors.Is(err, verror.ErrNoAccess), errors.Is(err, verror.ErrNotTrusted):
+		return http.StatusForbidden
+	case errors.Is(err, verror.ErrNoExist), errors.Is(err, verror.ErrNoExistOrNoAccess),
+		errors.Is(err, verror.ErrUnknownMethod), errors.Is(err, verror.ErrUnknownSuffix):
+		return http.StatusNotFound
+	case errors.Is(err, verror.ErrExist), errors.Is(err, verror.ErrBadVersion):
+		return http.StatusConflict
+	case errors.Is(err, verror.ErrNoServers):
+		return http.StatusBadGateway
+	case errors.Is(err, verror.ErrTimeout):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, verror.ErrNotImplemented):
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,247 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gatewaylib_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/vdl"
+	seclib "v.io/x/ref/lib/security"
+	_ "v.io/x/ref/runtime/factories/generic"
+	"v.io/x/ref/services/gateway/gatewaylib"
+	"v.io/x/ref/test"
+	"v.io/x/ref/test/testutil"
+)
+
+type Point struct {
+	X, Y int32
+}
+
+type server struct{}
+
+func (server) Scale(_ *context.T, _ rpc.ServerCall, p Point, f int32) (Point, error) {
+	return Point{p.X * f, p.Y * f}, nil
+}
+
+func (server) Split(_ *context.T, _ rpc.ServerCall, s string) (string, string, error) {
+	i := strings.Index(s, ",")
+	return s[:i], s[i+1:], nil
+}
+
+func (server) Count(_ *context.T, call rpc.StreamServerCall, n int32) error {
+	for i := int32(0); i < n; i++ {
+		if err := call.Send(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (server) Sum(_ *context.T, call rpc.StreamServerCall) (int64, error) {
+	var sum int64
+	for {
+		var n int64
+		if err := call.Recv(&n); err == io.EOF {
+			return sum, nil
+		} else if err != nil {
+			return 0, err
+		}
+		sum += n
+	}
+}
+
+func (server) Caller(ctx *context.T, call rpc.ServerCall) ([]string, error) {
+	names, _ := security.RemoteBlessingNames(ctx, call.Security())
+	sort.Strings(names)
+	return names, nil
+}
+
+// Describe__ tags Split, but no other method, with access.Read.
+func (server) Describe__() []rpc.InterfaceDesc {
+	return []rpc.InterfaceDesc{{
+		Name: "server",
+		Methods: []rpc.MethodDesc{{
+			Name:    "Split",
+			InArgs:  []rpc.ArgDesc{{Name: "s"}},
+			OutArgs: []rpc.ArgDesc{{}, {}},
+			Tags:    []*vdl.Value{vdl.ValueOf(access.Read)},
+		}},
+	}}
+}
+
+func TestGateway(t *testing.T) {
+	ctx, shutdown := test.V23Init()
+	defer shutdown()
+
+	_, s, err := v23.WithNewServer(ctx, "", server{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.WaitForServerReady(s)
+	name := s.Status().Endpoints[0].Name()
+
+	gw := httptest.NewServer(gatewaylib.NewHandler(ctx, time.Minute))
+	defer gw.Close()
+
+	p := v23.GetPrincipal(ctx)
+	def, _ := p.BlessingStore().Default()
+	granted, err := p.Bless(p.PublicKey(), def, "gateway", security.UnconstrainedUse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := seclib.EncodeBlessingsBase64(granted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		httpMethod              string
+		method, body, blessings string
+		status                  int
+		contentType, response   string
+	}{
+		{
+			method:      "Scale",
+			body:        `[{"x":1,"y":2},3]`,
+			status:      http.StatusOK,
+			contentType: "application/json",
+			response:    `{"results":[{"x":3,"y":6}]}` + "\n",
+		},
+		{
+			method:      "Split",
+			body:        `["a,b"]`,
+			status:      http.StatusOK,
+			contentType: "application/json",
+			response:    `{"results":["a","b"]}` + "\n",
+		},
+		{
+			method:      "Count",
+			body:        `[2]`,
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			response:    `{"item":0}` + "\n" + `{"item":1}` + "\n" + `{"results":[]}` + "\n",
+		},
+		{
+			method:      "Count",
+			body:        `[0]`,
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			response:    `{"results":[]}` + "\n",
+		},
+		{
+			// Sum is not described by VDL, so its signature declares an
+			// output stream too.
+			method:      "Sum",
+			body:        "[]\n1\n2\n3\n",
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson",
+			response:    `{"results":["6"]}` + "\n",
+		},
+		{
+			method:      "Caller",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			response:    `{"results":[["test-blessing"]]}` + "\n",
+		},
+		{
+			method:      "Caller",
+			blessings:   encoded,
+			status:      http.StatusOK,
+			contentType: "application/json",
+			response:    `{"results":[["test-blessing:gateway"]]}` + "\n",
+		},
+		{
+			method: "Caller",
+			blessings: func() string {
+				other := testutil.NewPrincipal("other")
+				b, _ := other.BlessingStore().Default()
+				enc, _ := seclib.EncodeBlessingsBase64(b)
+				return enc
+			}(),
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+		},
+		{
+			method:      "Scale",
+			body:        `[{"x":1,"y":2}]`,
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+		},
+		{
+			method:      "Scale",
+			body:        `[{"x":1,"z":2},3]`,
+			status:      http.StatusBadRequest,
+			contentType: "application/json",
+		},
+		{
+			// Split is tagged with access.Read, so it may be called by GET.
+			httpMethod:  http.MethodGet,
+			method:      "Split",
+			body:        `["a,b"]`,
+			status:      http.StatusOK,
+			contentType: "application/json",
+			response:    `{"results":["a","b"]}` + "\n",
+		},
+		{
+			httpMethod:  http.MethodGet,
+			method:      "Scale",
+			body:        `[{"x":1,"y":2},3]`,
+			status:      http.StatusMethodNotAllowed,
+			contentType: "application/json",
+		},
+		{
+			method:      "NoSuchMethod",
+			status:      http.StatusNotFound,
+			contentType: "application/json",
+		},
+	} {
+		httpMethod := tc.httpMethod
+		if len(httpMethod) == 0 {
+			httpMethod = http.MethodPost
+		}
+		req, err := http.NewRequest(httpMethod, gw.URL+"/"+name+"/"+tc.method, strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tc.blessings) > 0 {
+			req.Header.Set(gatewaylib.BlessingsHeader, tc.blessings)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.StatusCode, tc.status; got != want {
+			t.Errorf("%v: got status %v, want %v: %s", tc.method, got, want, body)
+		}
+		if got, want := resp.Header.Get("Content-Type"), tc.contentType; got != want {
+			t.Errorf("%v: got content type %v, want %v", tc.method, got, want)
+		}
+		if tc.status != http.StatusOK {
+			if !strings.HasPrefix(string(body), `{"error":`) {
+				t.Errorf("%v: got %s, want an error", tc.method, body)
+			}
+			continue
+		}
+		if got, want := string(body), tc.response; got != want {
+			t.Errorf("%v: got %s, want %s", tc.method, got, want)
+		}
+	}
+}
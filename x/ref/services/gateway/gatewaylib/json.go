@@ -0,0 +1,333 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gatewaylib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"v.io/v23/vdl"
+	"v.io/x/ref/lib/vdl/vdlutil"
+)
+
+// The JSON representation of VDL values follows that used by vrpc's -json
+// flag:
+//
+//   - 64 bit integers are represented as strings, to avoid the loss of
+//     precision in languages that use 64 bit floats for all numbers; other
+//     numbers are represented as JSON numbers;
+//   - enums are represented by their labels;
+//   - lists, arrays and sets are represented as JSON arrays;
+//   - maps and structs are represented as JSON objects, with struct fields
+//     named by their VDL names with the first rune lower-cased; map keys that
+//     are not strings or enums are represented by their JSON encoding;
+//   - unions are represented as a JSON object with a single field;
+//   - nil any and optional values are represented as null;
+//   - type objects are represented by their VDL type strings.
+//
+// Either form of struct and union field names, and either strings or numbers
+// for integers, are accepted when converting from JSON.
+
+// toJSON returns a value that, when marshaled by encoding/json, is the JSON
+// representation of v.
+func toJSON(v *vdl.Value) interface{} { //nolint:gocyclo
+	if v == nil {
+		return nil
+	}
+	switch v.Kind() {
+	case vdl.Bool:
+		return v.Bool()
+	case vdl.Byte, vdl.Uint16, vdl.Uint32:
+		return v.Uint()
+	case vdl.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case vdl.Int8, vdl.Int16, vdl.Int32:
+		return v.Int()
+	case vdl.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case vdl.Float32, vdl.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return strconv.FormatFloat(f, 'g', -1, v.Kind().BitLen())
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, v.Kind().BitLen()))
+	case vdl.String:
+		return v.RawString()
+	case vdl.Enum:
+		return v.EnumLabel()
+	case vdl.TypeObject:
+		return v.TypeObject().String()
+	case vdl.Any, vdl.Optional:
+		return toJSON(v.Elem())
+	case vdl.Array, vdl.List:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i] = toJSON(v.Index(i))
+		}
+		return list
+	case vdl.Set:
+		keys := vdl.SortValuesAsString(v.Keys())
+		list := make([]interface{}, len(keys))
+		for i, key := range keys {
+			list[i] = toJSON(key)
+		}
+		return list
+	case vdl.Map:
+		obj := make(map[string]interface{}, v.Len())
+		for _, key := range v.Keys() {
+			obj[mapKeyToJSON(key)] = toJSON(v.MapIndex(key))
+		}
+		return obj
+	case vdl.Struct:
+		obj := make(map[string]interface{}, v.Type().NumField())
+		for i := 0; i < v.Type().NumField(); i++ {
+			obj[vdlutil.FirstRuneToLower(v.Type().Field(i).Name)] = toJSON(v.StructField(i))
+		}
+		return obj
+	case vdl.Union:
+		i, field := v.UnionField()
+		return map[string]interface{}{
+			vdlutil.FirstRuneToLower(v.Type().Field(i).Name): toJSON(field),
+		}
+	}
+	return nil
+}
+
+func mapKeyToJSON(key *vdl.Value) string {
+	switch j := toJSON(key).(type) {
+	case string:
+		return j
+	default:
+		buf, _ := json.Marshal(j)
+		return string(buf)
+	}
+}
+
+// decodeJSON decodes a single JSON value from data such that numbers are
+// represented as json.Number rather than float64.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var j interface{}
+	if err := dec.Decode(&j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// fromJSON converts j, a value decoded from JSON using json.Decoder.UseNumber,
+// to a VDL value of type t.
+func fromJSON(t *vdl.Type, j interface{}) (*vdl.Value, error) { //nolint:gocyclo
+	switch t.Kind() {
+	case vdl.Any:
+		if j == nil {
+			return vdl.ZeroValue(t), nil
+		}
+		return vdl.AnyValue(vdl.ValueOf(nativeJSON(j))), nil
+	case vdl.Optional:
+		if j == nil {
+			return vdl.ZeroValue(t), nil
+		}
+		elem, err := fromJSON(t.Elem(), j)
+		if err != nil {
+			return nil, err
+		}
+		return vdl.OptionalValue(elem), nil
+	}
+	v := vdl.ZeroValue(t)
+	switch t.Kind() {
+	case vdl.Bool:
+		b, ok := j.(bool)
+		if !ok {
+			return nil, errType(t, j)
+		}
+		v.AssignBool(b)
+	case vdl.Byte, vdl.Uint16, vdl.Uint32, vdl.Uint64:
+		n, err := strconv.ParseUint(numberString(j), 10, t.Kind().BitLen())
+		if err != nil {
+			return nil, errType(t, j)
+		}
+		v.AssignUint(n)
+	case vdl.Int8, vdl.Int16, vdl.Int32, vdl.Int64:
+		n, err := strconv.ParseInt(numberString(j), 10, t.Kind().BitLen())
+		if err != nil {
+			return nil, errType(t, j)
+		}
+		v.AssignInt(n)
+	case vdl.Float32, vdl.Float64:
+		f, err := strconv.ParseFloat(numberString(j), t.Kind().BitLen())
+		if err != nil {
+			return nil, errType(t, j)
+		}
+		v.AssignFloat(f)
+	case vdl.String:
+		s, ok := j.(string)
+		if !ok {
+			return nil, errType(t, j)
+		}
+		v.AssignString(s)
+	case vdl.Enum:
+		s, ok := j.(string)
+		if !ok || t.EnumIndex(s) < 0 {
+			return nil, errType(t, j)
+		}
+		v.AssignEnumLabel(s)
+	case vdl.Array, vdl.List, vdl.Set:
+		list, ok := j.([]interface{})
+		if !ok || (t.Kind() == vdl.Array && len(list) != t.Len()) {
+			return nil, errType(t, j)
+		}
+		if t.Kind() == vdl.List {
+			v.AssignLen(len(list))
+		}
+		for i, item := range list {
+			elem, err := fromJSON(elemOrKey(t), item)
+			if err != nil {
+				return nil, err
+			}
+			if t.Kind() == vdl.Set {
+				v.AssignSetKey(elem)
+			} else {
+				v.AssignIndex(i, elem)
+			}
+		}
+	case vdl.Map:
+		obj, ok := j.(map[string]interface{})
+		if !ok {
+			return nil, errType(t, j)
+		}
+		for _, k := range sortedKeys(obj) {
+			key, err := mapKeyFromJSON(t.Key(), k)
+			if err != nil {
+				return nil, err
+			}
+			elem, err := fromJSON(t.Elem(), obj[k])
+			if err != nil {
+				return nil, err
+			}
+			v.AssignMapIndex(key, elem)
+		}
+	case vdl.Struct:
+		obj, ok := j.(map[string]interface{})
+		if !ok {
+			return nil, errType(t, j)
+		}
+		for _, name := range sortedKeys(obj) {
+			index := fieldIndex(t, name)
+			if index < 0 {
+				return nil, fmt.Errorf("%v has no field %q", t, name)
+			}
+			field, err := fromJSON(t.Field(index).Type, obj[name])
+			if err != nil {
+				return nil, err
+			}
+			v.AssignField(index, field)
+		}
+	case vdl.Union:
+		obj, ok := j.(map[string]interface{})
+		if !ok || len(obj) != 1 {
+			return nil, errType(t, j)
+		}
+		for _, name := range sortedKeys(obj) {
+			index := fieldIndex(t, name)
+			if index < 0 {
+				return nil, fmt.Errorf("%v has no field %q", t, name)
+			}
+			field, err := fromJSON(t.Field(index).Type, obj[name])
+			if err != nil {
+				return nil, err
+			}
+			v.AssignField(index, field)
+		}
+	default:
+		return nil, fmt.Errorf("conversion from JSON to %v is not supported", t)
+	}
+	return v, nil
+}
+
+func elemOrKey(t *vdl.Type) *vdl.Type {
+	if t.Kind() == vdl.Set {
+		return t.Key()
+	}
+	return t.Elem()
+}
+
+func mapKeyFromJSON(t *vdl.Type, key string) (*vdl.Value, error) {
+	switch t.Kind() {
+	case vdl.String, vdl.Enum:
+		return fromJSON(t, key)
+	}
+	j, err := decodeJSON([]byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v map key %q: %v", t, key, err)
+	}
+	return fromJSON(t, j)
+}
+
+func fieldIndex(t *vdl.Type, name string) int {
+	if _, index := t.FieldByName(name); index >= 0 {
+		return index
+	}
+	_, index := t.FieldByName(vdlutil.FirstRuneToUpper(name))
+	return index
+}
+
+// numberString returns the text of j if it is a JSON number or string.
+func numberString(j interface{}) string {
+	switch n := j.(type) {
+	case json.Number:
+		return n.String()
+	case string:
+		return n
+	}
+	return ""
+}
+
+// nativeJSON converts j to a Go value with a natural VDL type, for use where
+// the VDL type is any: integers become int64 and other numbers float64, JSON
+// arrays become []interface{} and JSON objects map[string]interface{}.
+func nativeJSON(j interface{}) interface{} {
+	switch x := j.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, item := range x {
+			list[i] = nativeJSON(item)
+		}
+		return list
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			obj[k] = nativeJSON(item)
+		}
+		return obj
+	}
+	return j
+}
+
+func errType(t *vdl.Type, j interface{}) error {
+	buf, _ := json.Marshal(j)
+	return fmt.Errorf("cannot convert %s to %v", buf, t)
+}
+
+// sortedKeys returns the keys of obj in order, so that conversion errors are
+// reported deterministically.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
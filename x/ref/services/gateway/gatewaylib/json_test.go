@@ -0,0 +1,124 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gatewaylib
+
+import (
+	"encoding/json"
+	"testing"
+
+	"v.io/v23/vdl"
+)
+
+type jsonStruct struct {
+	A int32
+	B string
+	C []byte
+	D map[int16]bool
+	E *jsonStruct
+}
+
+var (
+	enumType  = vdl.NamedType("gatewaylib.Enum", vdl.EnumType("Foo", "Bar"))
+	unionType = vdl.NamedType("gatewaylib.Union", vdl.UnionType(
+		vdl.Field{Name: "X", Type: vdl.Int64Type},
+		vdl.Field{Name: "Y", Type: vdl.StringType},
+	))
+)
+
+func TestJSON(t *testing.T) {
+	for _, tc := range []struct {
+		value interface{}
+		json  string
+	}{
+		{true, `true`},
+		{int8(-3), `-3`},
+		{uint32(7), `7`},
+		{int64(-1) << 62, `"-4611686018427387904"`},
+		{uint64(1) << 63, `"9223372036854775808"`},
+		{float32(1.5), `1.5`},
+		{"abc", `"abc"`},
+		{vdl.EnumValue(enumType, 1), `"Bar"`},
+		{[3]int16{1, 2, 3}, `[1,2,3]`},
+		{map[string]bool{"a": true}, `{"a":true}`},
+		{map[int32]string{1: "a", 2: "b"}, `{"1":"a","2":"b"}`},
+		{map[string]struct{}{"x": {}, "y": {}}, `["x","y"]`},
+		{vdl.UnionValue(unionType, 1, vdl.StringValue(nil, "y")), `{"y":"y"}`},
+		{
+			jsonStruct{A: 1, B: "b", C: []byte{2}, D: map[int16]bool{3: true}, E: &jsonStruct{A: 4}},
+			`{"a":1,"b":"b","c":[2],"d":{"3":true},"e":{"a":4,"b":"","c":[],"d":{},"e":null}}`,
+		},
+	} {
+		v := vdl.ValueOf(tc.value)
+		buf, err := json.Marshal(toJSON(v))
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		if got, want := string(buf), tc.json; got != want {
+			t.Errorf("%v: got %v, want %v", v, got, want)
+		}
+		j, err := decodeJSON(buf)
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		got, err := fromJSON(v.Type(), j)
+		if err != nil {
+			t.Fatalf("%v: %v", v, err)
+		}
+		if !vdl.EqualValue(got, v) {
+			t.Errorf("got %v, want %v", got, v)
+		}
+	}
+}
+
+func TestFromJSON(t *testing.T) {
+	for _, tc := range []struct {
+		json  string
+		value interface{}
+	}{
+		{`"12"`, int32(12)},
+		{`12`, int64(12)},
+		{`{"A":1,"b":"x"}`, jsonStruct{A: 1, B: "x"}},
+		{`{"X":2}`, vdl.UnionValue(unionType, 0, vdl.IntValue(vdl.Int64Type, 2))},
+		{`null`, (*jsonStruct)(nil)},
+		{`{"a":[1,"x",{"b":2.5}]}`, vdl.AnyValue(vdl.ValueOf(map[string]interface{}{
+			"a": []interface{}{int64(1), "x", map[string]interface{}{"b": 2.5}},
+		}))},
+	} {
+		v := vdl.ValueOf(tc.value)
+		j, err := decodeJSON([]byte(tc.json))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.json, err)
+		}
+		got, err := fromJSON(v.Type(), j)
+		if err != nil {
+			t.Fatalf("%v: %v", tc.json, err)
+		}
+		if !vdl.EqualValue(got, v) {
+			t.Errorf("%v: got %v, want %v", tc.json, got, v)
+		}
+	}
+	for _, tc := range []struct {
+		json  string
+		value interface{}
+	}{
+		{`300`, byte(0)},
+		{`-1`, uint16(0)},
+		{`1.5`, int32(0)},
+		{`"x"`, false},
+		{`"Baz"`, vdl.ZeroValue(enumType)},
+		{`[1,2]`, [3]int16{}},
+		{`{"z":1}`, jsonStruct{}},
+		{`{"X":1,"Y":"y"}`, vdl.ZeroValue(unionType)},
+		{`{"x":true}`, map[int32]bool{}},
+	} {
+		j, err := decodeJSON([]byte(tc.json))
+		if err != nil {
+			t.Fatalf("%v: %v", tc.json, err)
+		}
+		if _, err := fromJSON(vdl.ValueOf(tc.value).Type(), j); err == nil {
+			t.Errorf("%v: expected an error converting to %T", tc.json, tc.value)
+		}
+	}
+}
@@ -17,6 +17,16 @@ The mounttabled flags are:
 
 	-acls=
 	  ACL file.  Default is to allow all access.
+	-dns-address=
+	  If provided, the address, e.g. :53, on which to answer DNS queries, over UDP
+	  and TCP, for the names in -dns-zone.  The name b.a.<zone> is resolved as a/b
+	  in this mount table and SRV, A, AAAA and TXT queries are answered with the
+	  hosts, ports and object addresses of the servers mounted there.
+	-dns-zone=
+	  The DNS zone, e.g. mt.example.com, whose names are answered by the server
+	  started by -dns-address, which requires it.  Names are mapped to lower case
+	  before they are resolved, so mount table names with upper case letters can't
+	  be resolved.
 	-mounttable-logging=1
 	  Mounttabled specific logging control, 0 for no logging, 1 for mount/unmount
 	  and 2 for all other operations.
@@ -31,6 +41,22 @@ The mounttabled flags are:
 	  and everything in the neighborhood will be visible on this mount table.
 	-persist-dir=
 	  Directory in which to persist permissions.
+	-persist-mounts=false
+	  If true, the mounted servers, along with their deadlines and mount flags, are
+	  also persisted in -persist-dir and are restored, less any that have expired,
+	  when the mount table restarts.
+	-replica-blessings=
+	  A comma-separated list of the blessing patterns of the members of the group
+	  named by -replicas, only principals whose blessings match one of them may
+	  call the replication service.  The default is this mount table's own blessing
+	  names, in which case all of the members must run with the same blessings.
+	-replicas=
+	  If provided, a comma-separated list of the rooted names of all of the
+	  members, including this one, of a group of replicated mount tables.  Changes
+	  are made by the leader of the group, which is elected by a majority of the
+	  members, and are copied to the other members, any of which may be used to
+	  resolve or glob names.  A change succeeds once it has been copied to a
+	  majority of the group.  Earlier members are preferred as leader.
 
 The global flags are:
 
@@ -54,6 +80,13 @@ The global flags are:
 	  log level for V logs
 	-v23.credentials=
 	  directory to use for storing security credentials
+	-v23.dial.no-proxy=$NO_PROXY
+	  comma separated list of the destinations that are to be dialed directly
+	  rather than via --v23.dial.proxy
+	-v23.dial.proxy=$ALL_PROXY or $HTTPS_PROXY
+	  egress proxy to use for outbound connections, one of socks5://, socks5h://,
+	  http:// or https://[user:password@]host:port, an http proxy is assumed if no
+	  scheme is given
 	-v23.namespace.root=[/(dev.v.io:r:vprod:service:mounttabled)@ns.dev.v.io:8101]
 	  local namespace root; can be repeated to provided multiple roots
 	-v23.permissions.file=
@@ -5,17 +5,33 @@
 package main_test
 
 import (
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/security"
+	"v.io/v23/verror"
 	"v.io/x/ref"
+	"v.io/x/ref/runtime/factories/library"
 	"v.io/x/ref/services/mounttable/mounttablelib"
 	"v.io/x/ref/test/expect"
 	"v.io/x/ref/test/v23test"
 )
 
+func init() {
+	// Allow v23.Init to be called multiple times.
+	library.AllowMultipleInitializations = true
+}
+
 func getHostname(t *testing.T) string {
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -69,6 +85,94 @@ func TestV23Mount(t *testing.T) {
 	}
 }
 
+func waitForLeader(t *testing.T, ctx *context.T, names []string) int {
+	for start := time.Now(); time.Since(start) < time.Minute; time.Sleep(250 * time.Millisecond) {
+		if l := mounttablelib.LeaderForTest(ctx, names); l >= 0 {
+			return l
+		}
+	}
+	t.Fatalf("no leader was elected among %v", names)
+	return -1
+}
+
+// waitForGlob waits for the output of globbing pattern on name to match re.
+func waitForGlob(t *testing.T, sh *v23test.Shell, clientBin string, clientCreds *v23test.Credentials, name, pattern, re string) {
+	var out string
+	for start := time.Now(); time.Since(start) < time.Minute; time.Sleep(250 * time.Millisecond) {
+		out = sh.Cmd(clientBin, "glob", name, pattern).WithCredentials(clientCreds).Stdout()
+		if regexp.MustCompile(re).MatchString(out) {
+			return
+		}
+	}
+	t.Fatalf("glob %v %v: got %q, want a match for %q", name, pattern, out, re)
+}
+
+func TestV23ReplicatedMount(t *testing.T) {
+	v23test.SkipUnlessRunningIntegrationTests(t)
+	sh := v23test.NewShell(t, nil)
+	defer sh.Cleanup()
+
+	mtBin := v23test.BuildGoPkg(sh, "v.io/x/ref/services/mounttable/mounttabled")
+	clientBin := v23test.BuildGoPkg(sh, "v.io/x/ref/cmd/mounttable")
+	mtCreds := sh.ForkCredentials("mt")
+	clientCreds := sh.ForkCredentials("cmd")
+
+	// Pick the addresses of the replicas in advance since each needs to
+	// know those of all of the others.
+	var addrs []string
+	for i := 0; i < 3; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, l.Addr().String())
+		l.Close()
+	}
+	replicas := "/" + strings.Join(addrs, ",/")
+	cmds := make([]*v23test.Cmd, len(addrs))
+	names := make([]string, len(addrs))
+	for i, addr := range addrs {
+		cmds[i] = sh.Cmd(mtBin, "-v23.tcp.address="+addr, "-replicas="+replicas).WithCredentials(mtCreds)
+		names[i] = start(cmds[i]).ExpectVar("NAME")
+	}
+
+	// Only the members of the group may call the replication service.
+	mtCtx, err := v23.WithPrincipal(sh.Ctx, mtCreds.Principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCtx, err := v23.WithPrincipal(sh.Ctx, clientCreds.Principal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mounttablelib.ReplicaClient(naming.Join(names[0], "__replication")).Status(clientCtx, options.Preresolved{}, options.ServerAuthorizer{Authorizer: security.AllowEveryone()}); !errors.Is(err, verror.ErrNoAccess) {
+		t.Fatalf("Status: got %v, want %v", err, verror.ErrNoAccess)
+	}
+
+	// Mount via a follower, the mount is seen by every replica.
+	l := waitForLeader(t, mtCtx, names)
+	f1, f2 := (l+1)%3, (l+2)%3
+	sh.Cmd(clientBin, "mount", names[f1]+"/google", "/www.google.com:80", "5m").WithCredentials(clientCreds).Run()
+	for _, name := range names {
+		waitForGlob(t, sh, clientBin, clientCreds, name, "*", `(?m)^google /www\.google\.com:80 \(Deadline .*\)$`)
+	}
+
+	// Kill the leader, one of the followers takes over and changes can
+	// still be made via the other.
+	cmds[l].Terminate(os.Kill)
+	names[l] = ""
+	nl := waitForLeader(t, mtCtx, names)
+	if nl != f1 && nl != f2 {
+		t.Fatalf("unexpected leader %v", nl)
+	}
+	nf := f1 + f2 - nl
+	sh.Cmd(clientBin, "mount", names[nf]+"/myself", names[nf], "5m").WithCredentials(clientCreds).Run()
+	for _, name := range []string{names[nl], names[nf]} {
+		waitForGlob(t, sh, clientBin, clientCreds, name, "*", `(?m)^google /www\.google\.com:80 \(Deadline .*\)$`)
+		waitForGlob(t, sh, clientBin, clientCreds, name, "*", `(?m)^myself `+regexp.QuoteMeta(names[nf])+` \(Deadline .*\)$`)
+	}
+}
+
 func TestMain(m *testing.M) {
 	v23test.TestMain(m)
 }
@@ -0,0 +1,501 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/verror"
+)
+
+const (
+	// dnsAddrLabel is the label under the zone of the names that SRV
+	// records use as the targets of servers listening on IP addresses.
+	// For example, 10.0.0.1 is 10-0-0-1._addr.<zone> and ::1 is
+	// 00000000000000000000000000000001._addr.<zone>.
+	dnsAddrLabel = "_addr"
+	// dnsMaxTTL is the largest TTL, in seconds, of any record, so that
+	// resolvers notice servers that are unmounted before their mounts
+	// expire, such as those mounted without a TTL, which don't expire for
+	// years.
+	dnsMaxTTL = 60 * 60
+	// dnsResolveTimeout bounds the time spent resolving a name; DNS
+	// clients usually give up after a few seconds.
+	dnsResolveTimeout = 5 * time.Second
+	// dnsUDPSize is the largest response sent over UDP, larger responses
+	// are truncated so that the client retries over TCP.
+	dnsUDPSize = 512
+	// dnsTCPIdleTimeout is how long a TCP connection may stay idle.
+	dnsTCPIdleTimeout = 30 * time.Second
+	// dnsMaxUDPQueries is the number of queries received over UDP that
+	// are answered concurrently, further queries wait to be read.
+	dnsMaxUDPQueries = 64
+	// dnsNegativeTTL is the TTL, in seconds, of the zone's SOA record,
+	// and so the time for which resolvers cache negative answers.
+	dnsNegativeTTL = 60
+)
+
+// dnsServer answers DNS queries for the names in a zone by resolving the
+// corresponding names in a mount table.  The name b.a.<zone> is the mount
+// table name a/b.
+type dnsServer struct {
+	ctx  *context.T
+	zone string // lower case, with leading and trailing dots.
+	soa  dnsmessage.Resource
+	root string // rooted name of the mount table.
+
+	udp     *net.UDPConn
+	tcp     *net.TCPListener
+	queries chan struct{} // holds a token for each UDP query being answered.
+	// wg tracks the goroutines that read from udp and tcp, answer UDP
+	// queries and serve TCP connections.
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	stopped bool
+	conns   map[net.Conn]bool // the open TCP connections.
+}
+
+// StartDNSServer starts a DNS server listening on address, over both UDP
+// and TCP, that answers SRV, A, AAAA and TXT queries for the names in zone
+// by resolving the corresponding names relative to the mount table called
+// mtName:
+//
+//   - SRV records contain the host and port of each server with a tcp
+//     endpoint; any leading labels starting with _, such as _service._tcp,
+//     are ignored.
+//   - A and AAAA records contain the IP addresses of the servers with tcp
+//     endpoints.
+//   - TXT records contain the object addresses of all of the servers.
+//
+// The TTL of each record is the time left until the server's mount
+// expires.  Names that don't exist, and names without records of the type
+// asked for, are answered with the zone's SOA record.  DNS names are case
+// insensitive but mount table names are not, so names are mapped to lower
+// case before they are resolved, ie. B.A.<zone> is also resolved as a/b,
+// and mount table names with upper case letters can't be resolved.  Names
+// are resolved with the credentials in ctx.  The zone is required.  It
+// returns the address of the server and a function that stops it, closing
+// any open connections and waiting for queries being answered to finish.
+func StartDNSServer(ctx *context.T, address, zone, mtName string) (string, func(), error) {
+	zone = strings.ToLower(strings.Trim(zone, "."))
+	if len(zone) == 0 {
+		return "", nil, errors.New("a DNS zone is required")
+	}
+	soa, err := dnsSOA(zone + ".")
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid DNS zone %q: %v", zone, err)
+	}
+	udpAddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return "", nil, err
+	}
+	udp, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return "", nil, err
+	}
+	// Listen for TCP on the same port, which matters if address has port 0.
+	tcpAddr := &net.TCPAddr{IP: udpAddr.IP, Port: udp.LocalAddr().(*net.UDPAddr).Port, Zone: udpAddr.Zone}
+	tcp, err := net.ListenTCP("tcp", tcpAddr)
+	if err != nil {
+		udp.Close()
+		return "", nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := &dnsServer{
+		ctx:     ctx,
+		zone:    "." + zone + ".",
+		soa:     soa,
+		root:    mtName,
+		udp:     udp,
+		tcp:     tcp,
+		queries: make(chan struct{}, dnsMaxUDPQueries),
+		conns:   make(map[net.Conn]bool),
+	}
+	s.wg.Add(2)
+	go s.serveUDP()
+	go s.serveTCP()
+	stop := func() {
+		udp.Close()
+		tcp.Close()
+		s.mu.Lock()
+		s.stopped = true
+		for conn := range s.conns {
+			conn.Close()
+		}
+		s.mu.Unlock()
+		cancel()
+		s.wg.Wait()
+	}
+	return udp.LocalAddr().String(), stop, nil
+}
+
+func (s *dnsServer) serveUDP() {
+	defer s.wg.Done()
+	buf := make([]byte, 64*1024)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			if !isClosed(err) {
+				s.ctx.Errorf("dns: udp read failed: %v", err)
+			}
+			return
+		}
+		req := append([]byte(nil), buf[:n]...)
+		s.queries <- struct{}{}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { <-s.queries }()
+			if resp := s.handle(req, dnsUDPSize); resp != nil {
+				if _, err := s.udp.WriteTo(resp, addr); err != nil {
+					s.ctx.VI(2).Infof("dns: reply to %v failed: %v", addr, err)
+				}
+			}
+		}()
+	}
+}
+
+func (s *dnsServer) serveTCP() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if !isClosed(err) {
+				s.ctx.Errorf("dns: tcp accept failed: %v", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		if s.stopped {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers the queries on a TCP connection, each of which is
+// preceded by its length.
+func (s *dnsServer) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	for {
+		conn.SetDeadline(time.Now().Add(dnsTCPIdleTimeout)) //nolint:errcheck
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		resp := s.handle(req, 0xffff)
+		if resp == nil {
+			return
+		}
+		binary.BigEndian.PutUint16(length[:], uint16(len(resp)))
+		if _, err := conn.Write(append(length[:], resp...)); err != nil {
+			return
+		}
+	}
+}
+
+func isClosed(err error) bool {
+	return errors.Is(err, net.ErrClosed)
+}
+
+// handle returns the response to a query, no larger than maxSize, or nil
+// if the query can't be parsed well enough to reply to it.
+func (s *dnsServer) handle(req []byte, maxSize int) []byte {
+	var p dnsmessage.Parser
+	h, err := p.Start(req)
+	if err != nil || h.Response {
+		return nil
+	}
+	resp := dnsmessage.Header{
+		ID:               h.ID,
+		Response:         true,
+		OpCode:           h.OpCode,
+		Authoritative:    true,
+		RecursionDesired: h.RecursionDesired,
+	}
+	q, err := p.Question()
+	if err != nil {
+		resp.RCode = dnsmessage.RCodeFormatError
+		return s.build(resp, nil, nil, nil, nil, maxSize)
+	}
+	if h.OpCode != 0 {
+		resp.RCode = dnsmessage.RCodeNotImplemented
+		return s.build(resp, &q, nil, nil, nil, maxSize)
+	}
+	answers, additionals, rcode := s.answer(q)
+	resp.RCode = rcode
+	var authorities []dnsmessage.Resource
+	if rcode == dnsmessage.RCodeNameError || (rcode == dnsmessage.RCodeSuccess && len(answers) == 0) {
+		authorities = []dnsmessage.Resource{s.soa}
+	}
+	return s.build(resp, &q, answers, authorities, additionals, maxSize)
+}
+
+// build encodes a response, leaving out the records and setting the
+// truncated bit if it would be larger than maxSize.
+func (s *dnsServer) build(h dnsmessage.Header, q *dnsmessage.Question, answers, authorities, additionals []dnsmessage.Resource, maxSize int) []byte {
+	msg := dnsmessage.Message{Header: h, Answers: answers, Authorities: authorities, Additionals: additionals}
+	if q != nil {
+		msg.Questions = []dnsmessage.Question{*q}
+	}
+	resp, err := msg.Pack()
+	if err == nil && len(resp) <= maxSize {
+		return resp
+	}
+	if err != nil {
+		s.ctx.Errorf("dns: failed to encode the response to %v: %v", q, err)
+		msg.Header.RCode = dnsmessage.RCodeServerFailure
+	} else {
+		msg.Header.Truncated = true
+	}
+	msg.Answers, msg.Authorities, msg.Additionals = nil, nil, nil
+	resp, err = msg.Pack()
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+// answer returns the records that answer a question.
+func (s *dnsServer) answer(q dnsmessage.Question) ([]dnsmessage.Resource, []dnsmessage.Resource, dnsmessage.RCode) {
+	if q.Class != dnsmessage.ClassINET && q.Class != dnsmessage.ClassANY {
+		return nil, nil, dnsmessage.RCodeRefused
+	}
+	labels, ok := s.labels(q.Name.String())
+	if !ok {
+		return nil, nil, dnsmessage.RCodeRefused
+	}
+	if len(labels) == 2 && labels[1] == dnsAddrLabel {
+		return s.answerAddr(q, labels[0])
+	}
+	if q.Type == dnsmessage.TypeSRV {
+		for len(labels) > 0 && strings.HasPrefix(labels[0], "_") {
+			labels = labels[1:]
+		}
+	}
+	if len(labels) == 0 {
+		if q.Type == dnsmessage.TypeSOA || q.Type == dnsmessage.TypeALL {
+			return []dnsmessage.Resource{s.soa}, nil, dnsmessage.RCodeSuccess
+		}
+		return nil, nil, dnsmessage.RCodeSuccess
+	}
+	// The most significant label comes last in DNS names, but first in
+	// mount table names.
+	elems := make([]string, len(labels))
+	for i, l := range labels {
+		elems[len(labels)-1-i] = l
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, dnsResolveTimeout)
+	defer cancel()
+	me, err := v23.GetNamespace(ctx).Resolve(ctx, naming.Join(s.root, strings.Join(elems, "/")))
+	switch {
+	case err == nil:
+	case verror.ErrorID(err) == naming.ErrNoSuchName.ID, verror.ErrorID(err) == naming.ErrNoSuchNameRoot.ID:
+		return nil, nil, dnsmessage.RCodeNameError
+	default:
+		s.ctx.VI(2).Infof("dns: resolving %v failed: %v", q.Name, err)
+		return nil, nil, dnsmessage.RCodeServerFailure
+	}
+
+	var answers, additionals []dnsmessage.Resource
+	for _, server := range me.Servers {
+		hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: dnsTTL(server)}
+		if q.Type == dnsmessage.TypeTXT || q.Type == dnsmessage.TypeALL {
+			answers = append(answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.TXTResource{TXT: txtStrings(server.Server)}})
+		}
+		host, port, ok := tcpAddress(server.Server)
+		if !ok {
+			continue
+		}
+		ip := net.ParseIP(host)
+		if ip != nil {
+			if r := ipResource(hdr, ip, q.Type); r != nil {
+				answers = append(answers, *r)
+			}
+		}
+		if q.Type != dnsmessage.TypeSRV && q.Type != dnsmessage.TypeALL {
+			continue
+		}
+		target := host
+		if ip != nil {
+			target = addrLabel(ip) + "." + dnsAddrLabel + s.zone
+			ahdr := hdr
+			ahdr.Name, _ = dnsmessage.NewName(target)
+			if r := ipResource(ahdr, ip, dnsmessage.TypeALL); r != nil {
+				additionals = append(additionals, *r)
+			}
+		} else if !strings.HasSuffix(target, ".") {
+			target += "."
+		}
+		name, err := dnsmessage.NewName(target)
+		if err != nil {
+			continue
+		}
+		answers = append(answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.SRVResource{Weight: 1, Port: port, Target: name}})
+	}
+	return answers, additionals, dnsmessage.RCodeSuccess
+}
+
+// answerAddr answers a question about the name of an IP address used as
+// the target of an SRV record.
+func (s *dnsServer) answerAddr(q dnsmessage.Question, label string) ([]dnsmessage.Resource, []dnsmessage.Resource, dnsmessage.RCode) {
+	ip := net.ParseIP(strings.ReplaceAll(label, "-", "."))
+	if b, err := hex.DecodeString(label); err == nil && len(b) == net.IPv6len {
+		ip = net.IP(b)
+	}
+	if ip == nil {
+		return nil, nil, dnsmessage.RCodeNameError
+	}
+	hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: dnsMaxTTL}
+	if r := ipResource(hdr, ip, q.Type); r != nil {
+		return []dnsmessage.Resource{*r}, nil, dnsmessage.RCodeSuccess
+	}
+	return nil, nil, dnsmessage.RCodeSuccess
+}
+
+// labels returns the labels of name below the zone, in lower case, or false
+// if name isn't in the zone.
+func (s *dnsServer) labels(name string) ([]string, bool) {
+	name = strings.ToLower("." + name)
+	if !strings.HasSuffix(name, s.zone) {
+		return nil, false
+	}
+	name = strings.TrimPrefix(name[:len(name)-len(s.zone)], ".")
+	if name == "" {
+		return nil, true
+	}
+	return strings.Split(name, "."), true
+}
+
+// dnsSOA returns the SOA record of zone, which is sent with negative
+// answers.  The zone is never transferred, so the serial number and the
+// intervals used by secondary servers don't matter.
+func dnsSOA(zone string) (dnsmessage.Resource, error) {
+	name, err := dnsmessage.NewName(zone)
+	if err != nil {
+		return dnsmessage.Resource{}, err
+	}
+	mbox, err := dnsmessage.NewName("hostmaster." + zone)
+	if err != nil {
+		return dnsmessage.Resource{}, err
+	}
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: dnsNegativeTTL},
+		Body: &dnsmessage.SOAResource{
+			NS:      name,
+			MBox:    mbox,
+			Serial:  1,
+			Refresh: dnsMaxTTL,
+			Retry:   dnsNegativeTTL,
+			Expire:  dnsMaxTTL,
+			MinTTL:  dnsNegativeTTL,
+		},
+	}, nil
+}
+
+// addrLabel returns the label for ip in the names of SRV targets.
+func addrLabel(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return strings.ReplaceAll(ip4.String(), ".", "-")
+	}
+	return hex.EncodeToString(ip.To16())
+}
+
+// tcpAddress returns the host and port of a server with a tcp endpoint.
+func tcpAddress(server string) (string, uint16, bool) {
+	address, _ := naming.SplitAddressName(server)
+	ep, err := naming.ParseEndpoint(address)
+	if err != nil {
+		return "", 0, false
+	}
+	switch ep.Addr().Network() {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return "", 0, false
+	}
+	host, port, err := net.SplitHostPort(ep.Addr().String())
+	if err != nil {
+		return "", 0, false
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || p == 0 {
+		return "", 0, false
+	}
+	return host, uint16(p), true
+}
+
+// ipResource returns an A or AAAA record for ip if one was asked for.
+func ipResource(hdr dnsmessage.ResourceHeader, ip net.IP, qtype dnsmessage.Type) *dnsmessage.Resource {
+	if ip4 := ip.To4(); ip4 != nil {
+		if qtype != dnsmessage.TypeA && qtype != dnsmessage.TypeALL {
+			return nil
+		}
+		r := &dnsmessage.AResource{}
+		copy(r.A[:], ip4)
+		return &dnsmessage.Resource{Header: hdr, Body: r}
+	}
+	if qtype != dnsmessage.TypeAAAA && qtype != dnsmessage.TypeALL {
+		return nil
+	}
+	r := &dnsmessage.AAAAResource{}
+	copy(r.AAAA[:], ip.To16())
+	return &dnsmessage.Resource{Header: hdr, Body: r}
+}
+
+// txtStrings splits s into the 255 byte strings that a TXT record holds.
+func txtStrings(s string) []string {
+	var txt []string
+	for len(s) > 255 {
+		txt = append(txt, s[:255])
+		s = s[255:]
+	}
+	return append(txt, s)
+}
+
+// dnsTTL returns the number of seconds until a server's mount expires, but
+// no more than dnsMaxTTL.
+func dnsTTL(server naming.MountedServer) uint32 {
+	if server.Deadline.IsZero() {
+		return dnsMaxTTL
+	}
+	ttl := time.Until(server.Deadline.Time) / time.Second
+	switch {
+	case ttl < 0:
+		return 0
+	case ttl > dnsMaxTTL:
+		return dnsMaxTTL
+	}
+	return uint32(ttl)
+}
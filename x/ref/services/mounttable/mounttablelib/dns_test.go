@@ -0,0 +1,234 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib_test
+
+import (
+	gocontext "context"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	v23 "v.io/v23"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/x/ref/services/mounttable/mounttablelib"
+)
+
+// dnsQuery sends a query over UDP and returns the response.
+func dnsQuery(t *testing.T, addr, name string, qtype dnsmessage.Type) *dnsmessage.Message {
+	q := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	req, err := q.Pack()
+	if err != nil {
+		boom(t, "Pack: %v", err)
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		boom(t, "Dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(30 * time.Second)) //nolint:errcheck
+	if _, err := conn.Write(req); err != nil {
+		boom(t, "Write: %v", err)
+	}
+	buf := make([]byte, 64*1024)
+	n, err := conn.Read(buf)
+	if err != nil {
+		boom(t, "Read: %v", err)
+	}
+	var resp dnsmessage.Message
+	if err := resp.Unpack(buf[:n]); err != nil {
+		boom(t, "Unpack: %v", err)
+	}
+	if resp.Header.ID != 42 || !resp.Header.Response {
+		boom(t, "%v %v: unexpected header %v", name, qtype, resp.Header)
+	}
+	return &resp
+}
+
+// dnsStrings returns the answers in a response as strings, sorted.
+func dnsStrings(t *testing.T, rs []dnsmessage.Resource) []string {
+	var got []string
+	for _, r := range rs {
+		if r.Header.TTL > ttlSecs || r.Header.TTL < ttlSecs-60 {
+			boom(t, "%v: TTL %v not close to %v", r.Header.Name, r.Header.TTL, ttlSecs)
+		}
+		switch b := r.Body.(type) {
+		case *dnsmessage.AResource:
+			got = append(got, r.Header.Name.String()+" "+net.IP(b.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			got = append(got, r.Header.Name.String()+" "+net.IP(b.AAAA[:]).String())
+		case *dnsmessage.SRVResource:
+			got = append(got, b.Target.String()+" "+fmt.Sprint(b.Port))
+		case *dnsmessage.TXTResource:
+			got = append(got, strings.Join(b.TXT, ""))
+		default:
+			boom(t, "unexpected record %v", r)
+		}
+	}
+	sort.Strings(got)
+	return got
+}
+
+func checkDNS(t *testing.T, addr, name string, qtype dnsmessage.Type, rcode dnsmessage.RCode, want ...string) *dnsmessage.Message {
+	resp := dnsQuery(t, addr, name, qtype)
+	if resp.Header.RCode != rcode {
+		boom(t, "%v %v: got %v, want %v", name, qtype, resp.Header.RCode, rcode)
+	}
+	if got := dnsStrings(t, resp.Answers); !reflect.DeepEqual(got, want) && (len(got) > 0 || len(want) > 0) {
+		boom(t, "%v %v: got %v, want %v", name, qtype, got, want)
+	}
+	return resp
+}
+
+func TestDNS(t *testing.T) {
+	rootCtx, _, _, shutdown := initTest()
+	defer shutdown()
+
+	mtName, stop, err := mounttablelib.StartServersWithOpts(rootCtx, v23.GetListenSpec(rootCtx), mounttablelib.Opts{}, "testDNS")
+	if err != nil {
+		boom(t, "StartServersWithOpts: %v", err)
+	}
+	defer stop()
+	addr, stopDNS, err := mounttablelib.StartDNSServer(rootCtx, "127.0.0.1:0", "mt.example.com", mtName)
+	if err != nil {
+		boom(t, "StartDNSServer: %v", err)
+	}
+	defer stopDNS()
+
+	ep := func(protocol, address string) string {
+		return "/@6@" + protocol + "@" + address + "@@00000000000000000000000000000000@s@@"
+	}
+	doMount(t, rootCtx, mtName, "a/b", ep("tcp", "127.0.0.1:1111"), true)
+	doMount(t, rootCtx, mtName, "a/b", ep("tcp6", "[::1]:2222"), true)
+	doMount(t, rootCtx, mtName, "a/b", ep("tcp4", "127.0.0.1:3333"), true)
+	doMount(t, rootCtx, mtName, "a/b", ep("ws", "127.0.0.1:5555"), true)
+	doMount(t, rootCtx, mtName, "a/c", ep("tcp", "server.example.com:4444"), true)
+
+	// SRV records, with the addresses of their targets.
+	resp := checkDNS(t, addr, "_svc._tcp.b.a.mt.example.com.", dnsmessage.TypeSRV, dnsmessage.RCodeSuccess,
+		"00000000000000000000000000000001._addr.mt.example.com. 2222",
+		"127-0-0-1._addr.mt.example.com. 1111",
+		"127-0-0-1._addr.mt.example.com. 3333",
+	)
+	if got, want := dnsStrings(t, resp.Additionals), []string{
+		"00000000000000000000000000000001._addr.mt.example.com. ::1",
+		"127-0-0-1._addr.mt.example.com. 127.0.0.1",
+		"127-0-0-1._addr.mt.example.com. 127.0.0.1",
+	}; !reflect.DeepEqual(got, want) {
+		boom(t, "got %v, want %v", got, want)
+	}
+	checkDNS(t, addr, "c.a.mt.example.com.", dnsmessage.TypeSRV, dnsmessage.RCodeSuccess, "server.example.com. 4444")
+
+	// A, AAAA and TXT records.
+	checkDNS(t, addr, "b.a.mt.example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess,
+		"b.a.mt.example.com. 127.0.0.1",
+		"b.a.mt.example.com. 127.0.0.1",
+	)
+	checkDNS(t, addr, "b.a.mt.example.com.", dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess, "b.a.mt.example.com. ::1")
+	checkDNS(t, addr, "b.a.mt.example.com.", dnsmessage.TypeTXT, dnsmessage.RCodeSuccess,
+		ep("tcp4", "127.0.0.1:3333"),
+		ep("tcp6", "[::1]:2222"),
+		ep("tcp", "127.0.0.1:1111"),
+		ep("ws", "127.0.0.1:5555"),
+	)
+	checkDNS(t, addr, "c.a.mt.example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess)
+
+	// The targets of SRV records.
+	resp = dnsQuery(t, addr, "127-0-0-1._addr.mt.example.com.", dnsmessage.TypeA)
+	if len(resp.Answers) != 1 || resp.Answers[0].Body.(*dnsmessage.AResource).A != [4]byte{127, 0, 0, 1} {
+		boom(t, "unexpected answers %v", resp.Answers)
+	}
+	resp = dnsQuery(t, addr, "00000000000000000000000000000001._addr.mt.example.com.", dnsmessage.TypeAAAA)
+	if len(resp.Answers) != 1 || !net.IP(resp.Answers[0].Body.(*dnsmessage.AAAAResource).AAAA[:]).Equal(net.IPv6loopback) {
+		boom(t, "unexpected answers %v", resp.Answers)
+	}
+
+	// Servers mounted without a TTL don't expire for years, the TTL of
+	// their records is capped.
+	if err := v23.GetClient(rootCtx).Call(rootCtx, naming.JoinAddressName(mtName, "a/d"), "Mount", []interface{}{ep("tcp", "127.0.0.1:6666"), uint32(0), 0}, nil, options.Preresolved{}); err != nil {
+		boom(t, "Mount: %v", err)
+	}
+	checkDNS(t, addr, "d.a.mt.example.com.", dnsmessage.TypeA, dnsmessage.RCodeSuccess, "d.a.mt.example.com. 127.0.0.1")
+
+	// Names are case insensitive.
+	checkDNS(t, addr, "B.a.MT.example.COM.", dnsmessage.TypeA, dnsmessage.RCodeSuccess,
+		"B.a.MT.example.COM. 127.0.0.1",
+		"B.a.MT.example.COM. 127.0.0.1",
+	)
+
+	// Names that don't exist or are outside the zone, negative answers
+	// include the zone's SOA record.
+	checkSOA := func(resp *dnsmessage.Message) {
+		if len(resp.Authorities) != 1 {
+			boom(t, "got authorities %v, want the SOA record", resp.Authorities)
+		}
+		soa, ok := resp.Authorities[0].Body.(*dnsmessage.SOAResource)
+		if !ok || resp.Authorities[0].Header.Name.String() != "mt.example.com." || soa.MinTTL == 0 {
+			boom(t, "unexpected authority %v", resp.Authorities[0])
+		}
+	}
+	checkSOA(checkDNS(t, addr, "x.a.mt.example.com.", dnsmessage.TypeA, dnsmessage.RCodeNameError))
+	checkSOA(checkDNS(t, addr, "c.a.mt.example.com.", dnsmessage.TypeAAAA, dnsmessage.RCodeSuccess))
+	resp = checkDNS(t, addr, "b.a.example.com.", dnsmessage.TypeA, dnsmessage.RCodeRefused)
+	if len(resp.Authorities) != 0 {
+		boom(t, "unexpected authorities %v", resp.Authorities)
+	}
+	resp = dnsQuery(t, addr, "MT.example.com.", dnsmessage.TypeSOA)
+	if len(resp.Answers) != 1 || resp.Answers[0].Header.Type != dnsmessage.TypeSOA {
+		boom(t, "unexpected answers %v", resp.Answers)
+	}
+
+	// A zone is required.
+	if _, _, err := mounttablelib.StartDNSServer(rootCtx, "127.0.0.1:0", "", mtName); err == nil {
+		boom(t, "StartDNSServer: expected an error for an empty zone")
+	}
+
+	// Clients that use TCP.
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx gocontext.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "tcp", addr)
+		},
+	}
+	_, srvs, err := resolver.LookupSRV(rootCtx, "svc", "tcp", "b.a.mt.example.com")
+	if err != nil {
+		boom(t, "LookupSRV: %v", err)
+	}
+	var ports []int
+	for _, srv := range srvs {
+		ports = append(ports, int(srv.Port))
+	}
+	sort.Ints(ports)
+	if want := []int{1111, 2222, 3333}; !reflect.DeepEqual(ports, want) {
+		boom(t, "got %v, want %v", ports, want)
+	}
+
+	// Stopping the server closes open TCP connections.
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		boom(t, "Dial: %v", err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	stopDNS()
+	if d := time.Since(start); d > 10*time.Second {
+		boom(t, "stopping the server took %v", d)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint:errcheck
+	if _, err := conn.Read(make([]byte, 1)); err == nil || os.IsTimeout(err) {
+		boom(t, "Read: got %v, want the connection to be closed", err)
+	}
+}
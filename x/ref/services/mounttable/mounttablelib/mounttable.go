@@ -44,6 +44,8 @@ var (
 type persistence interface {
 	persistPerms(name, creator string, perm *VersionedPermissions) error
 	persistDelete(name string) error
+	persistMount(name, server string, expires time.Time, flags naming.MountFlag) error
+	persistUnmount(name, server string) error
 	close()
 }
 
@@ -61,6 +63,8 @@ type mountTable struct {
 	perUserRPCCounter  *stats.Map
 	maxNodesPerUser    int64
 	slm                *serverListManager
+	repl               *replicator // nil unless the mount table is replicated.
+	changes            *changeLog
 }
 
 var _ rpc.Dispatcher = (*mountTable)(nil)
@@ -124,6 +128,23 @@ func NewMountTableDispatcher(ctx *context.T, permsFile, persistDir, statsPrefix
 	return NewMountTableDispatcherWithClock(ctx, permsFile, persistDir, statsPrefix, timekeeper.RealTime(), 0)
 }
 func NewMountTableDispatcherWithClock(ctx *context.T, permsFile, persistDir, statsPrefix string, clock timekeeper.TimeKeeper, logLevel int) (rpc.Dispatcher, error) {
+	return newMountTableDispatcher(ctx, Opts{AclFile: permsFile, PersistDir: persistDir, LogLevel: logLevel}, statsPrefix, clock)
+}
+
+// NewMountTableDispatcherWithOpts is like NewMountTableDispatcherWithClock
+// with the perms file, persist directory and log level taken from opts. If
+// opts.PersistMounts is set, the mounted servers, along with their deadlines
+// and mount flags, are also persisted in opts.PersistDir and are restored,
+// less any that have expired, when the mount table is restarted. If
+// opts.Replicas is set, the mount table is a member of a replicated group,
+// see newReplicator; the replication service itself must be served by the
+// caller, as StartServersWithOpts does.
+func NewMountTableDispatcherWithOpts(ctx *context.T, opts Opts, statsPrefix string, clock timekeeper.TimeKeeper) (rpc.Dispatcher, error) {
+	return newMountTableDispatcher(ctx, opts, statsPrefix, clock)
+}
+
+func newMountTableDispatcher(ctx *context.T, opts Opts, statsPrefix string, clock timekeeper.TimeKeeper) (rpc.Dispatcher, error) {
+	permsFile, persistDir, logLevel := opts.AclFile, opts.PersistDir, opts.LogLevel
 	mt := &mountTable{
 		root:               new(node),
 		nodeCounter:        stats.NewInteger(naming.Join(statsPrefix, "num-nodes")),
@@ -133,15 +154,19 @@ func NewMountTableDispatcherWithClock(ctx *context.T, permsFile, persistDir, sta
 		maxNodesPerUser:    defaultMaxNodesPerUser,
 		slm:                newServerListManager(clock),
 		logLevel:           logLevel,
+		changes:            newChangeLog(ctx),
 	}
 	mt.root.parent = mt.newNode() // just for its lock
 	if persistDir != "" {
-		mt.persist = newPersistentStore(ctx, mt, persistDir)
+		mt.persist = newPersistentStore(ctx, mt, persistDir, opts.PersistMounts)
 		mt.persisting = mt.persist != nil
 	}
 	if err := mt.parsePermFile(ctx, permsFile); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("perms file %v invalid: %v", permsFile, err)
 	}
+	if len(opts.Replicas) > 0 {
+		mt.repl = newReplicator(ctx, mt, strings.Split(opts.Replicas, ","), opts.ReplicaBlessings)
+	}
 	return mt, nil
 }
 
@@ -195,6 +220,12 @@ func (mt *mountTable) Lookup(ctx *context.T, name string) (interface{}, security
 	} else {
 		ctx.VI(2).Infof("********************* Lookup %s", name)
 	}
+	ms := mt.newMountContext(name)
+	return WatchableMountTableServer(ms), ms, nil
+}
+
+// newMountContext returns a mountContext for name.
+func (mt *mountTable) newMountContext(name string) *mountContext {
 	ms := &mountContext{
 		logLevel: mt.logLevel,
 		name:     name,
@@ -203,7 +234,7 @@ func (mt *mountTable) Lookup(ctx *context.T, name string) (interface{}, security
 	if len(name) > 0 {
 		ms.elems = strings.Split(name, "/")
 	}
-	return mounttable.MountTableServer(ms), ms, nil
+	return ms
 }
 
 // isActive returns true if a mount has unexpired servers attached.
@@ -506,9 +537,31 @@ func (ms *mountContext) Mount(ctx *context.T, call rpc.ServerCall, server string
 		return err
 	}
 	mt, cc := ms.newCallContext(ctx, call.Security(), createMissingNodes)
+	if mt.repl != nil {
+		return mt.repl.forward(cc, ForwardedCall{Method: "Mount", Name: ms.name, Server: server, Ttl: ttlsecs, Flags: flags})
+	}
+	return ms.mount(cc, server, ttlsecs, flags)
+}
+
+func (ms *mountContext) mount(cc *callContext, server string, ttlsecs uint32, flags naming.MountFlag) error {
 	if ttlsecs == 0 {
 		ttlsecs = 10 * 365 * 24 * 60 * 60 // a really long time
 	}
+	return ms.mountWithTTL(cc, server, time.Duration(ttlsecs)*time.Second, flags)
+}
+
+// mountWithTTL adds server to the servers mounted on the name in the
+// receiver until ttl has passed.
+func (ms *mountContext) mountWithTTL(cc *callContext, server string, ttl time.Duration, flags naming.MountFlag) error {
+	mt, ctx := ms.mt, cc.ctx
+	// Watchers are not notified when a server merely refreshes its mount,
+	// since only its deadline changes.
+	refreshed := false
+	defer func() {
+		if !refreshed {
+			mt.changes.notify()
+		}
+	}()
 
 	// Make sure the server address is reasonable.
 	epString := server
@@ -543,6 +596,7 @@ func (ms *mountContext) Mount(ctx *context.T, call rpc.ServerCall, server string
 		}
 	}
 	// Remove any existing children.
+	hadChildren := len(n.children) > 0
 	for child := range n.children {
 		mt.deleteNode(n, child)
 	}
@@ -554,8 +608,12 @@ func (ms *mountContext) Mount(ctx *context.T, call rpc.ServerCall, server string
 	if n.mount == nil {
 		n.mount = &mount{servers: mt.slm.newServerList(), mt: wantMT, leaf: wantLeaf}
 	}
-	n.mount.servers.add(server, time.Duration(ttlsecs)*time.Second)
+	expires, existing := n.mount.servers.add(server, ttl)
+	refreshed = existing && !hadChildren
 	mt.serverCounter.Incr(numServers(n) - nServersBefore)
+	if mt.persisting {
+		mt.persist.persistMount(ms.name, server, expires, flags) //nolint:errcheck
+	}
 	return nil
 }
 
@@ -605,6 +663,15 @@ func (ms *mountContext) Unmount(ctx *context.T, call rpc.ServerCall, server stri
 		ctx.VI(2).Infof("********************* Unmount %q, %s", ms.name, server)
 	}
 	mt, cc := ms.newCallContext(ctx, call.Security(), !createMissingNodes)
+	if mt.repl != nil {
+		return mt.repl.forward(cc, ForwardedCall{Method: "Unmount", Name: ms.name, Server: server})
+	}
+	return ms.unmount(cc, server)
+}
+
+func (ms *mountContext) unmount(cc *callContext, server string) error {
+	mt := ms.mt
+	defer mt.changes.notify()
 	n, err := mt.findNode(cc, ms.elems, mountTags, nil)
 	if err != nil {
 		return err
@@ -619,6 +686,9 @@ func (ms *mountContext) Unmount(ctx *context.T, call rpc.ServerCall, server stri
 		n.mount = nil
 	}
 	mt.serverCounter.Incr(numServers(n) - nServersBefore)
+	if mt.persisting {
+		mt.persist.persistUnmount(ms.name, server) //nolint:errcheck
+	}
 	removed := n.removeUseless(mt)
 	n.parent.Unlock()
 	n.Unlock()
@@ -638,6 +708,15 @@ func (ms *mountContext) Delete(ctx *context.T, call rpc.ServerCall, deleteSubTre
 		ctx.VI(2).Infof("********************* Delete %q, %v", ms.name, deleteSubTree)
 	}
 	mt, cc := ms.newCallContext(ctx, call.Security(), !createMissingNodes)
+	if mt.repl != nil {
+		return mt.repl.forward(cc, ForwardedCall{Method: "Delete", Name: ms.name, DeleteSubtree: deleteSubTree})
+	}
+	return ms.delete(cc, deleteSubTree)
+}
+
+func (ms *mountContext) delete(cc *callContext, deleteSubTree bool) error {
+	mt := ms.mt
+	defer mt.changes.notify()
 	if len(ms.elems) == 0 {
 		// We can't delete the root.
 		return fmt.Errorf("cannot delete root node")
@@ -790,21 +869,27 @@ func (ms *mountContext) Glob__(ctx *context.T, call rpc.GlobServerCall, g *glob.
 	} else {
 		ctx.VI(2).Infof("********************* Glob__ %v", ms.elems)
 	}
-	mt, cc := ms.newCallContext(ctx, call.Security(), !createMissingNodes)
+	_, cc := ms.newCallContext(ctx, call.Security(), !createMissingNodes)
+	ms.glob(cc, g, call)
+	return nil
+}
+
+// glob sends the names matching g on gCall.
+func (ms *mountContext) glob(cc *callContext, g *glob.Glob, gCall rpc.GlobServerCall) {
+	mt := ms.mt
 	// If there was an access error, just ignore the entry, i.e., make it invisible.
 	n, err := mt.findNode(cc, ms.elems, nil, nil)
 	if err != nil {
-		return nil
+		return
 	}
 	// If the current name is not fully resolvable on this nameserver we
 	// don't need to evaluate the glob expression. Send a partially resolved
 	// name back to the client.
 	if n == nil {
-		ms.linkToLeaf(cc, call)
-		return nil
+		ms.linkToLeaf(cc, gCall)
+		return
 	}
-	mt.globStep(cc, n, "", g, call)
-	return nil
+	mt.globStep(cc, n, "", g, gCall)
 }
 
 func (ms *mountContext) linkToLeaf(cc *callContext, gCall rpc.GlobServerCall) {
@@ -831,6 +916,15 @@ func (ms *mountContext) SetPermissions(ctx *context.T, call rpc.ServerCall, perm
 		return err
 	}
 	mt, cc := ms.newCallContext(ctx, call.Security(), createMissingNodes)
+	if mt.repl != nil {
+		return mt.repl.forward(cc, ForwardedCall{Method: "SetPermissions", Name: ms.name, Perms: perms, Version: version})
+	}
+	return ms.setPermissions(cc, perms, version)
+}
+
+func (ms *mountContext) setPermissions(cc *callContext, perms access.Permissions, version string) error {
+	mt, ctx := ms.mt, cc.ctx
+	defer mt.changes.notify()
 
 	// Find/create node in namespace and add the mount.
 	n, err := mt.findNode(cc, ms.elems, setTags, nil)
@@ -190,12 +190,17 @@ func checkContents(t *testing.T, ctx *context.T, name, expected string, shouldSu
 }
 
 func newMT(t *testing.T, permsFile, persistDir, statsDir string, rootCtx *context.T) (func(), string, timekeeper.ManualTime) {
+	clock := timekeeper.NewManualTime()
+	stop, estr := newMTWithOpts(t, mounttablelib.Opts{AclFile: permsFile, PersistDir: persistDir}, statsDir, rootCtx, clock)
+	return stop, estr, clock
+}
+
+func newMTWithOpts(t *testing.T, opts mounttablelib.Opts, statsDir string, rootCtx *context.T, clock timekeeper.ManualTime) (func(), string) {
 	reservedDisp := debuglib.NewDispatcher(nil)
 	ctx := v23.WithReservedNameDispatcher(rootCtx, reservedDisp)
 
 	// Add mount table service.
-	clock := timekeeper.NewManualTime()
-	mt, err := mounttablelib.NewMountTableDispatcherWithClock(ctx, permsFile, persistDir, statsDir, clock, 0)
+	mt, err := mounttablelib.NewMountTableDispatcherWithOpts(ctx, opts, statsDir, clock)
 	if err != nil {
 		boom(t, "mounttablelib.NewMountTableDispatcher: %v", err)
 	}
@@ -212,7 +217,7 @@ func newMT(t *testing.T, permsFile, persistDir, statsDir string, rootCtx *contex
 	return func() {
 		cancel()
 		<-server.Closed()
-	}, estr, clock
+	}, estr
 }
 
 func newCollection(t *testing.T, rootCtx *context.T) (func(), string) {
@@ -460,6 +465,10 @@ func (fakeServerCall) RemoteEndpoint() naming.Endpoint      { return naming.Endp
 func (fakeServerCall) RemoteAddr() net.Addr                 { return nil }
 func (fakeServerCall) GrantedBlessings() security.Blessings { return security.Blessings{} }
 func (fakeServerCall) Server() rpc.Server                   { return nil }
+
+func (fakeServerCall) Metadata() map[string]string           { return nil }
+func (fakeServerCall) SetResponseMetadata(map[string]string) {}
+
 func (c *fakeServerCall) SendStream() interface {
 	Send(naming.GlobReply) error
 } {
@@ -1,4 +1,4 @@
-// Copyright 2015 The Vanadium Authors. All rights reserved.
+// Copyright 2022 The Vanadium Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
@@ -9,14 +9,1018 @@
 package mounttablelib
 
 import (
+	"fmt"
+	"time"
+
 	v23 "v.io/v23"
 	"v.io/v23/context"
+	"v.io/v23/naming"
 	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/services/mounttable"
+	"v.io/v23/services/permissions"
+	"v.io/v23/services/watch"
+	"v.io/v23/vdl"
+	vdltime "v.io/v23/vdlroot/time"
+	"v.io/v23/verror"
 )
 
 var initializeVDLCalled = false
 var _ = initializeVDL() // Must be first; see initializeVDL comments for details.
 
+// Hold type definitions in package-level variables, for better performance.
+// Declare and initialize with default values here so that the initializeVDL
+// method will be considered ready to initialize before any of the type
+// definitions that appear below.
+//
+//nolint:unused
+var (
+	vdlTypeStruct1 *vdl.Type = nil
+	vdlTypeStruct2 *vdl.Type = nil
+	vdlTypeMap3    *vdl.Type = nil
+	vdlTypeStruct4 *vdl.Type = nil
+	vdlTypeUint325 *vdl.Type = nil
+	vdlTypeStruct6 *vdl.Type = nil
+	vdlTypeList7   *vdl.Type = nil
+	vdlTypeStruct8 *vdl.Type = nil
+	vdlTypeStruct9 *vdl.Type = nil
+	vdlTypeList10  *vdl.Type = nil
+	vdlTypeUnion11 *vdl.Type = nil
+)
+
+// Type definitions
+// ================
+// ReplicaStatus describes the state of a member of a group of replicated
+// mount tables.
+type ReplicaStatus struct {
+	// Id identifies this instance of the replica, it changes each time the
+	// replica is restarted.
+	Id string
+	// Leader is true if the replica is the leader of the group.
+	Leader bool
+	// Epoch increases each time a new leader is elected, and is never the
+	// same for two members. It is the epoch of the leader that the replica
+	// is, or was most recently, following, or of the replica itself if it
+	// is the leader.
+	Epoch uint64
+	// Seq is the sequence number of the last change made in Epoch by the
+	// leader, or applied by a follower.
+	Seq uint64
+	// Promised is the latest epoch for which the replica has voted, see
+	// Replica.Vote.
+	Promised uint64
+}
+
+func (ReplicaStatus) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/services/mounttable/mounttablelib.ReplicaStatus"`
+}) {
+}
+
+func (x ReplicaStatus) VDLIsZero() bool { //nolint:gocyclo
+	return x == ReplicaStatus{}
+}
+
+func (x ReplicaStatus) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeStruct1); err != nil {
+		return err
+	}
+	if x.Id != "" {
+		if err := enc.NextFieldValueString(0, vdl.StringType, x.Id); err != nil {
+			return err
+		}
+	}
+	if x.Leader {
+		if err := enc.NextFieldValueBool(1, vdl.BoolType, x.Leader); err != nil {
+			return err
+		}
+	}
+	if x.Epoch != 0 {
+		if err := enc.NextFieldValueUint(2, vdl.Uint64Type, x.Epoch); err != nil {
+			return err
+		}
+	}
+	if x.Seq != 0 {
+		if err := enc.NextFieldValueUint(3, vdl.Uint64Type, x.Seq); err != nil {
+			return err
+		}
+	}
+	if x.Promised != 0 {
+		if err := enc.NextFieldValueUint(4, vdl.Uint64Type, x.Promised); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *ReplicaStatus) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	*x = ReplicaStatus{}
+	if err := dec.StartValue(vdlTypeStruct1); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != vdlTypeStruct1 {
+			index = vdlTypeStruct1.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Id = value
+			}
+		case 1:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Leader = value
+			}
+		case 2:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Epoch = value
+			}
+		case 3:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Seq = value
+			}
+		case 4:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Promised = value
+			}
+		}
+	}
+}
+
+// ReplicationEntry is a change to a replicated mount table that is shipped
+// from the leader of the group to its followers.
+type ReplicationEntry struct {
+	// Name is the name of the affected node.
+	Name string
+	// Delete is true if the subtree at Name was deleted.
+	Delete bool
+	// SetPerms is true if the permissions of Name were set to Perms,
+	// with version PermsVersion, by Creator.
+	SetPerms     bool
+	Perms        access.Permissions
+	PermsVersion int32
+	Creator      string
+	// Mount is true if Server was mounted at Name until Deadline using
+	// Flags.
+	Mount    bool
+	Server   string
+	Deadline time.Time
+	Flags    naming.MountFlag
+	// Unmount is true if Server, or all servers if Server is empty, was
+	// unmounted from Name.
+	Unmount bool
+}
+
+func (ReplicationEntry) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/services/mounttable/mounttablelib.ReplicationEntry"`
+}) {
+}
+
+func (x ReplicationEntry) VDLIsZero() bool { //nolint:gocyclo
+	if x.Name != "" {
+		return false
+	}
+	if x.Delete {
+		return false
+	}
+	if x.SetPerms {
+		return false
+	}
+	if len(x.Perms) != 0 {
+		return false
+	}
+	if x.PermsVersion != 0 {
+		return false
+	}
+	if x.Creator != "" {
+		return false
+	}
+	if x.Mount {
+		return false
+	}
+	if x.Server != "" {
+		return false
+	}
+	if !x.Deadline.IsZero() {
+		return false
+	}
+	if x.Flags != 0 {
+		return false
+	}
+	if x.Unmount {
+		return false
+	}
+	return true
+}
+
+func (x ReplicationEntry) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeStruct2); err != nil {
+		return err
+	}
+	if x.Name != "" {
+		if err := enc.NextFieldValueString(0, vdl.StringType, x.Name); err != nil {
+			return err
+		}
+	}
+	if x.Delete {
+		if err := enc.NextFieldValueBool(1, vdl.BoolType, x.Delete); err != nil {
+			return err
+		}
+	}
+	if x.SetPerms {
+		if err := enc.NextFieldValueBool(2, vdl.BoolType, x.SetPerms); err != nil {
+			return err
+		}
+	}
+	if len(x.Perms) != 0 {
+		if err := enc.NextField(3); err != nil {
+			return err
+		}
+		if err := x.Perms.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.PermsVersion != 0 {
+		if err := enc.NextFieldValueInt(4, vdl.Int32Type, int64(x.PermsVersion)); err != nil {
+			return err
+		}
+	}
+	if x.Creator != "" {
+		if err := enc.NextFieldValueString(5, vdl.StringType, x.Creator); err != nil {
+			return err
+		}
+	}
+	if x.Mount {
+		if err := enc.NextFieldValueBool(6, vdl.BoolType, x.Mount); err != nil {
+			return err
+		}
+	}
+	if x.Server != "" {
+		if err := enc.NextFieldValueString(7, vdl.StringType, x.Server); err != nil {
+			return err
+		}
+	}
+	if !x.Deadline.IsZero() {
+		if err := enc.NextField(8); err != nil {
+			return err
+		}
+		var wire vdltime.Time
+		if err := vdltime.TimeFromNative(&wire, x.Deadline); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.Flags != 0 {
+		if err := enc.NextFieldValueUint(9, vdlTypeUint325, uint64(x.Flags)); err != nil {
+			return err
+		}
+	}
+	if x.Unmount {
+		if err := enc.NextFieldValueBool(10, vdl.BoolType, x.Unmount); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *ReplicationEntry) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	*x = ReplicationEntry{}
+	if err := dec.StartValue(vdlTypeStruct2); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != vdlTypeStruct2 {
+			index = vdlTypeStruct2.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Name = value
+			}
+		case 1:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Delete = value
+			}
+		case 2:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.SetPerms = value
+			}
+		case 3:
+			if err := x.Perms.VDLRead(dec); err != nil {
+				return err
+			}
+		case 4:
+			switch value, err := dec.ReadValueInt(32); {
+			case err != nil:
+				return err
+			default:
+				x.PermsVersion = int32(value)
+			}
+		case 5:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Creator = value
+			}
+		case 6:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Mount = value
+			}
+		case 7:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Server = value
+			}
+		case 8:
+			var wire vdltime.Time
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := vdltime.TimeToNative(wire, &x.Deadline); err != nil {
+				return err
+			}
+		case 9:
+			switch value, err := dec.ReadValueUint(32); {
+			case err != nil:
+				return err
+			default:
+				x.Flags = naming.MountFlag(value)
+			}
+		case 10:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Unmount = value
+			}
+		}
+	}
+}
+
+// ReplicationBatch is a sequence of changes returned by Replica.Pull.
+type ReplicationBatch struct {
+	// LeaderId is the Id of the leader.
+	LeaderId string
+	// Epoch is the epoch of the leader.
+	Epoch uint64
+	// Seq is the sequence number of the last of the Entries.
+	Seq uint64
+	// Reset is true if Entries is a snapshot of the entire state of the
+	// mount table, which replaces that of the follower.
+	Reset   bool
+	Entries []ReplicationEntry
+}
+
+func (ReplicationBatch) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/services/mounttable/mounttablelib.ReplicationBatch"`
+}) {
+}
+
+func (x ReplicationBatch) VDLIsZero() bool { //nolint:gocyclo
+	if x.LeaderId != "" {
+		return false
+	}
+	if x.Epoch != 0 {
+		return false
+	}
+	if x.Seq != 0 {
+		return false
+	}
+	if x.Reset {
+		return false
+	}
+	if len(x.Entries) != 0 {
+		return false
+	}
+	return true
+}
+
+func (x ReplicationBatch) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeStruct6); err != nil {
+		return err
+	}
+	if x.LeaderId != "" {
+		if err := enc.NextFieldValueString(0, vdl.StringType, x.LeaderId); err != nil {
+			return err
+		}
+	}
+	if x.Epoch != 0 {
+		if err := enc.NextFieldValueUint(1, vdl.Uint64Type, x.Epoch); err != nil {
+			return err
+		}
+	}
+	if x.Seq != 0 {
+		if err := enc.NextFieldValueUint(2, vdl.Uint64Type, x.Seq); err != nil {
+			return err
+		}
+	}
+	if x.Reset {
+		if err := enc.NextFieldValueBool(3, vdl.BoolType, x.Reset); err != nil {
+			return err
+		}
+	}
+	if len(x.Entries) != 0 {
+		if err := enc.NextField(4); err != nil {
+			return err
+		}
+		if err := vdlWriteAnonList1(enc, x.Entries); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func vdlWriteAnonList1(enc vdl.Encoder, x []ReplicationEntry) error {
+	if err := enc.StartValue(vdlTypeList7); err != nil {
+		return err
+	}
+	if err := enc.SetLenHint(len(x)); err != nil {
+		return err
+	}
+	for _, elem := range x {
+		if err := enc.NextEntry(false); err != nil {
+			return err
+		}
+		if err := elem.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextEntry(true); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *ReplicationBatch) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	*x = ReplicationBatch{}
+	if err := dec.StartValue(vdlTypeStruct6); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != vdlTypeStruct6 {
+			index = vdlTypeStruct6.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.LeaderId = value
+			}
+		case 1:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Epoch = value
+			}
+		case 2:
+			switch value, err := dec.ReadValueUint(64); {
+			case err != nil:
+				return err
+			default:
+				x.Seq = value
+			}
+		case 3:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.Reset = value
+			}
+		case 4:
+			if err := vdlReadAnonList1(dec, &x.Entries); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func vdlReadAnonList1(dec vdl.Decoder, x *[]ReplicationEntry) error {
+	if err := dec.StartValue(vdlTypeList7); err != nil {
+		return err
+	}
+	if len := dec.LenHint(); len > 0 {
+		*x = make([]ReplicationEntry, 0, len)
+	} else {
+		*x = nil
+	}
+	for {
+		switch done, err := dec.NextEntry(); {
+		case err != nil:
+			return err
+		case done:
+			return dec.FinishValue()
+		default:
+			var elem ReplicationEntry
+			if err := elem.VDLRead(dec); err != nil {
+				return err
+			}
+			*x = append(*x, elem)
+		}
+	}
+}
+
+// ForwardedCall is a call that changes the mount table which has been
+// forwarded by a follower to the leader of the group, along with the
+// blessings and discharges presented by the caller, from which the leader
+// determines the caller's blessing names for itself.
+type ForwardedCall struct {
+	// Method is one of Mount, Unmount, Delete or SetPermissions.
+	Method        string
+	Name          string
+	Server        string
+	Ttl           uint32
+	Flags         naming.MountFlag
+	DeleteSubtree bool
+	Perms         access.Permissions
+	Version       string
+	Blessings     security.Blessings
+	Discharges    []security.Discharge
+}
+
+func (ForwardedCall) VDLReflect(struct {
+	Name string `vdl:"v.io/x/ref/services/mounttable/mounttablelib.ForwardedCall"`
+}) {
+}
+
+func (x ForwardedCall) VDLIsZero() bool { //nolint:gocyclo
+	if x.Method != "" {
+		return false
+	}
+	if x.Name != "" {
+		return false
+	}
+	if x.Server != "" {
+		return false
+	}
+	if x.Ttl != 0 {
+		return false
+	}
+	if x.Flags != 0 {
+		return false
+	}
+	if x.DeleteSubtree {
+		return false
+	}
+	if len(x.Perms) != 0 {
+		return false
+	}
+	if x.Version != "" {
+		return false
+	}
+	if !x.Blessings.IsZero() {
+		return false
+	}
+	if len(x.Discharges) != 0 {
+		return false
+	}
+	return true
+}
+
+func (x ForwardedCall) VDLWrite(enc vdl.Encoder) error { //nolint:gocyclo
+	if err := enc.StartValue(vdlTypeStruct8); err != nil {
+		return err
+	}
+	if x.Method != "" {
+		if err := enc.NextFieldValueString(0, vdl.StringType, x.Method); err != nil {
+			return err
+		}
+	}
+	if x.Name != "" {
+		if err := enc.NextFieldValueString(1, vdl.StringType, x.Name); err != nil {
+			return err
+		}
+	}
+	if x.Server != "" {
+		if err := enc.NextFieldValueString(2, vdl.StringType, x.Server); err != nil {
+			return err
+		}
+	}
+	if x.Ttl != 0 {
+		if err := enc.NextFieldValueUint(3, vdl.Uint32Type, uint64(x.Ttl)); err != nil {
+			return err
+		}
+	}
+	if x.Flags != 0 {
+		if err := enc.NextFieldValueUint(4, vdlTypeUint325, uint64(x.Flags)); err != nil {
+			return err
+		}
+	}
+	if x.DeleteSubtree {
+		if err := enc.NextFieldValueBool(5, vdl.BoolType, x.DeleteSubtree); err != nil {
+			return err
+		}
+	}
+	if len(x.Perms) != 0 {
+		if err := enc.NextField(6); err != nil {
+			return err
+		}
+		if err := x.Perms.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if x.Version != "" {
+		if err := enc.NextFieldValueString(7, vdl.StringType, x.Version); err != nil {
+			return err
+		}
+	}
+	if !x.Blessings.IsZero() {
+		if err := enc.NextField(8); err != nil {
+			return err
+		}
+		var wire security.WireBlessings
+		if err := security.WireBlessingsFromNative(&wire, x.Blessings); err != nil {
+			return err
+		}
+		if err := wire.VDLWrite(enc); err != nil {
+			return err
+		}
+	}
+	if len(x.Discharges) != 0 {
+		if err := enc.NextField(9); err != nil {
+			return err
+		}
+		if err := vdlWriteAnonList2(enc, x.Discharges); err != nil {
+			return err
+		}
+	}
+	if err := enc.NextField(-1); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func vdlWriteAnonList2(enc vdl.Encoder, x []security.Discharge) error {
+	if err := enc.StartValue(vdlTypeList10); err != nil {
+		return err
+	}
+	if err := enc.SetLenHint(len(x)); err != nil {
+		return err
+	}
+	for _, elem := range x {
+		if err := enc.NextEntry(false); err != nil {
+			return err
+		}
+		var wire security.WireDischarge
+		if err := security.WireDischargeFromNative(&wire, elem); err != nil {
+			return err
+		}
+		switch {
+		case wire == nil:
+			// Write the zero value of the union type.
+			if err := vdl.ZeroValue(vdlTypeUnion11).VDLWrite(enc); err != nil {
+				return err
+			}
+		default:
+			if err := wire.VDLWrite(enc); err != nil {
+				return err
+			}
+		}
+	}
+	if err := enc.NextEntry(true); err != nil {
+		return err
+	}
+	return enc.FinishValue()
+}
+
+func (x *ForwardedCall) VDLRead(dec vdl.Decoder) error { //nolint:gocyclo
+	*x = ForwardedCall{}
+	if err := dec.StartValue(vdlTypeStruct8); err != nil {
+		return err
+	}
+	decType := dec.Type()
+	for {
+		index, err := dec.NextField()
+		switch {
+		case err != nil:
+			return err
+		case index == -1:
+			return dec.FinishValue()
+		}
+		if decType != vdlTypeStruct8 {
+			index = vdlTypeStruct8.FieldIndexByName(decType.Field(index).Name)
+			if index == -1 {
+				if err := dec.SkipValue(); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		switch index {
+		case 0:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Method = value
+			}
+		case 1:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Name = value
+			}
+		case 2:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Server = value
+			}
+		case 3:
+			switch value, err := dec.ReadValueUint(32); {
+			case err != nil:
+				return err
+			default:
+				x.Ttl = uint32(value)
+			}
+		case 4:
+			switch value, err := dec.ReadValueUint(32); {
+			case err != nil:
+				return err
+			default:
+				x.Flags = naming.MountFlag(value)
+			}
+		case 5:
+			switch value, err := dec.ReadValueBool(); {
+			case err != nil:
+				return err
+			default:
+				x.DeleteSubtree = value
+			}
+		case 6:
+			if err := x.Perms.VDLRead(dec); err != nil {
+				return err
+			}
+		case 7:
+			switch value, err := dec.ReadValueString(); {
+			case err != nil:
+				return err
+			default:
+				x.Version = value
+			}
+		case 8:
+			var wire security.WireBlessings
+			if err := wire.VDLRead(dec); err != nil {
+				return err
+			}
+			if err := security.WireBlessingsToNative(wire, &x.Blessings); err != nil {
+				return err
+			}
+		case 9:
+			if err := vdlReadAnonList2(dec, &x.Discharges); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func vdlReadAnonList2(dec vdl.Decoder, x *[]security.Discharge) error {
+	if err := dec.StartValue(vdlTypeList10); err != nil {
+		return err
+	}
+	if len := dec.LenHint(); len > 0 {
+		*x = make([]security.Discharge, 0, len)
+	} else {
+		*x = nil
+	}
+	for {
+		switch done, err := dec.NextEntry(); {
+		case err != nil:
+			return err
+		case done:
+			return dec.FinishValue()
+		default:
+			var elem security.Discharge
+			var wire security.WireDischarge
+			if err := security.VDLReadWireDischarge(dec, &wire); err != nil {
+				return err
+			}
+			if err := security.WireDischargeToNative(wire, &elem); err != nil {
+				return err
+			}
+			*x = append(*x, elem)
+		}
+	}
+}
+
+// Error definitions
+// =================
+
+var (
+
+	// ErrNoLeader indicates that a change could not be made because the
+	// group of replicated mount tables currently has no leader.
+	ErrNoLeader = verror.NewIDAction("v.io/x/ref/services/mounttable/mounttablelib.NoLeader", verror.RetryBackoff)
+	// ErrNotLeader indicates that a replica that is not the leader of its
+	// group was asked to perform an operation reserved for the leader.
+	ErrNotLeader = verror.NewIDAction("v.io/x/ref/services/mounttable/mounttablelib.NotLeader", verror.RetryBackoff)
+	// ErrNoQuorum indicates that a change was not acknowledged by a majority
+	// of the group of replicated mount tables in time, it may or may not
+	// take effect.
+	ErrNoQuorum = verror.NewIDAction("v.io/x/ref/services/mounttable/mounttablelib.NoQuorum", verror.RetryBackoff)
+)
+
+// ErrorfNoLeader calls ErrNoLeader.Errorf with the supplied arguments.
+func ErrorfNoLeader(ctx *context.T, format string) error {
+	return ErrNoLeader.Errorf(ctx, format)
+}
+
+// MessageNoLeader calls ErrNoLeader.Message with the supplied arguments.
+func MessageNoLeader(ctx *context.T, message string) error {
+	return ErrNoLeader.Message(ctx, message)
+}
+
+// ParamsErrNoLeader extracts the expected parameters from the error's ParameterList.
+func ParamsErrNoLeader(argumentError error) (verrorComponent string, verrorOperation string, returnErr error) {
+	params := verror.Params(argumentError)
+	if params == nil {
+		returnErr = fmt.Errorf("no parameters found in: %T: %v", argumentError, argumentError)
+		return
+	}
+	iter := &paramListIterator{params: params, max: len(params)}
+
+	if verrorComponent, verrorOperation, returnErr = iter.preamble(); returnErr != nil {
+		return
+	}
+
+	return
+}
+
+// ErrorfNotLeader calls ErrNotLeader.Errorf with the supplied arguments.
+func ErrorfNotLeader(ctx *context.T, format string) error {
+	return ErrNotLeader.Errorf(ctx, format)
+}
+
+// MessageNotLeader calls ErrNotLeader.Message with the supplied arguments.
+func MessageNotLeader(ctx *context.T, message string) error {
+	return ErrNotLeader.Message(ctx, message)
+}
+
+// ParamsErrNotLeader extracts the expected parameters from the error's ParameterList.
+func ParamsErrNotLeader(argumentError error) (verrorComponent string, verrorOperation string, returnErr error) {
+	params := verror.Params(argumentError)
+	if params == nil {
+		returnErr = fmt.Errorf("no parameters found in: %T: %v", argumentError, argumentError)
+		return
+	}
+	iter := &paramListIterator{params: params, max: len(params)}
+
+	if verrorComponent, verrorOperation, returnErr = iter.preamble(); returnErr != nil {
+		return
+	}
+
+	return
+}
+
+// ErrorfNoQuorum calls ErrNoQuorum.Errorf with the supplied arguments.
+func ErrorfNoQuorum(ctx *context.T, format string) error {
+	return ErrNoQuorum.Errorf(ctx, format)
+}
+
+// MessageNoQuorum calls ErrNoQuorum.Message with the supplied arguments.
+func MessageNoQuorum(ctx *context.T, message string) error {
+	return ErrNoQuorum.Message(ctx, message)
+}
+
+// ParamsErrNoQuorum extracts the expected parameters from the error's ParameterList.
+func ParamsErrNoQuorum(argumentError error) (verrorComponent string, verrorOperation string, returnErr error) {
+	params := verror.Params(argumentError)
+	if params == nil {
+		returnErr = fmt.Errorf("no parameters found in: %T: %v", argumentError, argumentError)
+		return
+	}
+	iter := &paramListIterator{params: params, max: len(params)}
+
+	if verrorComponent, verrorOperation, returnErr = iter.preamble(); returnErr != nil {
+		return
+	}
+
+	return
+}
+
+type paramListIterator struct {
+	err      error
+	idx, max int
+	params   []interface{}
+}
+
+func (pl *paramListIterator) next() (interface{}, error) {
+	if pl.err != nil {
+		return nil, pl.err
+	}
+	if pl.idx+1 > pl.max {
+		pl.err = fmt.Errorf("too few parameters: have %v", pl.max)
+		return nil, pl.err
+	}
+	pl.idx++
+	return pl.params[pl.idx-1], nil
+}
+
+func (pl *paramListIterator) preamble() (component, operation string, err error) {
+	var tmp interface{}
+	if tmp, err = pl.next(); err != nil {
+		return
+	}
+	var ok bool
+	if component, ok = tmp.(string); !ok {
+		return "", "", fmt.Errorf("ParamList[0]: component name is not a string: %T", tmp)
+	}
+	if tmp, err = pl.next(); err != nil {
+		return
+	}
+	if operation, ok = tmp.(string); !ok {
+		return "", "", fmt.Errorf("ParamList[1]: operation name is not a string: %T", tmp)
+	}
+	return
+}
+
 // Interface definitions
 // =====================
 
@@ -148,6 +1152,338 @@ var descCollection = rpc.InterfaceDesc{
 	},
 }
 
+// ReplicaClientMethods is the client interface
+// containing Replica methods.
+//
+// Replica is the interface implemented by each member of a group of
+// replicated mount tables for use by the other members.
+type ReplicaClientMethods interface {
+	// Status returns the status of the replica.
+	Status(*context.T, ...rpc.CallOpt) (ReplicaStatus, error)
+	// Vote asks the replica to vote for Candidate as the leader for Epoch,
+	// which it does, at most once per epoch, if it has not seen changes
+	// more recent than the last change, LastSeq in LastEpoch, seen by
+	// Candidate. A replica that votes for a leader for Epoch no longer
+	// applies or acknowledges changes made in earlier epochs.
+	Vote(_ *context.T, Candidate string, Epoch uint64, LastEpoch uint64, LastSeq uint64, _ ...rpc.CallOpt) (bool, error)
+	// Pull returns the changes made by the leader after Seq in Epoch,
+	// waiting for a short time for changes to be made if there are none,
+	// or a snapshot of its state if they are no longer available or were
+	// made by a different leader than LeaderId. Member is the name of the
+	// calling follower, for which the call acknowledges that it has applied
+	// the changes up to and including Seq.
+	Pull(_ *context.T, Member string, LeaderId string, Epoch uint64, Seq uint64, _ ...rpc.CallOpt) (ReplicationBatch, error)
+	// Forward makes a change on behalf of a caller of a follower and
+	// returns the sequence number of the last change made, once it has
+	// been applied by a majority of the group.
+	Forward(_ *context.T, Call ForwardedCall, _ ...rpc.CallOpt) (uint64, error)
+}
+
+// ReplicaClientStub embeds ReplicaClientMethods and is a
+// placeholder for additional management operations.
+type ReplicaClientStub interface {
+	ReplicaClientMethods
+}
+
+// ReplicaClient returns a client stub for Replica.
+func ReplicaClient(name string) ReplicaClientStub {
+	return implReplicaClientStub{name}
+}
+
+type implReplicaClientStub struct {
+	name string
+}
+
+func (c implReplicaClientStub) Status(ctx *context.T, opts ...rpc.CallOpt) (o0 ReplicaStatus, err error) {
+	err = v23.GetClient(ctx).Call(ctx, c.name, "Status", nil, []interface{}{&o0}, opts...)
+	return
+}
+
+func (c implReplicaClientStub) Vote(ctx *context.T, i0 string, i1 uint64, i2 uint64, i3 uint64, opts ...rpc.CallOpt) (o0 bool, err error) {
+	err = v23.GetClient(ctx).Call(ctx, c.name, "Vote", []interface{}{i0, i1, i2, i3}, []interface{}{&o0}, opts...)
+	return
+}
+
+func (c implReplicaClientStub) Pull(ctx *context.T, i0 string, i1 string, i2 uint64, i3 uint64, opts ...rpc.CallOpt) (o0 ReplicationBatch, err error) {
+	err = v23.GetClient(ctx).Call(ctx, c.name, "Pull", []interface{}{i0, i1, i2, i3}, []interface{}{&o0}, opts...)
+	return
+}
+
+func (c implReplicaClientStub) Forward(ctx *context.T, i0 ForwardedCall, opts ...rpc.CallOpt) (o0 uint64, err error) {
+	err = v23.GetClient(ctx).Call(ctx, c.name, "Forward", []interface{}{i0}, []interface{}{&o0}, opts...)
+	return
+}
+
+// ReplicaServerMethods is the interface a server writer
+// implements for Replica.
+//
+// Replica is the interface implemented by each member of a group of
+// replicated mount tables for use by the other members.
+type ReplicaServerMethods interface {
+	// Status returns the status of the replica.
+	Status(*context.T, rpc.ServerCall) (ReplicaStatus, error)
+	// Vote asks the replica to vote for Candidate as the leader for Epoch,
+	// which it does, at most once per epoch, if it has not seen changes
+	// more recent than the last change, LastSeq in LastEpoch, seen by
+	// Candidate. A replica that votes for a leader for Epoch no longer
+	// applies or acknowledges changes made in earlier epochs.
+	Vote(_ *context.T, _ rpc.ServerCall, Candidate string, Epoch uint64, LastEpoch uint64, LastSeq uint64) (bool, error)
+	// Pull returns the changes made by the leader after Seq in Epoch,
+	// waiting for a short time for changes to be made if there are none,
+	// or a snapshot of its state if they are no longer available or were
+	// made by a different leader than LeaderId. Member is the name of the
+	// calling follower, for which the call acknowledges that it has applied
+	// the changes up to and including Seq.
+	Pull(_ *context.T, _ rpc.ServerCall, Member string, LeaderId string, Epoch uint64, Seq uint64) (ReplicationBatch, error)
+	// Forward makes a change on behalf of a caller of a follower and
+	// returns the sequence number of the last change made, once it has
+	// been applied by a majority of the group.
+	Forward(_ *context.T, _ rpc.ServerCall, Call ForwardedCall) (uint64, error)
+}
+
+// ReplicaServerStubMethods is the server interface containing
+// Replica methods, as expected by rpc.Server.
+// There is no difference between this interface and ReplicaServerMethods
+// since there are no streaming methods.
+type ReplicaServerStubMethods ReplicaServerMethods
+
+// ReplicaServerStub adds universal methods to ReplicaServerStubMethods.
+type ReplicaServerStub interface {
+	ReplicaServerStubMethods
+	// DescribeInterfaces the Replica interfaces.
+	Describe__() []rpc.InterfaceDesc
+}
+
+// ReplicaServer returns a server stub for Replica.
+// It converts an implementation of ReplicaServerMethods into
+// an object that may be used by rpc.Server.
+func ReplicaServer(impl ReplicaServerMethods) ReplicaServerStub {
+	stub := implReplicaServerStub{
+		impl: impl,
+	}
+	// Initialize GlobState; always check the stub itself first, to handle the
+	// case where the user has the Glob method defined in their VDL source.
+	if gs := rpc.NewGlobState(stub); gs != nil {
+		stub.gs = gs
+	} else if gs := rpc.NewGlobState(impl); gs != nil {
+		stub.gs = gs
+	}
+	return stub
+}
+
+type implReplicaServerStub struct {
+	impl ReplicaServerMethods
+	gs   *rpc.GlobState
+}
+
+func (s implReplicaServerStub) Status(ctx *context.T, call rpc.ServerCall) (ReplicaStatus, error) {
+	return s.impl.Status(ctx, call)
+}
+
+func (s implReplicaServerStub) Vote(ctx *context.T, call rpc.ServerCall, i0 string, i1 uint64, i2 uint64, i3 uint64) (bool, error) {
+	return s.impl.Vote(ctx, call, i0, i1, i2, i3)
+}
+
+func (s implReplicaServerStub) Pull(ctx *context.T, call rpc.ServerCall, i0 string, i1 string, i2 uint64, i3 uint64) (ReplicationBatch, error) {
+	return s.impl.Pull(ctx, call, i0, i1, i2, i3)
+}
+
+func (s implReplicaServerStub) Forward(ctx *context.T, call rpc.ServerCall, i0 ForwardedCall) (uint64, error) {
+	return s.impl.Forward(ctx, call, i0)
+}
+
+func (s implReplicaServerStub) Globber() *rpc.GlobState {
+	return s.gs
+}
+
+func (s implReplicaServerStub) Describe__() []rpc.InterfaceDesc {
+	return []rpc.InterfaceDesc{ReplicaDesc}
+}
+
+// ReplicaDesc describes the Replica interface.
+var ReplicaDesc rpc.InterfaceDesc = descReplica
+
+// descReplica hides the desc to keep godoc clean.
+var descReplica = rpc.InterfaceDesc{
+	Name:    "Replica",
+	PkgPath: "v.io/x/ref/services/mounttable/mounttablelib",
+	Doc:     "// Replica is the interface implemented by each member of a group of\n// replicated mount tables for use by the other members.",
+	Methods: []rpc.MethodDesc{
+		{
+			Name: "Status",
+			Doc:  "// Status returns the status of the replica.",
+			OutArgs: []rpc.ArgDesc{
+				{Name: "", Doc: ``}, // ReplicaStatus
+			},
+		},
+		{
+			Name: "Vote",
+			Doc:  "// Vote asks the replica to vote for Candidate as the leader for Epoch,\n// which it does, at most once per epoch, if it has not seen changes\n// more recent than the last change, LastSeq in LastEpoch, seen by\n// Candidate. A replica that votes for a leader for Epoch no longer\n// applies or acknowledges changes made in earlier epochs.",
+			InArgs: []rpc.ArgDesc{
+				{Name: "Candidate", Doc: ``}, // string
+				{Name: "Epoch", Doc: ``},     // uint64
+				{Name: "LastEpoch", Doc: ``}, // uint64
+				{Name: "LastSeq", Doc: ``},   // uint64
+			},
+			OutArgs: []rpc.ArgDesc{
+				{Name: "", Doc: ``}, // bool
+			},
+		},
+		{
+			Name: "Pull",
+			Doc:  "// Pull returns the changes made by the leader after Seq in Epoch,\n// waiting for a short time for changes to be made if there are none,\n// or a snapshot of its state if they are no longer available or were\n// made by a different leader than LeaderId. Member is the name of the\n// calling follower, for which the call acknowledges that it has applied\n// the changes up to and including Seq.",
+			InArgs: []rpc.ArgDesc{
+				{Name: "Member", Doc: ``},   // string
+				{Name: "LeaderId", Doc: ``}, // string
+				{Name: "Epoch", Doc: ``},    // uint64
+				{Name: "Seq", Doc: ``},      // uint64
+			},
+			OutArgs: []rpc.ArgDesc{
+				{Name: "", Doc: ``}, // ReplicationBatch
+			},
+		},
+		{
+			Name: "Forward",
+			Doc:  "// Forward makes a change on behalf of a caller of a follower and\n// returns the sequence number of the last change made, once it has\n// been applied by a majority of the group.",
+			InArgs: []rpc.ArgDesc{
+				{Name: "Call", Doc: ``}, // ForwardedCall
+			},
+			OutArgs: []rpc.ArgDesc{
+				{Name: "", Doc: ``}, // uint64
+			},
+		},
+	},
+}
+
+// WatchableMountTableClientMethods is the client interface
+// containing WatchableMountTable methods.
+//
+// WatchableMountTable is a mount table that also streams changes to the
+// names that match a glob pattern.  The Value of each watch.Change is the
+// naming.MountEntry that Glob would return for the name.
+//
+// Watching is not cheap for large mount tables: after every change other
+// than a refreshed mount, the whole tree is walked once to find what
+// changed, and each watcher then globs the whole tree again to find which
+// of the changes it may see.
+type WatchableMountTableClientMethods interface {
+	// MountTable defines the interface to talk to a mounttable.
+	//
+	// In all methods of MountTable, the receiver is the name bound to.
+	mounttable.MountTableClientMethods
+	// GlobWatcher allows a client to receive updates for changes to objects
+	// that match a pattern.  See the package comments for details.
+	watch.GlobWatcherClientMethods
+}
+
+// WatchableMountTableClientStub embeds WatchableMountTableClientMethods and is a
+// placeholder for additional management operations.
+type WatchableMountTableClientStub interface {
+	WatchableMountTableClientMethods
+}
+
+// WatchableMountTableClient returns a client stub for WatchableMountTable.
+func WatchableMountTableClient(name string) WatchableMountTableClientStub {
+	return implWatchableMountTableClientStub{name, mounttable.MountTableClient(name), watch.GlobWatcherClient(name)}
+}
+
+type implWatchableMountTableClientStub struct {
+	name string
+
+	mounttable.MountTableClientStub
+	watch.GlobWatcherClientStub
+}
+
+// WatchableMountTableServerMethods is the interface a server writer
+// implements for WatchableMountTable.
+//
+// WatchableMountTable is a mount table that also streams changes to the
+// names that match a glob pattern.  The Value of each watch.Change is the
+// naming.MountEntry that Glob would return for the name.
+//
+// Watching is not cheap for large mount tables: after every change other
+// than a refreshed mount, the whole tree is walked once to find what
+// changed, and each watcher then globs the whole tree again to find which
+// of the changes it may see.
+type WatchableMountTableServerMethods interface {
+	// MountTable defines the interface to talk to a mounttable.
+	//
+	// In all methods of MountTable, the receiver is the name bound to.
+	mounttable.MountTableServerMethods
+	// GlobWatcher allows a client to receive updates for changes to objects
+	// that match a pattern.  See the package comments for details.
+	watch.GlobWatcherServerMethods
+}
+
+// WatchableMountTableServerStubMethods is the server interface containing
+// WatchableMountTable methods, as expected by rpc.Server.
+// The only difference between this interface and WatchableMountTableServerMethods
+// is the streaming methods.
+type WatchableMountTableServerStubMethods interface {
+	// MountTable defines the interface to talk to a mounttable.
+	//
+	// In all methods of MountTable, the receiver is the name bound to.
+	mounttable.MountTableServerStubMethods
+	// GlobWatcher allows a client to receive updates for changes to objects
+	// that match a pattern.  See the package comments for details.
+	watch.GlobWatcherServerStubMethods
+}
+
+// WatchableMountTableServerStub adds universal methods to WatchableMountTableServerStubMethods.
+type WatchableMountTableServerStub interface {
+	WatchableMountTableServerStubMethods
+	// DescribeInterfaces the WatchableMountTable interfaces.
+	Describe__() []rpc.InterfaceDesc
+}
+
+// WatchableMountTableServer returns a server stub for WatchableMountTable.
+// It converts an implementation of WatchableMountTableServerMethods into
+// an object that may be used by rpc.Server.
+func WatchableMountTableServer(impl WatchableMountTableServerMethods) WatchableMountTableServerStub {
+	stub := implWatchableMountTableServerStub{
+		impl:                  impl,
+		MountTableServerStub:  mounttable.MountTableServer(impl),
+		GlobWatcherServerStub: watch.GlobWatcherServer(impl),
+	}
+	// Initialize GlobState; always check the stub itself first, to handle the
+	// case where the user has the Glob method defined in their VDL source.
+	if gs := rpc.NewGlobState(stub); gs != nil {
+		stub.gs = gs
+	} else if gs := rpc.NewGlobState(impl); gs != nil {
+		stub.gs = gs
+	}
+	return stub
+}
+
+type implWatchableMountTableServerStub struct {
+	impl WatchableMountTableServerMethods
+	mounttable.MountTableServerStub
+	watch.GlobWatcherServerStub
+	gs *rpc.GlobState
+}
+
+func (s implWatchableMountTableServerStub) Globber() *rpc.GlobState {
+	return s.gs
+}
+
+func (s implWatchableMountTableServerStub) Describe__() []rpc.InterfaceDesc {
+	return []rpc.InterfaceDesc{WatchableMountTableDesc, mounttable.MountTableDesc, permissions.ObjectDesc, watch.GlobWatcherDesc}
+}
+
+// WatchableMountTableDesc describes the WatchableMountTable interface.
+var WatchableMountTableDesc rpc.InterfaceDesc = descWatchableMountTable
+
+// descWatchableMountTable hides the desc to keep godoc clean.
+var descWatchableMountTable = rpc.InterfaceDesc{
+	Name:    "WatchableMountTable",
+	PkgPath: "v.io/x/ref/services/mounttable/mounttablelib",
+	Doc:     "// WatchableMountTable is a mount table that also streams changes to the\n// names that match a glob pattern.  The Value of each watch.Change is the\n// naming.MountEntry that Glob would return for the name.\n//\n// Watching is not cheap for large mount tables: after every change other\n// than a refreshed mount, the whole tree is walked once to find what\n// changed, and each watcher then globs the whole tree again to find which\n// of the changes it may see.",
+	Embeds: []rpc.EmbedDesc{
+		{Name: "MountTable", PkgPath: "v.io/v23/services/mounttable", Doc: "// MountTable defines the interface to talk to a mounttable.\n//\n// In all methods of MountTable, the receiver is the name bound to."},
+		{Name: "GlobWatcher", PkgPath: "v.io/v23/services/watch", Doc: "// GlobWatcher allows a client to receive updates for changes to objects\n// that match a pattern.  See the package comments for details."},
+	},
+}
+
 // initializeVDL performs vdl initialization.  It is safe to call multiple times.
 // If you have an init ordering issue, just insert the following line verbatim
 // into your source files in this package, right after the "package foo" clause:
@@ -167,5 +1503,24 @@ func initializeVDL() struct{} {
 	}
 	initializeVDLCalled = true
 
+	// Register types.
+	vdl.Register((*ReplicaStatus)(nil))
+	vdl.Register((*ReplicationEntry)(nil))
+	vdl.Register((*ReplicationBatch)(nil))
+	vdl.Register((*ForwardedCall)(nil))
+
+	// Initialize type definitions.
+	vdlTypeStruct1 = vdl.TypeOf((*ReplicaStatus)(nil)).Elem()
+	vdlTypeStruct2 = vdl.TypeOf((*ReplicationEntry)(nil)).Elem()
+	vdlTypeMap3 = vdl.TypeOf((*access.Permissions)(nil))
+	vdlTypeStruct4 = vdl.TypeOf((*vdltime.Time)(nil)).Elem()
+	vdlTypeUint325 = vdl.TypeOf((*naming.MountFlag)(nil))
+	vdlTypeStruct6 = vdl.TypeOf((*ReplicationBatch)(nil)).Elem()
+	vdlTypeList7 = vdl.TypeOf((*[]ReplicationEntry)(nil))
+	vdlTypeStruct8 = vdl.TypeOf((*ForwardedCall)(nil)).Elem()
+	vdlTypeStruct9 = vdl.TypeOf((*security.WireBlessings)(nil)).Elem()
+	vdlTypeList10 = vdl.TypeOf((*[]security.Discharge)(nil))
+	vdlTypeUnion11 = vdl.TypeOf((*security.WireDischarge)(nil))
+
 	return struct{}{}
 }
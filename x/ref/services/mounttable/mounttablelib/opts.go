@@ -9,11 +9,16 @@ import (
 )
 
 type Opts struct {
-	MountName  string
-	AclFile    string //nolint:revive // API change required.
-	NhName     string
-	PersistDir string
-	LogLevel   int
+	MountName        string
+	AclFile          string //nolint:revive // API change required.
+	NhName           string
+	PersistDir       string
+	PersistMounts    bool
+	Replicas         string
+	ReplicaBlessings string
+	DNSAddress       string
+	DNSZone          string
+	LogLevel         int
 }
 
 // Note: Where possible, we have flag default values be zero values, so that
@@ -23,5 +28,10 @@ func (o *Opts) InitFlags(f *flag.FlagSet) {
 	f.StringVar(&o.AclFile, "acls", "", "ACL file.  Default is to allow all access.")
 	f.StringVar(&o.NhName, "neighborhood-name", "", "If provided, enables sharing with the local neighborhood with the provided name.  The address of this mount table will be published to the neighboorhood and everything in the neighborhood will be visible on this mount table.")
 	f.StringVar(&o.PersistDir, "persist-dir", "", "Directory in which to persist permissions.")
+	f.BoolVar(&o.PersistMounts, "persist-mounts", false, "If true, the mounted servers, along with their deadlines and mount flags, are also persisted in -persist-dir and are restored, less any that have expired, when the mount table restarts.")
+	f.StringVar(&o.Replicas, "replicas", "", "If provided, a comma-separated list of the rooted names of all of the members, including this one, of a group of replicated mount tables.  Changes are made by the leader of the group, which is elected by a majority of the members, and are copied to the other members, any of which may be used to resolve or glob names.  A change succeeds once it has been copied to a majority of the group.  Earlier members are preferred as leader.")
+	f.StringVar(&o.ReplicaBlessings, "replica-blessings", "", "A comma-separated list of the blessing patterns of the members of the group named by -replicas, only principals whose blessings match one of them may call the replication service.  The default is this mount table's own blessing names, in which case all of the members must run with the same blessings.")
+	f.StringVar(&o.DNSAddress, "dns-address", "", "If provided, the address, e.g. :53, on which to answer DNS queries, over UDP and TCP, for the names in -dns-zone.  The name b.a.<zone> is resolved as a/b in this mount table and SRV, A, AAAA and TXT queries are answered with the hosts, ports and object addresses of the servers mounted there.")
+	f.StringVar(&o.DNSZone, "dns-zone", "", "The DNS zone, e.g. mt.example.com, whose names are answered by the server started by -dns-address, which requires it.  Names are mapped to lower case before they are resolved, so mount table names with upper case letters can't be resolved.")
 	f.IntVar(&o.LogLevel, "mounttable-logging", 1, "Mounttabled specific logging control, 0 for no logging, 1 for mount/unmount and 2 for all other operations.")
 }
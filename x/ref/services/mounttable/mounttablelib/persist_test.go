@@ -7,11 +7,20 @@ package mounttablelib_test
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
 	"v.io/v23/security"
 	"v.io/v23/security/access"
+	libstats "v.io/x/ref/lib/stats"
+	"v.io/x/ref/services/mounttable/mounttablelib"
+	"v.io/x/ref/test/timekeeper"
 )
 
 func TestPersistence(t *testing.T) {
@@ -75,3 +84,128 @@ func TestPersistence(t *testing.T) {
 	}
 	stop()
 }
+
+func doMountWithTTL(t *testing.T, ctx *context.T, ep, suffix, service string, ttl time.Duration, flags naming.MountFlag) {
+	name := naming.JoinAddressName(ep, suffix)
+	client := v23.GetClient(ctx)
+	if err := client.Call(ctx, name, "Mount", []interface{}{service, uint32(ttl.Seconds()), flags}, nil, options.Preresolved{}); err != nil {
+		boom(t, "Failed to Mount %s onto %s: %s", service, name, err)
+	}
+}
+
+func TestMountPersistence(t *testing.T) {
+	rootCtx, _, _, shutdown := initTest()
+	defer shutdown()
+
+	td := t.TempDir()
+	opts := mounttablelib.Opts{PersistDir: td, PersistMounts: true}
+	clock := timekeeper.NewManualTime()
+	stop, mtAddr := newMTWithOpts(t, opts, "testMountPersistence0", rootCtx, clock)
+
+	addr := mtAddr
+	server := func(n string) string {
+		return naming.JoinAddressName(addr, n)
+	}
+	doMountWithTTL(t, rootCtx, mtAddr, "a/b", server("1"), time.Hour, 0)
+	doMountWithTTL(t, rootCtx, mtAddr, "a/b", server("2"), time.Hour, 0)
+	doMountWithTTL(t, rootCtx, mtAddr, "c", server("3"), time.Minute, 0)
+	doMountWithTTL(t, rootCtx, mtAddr, "d", server("4"), time.Hour, naming.MT)
+	doMountWithTTL(t, rootCtx, mtAddr, "e", server("5"), time.Hour, 0)
+	doUnmount(t, rootCtx, mtAddr, "e", "", true)
+	doMountWithTTL(t, rootCtx, mtAddr, "f/g", server("6"), time.Hour, 0)
+	doDeleteSubtree(t, rootCtx, mtAddr, "f", true)
+	doMountWithTTL(t, rootCtx, mtAddr, "h", server("7"), time.Hour, 0)
+	doMountWithTTL(t, rootCtx, mtAddr, "h", server("8"), time.Hour, naming.Replace)
+	doUnmount(t, rootCtx, mtAddr, "a/b", server("2"), true)
+	stop()
+
+	// Restart with the persisted mounts, by which time the mount on c
+	// has expired.
+	clock.AdvanceTime(2 * time.Minute)
+	check := func(i int, mounted int64) {
+		stop, mtAddr = newMTWithOpts(t, opts, fmt.Sprintf("testMountPersistence%d", i), rootCtx, clock)
+		defer stop()
+		v, err := libstats.Value(fmt.Sprintf("testMountPersistence%d/num-mounted-servers", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := v.(int64), mounted; got != want {
+			t.Errorf("%v: got %v mounted servers, want %v", i, got, want)
+		}
+		if mounted == 0 {
+			return
+		}
+		for _, tc := range []struct {
+			name, server string
+			mt           bool
+		}{
+			{"a/b", server("1"), false},
+			{"d", server("4"), true},
+			{"h", server("8"), false},
+		} {
+			entry, err := resolve(rootCtx, naming.JoinAddressName(mtAddr, tc.name))
+			if err != nil {
+				t.Fatalf("%v: %v: %v", i, tc.name, err)
+			}
+			if got, want := len(entry.Servers), 1; got != want {
+				t.Fatalf("%v: %v: got %v, want %v", i, tc.name, got, want)
+			}
+			if got, want := entry.Servers[0].Server, tc.server; got != want {
+				t.Errorf("%v: %v: got %v, want %v", i, tc.name, got, want)
+			}
+			if got, want := entry.ServesMountTable, tc.mt; got != want {
+				t.Errorf("%v: %v: got %v, want %v", i, tc.name, got, want)
+			}
+		}
+		for _, name := range []string{"c", "e", "f/g"} {
+			if _, err := resolve(rootCtx, naming.JoinAddressName(mtAddr, name)); err == nil {
+				t.Errorf("%v: %v: expected an error", i, name)
+			}
+		}
+	}
+	check(1, 3)
+	// Restart again to use the compacted log.
+	check(2, 3)
+	// The servers are restored with their original deadlines rather than
+	// with their TTLs renewed.
+	clock.AdvanceTime(time.Hour)
+	check(3, 0)
+}
+
+func TestMountLogCompaction(t *testing.T) {
+	rootCtx, _, _, shutdown := initTest()
+	defer shutdown()
+
+	td := t.TempDir()
+	opts := mounttablelib.Opts{PersistDir: td, PersistMounts: true}
+	clock := timekeeper.NewManualTime()
+	stop, mtAddr := newMTWithOpts(t, opts, "testMountLogCompaction0", rootCtx, clock)
+
+	// Refreshing a mount appends to the log, which must be compacted
+	// rather than growing without bound.
+	server := naming.JoinAddressName(mtAddr, "1")
+	doMountWithTTL(t, rootCtx, mtAddr, "b", naming.JoinAddressName(mtAddr, "2"), time.Hour, 0)
+	for i := 0; i < 1000; i++ {
+		doMountWithTTL(t, rootCtx, mtAddr, "a", server, time.Hour, 0)
+	}
+	log := filepath.Join(td, "persistent.mountlog")
+	for {
+		fi, err := os.Stat(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Size() < 128<<10 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+
+	stop, mtAddr = newMTWithOpts(t, opts, "testMountLogCompaction1", rootCtx, clock)
+	defer stop()
+	for _, name := range []string{"a", "b"} {
+		if _, err := resolve(rootCtx, naming.JoinAddressName(mtAddr, name)); err != nil {
+			t.Errorf("%v: %v", name, err)
+		}
+	}
+}
@@ -9,20 +9,53 @@ import (
 	"io"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"v.io/v23/context"
+	"v.io/v23/naming"
 
 	"v.io/x/ref/internal/logger"
 )
 
+// The mount log is compacted, while the mount table is running, once it is
+// larger than both minMountLogCompactionSize and mountLogCompactionFactor
+// times its size following the previous compaction.  This bounds its growth
+// due to the refreshing of mounts, which happens far more often than the
+// changes to permissions that make up the permissions log.
+const (
+	mountLogCompactionFactor  = 4
+	minMountLogCompactionSize = 64 << 10
+)
+
 type store struct {
-	l   sync.Mutex
-	mt  *mountTable
-	dir string
-	enc *json.Encoder
-	f   *os.File
+	l        sync.Mutex
+	ctx      *context.T
+	mt       *mountTable
+	dir      string
+	enc      *json.Encoder
+	f        *os.File
+	mountEnc *json.Encoder // nil unless mounts are being persisted.
+	mountF   *os.File
+
+	mountLogSize      int64          // the current size of the mount log.
+	mountSnapshotSize int64          // the size of the mount log after it was last compacted.
+	compacting        bool           // true while the mount log is being compacted.
+	pending           []mountElement // the mount log entries appended while compacting.
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	*c.n += int64(n)
+	return n, err
 }
 
 type storeElement struct {
@@ -32,13 +65,26 @@ type storeElement struct {
 	C string // Creator
 }
 
+type mountElement struct {
+	N string           // Name of the mount point
+	S string           // Mounted server
+	E time.Time        // Time at which the server expires
+	F naming.MountFlag // Flags used to mount the server
+	U bool             // True if S, or all servers if S is empty, have been unmounted
+	D bool             // True if the subtree at N has been deleted
+}
+
 // newPersistentStore will read the permissions log from the directory and apply them to the
 // in memory tree.  It will then write a new file from the in memory tree and any new permission
 // changes will be appened to this file.  By writing into a new file, we effectively compress
 // the permissions file since any set permissions that have been deleted or overwritten will be
 // lost.
 //
-// The code manages three files in the directory 'dir':
+// If mounts is true, the servers mounted in the tree are persisted in the same way in a
+// separate log, which is applied after the permissions log.  Servers whose deadlines have
+// passed are not restored.
+//
+// The code manages three files in the directory 'dir' for each log, for the permissions:
 //
 //	persistent.permslog - the log of permissions.  A new log entry is added with each SetPermissions or
 //	   Delete RPC.
@@ -46,13 +92,47 @@ type storeElement struct {
 //	   it will be renamed persistent.perms becoming the new log.
 //	old.permslog - the previous version of persistent.perms.  This is left around primarily for debugging
 //	   and as an emergency backup.
-func newPersistentStore(ctx *context.T, mt *mountTable, dir string) persistence { //nolint:gocyclo
-	s := &store{mt: mt, dir: dir}
-	file := path.Join(dir, "persistent.permslog")
-	tmp := path.Join(dir, "tmp.permslog")
-	old := path.Join(dir, "old.permslog")
+//
+// and similarly persistent.mountlog, tmp.mountlog and old.mountlog for the mounts, whose log
+// has an entry added with each Mount, Unmount or Delete RPC.  Since every refresh of a mount
+// is logged, the mount log is also rewritten in the same way whenever it grows too large.
+func newPersistentStore(ctx *context.T, mt *mountTable, dir string, mounts bool) persistence {
+	s := &store{ctx: ctx, mt: mt, dir: dir}
+	s.f = openLog(ctx, dir, "permslog", s.parseLogFile, func(enc *json.Encoder) error {
+		s.enc = enc
+		return s.depthFirstPersist(mt.root, "")
+	})
+	s.enc = json.NewEncoder(s.f)
+	if mounts {
+		s.mountF = openLog(ctx, dir, "mountlog", s.parseMountLogFile, func(enc *json.Encoder) error {
+			return encodeMounts(enc, mountSnapshot(mt))
+		})
+		s.resetMountLogLocked(s.mountF)
+	}
+	return s
+}
+
+// resetMountLogLocked starts appending to f, which is the newly compacted
+// mount log.
+func (s *store) resetMountLogLocked(f *os.File) {
+	s.mountF = f
+	s.mountLogSize = 0
+	if fi, err := f.Stat(); err == nil {
+		s.mountLogSize = fi.Size()
+	}
+	s.mountSnapshotSize = s.mountLogSize
+	s.mountEnc = json.NewEncoder(countingWriter{w: f, n: &s.mountLogSize})
+}
+
+// openLog reads the log persistent.<suffix> in dir, applying it to the in memory tree using
+// parse, and then replaces it with a log containing only the current state of the tree, as
+// written by write.  It returns the new log, opened for appending.
+func openLog(ctx *context.T, dir, suffix string, parse func(*context.T, *os.File) error, write func(*json.Encoder) error) *os.File { //nolint:gocyclo
+	file := path.Join(dir, "persistent."+suffix)
+	tmp := path.Join(dir, "tmp."+suffix)
+	old := path.Join(dir, "old."+suffix)
 
-	// If the permissions file doesn't exist, try renaming the temporary one.
+	// If the log file doesn't exist, try renaming the temporary one.
 	f, err := os.Open(file)
 	if err != nil {
 		if !os.IsNotExist(err) {
@@ -70,34 +150,32 @@ func newPersistentStore(ctx *context.T, mt *mountTable, dir string) persistence
 		os.Remove(tmp)
 	}
 
-	// Parse the permissions file and apply it to the in memory tree.
+	// Parse the log file and apply it to the in memory tree.
 	if f != nil {
-		if err := s.parseLogFile(ctx, f); err != nil {
+		if err := parse(ctx, f); err != nil {
 			f.Close()
 			// Log the error but keep going.  There's not much else we can do.
-			logger.Global().Infof("parsing old persistent permissions file %s: %s", file, err)
+			logger.Global().Infof("parsing old persistent log file %s: %s", file, err)
 		}
 		f.Close()
 	}
 
-	// Write the permissions to a temporary file.  This compresses
+	// Write the current state to a temporary file.  This compresses
 	// the file since it writes out only the end state.
 	f, err = os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
 	if err != nil {
 		// Log the error but keep going, don't compress, just append to the current file.
-		logger.Global().Infof("can't rewrite persistent permissions file %s: %s", file, err)
+		logger.Global().Infof("can't rewrite persistent log file %s: %s", file, err)
 		if f, err = os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
 			logger.Global().Fatalf("can't append to log %s: %s", file, err)
 		}
 		if _, err := f.Seek(0, 2); err != nil {
 			logger.Global().Fatalf("can't seek to end of %s: %s", file, err)
 		}
-		s.enc = json.NewEncoder(f)
-		return s
+		return f
 	}
-	s.enc = json.NewEncoder(f)
-	if err := s.depthFirstPersist(mt.root, ""); err != nil {
-		ctx.Infof("depthFirstPersist of %v: %v", mt.root, err)
+	if err := write(json.NewEncoder(f)); err != nil {
+		ctx.Infof("persisting the state of %s: %v", file, err)
 	}
 	f.Close()
 
@@ -122,8 +200,7 @@ func newPersistentStore(ctx *context.T, mt *mountTable, dir string) persistence
 	if _, err := f.Seek(0, 2); err != nil {
 		ctx.Fatalf("can't seek to end of %s: %s", file, err)
 	}
-	s.enc = json.NewEncoder(f)
-	return s
+	return f
 }
 
 // parseLogFile reads a file and parses the contained VersionedPermissions .
@@ -170,6 +247,109 @@ func (s *store) parseLogFile(ctx *context.T, f *os.File) error {
 	return nil
 }
 
+// restoredMount is the state of a mount point as recorded in the mount log.
+type restoredMount struct {
+	flags   naming.MountFlag
+	servers map[string]time.Time
+}
+
+// parseMountLogFile reads a file of mountElements and applies the resulting
+// mounts, less any expired servers, to the in memory tree.  Since the
+// permissions log has already been applied, the entire log is replayed
+// before any mount is added so that deletions only affect the mounts.
+func (s *store) parseMountLogFile(ctx *context.T, f *os.File) error {
+	if f == nil {
+		return nil
+	}
+	ctx.VI(2).Infof("parseMountLogFile(%s)", f.Name())
+	mounts := map[string]*restoredMount{}
+	removeSubtree := func(name string) {
+		for n := range mounts {
+			if strings.HasPrefix(n, name+"/") {
+				delete(mounts, n)
+			}
+		}
+	}
+	decoder := json.NewDecoder(f)
+	var err error
+	for {
+		var e mountElement
+		if err = decoder.Decode(&e); err != nil {
+			break
+		}
+		switch {
+		case e.D:
+			delete(mounts, e.N)
+			removeSubtree(e.N)
+		case e.U:
+			m := mounts[e.N]
+			if m != nil && len(e.S) > 0 {
+				delete(m.servers, e.S)
+			}
+			if m != nil && (len(e.S) == 0 || len(m.servers) == 0) {
+				delete(mounts, e.N)
+			}
+		default:
+			m := mounts[e.N]
+			if m == nil || hasReplaceFlag(e.F) {
+				m = &restoredMount{servers: map[string]time.Time{}}
+				mounts[e.N] = m
+			}
+			m.flags = e.F &^ naming.Replace
+			m.servers[e.S] = e.E
+			// Mounting removes any existing children.
+			removeSubtree(e.N)
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+
+	// Add the mounts, parents first.
+	names := make([]string, 0, len(mounts))
+	for n := range mounts {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	mt := s.mt
+	cc := &callContext{ctx: ctx,
+		create:       true,
+		ignorePerms:  true,
+		ignoreLimits: true,
+	}
+	now := mt.slm.clock.Now()
+	for _, name := range names {
+		m := mounts[name]
+		var elems []string
+		if len(name) > 0 {
+			elems = strings.Split(name, "/")
+		}
+		n, ferr := mt.findNode(cc, elems, nil, nil)
+		if n == nil {
+			continue
+		}
+		if ferr == nil && n.mount == nil {
+			for server, expires := range m.servers {
+				if !now.Before(expires) {
+					continue
+				}
+				if n.mount == nil {
+					n.mount = &mount{servers: mt.slm.newServerList(), mt: hasMTFlag(m.flags), leaf: hasLeafFlag(m.flags)}
+				}
+				n.mount.servers.add(server, expires.Sub(now))
+				mt.serverCounter.Incr(1)
+				ctx.VI(2).Infof("restored mount of %s on %s until %v", server, name, expires)
+			}
+			if n.mount == nil {
+				n.removeUseless(mt)
+			}
+		}
+		n.parent.Unlock()
+		n.Unlock()
+	}
+	return err
+}
+
 // depthFirstPersist performs a recursive depth first traversal logging any explicit permissions.
 // Doing this immediately after reading in a log file effectively compresses the log file since
 // any duplicate or deleted entries disappear.
@@ -180,11 +360,129 @@ func (s *store) depthFirstPersist(n *node, name string) error {
 		}
 	}
 	for nodeName, c := range n.children {
-		return s.depthFirstPersist(c, path.Join(name, nodeName))
+		if err := s.depthFirstPersist(c, path.Join(name, nodeName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mountSnapshot returns the mount log entries that recreate all of the
+// servers mounted in the tree.  Since each node is locked in turn, it may be
+// called while the mount table is in use.
+func mountSnapshot(mt *mountTable) []mountElement {
+	var entries []mountElement
+	var walk func(n *node, name string)
+	walk = func(n *node, name string) {
+		n.Lock()
+		if n.mount != nil {
+			var flags naming.MountFlag
+			if n.mount.mt {
+				flags |= naming.MT
+			}
+			if n.mount.leaf {
+				flags |= naming.Leaf
+			}
+			for _, ms := range n.mount.servers.copyToSlice() {
+				entries = append(entries, mountElement{N: name, S: ms.Server, E: ms.Deadline.Time, F: flags})
+			}
+		}
+		children := make(map[string]*node, len(n.children))
+		for k, c := range n.children {
+			children[k] = c
+		}
+		n.Unlock()
+		for k, c := range children {
+			walk(c, path.Join(name, k))
+		}
+	}
+	walk(mt.root, "")
+	return entries
+}
+
+func encodeMounts(enc *json.Encoder, entries []mountElement) error {
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendMountLocked appends e to the mount log, and starts compacting the
+// log if it has grown too large.
+func (s *store) appendMountLocked(e mountElement) error {
+	if err := s.mountEnc.Encode(&e); err != nil {
+		return err
+	}
+	if s.compacting {
+		s.pending = append(s.pending, e)
+		return nil
+	}
+	if s.mountLogSize > minMountLogCompactionSize && s.mountLogSize > mountLogCompactionFactor*s.mountSnapshotSize {
+		s.compacting = true
+		go s.compactMounts()
 	}
 	return nil
 }
 
+// compactMounts replaces the mount log with one containing only the servers
+// currently mounted, followed by any entries appended in the meantime.  The
+// tree is traversed without holding s.l since the mount table calls the
+// persist methods with nodes locked.
+func (s *store) compactMounts() {
+	ctx := s.ctx
+	file := path.Join(s.dir, "persistent.mountlog")
+	tmp := path.Join(s.dir, "tmp.mountlog")
+	old := path.Join(s.dir, "old.mountlog")
+	entries := mountSnapshot(s.mt)
+
+	s.l.Lock()
+	defer s.l.Unlock()
+	defer func() {
+		s.compacting = false
+		s.pending = nil
+	}()
+	if s.mountF == nil {
+		// The store has been closed.
+		return
+	}
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600)
+	if err != nil {
+		ctx.Infof("can't compact persistent log file %s: %s", file, err)
+		return
+	}
+	enc := json.NewEncoder(f)
+	if err := encodeMounts(enc, entries); err == nil {
+		err = encodeMounts(enc, s.pending)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	f.Close()
+	if err != nil {
+		ctx.Infof("compacting persistent log file %s: %s", file, err)
+		os.Remove(tmp)
+		return
+	}
+	if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+		ctx.Infof("removing %s: %s", old, err)
+	}
+	if err := os.Rename(file, old); err != nil {
+		ctx.Infof("renaming %s to %s: %s", file, old, err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		ctx.Fatalf("renaming %s to %s: %s", tmp, file, err)
+	}
+	f, err = os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		ctx.Fatalf("can't open %s: %s", file, err)
+	}
+	s.mountF.Close()
+	s.resetMountLogLocked(f)
+	ctx.VI(2).Infof("compacted %s to %v bytes", file, s.mountLogSize)
+}
+
 // persistPerms appends a changed permission to the log.
 func (s *store) persistPerms(name, creator string, vPerms *VersionedPermissions) error {
 	s.l.Lock()
@@ -193,14 +491,48 @@ func (s *store) persistPerms(name, creator string, vPerms *VersionedPermissions)
 	return s.enc.Encode(&e)
 }
 
-// persistDelete appends a single deletion to the log.
+// persistDelete appends a single deletion to the logs.
 func (s *store) persistDelete(name string) error {
 	s.l.Lock()
 	defer s.l.Unlock()
 	e := storeElement{N: name, D: true}
-	return s.enc.Encode(&e)
+	if err := s.enc.Encode(&e); err != nil {
+		return err
+	}
+	if s.mountEnc == nil {
+		return nil
+	}
+	return s.appendMountLocked(mountElement{N: name, D: true})
+}
+
+// persistMount appends a mounted server to the mount log, if mounts are
+// being persisted.
+func (s *store) persistMount(name, server string, expires time.Time, flags naming.MountFlag) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.mountEnc == nil {
+		return nil
+	}
+	return s.appendMountLocked(mountElement{N: name, S: server, E: expires, F: flags})
+}
+
+// persistUnmount appends the removal of server, or all servers if server is
+// empty, to the mount log, if mounts are being persisted.
+func (s *store) persistUnmount(name, server string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.mountEnc == nil {
+		return nil
+	}
+	return s.appendMountLocked(mountElement{N: name, S: server, U: true})
 }
 
 func (s *store) close() {
+	s.l.Lock()
+	defer s.l.Unlock()
 	s.f.Close()
+	if s.mountF != nil {
+		s.mountF.Close()
+		s.mountF = nil
+	}
 }
@@ -0,0 +1,953 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/verror"
+)
+
+const (
+	// replicationName is the reserved name under which each member of a
+	// group of replicated mount tables serves the Replica interface.
+	replicationName = "__replication"
+	// maxReplicationLog is the number of changes retained by the leader
+	// for its followers, those that fall further behind are sent a
+	// snapshot.
+	maxReplicationLog = 10000
+	// maxReplicationBatch is the maximum number of changes returned by Pull.
+	maxReplicationBatch = 1000
+)
+
+var (
+	// electionInterval is the interval at which the members of the group
+	// are polled for their status.
+	electionInterval = time.Second
+	// electionMisses is the number of consecutive polls that must find
+	// no leader, or no majority, before the leadership changes.
+	electionMisses = 2
+	// pullWait is the time for which Pull waits for new changes.
+	pullWait = 5 * time.Second
+	// forwardWait is the maximum time for which a follower waits for a
+	// change that it has forwarded to the leader to be replicated back.
+	forwardWait = 5 * time.Second
+)
+
+// replicator makes a mount table a member of a group of replicated mount
+// tables.
+//
+// One member of the group is the leader and all changes, ie. Mount, Unmount,
+// Delete and SetPermissions, are made by the leader; the other members, the
+// followers, forward the changes made by their callers, along with the
+// blessings and discharges presented by the callers, to the leader, which
+// authorizes and applies them and records them in a log.  The followers pull
+// the log from the leader and apply it to their own trees, from which
+// ResolveStep, Glob and GetPermissions are served.  A follower that has
+// fallen too far behind, or that followed a different leader, is sent a
+// snapshot of the leader's tree instead.  Each pull acknowledges the changes
+// that the follower has applied so far, and a change is only reported as
+// made once it has been applied by a majority of the group.
+//
+// The members poll each other's status.  If there is no leader, the member
+// that has seen the most recent changes, earlier members breaking any ties,
+// asks the others to vote for it as the leader for a new epoch, which no
+// other member can choose, and becomes the leader if a majority of the group
+// does so.  A member votes only for a candidate that has seen all of the
+// changes that it has applied, and once it has voted it no longer applies,
+// or acknowledges, changes made in earlier epochs, so every change that has
+// been applied by a majority of the group survives the election of a new
+// leader.  A leader that can no longer reach a majority steps down, and an
+// existing leader is never preempted, other than by a leader that was
+// elected later.
+//
+// Only the members of the group, as identified by their blessings, may call
+// the replication service, and the leader relies on them to have
+// authenticated the callers whose blessings they forward.
+//
+// The replicator is the mount table's persistence, it records the changes
+// made by the leader and passes all changes on to the mount table's store,
+// if any.
+//
+// The permissions of nodes that are created implicitly, rather than by
+// SetPermissions, are not replicated and so the blessings that created them
+// are not added to their Admin access lists on the followers.  Changes
+// accepted by a leader that were not applied by a majority of the group,
+// which are reported to their callers as ErrNoQuorum, may be lost when the
+// leadership changes.  The state of the replicator is not persisted, so a
+// member that restarts rejoins the group as a new follower, and changes are
+// only guaranteed to survive as long as a majority of the group is running.
+type replicator struct {
+	mt      *mountTable
+	store   persistence // nil unless the mount table is persisted locally.
+	id      string
+	members []string
+	auth    memberAuthorizer
+	done    <-chan struct{} // closed when the replicator is stopped.
+	// interval is the value of electionInterval when the replicator
+	// was created.
+	interval time.Duration
+
+	// applyMu serializes the application of changes pulled from the leader
+	// with changes of leadership and votes.
+	applyMu sync.Mutex
+
+	mu         sync.Mutex
+	self       int // the index of this member in members, or -1 if not yet known.
+	leader     bool
+	leaderName string // the name of the member being followed, if any.
+	leaderID   string // the Id of the leader whose changes were last applied.
+	epoch      uint64
+	seq        uint64
+	promised   uint64             // the latest epoch for which this member has voted.
+	log        []ReplicationEntry // the changes up to and including seq.
+	acks       map[string]uint64  // the last change applied by each follower in epoch, if leader.
+	misses     int
+	polling    []bool        // true for the members whose status is being polled.
+	changed    chan struct{} // closed, and replaced, when seq, the acks or the leadership change.
+}
+
+var _ persistence = (*replicator)(nil)
+
+// newReplicator makes mt a member of the group of replicated mount tables
+// named by members, which must include mt itself, and starts the goroutines
+// that elect a leader and follow it until ctx is canceled.  blessings is a
+// comma-separated list of the blessing patterns of the members, see
+// newMemberAuthorizer.
+func newReplicator(ctx *context.T, mt *mountTable, members []string, blessings string) *replicator {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		ctx.Fatalf("rand.Read failed: %v", err)
+	}
+	r := &replicator{
+		mt:       mt,
+		id:       hex.EncodeToString(id),
+		auth:     newMemberAuthorizer(ctx, blessings),
+		done:     ctx.Done(),
+		self:     -1,
+		interval: electionInterval,
+		changed:  make(chan struct{}),
+	}
+	for _, m := range members {
+		if m = strings.TrimSpace(m); len(m) > 0 {
+			r.members = append(r.members, m)
+		}
+	}
+	r.polling = make([]bool, len(r.members))
+	if mt.persisting {
+		r.store = mt.persist
+	}
+	mt.persist = r
+	mt.persisting = true
+	go r.elect(ctx)
+	go r.follow(ctx)
+	return r
+}
+
+// broadcastLocked wakes up anyone waiting for a change.  r.mu must be held.
+func (r *replicator) broadcastLocked() {
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// record appends a change to the log if this member is the leader.
+func (r *replicator) record(e ReplicationEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.leader {
+		return
+	}
+	r.seq++
+	if len(r.log) >= maxReplicationLog {
+		r.log = append([]ReplicationEntry(nil), r.log[len(r.log)/2:]...)
+	}
+	r.log = append(r.log, e)
+	r.broadcastLocked()
+}
+
+func (r *replicator) persistPerms(name, creator string, vPerms *VersionedPermissions) error {
+	r.record(ReplicationEntry{Name: name, SetPerms: true, Perms: vPerms.P.Copy(), PermsVersion: vPerms.V, Creator: creator})
+	if r.store == nil {
+		return nil
+	}
+	return r.store.persistPerms(name, creator, vPerms)
+}
+
+func (r *replicator) persistDelete(name string) error {
+	r.record(ReplicationEntry{Name: name, Delete: true})
+	if r.store == nil {
+		return nil
+	}
+	return r.store.persistDelete(name)
+}
+
+func (r *replicator) persistMount(name, server string, expires time.Time, flags naming.MountFlag) error {
+	r.record(ReplicationEntry{Name: name, Mount: true, Server: server, Deadline: expires, Flags: flags})
+	if r.store == nil {
+		return nil
+	}
+	return r.store.persistMount(name, server, expires, flags)
+}
+
+func (r *replicator) persistUnmount(name, server string) error {
+	r.record(ReplicationEntry{Name: name, Unmount: true, Server: server})
+	if r.store == nil {
+		return nil
+	}
+	return r.store.persistUnmount(name, server)
+}
+
+func (r *replicator) close() {
+	if r.store != nil {
+		r.store.close()
+	}
+}
+
+// replica returns a client for the replication service of member.
+func replica(member string) ReplicaClientStub {
+	return ReplicaClient(naming.Join(member, replicationName))
+}
+
+// LeaderForTest returns the index of the member of a replicated group, with
+// the specified names, that claims to be the leader, or -1 if there isn't
+// exactly one.  Empty names are skipped.  It is intended for use in tests
+// by principals that are members of the group.
+func LeaderForTest(ctx *context.T, names []string) int {
+	found := -1
+	for i, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+		sctx, cancel := context.WithTimeout(ctx, time.Second)
+		status, err := replica(name).Status(sctx, options.Preresolved{}, options.NoRetry{}, options.ServerAuthorizer{Authorizer: security.AllowEveryone()})
+		cancel()
+		if err != nil || !status.Leader {
+			continue
+		}
+		if found >= 0 {
+			return -1
+		}
+		found = i
+	}
+	return found
+}
+
+// opts returns the options for calls to the other members of the group,
+// which are named by address and are authorized by their blessings.
+func (r *replicator) opts(opts ...rpc.CallOpt) []rpc.CallOpt {
+	return append(opts, options.Preresolved{}, options.ServerAuthorizer{Authorizer: r.auth})
+}
+
+// memberAuthorizer authorizes the members of the group, both as the callers
+// of the replication service and as the servers called by the other
+// members.
+type memberAuthorizer struct {
+	patterns []security.BlessingPattern
+}
+
+// newMemberAuthorizer returns an authorizer for principals whose blessings
+// match one of the comma-separated patterns in blessings or, if there are
+// none, one of the blessing names of the principal in ctx exactly.
+func newMemberAuthorizer(ctx *context.T, blessings string) memberAuthorizer {
+	var a memberAuthorizer
+	for _, b := range strings.Split(blessings, ",") {
+		if b = strings.TrimSpace(b); len(b) > 0 {
+			a.patterns = append(a.patterns, security.BlessingPattern(b))
+		}
+	}
+	if len(a.patterns) == 0 {
+		p := v23.GetPrincipal(ctx)
+		def, _ := p.BlessingStore().Default()
+		for _, name := range security.BlessingNames(p, def) {
+			a.patterns = append(a.patterns, security.BlessingPattern(name).MakeNonExtendable())
+		}
+	}
+	return a
+}
+
+func (a memberAuthorizer) Authorize(ctx *context.T, call security.Call) error {
+	names, rejected := security.RemoteBlessingNames(ctx, call)
+	for _, p := range a.patterns {
+		if p.MatchedBy(names...) {
+			return nil
+		}
+	}
+	return verror.ErrNoAccess.Errorf(ctx, "%v (rejected %v) is not a member of the replicated mount table, whose members have blessings %v", names, rejected, a.patterns)
+}
+
+// forward makes the change described by fc, on behalf of the caller
+// described by cc, either directly if this member is the leader or by
+// forwarding it to the leader.  In the latter case it waits for the change
+// to be replicated back so that the caller sees its own changes.
+func (r *replicator) forward(cc *callContext, fc ForwardedCall) error {
+	r.mu.Lock()
+	leader, leaderName, epoch := r.leader, r.leaderName, r.epoch
+	r.mu.Unlock()
+	if leader {
+		_, err := r.commit(cc, fc)
+		return err
+	}
+	if len(leaderName) == 0 {
+		return ErrorfNoLeader(cc.ctx, "the replicated mount table has no leader")
+	}
+	if cc.call != nil {
+		fc.Blessings, fc.Discharges = cc.call.RemoteBlessings(), cc.call.RemoteDischarges()
+	}
+	seq, err := replica(leaderName).Forward(cc.ctx, fc, r.opts()...)
+	if err != nil {
+		return err
+	}
+	r.waitFor(cc.ctx, epoch, seq)
+	return nil
+}
+
+// waitFor waits until seq has been applied in epoch, the epoch changes,
+// ctx is done or forwardWait has passed.
+func (r *replicator) waitFor(ctx *context.T, epoch, seq uint64) {
+	timer := time.NewTimer(forwardWait)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		if r.epoch != epoch || r.seq >= seq {
+			r.mu.Unlock()
+			return
+		}
+		ch := r.changed
+		r.mu.Unlock()
+		select {
+		case <-ch:
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// commit makes the change described by fc, which must be made by the
+// leader, and waits for it to be applied by a majority of the group.  It
+// returns the sequence number of the last change made.
+func (r *replicator) commit(cc *callContext, fc ForwardedCall) (uint64, error) {
+	if err := r.execute(cc, fc); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	epoch, seq := r.epoch, r.seq
+	r.mu.Unlock()
+	return seq, r.waitForCommit(cc.ctx, epoch, seq)
+}
+
+// waitForCommit waits until seq has been applied in epoch by a majority of
+// the group, returning ErrNoQuorum if this member stops being the leader for
+// epoch, or forwardWait passes, first.
+func (r *replicator) waitForCommit(ctx *context.T, epoch, seq uint64) error {
+	timer := time.NewTimer(forwardWait)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		if !r.leader || r.epoch != epoch {
+			r.mu.Unlock()
+			return ErrorfNoQuorum(ctx, "the leadership changed before the change was applied by a majority of the replicated mount table")
+		}
+		if r.committedLocked() >= seq {
+			r.mu.Unlock()
+			return nil
+		}
+		ch := r.changed
+		r.mu.Unlock()
+		select {
+		case <-ch:
+		case <-timer.C:
+			return ErrorfNoQuorum(ctx, "the change was not applied by a majority of the replicated mount table in time")
+		case <-ctx.Done():
+			return ErrorfNoQuorum(ctx, "the change was not applied by a majority of the replicated mount table before the call ended")
+		}
+	}
+}
+
+// committedLocked returns the sequence number of the last change made by
+// the leader in its epoch that has been applied by a majority of the group.
+// r.mu must be held.
+func (r *replicator) committedLocked() uint64 {
+	seqs := []uint64{r.seq}
+	for _, seq := range r.acks {
+		seqs = append(seqs, seq)
+	}
+	majority := len(r.members)/2 + 1
+	if len(seqs) < majority {
+		return 0
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] > seqs[j] })
+	return seqs[majority-1]
+}
+
+// ackLocked records that member has applied the changes up to and including
+// seq in the leader's epoch.  r.mu must be held.
+func (r *replicator) ackLocked(member string, seq uint64) {
+	if !r.isPeerLocked(member) || r.acks[member] >= seq {
+		return
+	}
+	r.acks[member] = seq
+	r.broadcastLocked()
+}
+
+// isPeerLocked returns true if member is one of the other members of the
+// group.  r.mu must be held.
+func (r *replicator) isPeerLocked(member string) bool {
+	for i, m := range r.members {
+		if m == member {
+			return i != r.self
+		}
+	}
+	return false
+}
+
+// execute makes the change described by fc with the permissions of the
+// caller described by cc.
+func (r *replicator) execute(cc *callContext, fc ForwardedCall) error {
+	ms := r.mt.newMountContext(fc.Name)
+	switch fc.Method {
+	case "Mount":
+		cc.create = createMissingNodes
+		return ms.mount(cc, fc.Server, fc.Ttl, fc.Flags)
+	case "Unmount":
+		return ms.unmount(cc, fc.Server)
+	case "Delete":
+		return ms.delete(cc, fc.DeleteSubtree)
+	case "SetPermissions":
+		cc.create = createMissingNodes
+		return ms.setPermissions(cc, fc.Perms, fc.Version)
+	}
+	return fmt.Errorf("unknown method %q", fc.Method)
+}
+
+// Status implements ReplicaServerMethods.Status.
+func (r *replicator) Status(*context.T, rpc.ServerCall) (ReplicaStatus, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return ReplicaStatus{Id: r.id, Leader: r.leader, Epoch: r.epoch, Seq: r.seq, Promised: r.promised}, nil
+}
+
+// Vote implements ReplicaServerMethods.Vote.
+func (r *replicator) Vote(ctx *context.T, _ rpc.ServerCall, candidate string, epoch, lastEpoch, lastSeq uint64) (bool, error) {
+	return r.vote(ctx, candidate, epoch, lastEpoch, lastSeq), nil
+}
+
+// vote votes for candidate as the leader for epoch if this member has not
+// already voted for epoch, or a later one, and candidate has seen all of the
+// changes applied by this member, stepping down if it is the leader.
+func (r *replicator) vote(ctx *context.T, candidate string, epoch, lastEpoch, lastSeq uint64) bool {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if epoch <= r.promised || epoch <= r.epoch || lastEpoch < r.epoch || (lastEpoch == r.epoch && lastSeq < r.seq) {
+		return false
+	}
+	r.promised = epoch
+	if r.leader {
+		ctx.Infof("replicated mount table: stepping down, voted for %v as the leader for epoch %v", candidate, epoch)
+		r.setLeaderLocked(ctx, false, "")
+	}
+	return true
+}
+
+// Forward implements ReplicaServerMethods.Forward.  The caller's blessing
+// names are determined from the blessings and discharges that it presented
+// to the forwarding member, which is trusted to have authenticated it.
+func (r *replicator) Forward(ctx *context.T, _ rpc.ServerCall, fc ForwardedCall) (uint64, error) {
+	r.mu.Lock()
+	leader := r.leader
+	r.mu.Unlock()
+	if !leader {
+		return 0, ErrorfNotLeader(ctx, "this replica is not the leader")
+	}
+	p := v23.GetPrincipal(ctx)
+	def, _ := p.BlessingStore().Default()
+	call := security.NewCall(&security.CallParams{
+		Timestamp:        time.Now(),
+		Method:           fc.Method,
+		Suffix:           fc.Name,
+		LocalPrincipal:   p,
+		LocalBlessings:   def,
+		RemoteBlessings:  fc.Blessings,
+		RemoteDischarges: fc.Discharges,
+	})
+	_, cc := r.mt.newMountContext(fc.Name).newCallContext(ctx, call, !createMissingNodes)
+	return r.commit(cc, fc)
+}
+
+// Pull implements ReplicaServerMethods.Pull.
+func (r *replicator) Pull(ctx *context.T, _ rpc.ServerCall, member, leaderID string, epoch, seq uint64) (ReplicationBatch, error) {
+	timer := time.NewTimer(pullWait)
+	defer timer.Stop()
+	for {
+		r.mu.Lock()
+		if !r.leader {
+			r.mu.Unlock()
+			return ReplicationBatch{}, ErrorfNotLeader(ctx, "this replica is not the leader")
+		}
+		if epoch != r.epoch || leaderID != r.id || seq > r.seq || r.seq-seq > uint64(len(r.log)) {
+			// Any changes made while the snapshot is taken are
+			// replayed by the follower when it next pulls.
+			delete(r.acks, member)
+			batch := ReplicationBatch{LeaderId: r.id, Epoch: r.epoch, Seq: r.seq, Reset: true}
+			r.mu.Unlock()
+			batch.Entries = r.mt.snapshot()
+			return batch, nil
+		}
+		r.ackLocked(member, seq)
+		if seq < r.seq {
+			entries := r.log[len(r.log)-int(r.seq-seq):]
+			if len(entries) > maxReplicationBatch {
+				entries = entries[:maxReplicationBatch]
+			}
+			batch := ReplicationBatch{
+				LeaderId: leaderID,
+				Epoch:    epoch,
+				Seq:      seq + uint64(len(entries)),
+				Entries:  append([]ReplicationEntry(nil), entries...),
+			}
+			r.mu.Unlock()
+			return batch, nil
+		}
+		ch := r.changed
+		r.mu.Unlock()
+		empty := ReplicationBatch{LeaderId: leaderID, Epoch: epoch, Seq: seq}
+		select {
+		case <-ch:
+		case <-timer.C:
+			return empty, nil
+		case <-ctx.Done():
+			return empty, nil
+		case <-r.done:
+			return empty, nil
+		}
+	}
+}
+
+// elect polls the members of the group, and changes the leadership as
+// required, every electionInterval until ctx is canceled.
+func (r *replicator) elect(ctx *context.T) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if c := r.electOnce(ctx, r.poll(ctx)); c != nil {
+			r.campaign(ctx, *c)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll returns the status of each member of the group, or nil for those
+// that can't be reached.
+func (r *replicator) poll(ctx *context.T) []*ReplicaStatus {
+	// Calls to a member that has failed may outlast their deadlines while
+	// the connection to it is being resumed, so give up on them after the
+	// interval and don't call members that are still being waited for.
+	var (
+		mu       sync.Mutex
+		statuses = make([]*ReplicaStatus, len(r.members))
+		done     = make(chan struct{}, len(r.members))
+		calls    = 0
+	)
+	for i, m := range r.members {
+		r.mu.Lock()
+		busy := r.polling[i]
+		r.polling[i] = true
+		r.mu.Unlock()
+		if busy {
+			continue
+		}
+		calls++
+		go func(i int, m string) {
+			sctx, cancel := context.WithTimeout(ctx, r.interval)
+			defer cancel()
+			status, err := replica(m).Status(sctx, r.opts(options.NoRetry{})...)
+			r.mu.Lock()
+			r.polling[i] = false
+			r.mu.Unlock()
+			if err != nil {
+				ctx.VI(2).Infof("replica %v: Status failed: %v", m, err)
+			} else {
+				mu.Lock()
+				statuses[i] = &status
+				mu.Unlock()
+			}
+			done <- struct{}{}
+		}(i, m)
+	}
+	timer := time.NewTimer(r.interval)
+	defer timer.Stop()
+wait:
+	for ; calls > 0; calls-- {
+		select {
+		case <-done:
+		case <-timer.C:
+			break wait
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]*ReplicaStatus(nil), statuses...)
+}
+
+// candidacy describes a member's candidacy to be the leader for epoch,
+// having last seen the change lastSeq in lastEpoch.
+type candidacy struct {
+	epoch, lastEpoch, lastSeq uint64
+}
+
+// electOnce changes the leadership as required by the statuses of the
+// members, returning a candidacy if this member should ask to be elected.
+func (r *replicator) electOnce(ctx *context.T, statuses []*ReplicaStatus) *candidacy {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	self, leader, candidate, reachable, maxEpoch, promised := -1, -1, -1, 0, r.epoch, r.promised
+	for i, s := range statuses {
+		if s == nil {
+			continue
+		}
+		reachable++
+		if s.Id == r.id {
+			self = i
+			// Use our current, rather than our polled, status.
+			s = &ReplicaStatus{Id: r.id, Leader: r.leader, Epoch: r.epoch, Seq: r.seq, Promised: r.promised}
+			statuses[i] = s
+		}
+		if s.Epoch > maxEpoch {
+			maxEpoch = s.Epoch
+		}
+		if s.Promised > promised {
+			promised = s.Promised
+		}
+		// A leader for an epoch earlier than one that we have voted
+		// for can't be followed.
+		if s.Leader && s.Epoch >= r.promised && (leader < 0 || s.Epoch > statuses[leader].Epoch) {
+			leader = i
+		}
+		if candidate < 0 || s.Epoch > statuses[candidate].Epoch || (s.Epoch == statuses[candidate].Epoch && s.Seq > statuses[candidate].Seq) {
+			candidate = i
+		}
+	}
+	if self < 0 {
+		ctx.VI(1).Infof("replicated mount table: this replica is not one of %v", r.members)
+		return nil
+	}
+	r.self = self
+	if promised > maxEpoch {
+		maxEpoch = promised
+	}
+	quorum := reachable > len(r.members)/2
+	switch {
+	case r.leader && promised > r.epoch:
+		// A member has voted for a later leader, which may have been
+		// elected, and so no longer acknowledges our changes.
+		ctx.Infof("replicated mount table: %v stepping down, a replica has voted for epoch %v", r.members[self], promised)
+		r.setLeaderLocked(ctx, false, "")
+	case r.leader && !quorum:
+		if r.misses++; r.misses >= electionMisses {
+			ctx.Infof("replicated mount table: %v stepping down, only %v of %v replicas are reachable", r.members[self], reachable, len(r.members))
+			r.setLeaderLocked(ctx, false, "")
+		}
+	case leader == self:
+		r.misses = 0
+	case leader >= 0:
+		r.misses = 0
+		if r.leader || r.leaderName != r.members[leader] {
+			ctx.Infof("replicated mount table: %v following %v", r.members[self], r.members[leader])
+			r.setLeaderLocked(ctx, false, r.members[leader])
+		}
+	default:
+		r.leaderName = ""
+		if r.misses++; r.misses < electionMisses || !quorum || candidate != self {
+			return nil
+		}
+		r.misses = 0
+		return &candidacy{epoch: nextEpoch(maxEpoch, self, len(r.members)), lastEpoch: r.epoch, lastSeq: r.seq}
+	}
+	return nil
+}
+
+// nextEpoch returns the first epoch after epoch that may be chosen by the
+// member with index self in a group of n members, so that no two members
+// can be elected as the leader for the same epoch.
+func nextEpoch(epoch uint64, self, n int) uint64 {
+	next := epoch + 1
+	return next + (uint64(self)+uint64(n)-next%uint64(n))%uint64(n)
+}
+
+// campaign asks the members of the group to vote for this member as the
+// leader for c.epoch, and makes it the leader if a majority of them do so
+// and it has seen no further changes in the meantime.
+func (r *replicator) campaign(ctx *context.T, c candidacy) {
+	r.mu.Lock()
+	self := r.self
+	r.mu.Unlock()
+	name := r.members[self]
+	if !r.vote(ctx, name, c.epoch, c.lastEpoch, c.lastSeq) {
+		return
+	}
+	votes := make(chan bool, len(r.members))
+	for i, m := range r.members {
+		if i == self {
+			continue
+		}
+		go func(m string) {
+			vctx, cancel := context.WithTimeout(ctx, r.interval)
+			defer cancel()
+			ok, err := replica(m).Vote(vctx, name, c.epoch, c.lastEpoch, c.lastSeq, r.opts(options.NoRetry{})...)
+			if err != nil {
+				ctx.VI(2).Infof("replica %v: Vote failed: %v", m, err)
+			}
+			votes <- ok
+		}(m)
+	}
+	granted := 1
+	for i := 1; i < len(r.members) && granted <= len(r.members)/2; i++ {
+		if <-votes {
+			granted++
+		}
+	}
+	if granted <= len(r.members)/2 {
+		ctx.VI(1).Infof("replicated mount table: %v received %v of %v votes for epoch %v", name, granted, len(r.members), c.epoch)
+		return
+	}
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.leader || r.promised != c.epoch || r.epoch != c.lastEpoch || r.seq != c.lastSeq {
+		return
+	}
+	r.epoch = c.epoch
+	r.log = nil
+	r.acks = make(map[string]uint64)
+	ctx.Infof("replicated mount table: %v is the leader for epoch %v", name, r.epoch)
+	r.setLeaderLocked(ctx, true, name)
+}
+
+// setLeaderLocked changes the leadership.  r.mu must be held.
+func (r *replicator) setLeaderLocked(ctx *context.T, leader bool, leaderName string) {
+	r.leader, r.leaderName = leader, leaderName
+	r.broadcastLocked()
+}
+
+// follow pulls changes from the leader, while there is one that isn't this
+// member, until ctx is canceled.
+func (r *replicator) follow(ctx *context.T) {
+	for {
+		r.mu.Lock()
+		leader, leaderName, leaderID, epoch, seq, ch := r.leader, r.leaderName, r.leaderID, r.epoch, r.seq, r.changed
+		self := ""
+		if r.self >= 0 {
+			self = r.members[r.self]
+		}
+		r.mu.Unlock()
+		if leader || len(leaderName) == 0 {
+			select {
+			case <-ch:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		// Stop waiting for the leader if the leadership changes, since
+		// a call to a leader that has failed may outlast its deadline.
+		type result struct {
+			batch ReplicationBatch
+			err   error
+		}
+		pctx, cancel := context.WithTimeout(ctx, pullWait+r.interval)
+		results := make(chan result, 1)
+		go func() {
+			batch, err := replica(leaderName).Pull(pctx, self, leaderID, epoch, seq, r.opts(options.NoRetry{})...)
+			results <- result{batch, err}
+		}()
+		var res result
+		select {
+		case res = <-results:
+		case <-ch:
+			cancel()
+			continue
+		case <-ctx.Done():
+			cancel()
+			return
+		}
+		cancel()
+		if res.err != nil {
+			ctx.VI(2).Infof("replica %v: Pull failed: %v", leaderName, res.err)
+			select {
+			case <-time.After(r.interval / 4):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		batch := res.batch
+		r.apply(ctx, leaderName, batch)
+	}
+}
+
+// apply applies a batch of changes pulled from leaderName, provided that it
+// is still the leader being followed and this member has not since voted
+// for a later leader.
+func (r *replicator) apply(ctx *context.T, leaderName string, batch ReplicationBatch) {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	r.mu.Lock()
+	current := !r.leader && r.leaderName == leaderName && batch.Epoch >= r.promised
+	r.mu.Unlock()
+	if !current || (!batch.Reset && len(batch.Entries) == 0) {
+		return
+	}
+	if batch.Reset {
+		r.mt.reset(ctx)
+	}
+	for _, e := range batch.Entries {
+		r.mt.applyEntry(ctx, e)
+	}
+	r.mu.Lock()
+	r.leaderID, r.epoch, r.seq = batch.LeaderId, batch.Epoch, batch.Seq
+	r.broadcastLocked()
+	r.mu.Unlock()
+}
+
+// snapshot returns the changes that recreate the explicit permissions and
+// the mounts in the tree.
+func (mt *mountTable) snapshot() []ReplicationEntry {
+	var entries []ReplicationEntry
+	var walk func(n *node, name string)
+	walk = func(n *node, name string) {
+		n.Lock()
+		if n.explicitPermissions && n.vPerms != nil {
+			entries = append(entries, ReplicationEntry{Name: name, SetPerms: true, Perms: n.vPerms.P.Copy(), PermsVersion: n.vPerms.V, Creator: n.creator})
+		}
+		if n.mount != nil {
+			var flags naming.MountFlag
+			if n.mount.mt {
+				flags |= naming.MT
+			}
+			if n.mount.leaf {
+				flags |= naming.Leaf
+			}
+			for _, ms := range n.mount.servers.copyToSlice() {
+				entries = append(entries, ReplicationEntry{Name: name, Mount: true, Server: ms.Server, Deadline: ms.Deadline.Time, Flags: flags})
+			}
+		}
+		children := make(map[string]*node, len(n.children))
+		for k, c := range n.children {
+			children[k] = c
+		}
+		n.Unlock()
+		for k, c := range children {
+			walk(c, path.Join(name, k))
+		}
+	}
+	walk(mt.root, "")
+	return entries
+}
+
+// reset removes everything but the root's permissions from the tree.
+func (mt *mountTable) reset(ctx *context.T) {
+	defer mt.changes.notify()
+	n := mt.root
+	n.parent.Lock()
+	n.Lock()
+	for child := range n.children {
+		mt.deleteNode(n, child)
+		mt.persist.persistDelete(child) //nolint:errcheck
+	}
+	if n.mount != nil {
+		mt.serverCounter.Incr(-numServers(n))
+		n.mount = nil
+		mt.persist.persistUnmount("", "") //nolint:errcheck
+	}
+	n.Unlock()
+	n.parent.Unlock()
+}
+
+// applyEntry applies a change made by the leader.
+func (mt *mountTable) applyEntry(ctx *context.T, e ReplicationEntry) {
+	cc := &callContext{ctx: ctx, creator: e.Creator, ignorePerms: true, ignoreLimits: true}
+	ms := mt.newMountContext(e.Name)
+	var err error
+	switch {
+	case e.Delete:
+		err = ms.delete(cc, true)
+	case e.Unmount:
+		err = ms.unmount(cc, e.Server)
+	case e.SetPerms:
+		cc.create = createMissingNodes
+		err = ms.replacePermissions(cc, &VersionedPermissions{V: e.PermsVersion, P: e.Perms})
+	case e.Mount:
+		cc.create = createMissingNodes
+		if ttl := e.Deadline.Sub(mt.slm.clock.Now()); ttl > 0 {
+			err = ms.mountWithTTL(cc, e.Server, ttl, e.Flags)
+		}
+	}
+	if err != nil {
+		ctx.Errorf("replicated mount table: failed to apply change to %q: %v", e.Name, err)
+	}
+}
+
+// replacePermissions sets the permissions, including their version, of the
+// name in the receiver.
+func (ms *mountContext) replacePermissions(cc *callContext, vPerms *VersionedPermissions) error {
+	mt := ms.mt
+	defer mt.changes.notify()
+	n, err := mt.findNode(cc, ms.elems, nil, nil)
+	if err != nil {
+		return err
+	}
+	if n == nil {
+		return naming.ErrNoSuchName.Errorf(cc.ctx, "name %s doesn't exist", ms.name)
+	}
+	n.parent.Unlock()
+	defer n.Unlock()
+	n.vPerms = vPerms
+	n.explicitPermissions = true
+	mt.persist.persistPerms(ms.name, n.creator, n.vPerms) //nolint:errcheck
+	return nil
+}
+
+// replicationDispatcher serves the Replica interface under replicationName
+// and passes all other reserved names on to next.
+type replicationDispatcher struct {
+	r    *replicator
+	next rpc.Dispatcher
+}
+
+// Lookup implements rpc.Dispatcher.Lookup.  Only the members of the group
+// may call the replication service since the leader trusts them to have
+// authenticated the callers of the changes that they forward.
+func (d replicationDispatcher) Lookup(ctx *context.T, suffix string) (interface{}, security.Authorizer, error) {
+	if suffix == replicationName {
+		return ReplicaServer(d.r), d.r.auth, nil
+	}
+	if d.next == nil {
+		return nil, nil, nil
+	}
+	return d.next.Lookup(ctx, suffix)
+}
@@ -0,0 +1,196 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib_test
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v23 "v.io/v23"
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/rpc"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/verror"
+	"v.io/x/ref/services/mounttable/mounttablelib"
+)
+
+// freePorts returns n ports that are free on the loopback interface.
+func freePorts(t *testing.T, n int) []string {
+	var ports []string
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		_, port, _ := net.SplitHostPort(l.Addr().String())
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+func waitForLeader(t *testing.T, ctx *context.T, replicas []string) int {
+	for start := time.Now(); time.Since(start) < 30*time.Second; time.Sleep(100 * time.Millisecond) {
+		if l := mounttablelib.LeaderForTest(ctx, replicas); l >= 0 {
+			return l
+		}
+	}
+	boom(t, "no leader was elected among %v", replicas)
+	return -1
+}
+
+// waitForGlob waits for the replica at ep to return want for pattern.
+func waitForGlob(t *testing.T, ctx *context.T, ep, pattern string, want []string) {
+	var got []string
+	for start := time.Now(); time.Since(start) < 30*time.Second; time.Sleep(100 * time.Millisecond) {
+		got = doGlobX(t, ctx, ep, "", pattern, true)
+		if len(got) == len(want) {
+			checkMatch(t, want, got)
+			return
+		}
+	}
+	boom(t, "Glob %v on %v: expected %v got %v", pattern, ep, want, got)
+}
+
+// startReplicas starts a group of n replicated mount tables, returning
+// their names, endpoints and the functions that stop them.
+func startReplicas(t *testing.T, ctx *context.T, n int, statsPrefix string) (names, eps []string, stops []func()) {
+	ports := freePorts(t, n)
+	for _, p := range ports {
+		names = append(names, "/127.0.0.1:"+p)
+	}
+	stops = make([]func(), n)
+	eps = make([]string, n)
+	for i, p := range ports {
+		listenSpec := rpc.ListenSpec{Addrs: rpc.ListenAddrs{{Protocol: "tcp", Address: "127.0.0.1:" + p}}}
+		opts := mounttablelib.Opts{Replicas: strings.Join(names, ",")}
+		var err error
+		if eps[i], stops[i], err = mounttablelib.StartServersWithOpts(ctx, listenSpec, opts, fmt.Sprintf("%s%d", statsPrefix, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return names, eps, stops
+}
+
+func TestReplication(t *testing.T) {
+	defer mounttablelib.SetElectionInterval(100 * time.Millisecond)()
+	rootCtx, aliceCtx, _, shutdown := initTest()
+	defer shutdown()
+
+	names, eps, stops := startReplicas(t, rootCtx, 3, "testReplication")
+	defer func() {
+		for _, stop := range stops {
+			if stop != nil {
+				stop()
+			}
+		}
+	}()
+
+	l := waitForLeader(t, rootCtx, names)
+	f1, f2 := (l+1)%3, (l+2)%3
+
+	// Changes made via a follower are seen by every replica.
+	doMount(t, rootCtx, eps[f1], "a/b", "/127.0.0.1:1111", true)
+	doMount(t, aliceCtx, eps[f1], "a/c", "/127.0.0.1:2222", true)
+	for _, ep := range eps {
+		waitForGlob(t, rootCtx, ep, "a/*", []string{"/127.0.0.1:1111/a/b", "/127.0.0.1:2222/a/c"})
+	}
+	doUnmount(t, rootCtx, eps[f2], "a/c", "/127.0.0.1:2222", true)
+	doDeleteSubtree(t, rootCtx, eps[l], "a/b", true)
+	for _, ep := range eps {
+		waitForGlob(t, rootCtx, ep, "a/*", nil)
+	}
+
+	// Permissions are set, and enforced, by the leader.
+	perms := access.Permissions{
+		"Admin":   access.AccessList{In: []security.BlessingPattern{"root"}},
+		"Read":    access.AccessList{In: []security.BlessingPattern{security.AllPrincipals}},
+		"Resolve": access.AccessList{In: []security.BlessingPattern{security.AllPrincipals}},
+		"Mount":   access.AccessList{In: []security.BlessingPattern{"root"}},
+	}
+	doSetPermissions(t, rootCtx, eps[f2], "p", perms, "", true)
+	got, version := doGetPermissions(t, rootCtx, eps[f2], "p", true)
+	if !reflect.DeepEqual(got, perms) || version != "1" {
+		t.Fatalf("got %v, %v, want %v, 1", got, version, perms)
+	}
+	doMount(t, aliceCtx, eps[f1], "p", "/127.0.0.1:3333", false)
+	doMount(t, rootCtx, eps[f1], "p", "/127.0.0.1:4444", true)
+	for _, ep := range eps {
+		waitForGlob(t, rootCtx, ep, "p", []string{"/127.0.0.1:4444/p"})
+	}
+
+	// Stop the leader, another replica takes over and has all the changes.
+	stops[l]()
+	stops[l], names[l] = nil, ""
+	nl := waitForLeader(t, rootCtx, names)
+	if nl != f1 && nl != f2 {
+		t.Fatalf("unexpected leader %v", nl)
+	}
+	nf := f1 + f2 - nl
+	waitForGlob(t, rootCtx, eps[nl], "p", []string{"/127.0.0.1:4444/p"})
+	doMount(t, rootCtx, eps[nf], "x", "/127.0.0.1:5555", true)
+	for _, i := range []int{nl, nf} {
+		waitForGlob(t, rootCtx, eps[i], "p", []string{"/127.0.0.1:4444/p"})
+		waitForGlob(t, rootCtx, eps[i], "x", []string{"/127.0.0.1:5555/x"})
+	}
+}
+
+func TestReplicationMembers(t *testing.T) {
+	defer mounttablelib.SetElectionInterval(100 * time.Millisecond)()
+	defer mounttablelib.SetForwardWait(time.Second)()
+	rootCtx, aliceCtx, _, shutdown := initTest()
+	defer shutdown()
+
+	names, eps, stops := startReplicas(t, rootCtx, 3, "testReplicationMembers")
+	defer func() {
+		for _, stop := range stops {
+			if stop != nil {
+				stop()
+			}
+		}
+	}()
+	l := waitForLeader(t, rootCtx, names)
+
+	// Only the members of the group may call the replication service.
+	replica := mounttablelib.ReplicaClient(naming.Join(names[l], "__replication"))
+	opts := []rpc.CallOpt{options.Preresolved{}, options.NoRetry{}, options.ServerAuthorizer{Authorizer: security.AllowEveryone()}}
+	if _, err := replica.Status(rootCtx, opts...); err != nil {
+		t.Errorf("Status: %v", err)
+	}
+	if _, err := replica.Status(aliceCtx, opts...); verror.ErrorID(err) != verror.ErrNoAccess.ID {
+		t.Errorf("Status: got %v, want %v", err, verror.ErrNoAccess)
+	}
+	if _, err := replica.Pull(aliceCtx, "alice", "", 0, 0, opts...); verror.ErrorID(err) != verror.ErrNoAccess.ID {
+		t.Errorf("Pull: got %v, want %v", err, verror.ErrNoAccess)
+	}
+	if _, err := replica.Vote(aliceCtx, "alice", 1<<40, 1<<40, 0, opts...); verror.ErrorID(err) != verror.ErrNoAccess.ID {
+		t.Errorf("Vote: got %v, want %v", err, verror.ErrNoAccess)
+	}
+	fc := mounttablelib.ForwardedCall{Method: "Mount", Name: "x", Server: "/127.0.0.1:1111", Ttl: ttlSecs}
+	if _, err := replica.Forward(aliceCtx, fc, opts...); verror.ErrorID(err) != verror.ErrNoAccess.ID {
+		t.Errorf("Forward: got %v, want %v", err, verror.ErrNoAccess)
+	}
+	waitForGlob(t, rootCtx, eps[l], "x", nil)
+
+	// A change that can't be applied by a majority of the group fails.
+	for i := range stops {
+		if i != l {
+			stops[i]()
+			stops[i] = nil
+		}
+	}
+	name := naming.JoinAddressName(eps[l], "y")
+	err := v23.GetClient(rootCtx).Call(rootCtx, name, "Mount", []interface{}{"/127.0.0.1:2222", uint32(ttlSecs), 0}, nil, options.Preresolved{}, options.NoRetry{})
+	if id := verror.ErrorID(err); id != mounttablelib.ErrNoQuorum.ID && id != mounttablelib.ErrNoLeader.ID {
+		t.Errorf("Mount: got %v, want %v or %v", err, mounttablelib.ErrNoQuorum, mounttablelib.ErrNoLeader)
+	}
+}
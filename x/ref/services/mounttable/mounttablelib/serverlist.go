@@ -57,17 +57,20 @@ func (sl *serverList) Front() *server {
 
 // add to the front of the list if not already in the list, otherwise,
 // update the expiration time and move to the front of the list.  That
-// way the most recently refreshed is always first.
-func (sl *serverList) add(oa string, ttl time.Duration) {
-	expires := sl.m.clock.Now().Add(ttl)
+// way the most recently refreshed is always first.  It returns the new
+// expiration time and whether an unexpired entry for oa was refreshed.
+func (sl *serverList) add(oa string, ttl time.Duration) (time.Time, bool) {
+	now := sl.m.clock.Now()
+	expires := now.Add(ttl)
 	sl.Lock()
 	defer sl.Unlock()
 	for e := sl.l.Front(); e != nil; e = e.Next() {
 		s := e.Value.(*server)
 		if s.oa == oa {
+			refreshed := !now.After(s.expires)
 			s.expires = expires
 			sl.l.MoveToFront(e)
-			return
+			return expires, refreshed
 		}
 	}
 	s := &server{
@@ -75,6 +78,7 @@ func (sl *serverList) add(oa string, ttl time.Duration) {
 		expires: expires,
 	}
 	sl.l.PushFront(s) // innocent until proven guilty
+	return expires, false
 }
 
 // remove an element from the list.  Return the number of elements remaining.
@@ -24,7 +24,7 @@ func Main(opts Opts) error {
 }
 
 func MainWithCtx(ctx *context.T, opts Opts) error {
-	name, stop, err := StartServers(ctx, v23.GetListenSpec(ctx), opts.MountName, opts.NhName, opts.AclFile, opts.PersistDir, "mounttable", opts.LogLevel)
+	name, stop, err := StartServersWithOpts(ctx, v23.GetListenSpec(ctx), opts, "mounttable")
 	if err != nil {
 		return fmt.Errorf("mounttablelib.StartServers failed: %v", err)
 	}
@@ -36,6 +36,19 @@ func MainWithCtx(ctx *context.T, opts Opts) error {
 }
 
 func StartServers(ctx *context.T, listenSpec rpc.ListenSpec, mountName, nhName, permsFile, persistDir, debugPrefix string, logLevel int) (string, func(), error) {
+	return StartServersWithOpts(ctx, listenSpec, Opts{
+		MountName:  mountName,
+		NhName:     nhName,
+		AclFile:    permsFile,
+		PersistDir: persistDir,
+		LogLevel:   logLevel,
+	}, debugPrefix)
+}
+
+// StartServersWithOpts is like StartServers with the configuration of the
+// mount table and neighborhood servers taken from opts.
+func StartServersWithOpts(ctx *context.T, listenSpec rpc.ListenSpec, opts Opts, debugPrefix string) (string, func(), error) {
+	mountName, nhName, logLevel := opts.MountName, opts.NhName, opts.LogLevel
 	var stopFuncs []func()
 	ctx, cancel := context.WithCancel(ctx)
 	stop := func() {
@@ -45,12 +58,16 @@ func StartServers(ctx *context.T, listenSpec rpc.ListenSpec, mountName, nhName,
 		}
 	}
 
-	mt, err := NewMountTableDispatcherWithClock(ctx, permsFile, persistDir, debugPrefix, timekeeper.RealTime(), logLevel)
+	mt, err := NewMountTableDispatcherWithOpts(ctx, opts, debugPrefix, timekeeper.RealTime())
 	if err != nil {
 		ctx.Errorf("NewMountTable failed: %v", err)
 		return "", nil, err
 	}
 	ctx = v23.WithListenSpec(ctx, listenSpec)
+	if repl := mt.(*mountTable).repl; repl != nil {
+		// Serve the replication service alongside any other reserved names.
+		ctx = v23.WithReservedNameDispatcher(ctx, replicationDispatcher{r: repl, next: v23.GetReservedNameDispatcher(ctx)})
+	}
 	ctx, mtServer, err := v23.WithNewDispatchingServer(ctx, mountName, mt, options.ServesMountTable(true))
 	if err != nil {
 
@@ -73,6 +90,17 @@ func StartServers(ctx *context.T, listenSpec rpc.ListenSpec, mountName, nhName,
 	}
 	ctx.Infof("Mount table service at: %q endpoint: %s", mountName, mtName)
 
+	if len(opts.DNSAddress) > 0 {
+		dnsAddr, dnsStop, err := StartDNSServer(ctx, opts.DNSAddress, opts.DNSZone, mtName)
+		if err != nil {
+			ctx.Errorf("StartDNSServer failed: %v", err)
+			stop()
+			return "", nil, err
+		}
+		stopFuncs = append(stopFuncs, dnsStop)
+		ctx.Infof("DNS server for zone %q at: %s", opts.DNSZone, dnsAddr)
+	}
+
 	if len(nhName) > 0 {
 		// The ListenSpec code ensures that we have a valid address here.
 		host, port, _ := net.SplitHostPort(listenSpec.Addrs[0].Address)
@@ -4,7 +4,27 @@
 
 package mounttablelib
 
+import "time"
+
 // DefaultMaxNodesPerUser returns the maximum number of nodes per user.
 func DefaultMaxNodesPerUser() int {
 	return defaultMaxNodesPerUser
 }
+
+// SetElectionInterval sets the interval at which the members of a group of
+// replicated mount tables poll each other, and returns a function that
+// restores the previous interval.
+func SetElectionInterval(d time.Duration) func() {
+	old := electionInterval
+	electionInterval = d
+	return func() { electionInterval = old }
+}
+
+// SetForwardWait sets the maximum time for which a change made by a group
+// of replicated mount tables waits to be replicated, and returns a function
+// that restores the previous time.
+func SetForwardWait(d time.Duration) func() {
+	old := forwardWait
+	forwardWait = d
+	return func() { forwardWait = old }
+}
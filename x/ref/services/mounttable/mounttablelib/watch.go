@@ -0,0 +1,500 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/glob"
+	"v.io/v23/naming"
+	"v.io/v23/rpc"
+	"v.io/v23/services/watch"
+	"v.io/v23/verror"
+	"v.io/v23/vom"
+	"v.io/x/ref/lib/timekeeper"
+)
+
+// maxWatchLog is the number of changed names remembered for resuming
+// watches.
+const maxWatchLog = 10000
+
+// nowMarker is the watch.ResumeMarker that skips the initial state.
+const nowMarker = "now"
+
+// watchedNode is the state of a node that matters to watchers. Deadlines
+// are deliberately left out so that servers refreshing their mounts do not
+// generate changes.
+type watchedNode struct {
+	servers  string // sorted, space separated, unexpired servers.
+	mt, leaf bool
+	perms    bool  // true if permissions were set explicitly.
+	version  int32 // version of the permissions.
+}
+
+type watchLogEntry struct {
+	gen  uint64
+	name string
+}
+
+// changeLog tracks changes to the mount table for WatchGlob.
+//
+// Mutations only mark the log as dirty and wake up the watchers; the
+// watchers then bring the log up to date by comparing the tree with the
+// last state seen. The log remembers which names changed at which
+// generation so that watches can resume from a generation.
+type changeLog struct {
+	id   string          // identifies this instance of the mount table in markers.
+	done <-chan struct{} // closed when the mount table is stopped.
+
+	notifyMu sync.Mutex // guards the following two fields.
+	dirty    bool
+	changed  chan struct{} // closed when the mount table changes.
+
+	mu         sync.Mutex // guards the remaining fields.
+	gen        uint64
+	base       uint64 // changes after base are all in log.
+	nodes      map[string]watchedNode
+	log        []watchLogEntry
+	nextExpiry time.Time
+}
+
+func newChangeLog(ctx *context.T) *changeLog {
+	b := make([]byte, 8)
+	rand.Read(b) //nolint:errcheck
+	return &changeLog{
+		id:      hex.EncodeToString(b),
+		done:    ctx.Done(),
+		dirty:   true,
+		changed: make(chan struct{}),
+	}
+}
+
+// notify is called after every change to the mount table.  It may be called
+// with node locks held.
+func (l *changeLog) notify() {
+	l.notifyMu.Lock()
+	defer l.notifyMu.Unlock()
+	l.dirty = true
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// wait returns a channel that is closed by the next change.
+func (l *changeLog) wait() <-chan struct{} {
+	l.notifyMu.Lock()
+	defer l.notifyMu.Unlock()
+	return l.changed
+}
+
+// update brings the log up to date and returns the current generation.
+func (l *changeLog) update(mt *mountTable) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.notifyMu.Lock()
+	dirty := l.dirty
+	l.dirty = false
+	l.notifyMu.Unlock()
+	now := mt.slm.clock.Now()
+	if !dirty && (l.nextExpiry.IsZero() || now.Before(l.nextExpiry)) {
+		return l.gen
+	}
+	nodes, nextExpiry := mt.watchedNodes(now)
+	l.nextExpiry = nextExpiry
+	if l.nodes == nil {
+		l.nodes = nodes
+		return l.gen
+	}
+	changed := make(map[string]bool)
+	for name, n := range nodes {
+		if o, ok := l.nodes[name]; !ok || o != n {
+			changed[name] = true
+			if o.perms != n.perms || o.version != n.version {
+				markDescendants(changed, name, l.nodes, nodes)
+			}
+		}
+	}
+	for name, o := range l.nodes {
+		if _, ok := nodes[name]; !ok {
+			changed[name] = true
+			if o.perms {
+				markDescendants(changed, name, l.nodes, nodes)
+			}
+		}
+	}
+	l.nodes = nodes
+	if len(changed) == 0 {
+		return l.gen
+	}
+	l.gen++
+	for name := range changed {
+		l.log = append(l.log, watchLogEntry{l.gen, name})
+	}
+	for len(l.log) > maxWatchLog {
+		l.base = l.log[0].gen
+		for len(l.log) > 0 && l.log[0].gen <= l.base {
+			l.log = l.log[1:]
+		}
+	}
+	return l.gen
+}
+
+// markDescendants marks the names below name as changed since a change to
+// the permissions of name may change what can be seen below it.
+func markDescendants(changed map[string]bool, name string, maps ...map[string]watchedNode) {
+	for _, m := range maps {
+		for n := range m {
+			if isDescendant(n, name) {
+				changed[n] = true
+			}
+		}
+	}
+}
+
+func isDescendant(name, ancestor string) bool {
+	return ancestor == "" || strings.HasPrefix(name, ancestor+"/")
+}
+
+// changedSince returns the names that changed after generation gen.
+func (l *changeLog) changedSince(gen uint64) map[string]bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	names := make(map[string]bool)
+	for i := len(l.log) - 1; i >= 0 && l.log[i].gen > gen; i-- {
+		names[l.log[i].name] = true
+	}
+	return names
+}
+
+func (l *changeLog) marker(gen uint64) watch.ResumeMarker {
+	return watch.ResumeMarker(fmt.Sprintf("%s:%d", l.id, gen))
+}
+
+// parseMarker returns the generation encoded in marker, or false if the
+// changes since that generation are unknown.
+func (l *changeLog) parseMarker(marker watch.ResumeMarker) (uint64, bool) {
+	parts := strings.SplitN(string(marker), ":", 2)
+	if len(parts) != 2 || parts[0] != l.id {
+		return 0, false
+	}
+	gen, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return gen, gen >= l.base && gen <= l.gen
+}
+
+// watchedNodes returns the state of every node in the tree, and the time
+// at which the first of the mounted servers expires.
+func (mt *mountTable) watchedNodes(now time.Time) (map[string]watchedNode, time.Time) {
+	nodes := make(map[string]watchedNode)
+	var nextExpiry time.Time
+	var walk func(n *node, name string)
+	walk = func(n *node, name string) {
+		n.Lock()
+		var wn watchedNode
+		if n.explicitPermissions && n.vPerms != nil {
+			wn.perms, wn.version = true, n.vPerms.V
+		}
+		if n.mount != nil {
+			wn.mt, wn.leaf = n.mount.mt, n.mount.leaf
+			var servers []string
+			for _, s := range n.mount.servers.copyToSlice() {
+				if !now.Before(s.Deadline.Time) {
+					continue
+				}
+				servers = append(servers, s.Server)
+				if nextExpiry.IsZero() || s.Deadline.Time.Before(nextExpiry) {
+					nextExpiry = s.Deadline.Time
+				}
+			}
+			sort.Strings(servers)
+			wn.servers = strings.Join(servers, " ")
+		}
+		nodes[name] = wn
+		children := make(map[string]*node, len(n.children))
+		for k, c := range n.children {
+			children[k] = c
+		}
+		n.Unlock()
+		for k, c := range children {
+			walk(c, path.Join(name, k))
+		}
+	}
+	walk(mt.root, "")
+	return nodes, nextExpiry
+}
+
+// globCollector is an rpc.GlobServerCall that remembers the entries sent on
+// it.
+type globCollector struct {
+	rpc.ServerCall
+	entries map[string]naming.MountEntry
+}
+
+func (c *globCollector) SendStream() interface {
+	Send(reply naming.GlobReply) error
+} {
+	return c
+}
+
+func (c *globCollector) Send(reply naming.GlobReply) error {
+	if e, ok := reply.(naming.GlobReplyEntry); ok {
+		c.entries[e.Value.Name] = e.Value
+	}
+	return nil
+}
+
+// sameEntry returns true if a and b differ in no more than the server
+// deadlines.
+func sameEntry(a, b naming.MountEntry) bool {
+	if a.ServesMountTable != b.ServesMountTable || a.IsLeaf != b.IsLeaf || len(a.Servers) != len(b.Servers) {
+		return false
+	}
+	servers := make(map[string]bool, len(a.Servers))
+	for _, s := range a.Servers {
+		servers[s.Server] = true
+	}
+	for _, s := range b.Servers {
+		if !servers[s.Server] {
+			return false
+		}
+	}
+	return true
+}
+
+// matches returns true if the relative name matches g.
+func matches(g *glob.Glob, name string) bool {
+	if name != "" {
+		for _, elem := range strings.Split(name, "/") {
+			if !g.Head().Match(elem) {
+				return false
+			}
+			g = g.Tail()
+		}
+	}
+	return g.Len() == 0
+}
+
+// WatchGlob streams changes to the names below the receiver that match the
+// pattern in req.  The Value of each change is the naming.MountEntry that
+// Glob would return for the name.  An entry is sent when servers are
+// mounted or unmounted, when mounts expire and when permissions change,
+// since those may change what the caller can see; servers refreshing
+// their mounts do not generate changes.
+//
+// The initial state, or the changes since the resume marker, are sent as a
+// single atomic group which always includes the receiver itself, i.e., the
+// name "".
+//
+// Each change other than a refresh makes changeLog.update walk the whole
+// tree, and then every watcher run a full glob of its pattern, so the cost
+// of a change grows with both the size of the tree and the number of
+// watchers.
+func (ms *mountContext) WatchGlob(ctx *context.T, call watch.GlobWatcherWatchGlobServerCall, req watch.GlobRequest) error {
+	if ms.logLevel >= 1 {
+		ctx.Infof("********************* WatchGlob %q, %q", ms.name, req.Pattern)
+	} else {
+		ctx.VI(2).Infof("********************* WatchGlob %q, %q", ms.name, req.Pattern)
+	}
+	g, err := glob.Parse(req.Pattern)
+	if err != nil {
+		return verror.ErrBadArg.Errorf(ctx, "bad pattern %q: %v", req.Pattern, err)
+	}
+	mt, cc := ms.newCallContext(ctx, call.Security(), !createMissingNodes)
+	changes := mt.changes
+	wait := changes.wait()
+	gen := changes.update(mt)
+
+	var initial []watch.Change
+	var sent map[string]naming.MountEntry
+	switch marker := string(req.ResumeMarker); marker {
+	case nowMarker:
+		sent = ms.watchView(cc, call, g)
+		initial = []watch.Change{{Name: "", State: watch.InitialStateSkipped}}
+	default:
+		var since map[string]bool
+		if marker != "" {
+			from, ok := changes.parseMarker(req.ResumeMarker)
+			if !ok {
+				return watch.ErrorfUnknownResumeMarker(ctx, "unknown resume marker")
+			}
+			since = make(map[string]bool)
+			for name := range changes.changedSince(from) {
+				if rel, ok := ms.relativeName(name); ok && matches(g, rel) {
+					since[rel] = true
+				}
+			}
+		}
+		sent = ms.watchView(cc, call, g)
+		for _, name := range sortedNames(sent) {
+			if since == nil || since[name] {
+				initial = append(initial, existsChange(name, sent[name]))
+			}
+		}
+		for _, name := range sortedKeys(since) {
+			if _, ok := sent[name]; !ok {
+				initial = append(initial, watch.Change{Name: name, State: watch.DoesNotExist})
+			}
+		}
+		if !hasRoot(initial) {
+			// The receiver is always reported, even if it doesn't match.
+			if e, ok := ms.watchView(cc, call, &glob.Glob{})[""]; ok {
+				initial = append(initial, existsChange("", e))
+			} else {
+				initial = append(initial, watch.Change{Name: "", State: watch.DoesNotExist})
+			}
+		}
+	}
+	if err := ms.sendChanges(call, changes.marker(gen), initial); err != nil {
+		return err
+	}
+
+	for {
+		var expiry timekeeper.Timer
+		var expired <-chan time.Time
+		if d, ok := nextDeadline(sent); ok {
+			expiry = mt.slm.clock.NewTimer(d.Sub(mt.slm.clock.Now()) + time.Millisecond)
+			expired = expiry.C()
+		}
+		stopped := false
+		select {
+		case <-wait:
+		case <-expired:
+		case <-ctx.Done():
+			stopped = true
+		case <-changes.done:
+			stopped = true
+		}
+		if expiry != nil {
+			expiry.Stop()
+		}
+		if stopped {
+			return nil
+		}
+		wait = changes.wait()
+		from := gen
+		gen = changes.update(mt)
+		current := ms.watchView(cc, call, g)
+		var updates []watch.Change
+		for _, name := range sortedNames(current) {
+			if old, ok := sent[name]; !ok || !sameEntry(old, current[name]) {
+				updates = append(updates, existsChange(name, current[name]))
+			}
+		}
+		for _, name := range sortedNames(sent) {
+			if _, ok := current[name]; !ok {
+				updates = append(updates, watch.Change{Name: name, State: watch.DoesNotExist})
+			}
+		}
+		if gen != from {
+			// Permissions changes don't show in the entries themselves.
+			for _, name := range sortedKeys(changes.changedSince(from)) {
+				rel, ok := ms.relativeName(name)
+				if !ok {
+					continue
+				}
+				old, wasSent := sent[rel]
+				if e, ok := current[rel]; ok && wasSent && sameEntry(old, e) {
+					updates = append(updates, existsChange(rel, current[rel]))
+				}
+			}
+		}
+		sent = current
+		if len(updates) == 0 {
+			continue
+		}
+		if err := ms.sendChanges(call, changes.marker(gen), updates); err != nil {
+			return err
+		}
+	}
+}
+
+// watchView returns what a Glob of g by the caller would return.
+func (ms *mountContext) watchView(cc *callContext, call rpc.ServerCall, g *glob.Glob) map[string]naming.MountEntry {
+	gc := &globCollector{ServerCall: call, entries: make(map[string]naming.MountEntry)}
+	ms.glob(cc, g, gc)
+	return gc.entries
+}
+
+// relativeName returns name relative to the receiver, or false if name is
+// not below the receiver.
+func (ms *mountContext) relativeName(name string) (string, bool) {
+	switch {
+	case name == ms.name:
+		return "", true
+	case ms.name == "":
+		return name, true
+	case strings.HasPrefix(name, ms.name+"/"):
+		return name[len(ms.name)+1:], true
+	}
+	return "", false
+}
+
+// sendChanges sends changes as a single atomic group.
+func (ms *mountContext) sendChanges(call watch.GlobWatcherWatchGlobServerCall, marker watch.ResumeMarker, changes []watch.Change) error {
+	for i := range changes {
+		changes[i].ResumeMarker = marker
+		changes[i].Continued = i < len(changes)-1
+		if err := call.SendStream().Send(changes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasRoot(changes []watch.Change) bool {
+	for _, c := range changes {
+		if c.Name == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func existsChange(name string, entry naming.MountEntry) watch.Change {
+	return watch.Change{Name: name, State: watch.Exists, Value: vom.RawBytesOf(entry)}
+}
+
+// nextDeadline returns the earliest deadline of the servers in entries.
+func nextDeadline(entries map[string]naming.MountEntry) (time.Time, bool) {
+	var next time.Time
+	for _, e := range entries {
+		for _, s := range e.Servers {
+			if next.IsZero() || s.Deadline.Time.Before(next) {
+				next = s.Deadline.Time
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func sortedNames(entries map[string]naming.MountEntry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
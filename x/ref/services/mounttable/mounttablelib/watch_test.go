@@ -0,0 +1,233 @@
+// Copyright 2022 The Vanadium Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mounttablelib_test
+
+import (
+	"testing"
+	"time"
+
+	"v.io/v23/context"
+	"v.io/v23/naming"
+	"v.io/v23/options"
+	"v.io/v23/security"
+	"v.io/v23/security/access"
+	"v.io/v23/services/watch"
+	"v.io/v23/verror"
+	"v.io/x/ref/services/mounttable/mounttablelib"
+)
+
+type watcher struct {
+	changes chan watch.Change
+	done    chan error
+	cancel  func()
+}
+
+func startWatch(t *testing.T, ctx *context.T, ep, suffix, pattern string, marker watch.ResumeMarker) *watcher {
+	ctx, cancel := context.WithCancel(ctx)
+	name := naming.JoinAddressName(ep, suffix)
+	call, err := mounttablelib.WatchableMountTableClient(name).WatchGlob(ctx, watch.GlobRequest{Pattern: pattern, ResumeMarker: marker}, options.Preresolved{})
+	if err != nil {
+		cancel()
+		boom(t, "WatchGlob %v: %v", name, err)
+	}
+	w := &watcher{changes: make(chan watch.Change, 100), done: make(chan error, 1), cancel: cancel}
+	go func() {
+		stream := call.RecvStream()
+		for stream.Advance() {
+			w.changes <- stream.Value()
+		}
+		w.done <- call.Finish()
+	}()
+	return w
+}
+
+// nextGroup returns the next atomic group of changes keyed by name, and the
+// resume marker of the group.
+func (w *watcher) nextGroup(t *testing.T) (map[string]watch.Change, watch.ResumeMarker) {
+	group := make(map[string]watch.Change)
+	for {
+		select {
+		case c := <-w.changes:
+			group[c.Name] = c
+			if !c.Continued {
+				return group, c.ResumeMarker
+			}
+		case err := <-w.done:
+			boom(t, "watch ended: %v", err)
+		case <-time.After(30 * time.Second):
+			boom(t, "timed out waiting for changes, got %v", group)
+		}
+	}
+}
+
+// checkChanges checks that the only names in group other than "" are those
+// in want, with the servers mounted on them, or nil if they don't exist.
+func checkChanges(t *testing.T, group map[string]watch.Change, want map[string][]string) {
+	for name, c := range group {
+		if name == "" {
+			continue
+		}
+		servers, ok := want[name]
+		if !ok {
+			boom(t, "unexpected change for %q: %v", name, c)
+		}
+		if servers == nil {
+			if c.State != watch.DoesNotExist {
+				boom(t, "%q: got state %v, want %v", name, c.State, watch.DoesNotExist)
+			}
+			continue
+		}
+		if c.State != watch.Exists {
+			boom(t, "%q: got state %v, want %v", name, c.State, watch.Exists)
+		}
+		var entry naming.MountEntry
+		if err := c.Value.ToValue(&entry); err != nil {
+			boom(t, "%q: %v", name, err)
+		}
+		var got []string
+		for _, s := range entry.Servers {
+			got = append(got, s.Server)
+		}
+		checkMatch(t, servers, got)
+	}
+	for name := range want {
+		if _, ok := group[name]; !ok {
+			boom(t, "missing change for %q in %v", name, group)
+		}
+	}
+}
+
+func TestWatchGlob(t *testing.T) {
+	rootCtx, aliceCtx, _, shutdown := initTest()
+	defer shutdown()
+
+	stop, estr, clock := newMT(t, "", "", "testWatchGlob", rootCtx)
+	defer stop()
+
+	doMount(t, rootCtx, estr, "a/b", "/127.0.0.1:1111", true)
+
+	// The initial state.
+	w := startWatch(t, rootCtx, estr, "", "a/*", nil)
+	defer w.cancel()
+	group, _ := w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/b": {"/127.0.0.1:1111"}})
+	if _, ok := group[""]; !ok {
+		boom(t, "no change for the root in %v", group)
+	}
+
+	// Mounts, unmounts and permissions changes.
+	doMount(t, rootCtx, estr, "a/c", "/127.0.0.1:2222", true)
+	group, _ = w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/c": {"/127.0.0.1:2222"}})
+	doMount(t, rootCtx, estr, "a/c", "/127.0.0.1:3333", true)
+	group, _ = w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/c": {"/127.0.0.1:2222", "/127.0.0.1:3333"}})
+	doUnmount(t, rootCtx, estr, "a/b", "", true)
+	group, _ = w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/b": nil})
+	perms := access.Permissions{
+		"Admin": access.AccessList{In: []security.BlessingPattern{"root"}},
+		"Read":  access.AccessList{In: []security.BlessingPattern{security.AllPrincipals}},
+	}
+	doSetPermissions(t, rootCtx, estr, "a/c", perms, "", true)
+	group, _ = w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/c": {"/127.0.0.1:2222", "/127.0.0.1:3333"}})
+
+	// Refreshing a mount is not a change, but expiry is.
+	doMount(t, rootCtx, estr, "a/d", "/127.0.0.1:4444", true)
+	group, _ = w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/d": {"/127.0.0.1:4444"}})
+	clock.AdvanceTime(time.Duration(ttlSecs/2) * time.Second)
+	doMount(t, rootCtx, estr, "a/d", "/127.0.0.1:4444", true)
+	clock.AdvanceTime(time.Duration(ttlSecs/2+4) * time.Second)
+	group, marker := w.nextGroup(t)
+	// a/c remains since its permissions were set.
+	checkChanges(t, group, map[string][]string{"a/c": {}})
+	w.cancel()
+
+	// Resuming only sends what changed since the marker.
+	doMount(t, rootCtx, estr, "a/e", "/127.0.0.1:5555", true)
+	w = startWatch(t, rootCtx, estr, "", "a/*", marker)
+	defer w.cancel()
+	group, _ = w.nextGroup(t)
+	if _, ok := group["a/d"]; ok {
+		boom(t, "unchanged name sent after resuming: %v", group)
+	}
+	if c := group["a/e"]; c.State != watch.Exists {
+		boom(t, "missing change for a/e in %v", group)
+	}
+
+	// "now" skips the initial state.
+	w2 := startWatch(t, rootCtx, estr, "a", "*", watch.ResumeMarker("now"))
+	defer w2.cancel()
+	group, _ = w2.nextGroup(t)
+	if len(group) != 1 || group[""].State != watch.InitialStateSkipped {
+		boom(t, "unexpected initial state: %v", group)
+	}
+	doUnmount(t, rootCtx, estr, "a/e", "", true)
+	group, _ = w2.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"e": nil})
+
+	// Watchers only see what they could Glob.
+	w3 := startWatch(t, aliceCtx, estr, "", "...", nil)
+	defer w3.cancel()
+	group, _ = w3.nextGroup(t)
+	if _, ok := group["a/d"]; !ok {
+		boom(t, "alice can't see a/d: %v", group)
+	}
+	doSetPermissions(t, rootCtx, estr, "a", access.Permissions{"Admin": access.AccessList{In: []security.BlessingPattern{"root"}}}, "", true)
+	group, _ = w3.nextGroup(t)
+	if c, ok := group["a/d"]; !ok || c.State != watch.DoesNotExist {
+		boom(t, "alice can still see a/d: %v", group)
+	}
+
+	// Unknown markers are rejected.
+	w4 := startWatch(t, rootCtx, estr, "", "a/*", watch.ResumeMarker("bogus:1"))
+	defer w4.cancel()
+	select {
+	case err := <-w4.done:
+		if verror.ErrorID(err) != watch.ErrUnknownResumeMarker.ID {
+			boom(t, "got %v, want %v", err, watch.ErrUnknownResumeMarker.ID)
+		}
+	case <-time.After(30 * time.Second):
+		boom(t, "timed out waiting for the watch to fail")
+	}
+}
+
+func TestWatchGlobRefresh(t *testing.T) {
+	rootCtx, _, _, shutdown := initTest()
+	defer shutdown()
+
+	stop, estr, clock := newMT(t, "", "", "testWatchGlobRefresh", rootCtx)
+	defer stop()
+
+	// Each time the watcher wakes up it sets a timer for the next expiry,
+	// so the timers requested show how often it has been woken.
+	nextRequest := func() {
+		select {
+		case <-clock.Requests():
+		case <-time.After(30 * time.Second):
+			boom(t, "timed out waiting for the watcher to set a timer")
+		}
+	}
+	doMount(t, rootCtx, estr, "a/b", "/127.0.0.1:1111", true)
+	w := startWatch(t, rootCtx, estr, "", "a/*", nil)
+	defer w.cancel()
+	w.nextGroup(t)
+	nextRequest()
+
+	// Refreshing the mount doesn't wake the watcher, mounting another
+	// server does.
+	doMount(t, rootCtx, estr, "a/b", "/127.0.0.1:1111", true)
+	doMount(t, rootCtx, estr, "a/c", "/127.0.0.1:2222", true)
+	group, _ := w.nextGroup(t)
+	checkChanges(t, group, map[string][]string{"a/c": {"/127.0.0.1:2222"}})
+	nextRequest()
+	select {
+	case d := <-clock.Requests():
+		boom(t, "the watcher was woken by the refresh and set a timer for %v", d)
+	default:
+	}
+}
@@ -19,8 +19,8 @@ type ManualTime interface {
 	timekeeper.TimeKeeper
 	// AdvanceTime advances the current time by d.
 	AdvanceTime(d time.Duration)
-	// Requests provides a channel where the requested delays for After and
-	// Sleep can be observed.
+	// Requests provides a channel where the requested delays for After,
+	// NewTimer and Sleep can be observed.
 	Requests() <-chan time.Duration
 }
 
@@ -29,8 +29,10 @@ type ManualTime interface {
 // duration).  As current time advances, items are plucked from the heap and the
 // clients are notified.
 type item struct {
-	t  time.Time        // Wake-up time.
-	ch chan<- time.Time // Client notification channel.
+	t    time.Time      // Wake-up time.
+	ch   chan time.Time // Client notification channel.
+	mt   *manualTime    // The time keeper whose heap the item is in.
+	done bool           // True once the item has been woken up or stopped.
 }
 
 type timeHeap []*item
@@ -68,16 +70,37 @@ type manualTime struct {
 
 // After implements TimeKeeper.After.
 func (mt *manualTime) After(d time.Duration) <-chan time.Time {
+	return mt.NewTimer(d).C()
+}
+
+// NewTimer implements TimeKeeper.NewTimer.
+func (mt *manualTime) NewTimer(d time.Duration) timekeeper.Timer {
 	defer mt.Unlock()
 	mt.Lock()
-	ch := make(chan time.Time, 1)
+	it := &item{t: mt.current.Add(d), ch: make(chan time.Time, 1), mt: mt}
 	if d <= 0 {
-		ch <- mt.current
+		it.ch <- mt.current
+		it.done = true
 	} else {
-		heap.Push(&mt.schedule, &item{t: mt.current.Add(d), ch: ch})
+		heap.Push(&mt.schedule, it)
 	}
 	mt.requests <- d
-	return ch
+	return it
+}
+
+// C implements timekeeper.Timer.C.
+func (it *item) C() <-chan time.Time {
+	return it.ch
+}
+
+// Stop implements timekeeper.Timer.Stop.  A stopped item is left in the heap
+// until its wake-up time.
+func (it *item) Stop() bool {
+	defer it.mt.Unlock()
+	it.mt.Lock()
+	stopped := !it.done
+	it.done = true
+	return stopped
 }
 
 // Sleep implements TimeKeeper.Sleep.
@@ -100,7 +123,10 @@ func (mt *manualTime) AdvanceTime(d time.Duration) {
 		if top.t.After(mt.current) {
 			break
 		}
-		top.ch <- mt.current
+		if !top.done {
+			top.ch <- mt.current
+			top.done = true
+		}
 		heap.Pop(&mt.schedule)
 	}
 }
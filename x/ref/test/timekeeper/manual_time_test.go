@@ -117,3 +117,27 @@ func TestBlocking(t *testing.T) {
 	mt.AdvanceTime(5 * time.Second)
 	<-sync
 }
+
+func TestNewTimer(t *testing.T) {
+	mt := NewManualTime()
+	t1 := mt.NewTimer(5 * time.Second)
+	t2 := mt.NewTimer(3 * time.Second)
+	expectRequest(t, mt.Requests(), 5*time.Second)
+	expectRequest(t, mt.Requests(), 3*time.Second)
+
+	if !t2.Stop() {
+		t.Errorf("Expected Stop to stop the timer")
+	}
+	mt.AdvanceTime(4 * time.Second)
+	checkNotReady(t, t1.C())
+	checkNotReady(t, t2.C())
+	if t2.Stop() {
+		t.Errorf("Expected Stop to find the timer stopped")
+	}
+
+	mt.AdvanceTime(time.Second)
+	checkReady(t, t1.C())
+	if t1.Stop() {
+		t.Errorf("Expected Stop to find the timer fired")
+	}
+}